@@ -0,0 +1,620 @@
+package inventory
+
+import (
+	"crypto/sha1"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// csv detects the decimal separator from the amounts, the csv_decimal_*
+// formats name it
+const (
+	BankStatementFormatCSV             = "csv"
+	BankStatementFormatCSVDecimalPoint = "csv_decimal_point"
+	BankStatementFormatCSVDecimalComma = "csv_decimal_comma"
+	BankStatementFormatOFX             = "ofx"
+	BankStatementFormatCAMT053         = "camt053"
+)
+
+var ErrEmptyStatement = errors.New("statement has no lines")
+var ErrAmbiguousDecimalSeparator = errors.New("ambiguous decimal separator")
+var ErrStatementCurrencyMismatch = errors.New("statement currency differs from line currency")
+
+// amounts are seen from our side: positive is money coming into the account,
+// the same sign as a debit on the cash account
+type BankStatement struct {
+	ID             int
+	UUID           uuid.UUID
+	Account        *Account
+	Format         string
+	Currency       string
+	OpeningBalance Decimal
+	ClosingBalance Decimal
+	HasBalances    bool
+	FromDatetimeMs int64
+	ToDatetimeMs   int64
+	Lines          []*BankStatementLine
+	Skipped        int
+}
+
+type BankStatementLine struct {
+	ID              int
+	UUID            uuid.UUID
+	ExternalID      string
+	DatetimeMs      int64
+	Amount          Decimal
+	Currency        string
+	Reference       string
+	Description     string
+	TransactionLine *TransactionLine
+}
+
+func ParseBankStatement(r io.Reader, format string) (*BankStatement, error) {
+	var st *BankStatement
+	var err error
+	switch format {
+	case BankStatementFormatCSV:
+		st, err = parseBankStatementCSV(r, 0)
+	case BankStatementFormatCSVDecimalPoint:
+		st, err = parseBankStatementCSV(r, '.')
+	case BankStatementFormatCSVDecimalComma:
+		st, err = parseBankStatementCSV(r, ',')
+	case BankStatementFormatOFX:
+		st, err = parseBankStatementOFX(r)
+	case BankStatementFormatCAMT053:
+		st, err = parseBankStatementCAMT053(r)
+	default:
+		return nil, ErrUnknownImportFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(st.Lines) == 0 {
+		return nil, ErrEmptyStatement
+	}
+	st.Format = format
+	st.FromDatetimeMs, st.ToDatetimeMs = st.Lines[0].DatetimeMs, st.Lines[0].DatetimeMs
+	for _, l := range st.Lines {
+		st.FromDatetimeMs = min(st.FromDatetimeMs, l.DatetimeMs)
+		st.ToDatetimeMs = max(st.ToDatetimeMs, l.DatetimeMs)
+		if l.Currency == "" {
+			l.Currency = st.Currency
+		} else if st.Currency == "" {
+			st.Currency = l.Currency
+		} else if l.Currency != st.Currency {
+			return nil, fmt.Errorf("%s %s: %w", l.Reference, l.Currency, ErrStatementCurrencyMismatch)
+		}
+	}
+	return st, nil
+}
+
+// csv lines have no id of their own, the hash of their content plus the
+// occurrence keeps a re-import from adding them twice
+func csvExternalID(l *BankStatementLine, seen map[string]int) string {
+	key := fmt.Sprintf("%d|%d|%s|%s", l.DatetimeMs, l.Amount.Data, l.Reference, l.Description)
+	seen[key]++
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s|%d", key, seen[key])))
+	return "csv:" + hex.EncodeToString(sum[:8])
+}
+
+// decides between 1,234.56 and 1.234,56 from the amounts of a file. an amount
+// like 1,234 reads either way, so it needs another amount that doesn't.
+func detectDecimalSeparator(amounts []string) (byte, error) {
+	point, comma, ambiguous := false, false, false
+	for _, str := range amounts {
+		lastPoint, lastComma := strings.LastIndexByte(str, '.'), strings.LastIndexByte(str, ',')
+		if lastPoint < 0 && lastComma < 0 {
+			continue
+		}
+		if lastPoint >= 0 && lastComma >= 0 {
+			point, comma = point || lastPoint > lastComma, comma || lastComma > lastPoint
+			continue
+		}
+		i := max(lastPoint, lastComma)
+		sep := str[i]
+		intDigits := strings.TrimLeft(str[:i], "+-")
+		switch {
+		case strings.Count(str, string(sep)) > 1:
+			// only thousands repeat, 1.234.567
+			point, comma = point || sep == ',', comma || sep == '.'
+		case len(str)-i-1 != 3 || len(intDigits) > 3 || intDigits == "0" || intDigits == "":
+			point, comma = point || sep == '.', comma || sep == ','
+		default:
+			ambiguous = true
+		}
+	}
+	switch {
+	case point && comma:
+		return 0, ErrAmbiguousDecimalSeparator
+	case point:
+		return '.', nil
+	case comma:
+		return ',', nil
+	case ambiguous:
+		return 0, ErrAmbiguousDecimalSeparator
+	}
+	return '.', nil
+}
+
+// drops the thousands separator and makes decimalSep a point
+func parseAmount(str string, decimalSep byte) (Decimal, error) {
+	if str == "" {
+		return NewDecimal(0), nil
+	}
+	thousandsSep := ","
+	if decimalSep == ',' {
+		thousandsSep = "."
+	}
+	str = strings.ReplaceAll(str, thousandsSep, "")
+	if strings.Count(str, string(decimalSep)) > 1 {
+		return NewDecimal(0), ErrInvalidDecimal
+	}
+	return ParseDecimal(strings.Replace(str, string(decimalSep), ".", 1))
+}
+
+// header row with date, amount (or debit and credit), and optionally
+// reference, description, currency and balance, in any order. decimalSep 0
+// detects it.
+func parseBankStatementCSV(r io.Reader, decimalSep byte) (*BankStatement, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	cols := map[string]int{}
+	for i, h := range header {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := cols["date"]; !ok {
+		return nil, fmt.Errorf("%w: date", ErrMissingColumn)
+	}
+	_, hasAmount := cols["amount"]
+	_, hasDebit := cols["debit"]
+	_, hasCredit := cols["credit"]
+	if !hasAmount && !(hasDebit && hasCredit) {
+		return nil, fmt.Errorf("%w: amount or debit and credit", ErrMissingColumn)
+	}
+	_, hasBalance := cols["balance"]
+	get := func(row []string, col string) string {
+		i, ok := cols[col]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+	if decimalSep == 0 {
+		var amounts []string
+		for _, record := range records {
+			for _, col := range []string{"amount", "debit", "credit", "balance"} {
+				amounts = append(amounts, get(record, col))
+			}
+		}
+		decimalSep, err = detectDecimalSeparator(amounts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	amount := func(str string) (Decimal, error) {
+		return parseAmount(str, decimalSep)
+	}
+
+	st := &BankStatement{}
+	seen := map[string]int{}
+	for i, record := range records {
+		row := i + 2
+		l := &BankStatementLine{
+			Reference:   get(record, "reference"),
+			Description: get(record, "description"),
+			Currency:    get(record, "currency"),
+		}
+		l.DatetimeMs, err = ParseDatetimeMs(get(record, "date"))
+		if err != nil {
+			return nil, fmt.Errorf("row %d date: %w", row, err)
+		}
+		if hasAmount {
+			l.Amount, err = amount(get(record, "amount"))
+			if err != nil {
+				return nil, fmt.Errorf("row %d amount: %w", row, err)
+			}
+		} else {
+			// debit and credit are the bank's view, a bank credit is money in
+			debit, err := amount(get(record, "debit"))
+			if err != nil {
+				return nil, fmt.Errorf("row %d debit: %w", row, err)
+			}
+			credit, err := amount(get(record, "credit"))
+			if err != nil {
+				return nil, fmt.Errorf("row %d credit: %w", row, err)
+			}
+			l.Amount = NewDecimal(credit.Data - debit.Data)
+		}
+		if hasBalance {
+			balance, err := amount(get(record, "balance"))
+			if err != nil {
+				return nil, fmt.Errorf("row %d balance: %w", row, err)
+			}
+			if len(st.Lines) == 0 {
+				st.OpeningBalance = NewDecimal(balance.Data - l.Amount.Data)
+			}
+			st.ClosingBalance = balance
+			st.HasBalances = true
+		}
+		l.ExternalID = csvExternalID(l, seen)
+		st.Lines = append(st.Lines, l)
+	}
+	return st, nil
+}
+
+// YYYYMMDD[HHMMSS[.XXX]][[offset:TZ]], local time when no offset is given
+func parseOFXDate(str string) (int64, error) {
+	str = strings.TrimSpace(str)
+	loc := time.Local
+	if i := strings.Index(str, "["); i >= 0 {
+		tz := strings.TrimSuffix(str[i+1:], "]")
+		str = str[:i]
+		offset := strings.SplitN(tz, ":", 2)[0]
+		var hours float64
+		if _, err := fmt.Sscanf(offset, "%g", &hours); err == nil {
+			loc = time.FixedZone(tz, int(hours*3600))
+		}
+	}
+	if i := strings.Index(str, "."); i >= 0 {
+		str = str[:i]
+	}
+	layout := "20060102150405"
+	if len(str) < len(layout) {
+		layout = layout[:len(str)]
+	}
+	t, err := time.ParseInLocation(layout, str, loc)
+	if err != nil {
+		return 0, err
+	}
+	return t.UnixMilli(), nil
+}
+
+// handles both the SGML flavour of OFX 1.x, where leaf elements aren't
+// closed, and the XML of OFX 2.x
+func parseBankStatementOFX(r io.Reader) (*BankStatement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	body := string(data)
+	if i := strings.Index(strings.ToUpper(body), "<OFX>"); i >= 0 {
+		body = body[i:]
+	}
+
+	st := &BankStatement{}
+	var trn map[string]string
+	var inLedgerBal bool
+	var ledgerBal map[string]string
+	finish := func() error {
+		if trn == nil {
+			return nil
+		}
+		l := &BankStatementLine{
+			ExternalID:  trn["FITID"],
+			Reference:   trn["REFNUM"],
+			Description: strings.TrimSpace(trn["NAME"] + " " + trn["MEMO"]),
+			Currency:    trn["CURRENCY"],
+		}
+		if l.Reference == "" {
+			l.Reference = trn["CHECKNUM"]
+		}
+		if l.ExternalID != "" {
+			l.ExternalID = "ofx:" + l.ExternalID
+		}
+		var err error
+		l.DatetimeMs, err = parseOFXDate(trn["DTPOSTED"])
+		if err != nil {
+			return fmt.Errorf("transaction %s date: %w", trn["FITID"], err)
+		}
+		l.Amount, err = ParseDecimal(trn["TRNAMT"])
+		if err != nil {
+			return fmt.Errorf("transaction %s amount: %w", trn["FITID"], err)
+		}
+		st.Lines = append(st.Lines, l)
+		trn = nil
+		return nil
+	}
+
+	for len(body) > 0 {
+		start := strings.Index(body, "<")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(body[start:], ">")
+		if end < 0 {
+			break
+		}
+		tag := strings.ToUpper(strings.TrimSpace(body[start+1 : start+end]))
+		body = body[start+end+1:]
+		value := body
+		if next := strings.Index(body, "<"); next >= 0 {
+			value = body[:next]
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case tag == "STMTTRN":
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			trn = map[string]string{}
+		case tag == "/STMTTRN" || tag == "/BANKTRANLIST":
+			if err := finish(); err != nil {
+				return nil, err
+			}
+		case tag == "LEDGERBAL":
+			inLedgerBal = true
+			ledgerBal = map[string]string{}
+		case tag == "/LEDGERBAL":
+			inLedgerBal = false
+		case tag == "CURDEF":
+			st.Currency = value
+		case strings.HasPrefix(tag, "/") || strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!"):
+		case inLedgerBal:
+			ledgerBal[tag] = value
+		case trn != nil:
+			trn[tag] = value
+		}
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+
+	if balance, ok := ledgerBal["BALAMT"]; ok {
+		st.ClosingBalance, err = ParseDecimal(balance)
+		if err != nil {
+			return nil, fmt.Errorf("ledger balance: %w", err)
+		}
+		sum := int64(0)
+		for _, l := range st.Lines {
+			sum += l.Amount.Data
+		}
+		st.OpeningBalance = NewDecimal(st.ClosingBalance.Data - sum)
+		st.HasBalances = true
+	}
+	return st, nil
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type camtDate struct {
+	Dt   string `xml:"Dt"`
+	DtTm string `xml:"DtTm"`
+}
+
+type camtDocument struct {
+	Statements []struct {
+		Balances []struct {
+			Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+			Amount    camtAmount `xml:"Amt"`
+			CdtDbtInd string     `xml:"CdtDbtInd"`
+		} `xml:"Bal"`
+		Entries []struct {
+			Reference   string     `xml:"NtryRef"`
+			Amount      camtAmount `xml:"Amt"`
+			CdtDbtInd   string     `xml:"CdtDbtInd"`
+			BookingDate camtDate   `xml:"BookgDt"`
+			ValueDate   camtDate   `xml:"ValDt"`
+			ServicerRef string     `xml:"AcctSvcrRef"`
+			Info        string     `xml:"AddtlNtryInf"`
+			Details     []struct {
+				EndToEndID  string   `xml:"Refs>EndToEndId"`
+				Unstructed  []string `xml:"RmtInf>Ustrd"`
+				CreditorRef string   `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+			} `xml:"NtryDtls>TxDtls"`
+		} `xml:"Ntry"`
+	} `xml:"BkToCstmrStmt>Stmt"`
+}
+
+func (d camtDate) datetimeMs() (int64, error) {
+	if d.DtTm == "" {
+		return ParseDatetimeMs(d.Dt)
+	}
+	return ParseDatetimeMs(d.DtTm)
+}
+
+// CRDT is money in, DBIT money out
+func (a camtAmount) signed(cdtDbtInd string) (Decimal, error) {
+	amount, err := ParseDecimal(strings.TrimSpace(a.Value))
+	if err != nil {
+		return amount, err
+	}
+	if strings.TrimSpace(cdtDbtInd) == "DBIT" {
+		amount = NewDecimal(-amount.Data)
+	}
+	return amount, nil
+}
+
+// only the first statement of the document is read
+func parseBankStatementCAMT053(r io.Reader) (*BankStatement, error) {
+	var doc camtDocument
+	err := xml.NewDecoder(r).Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Statements) == 0 {
+		return nil, ErrEmptyStatement
+	}
+	stmt := doc.Statements[0]
+
+	st := &BankStatement{}
+	for _, b := range stmt.Balances {
+		amount, err := b.Amount.signed(b.CdtDbtInd)
+		if err != nil {
+			return nil, fmt.Errorf("balance %s: %w", b.Code, err)
+		}
+		switch b.Code {
+		case "OPBD", "PRCD":
+			st.OpeningBalance = amount
+			st.HasBalances = true
+		case "CLBD":
+			st.ClosingBalance = amount
+			st.HasBalances = true
+		}
+		if st.Currency == "" {
+			st.Currency = b.Amount.Currency
+		}
+	}
+
+	for i, e := range stmt.Entries {
+		l := &BankStatementLine{
+			Currency:    e.Amount.Currency,
+			Description: e.Info,
+		}
+		l.Amount, err = e.Amount.signed(e.CdtDbtInd)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d amount: %w", i+1, err)
+		}
+		date := e.BookingDate
+		if date.Dt == "" && date.DtTm == "" {
+			date = e.ValueDate
+		}
+		l.DatetimeMs, err = date.datetimeMs()
+		if err != nil {
+			return nil, fmt.Errorf("entry %d date: %w", i+1, err)
+		}
+		for _, d := range e.Details {
+			if l.Reference == "" && d.EndToEndID != "" && d.EndToEndID != "NOTPROVIDED" {
+				l.Reference = d.EndToEndID
+			}
+			if l.Reference == "" && d.CreditorRef != "" {
+				l.Reference = d.CreditorRef
+			}
+			if l.Description == "" {
+				l.Description = strings.Join(d.Unstructed, " ")
+			}
+		}
+		if l.Reference == "" {
+			l.Reference = e.Reference
+		}
+		if e.ServicerRef != "" {
+			l.ExternalID = "camt:" + e.ServicerRef
+		} else if e.Reference != "" {
+			l.ExternalID = "camt:" + e.Reference
+		}
+		st.Lines = append(st.Lines, l)
+	}
+	return st, nil
+}
+
+// stores the statement for the cash account. lines imported before (same
+// external id) are skipped and counted in Skipped.
+func ImportBankStatement(db *sql.DB, account *Account, r io.Reader, format string) (*BankStatement, error) {
+	st, err := ParseBankStatement(r, format)
+	if err != nil {
+		return nil, err
+	}
+	err = resolveAccount(db, account)
+	if err != nil {
+		return nil, err
+	}
+	st.Account = account
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stUUID, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	res, err := tx.Exec(`INSERT INTO bank_statements (uuid, account_id, format, currency, opening_balance, closing_balance,
+		has_balances, from_datetime_ms, to_datetime_ms, imported_ms) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		stUUID[:], account.ID, st.Format, st.Currency, st.OpeningBalance, st.ClosingBalance,
+		st.HasBalances, st.FromDatetimeMs, st.ToDatetimeMs, time.Now().UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	stID, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	var imported []*BankStatementLine
+	for _, l := range st.Lines {
+		if l.ExternalID != "" {
+			var count int
+			err = tx.QueryRow(`SELECT COUNT(*) FROM bank_statement_lines WHERE account_id=? AND external_id=?`,
+				account.ID, l.ExternalID).Scan(&count)
+			if err != nil {
+				return nil, err
+			}
+			if count > 0 {
+				st.Skipped++
+				continue
+			}
+		}
+		lineUUID, err := uuid.NewV7()
+		if err != nil {
+			return nil, err
+		}
+		res, err = tx.Exec(`INSERT INTO bank_statement_lines (uuid, statement_id, account_id, external_id, datetime_ms,
+			amount, currency, reference, description) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			lineUUID[:], stID, account.ID, sql.NullString{String: l.ExternalID, Valid: l.ExternalID != ""},
+			l.DatetimeMs, l.Amount, l.Currency, l.Reference, l.Description)
+		if err != nil {
+			return nil, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		l.ID = int(id)
+		l.UUID = lineUUID
+		imported = append(imported, l)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	st.ID = int(stID)
+	st.UUID = stUUID
+	st.Lines = imported
+	return st, nil
+}
+
+// format from the extension when empty: .csv, .ofx/.qfx, .xml/.camt for
+// camt.053
+func ImportBankStatementFile(db *sql.DB, account *Account, path string, format string) (*BankStatement, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			format = BankStatementFormatCSV
+		case ".ofx", ".qfx":
+			format = BankStatementFormatOFX
+		case ".xml", ".camt", ".053":
+			format = BankStatementFormatCAMT053
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ImportBankStatement(db, account, f, format)
+}
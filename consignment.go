@@ -0,0 +1,366 @@
+package inventory
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+const SettingConsignmentLiabilityAccount = "consignment_liability_account"
+
+var ErrNoConsignmentLiabilityAccount = errors.New("consignment liability account not set")
+var ErrNotConsignmentAccount = errors.New("account is not a consignment account")
+var ErrConsignmentExceedsStock = errors.New("quantity exceeds consignment stock")
+var ErrInvalidConsignmentQuantity = errors.New("consignment quantity must be positive")
+
+// stock a party leaves with us sits on its own consignment account under
+// assets, against the consignment liability account, at zero cost. it counts
+// as on hand but not in valuation until it is settled into a purchase.
+type ConsignmentAccount struct {
+	Account *Account
+	Party   *Party
+}
+
+type ConsignmentMovement struct {
+	Account    *Account
+	Item       *Item
+	Quantity   Decimal
+	Unit       string
+	DatetimeMs int64
+	Note       string
+}
+
+// moves Quantity from the consignment account to StockAccount and issues a
+// purchase invoice to the party at Price. settle what was drawn before it is
+// consumed so production picks it up at cost.
+type ConsignmentSettlement struct {
+	ID            int
+	UUID          uuid.UUID
+	Account       *Account
+	StockAccount  *Account
+	Item          *Item
+	Quantity      Decimal
+	Unit          string
+	Price         Decimal
+	Currency      string
+	Number        string
+	DatetimeMs    int64
+	DueDatetimeMs int64
+	Invoice       *Invoice
+}
+
+type ConsignmentBalance struct {
+	Party    *Party
+	Account  *Account
+	Item     *Item
+	Quantity Decimal
+	Unit     string
+}
+
+func SetConsignmentLiabilityAccount(db *sql.DB, acc *Account) error {
+	return SetAccountSetting(db, SettingConsignmentLiabilityAccount, acc)
+}
+
+func GetConsignmentLiabilityAccount(db *sql.DB) (*Account, error) {
+	acc, err := GetAccountSetting(db, SettingConsignmentLiabilityAccount)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoConsignmentLiabilityAccount
+	}
+	return acc, err
+}
+
+// marks acc as holding stock on behalf of the party
+func AddConsignmentAccount(db *sql.DB, acc *Account, party *Party) error {
+	if err := resolveAccount(db, acc); err != nil {
+		return err
+	}
+	partyID, err := resolvePartyID(db, party)
+	if err != nil {
+		return err
+	}
+	if !partyID.Valid {
+		return sql.ErrNoRows
+	}
+	_, err = db.Exec(`INSERT INTO consignment_accounts (account_id, party_id) VALUES (?, ?)
+		ON CONFLICT(account_id) DO UPDATE SET party_id=excluded.party_id`, acc.ID, partyID)
+	return err
+}
+
+func GetConsignmentAccounts(db *sql.DB) ([]ConsignmentAccount, error) {
+	rows, err := db.Query(`SELECT account_id, party_id FROM consignment_accounts ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	var ids [][2]int
+	for rows.Next() {
+		var accID, partyID int
+		if err = rows.Scan(&accID, &partyID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, [2]int{accID, partyID})
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var accounts []ConsignmentAccount
+	for _, id := range ids {
+		acc, err := GetAccountByID(db, id[0])
+		if err != nil {
+			return nil, err
+		}
+		party, err := GetPartyByID(db, id[1])
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, ConsignmentAccount{Account: acc, Party: party})
+	}
+	return accounts, nil
+}
+
+// consignment accounts plus the liability account, the ones left out of
+// valuation
+func consignmentAccountIDs(db *sql.DB) (map[int]bool, error) {
+	rows, err := db.Query(`SELECT account_id FROM consignment_accounts`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := map[int]bool{}
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	liability, err := getOptionalAccountSetting(db, SettingConsignmentLiabilityAccount)
+	if err != nil {
+		return nil, err
+	}
+	if liability != nil {
+		ids[liability.ID] = true
+	}
+	return ids, nil
+}
+
+func consignmentParty(db *sql.DB, acc *Account) (*Party, error) {
+	var partyID int
+	err := db.QueryRow(`SELECT party_id FROM consignment_accounts WHERE account_id=?`, acc.ID).Scan(&partyID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%s: %w", acc.Name, ErrNotConsignmentAccount)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return GetPartyByID(db, partyID)
+}
+
+func itemBalance(db *sql.DB, acc *Account, item *Item) (Decimal, error) {
+	qty := NewDecimal(0)
+	err := db.QueryRow(`
+		SELECT h.quantity FROM balance_history h
+		JOIN transactions t ON h.transaction_id = t.id
+		WHERE h.account_id=? AND h.item_id=?
+		ORDER BY t.datetime_ms DESC, h.id DESC LIMIT 1`, acc.ID, item.ID).Scan(&qty)
+	if err == sql.ErrNoRows {
+		return NewDecimal(0), nil
+	}
+	return qty, err
+}
+
+// quantity > 0 receives stock from the party, < 0 hands it back
+func moveConsignment(db *sql.DB, m *ConsignmentMovement, quantity Decimal, description string) ([]byte, error) {
+	if m.Quantity.Data <= 0 {
+		return nil, ErrInvalidConsignmentQuantity
+	}
+	if err := resolveAccount(db, m.Account); err != nil {
+		return nil, err
+	}
+	if _, err := resolveItemID(db, m.Item); err != nil {
+		return nil, err
+	}
+	party, err := consignmentParty(db, m.Account)
+	if err != nil {
+		return nil, err
+	}
+	liability, err := GetConsignmentLiabilityAccount(db)
+	if err != nil {
+		return nil, err
+	}
+	if quantity.Data < 0 {
+		onHand, err := itemBalance(db, m.Account, m.Item)
+		if err != nil {
+			return nil, err
+		}
+		if onHand.Data < m.Quantity.Data {
+			return nil, fmt.Errorf("%s has %s %s: %w", m.Account.Name, onHand.ToString(), m.Item.Name, ErrConsignmentExceedsStock)
+		}
+	}
+	unit := m.Unit
+	if unit == "" {
+		unit = m.Item.Unit
+	}
+	stockLine := CreateInventoryTrLine(m.Account, m.Item, quantity, unit, NewDecimal(0), "")
+	stockLine.Note = m.Note
+	liabilityLine := CreateInventoryTrLine(liability, m.Item, NewDecimal(-quantity.Data), unit, NewDecimal(0), "")
+	liabilityLine.Note = m.Note
+	return ApplyTransaction(db, &Transaction{
+		Description:      fmt.Sprintf("%s %s from %s", description, m.Item.Name, party.Name),
+		DatetimeMs:       m.DatetimeMs,
+		Party:            party,
+		TransactionLines: []*TransactionLine{stockLine, liabilityLine},
+	})
+}
+
+func ReceiveConsignment(db *sql.DB, m *ConsignmentMovement) ([]byte, error) {
+	return moveConsignment(db, m, m.Quantity, "Consignment in:")
+}
+
+func ReturnConsignment(db *sql.DB, m *ConsignmentMovement) ([]byte, error) {
+	return moveConsignment(db, m, NewDecimal(-m.Quantity.Data), "Consignment returned:")
+}
+
+func SettleConsignment(db *sql.DB, s *ConsignmentSettlement) ([]byte, error) {
+	if s.Quantity.Data <= 0 {
+		return nil, ErrInvalidConsignmentQuantity
+	}
+	if err := resolveAccount(db, s.Account); err != nil {
+		return nil, err
+	}
+	if err := resolveAccount(db, s.StockAccount); err != nil {
+		return nil, err
+	}
+	if _, err := resolveItemID(db, s.Item); err != nil {
+		return nil, err
+	}
+	party, err := consignmentParty(db, s.Account)
+	if err != nil {
+		return nil, err
+	}
+	liability, err := GetConsignmentLiabilityAccount(db)
+	if err != nil {
+		return nil, err
+	}
+	onHand, err := itemBalance(db, s.Account, s.Item)
+	if err != nil {
+		return nil, err
+	}
+	if onHand.Data < s.Quantity.Data {
+		return nil, fmt.Errorf("%s has %s %s: %w", s.Account.Name, onHand.ToString(), s.Item.Name, ErrConsignmentExceedsStock)
+	}
+	unit := s.Unit
+	if unit == "" {
+		unit = s.Item.Unit
+	}
+
+	inv := &Invoice{
+		Type:          InvoicePurchase,
+		Number:        s.Number,
+		Description:   fmt.Sprintf("Consignment settled: %s %s from %s", s.Quantity.ToString(), s.Item.Name, party.Name),
+		Party:         party,
+		DatetimeMs:    s.DatetimeMs,
+		DueDatetimeMs: s.DueDatetimeMs,
+		Currency:      s.Currency,
+		Lines: []*InvoiceLine{{
+			Account:  s.StockAccount,
+			Item:     s.Item,
+			Quantity: s.Quantity,
+			Unit:     unit,
+			Price:    s.Price,
+		}},
+	}
+	extra := []*TransactionLine{
+		CreateInventoryTrLine(s.Account, s.Item, NewDecimal(-s.Quantity.Data), unit, NewDecimal(0), ""),
+		CreateInventoryTrLine(liability, s.Item, s.Quantity, unit, NewDecimal(0), ""),
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = issueInvoiceTx(db, tx, inv, extra)
+	if err != nil {
+		return nil, err
+	}
+
+	setUUID, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	res, err := tx.Exec(`INSERT INTO consignment_settlements (uuid, account_id, stock_account_id, item_id, quantity, price, invoice_id, datetime_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		setUUID[:], s.Account.ID, s.StockAccount.ID, s.Item.ID, s.Quantity, s.Price, inv.ID, s.DatetimeMs)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	publishTransactions(db, int64(inv.Transaction.ID))
+	s.ID = int(id)
+	s.UUID = setUUID
+	s.Invoice = inv
+	return setUUID[:], nil
+}
+
+// quantities currently held on consignment, per party, account and item
+func GetConsignmentBalances(db *sql.DB) ([]ConsignmentBalance, error) {
+	accounts, err := GetConsignmentAccounts(db)
+	if err != nil {
+		return nil, err
+	}
+	var balances []ConsignmentBalance
+	for _, ca := range accounts {
+		rows, err := db.Query(`
+			SELECT i.id, i.uuid, i.name, i.unit, h.quantity FROM balance_history h
+			JOIN transactions t ON h.transaction_id = t.id
+			JOIN items i ON h.item_id = i.id
+			WHERE h.account_id=?
+			ORDER BY i.id, t.datetime_ms DESC, h.id DESC`, ca.Account.ID)
+		if err != nil {
+			return nil, err
+		}
+		lastItemID := 0
+		for rows.Next() {
+			item := &Item{}
+			var unit sql.NullString
+			qty := NewDecimal(0)
+			if err = rows.Scan(&item.ID, &item.UUID, &item.Name, &unit, &qty); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			// newest row of each item comes first
+			if item.ID == lastItemID {
+				continue
+			}
+			lastItemID = item.ID
+			item.Unit = unit.String
+			if qty.Data == 0 {
+				continue
+			}
+			balances = append(balances, ConsignmentBalance{Party: ca.Party, Account: ca.Account, Item: item, Quantity: qty, Unit: item.Unit})
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return balances, nil
+}
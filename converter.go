@@ -2,32 +2,175 @@ package inventory
 
 import (
 	"database/sql"
+	"errors"
+	"sort"
 )
 
+var ErrNoConversionPath = errors.New("no unit conversion path")
+var ErrInvalidConversionFactor = errors.New("conversion factor must be positive")
+var ErrNoCurrencyRate = errors.New("no currency conversion rate")
+
+// 1 FromUnit = Factor ToUnit, effective from DatetimeMs. Item nil means the
+// rule applies to every item.
 type UnitConversionRule struct {
-	FromUnit string
-	ToUnit   string
-	Factor   float64
+	FromUnit   string
+	ToUnit     string
+	Item       *Item
+	Factor     Decimal
+	DatetimeMs int64
 }
 
+// 1 FromCurrency = Rate ToCurrency, effective from DatetimeMs
 type CurrencyConversionRule struct {
 	FromCurrency string
 	ToCurrency   string
-	Rate         float64
+	Rate         Decimal
+	DatetimeMs   int64
+}
+
+type unitConversionEdge struct {
+	ToUnit  string
+	Factor  Decimal
+	Inverse bool
+}
+
+func unitPairKey(a, b string) string {
+	if a > b {
+		a, b = b, a
+	}
+	return a + "\x00" + b
+}
+
+// rules must be sorted by DatetimeMs ascending, a later rule for the same
+// pair of units replaces the earlier one. item specific rules always win
+// over generic ones regardless of date.
+func buildUnitConversionGraph(rules []UnitConversionRule) map[string][]unitConversionEdge {
+	generic := map[string]UnitConversionRule{}
+	specific := map[string]UnitConversionRule{}
+	for _, r := range rules {
+		key := unitPairKey(r.FromUnit, r.ToUnit)
+		if r.Item != nil {
+			specific[key] = r
+		} else {
+			generic[key] = r
+		}
+	}
+	for k, r := range specific {
+		generic[k] = r
+	}
+
+	graph := map[string][]unitConversionEdge{}
+	for _, r := range generic {
+		graph[r.FromUnit] = append(graph[r.FromUnit], unitConversionEdge{ToUnit: r.ToUnit, Factor: r.Factor})
+		graph[r.ToUnit] = append(graph[r.ToUnit], unitConversionEdge{ToUnit: r.FromUnit, Factor: r.Factor, Inverse: true})
+	}
+	for u := range graph {
+		edges := graph[u]
+		sort.Slice(edges, func(i, j int) bool { return edges[i].ToUnit < edges[j].ToUnit })
+	}
+	return graph
+}
+
+// breadth first, so the path with the fewest hops is chosen
+func findUnitConversionPath(graph map[string][]unitConversionEdge, fromUnit, toUnit string) ([]unitConversionEdge, error) {
+	type visit struct {
+		prev string
+		edge unitConversionEdge
+	}
+	visited := map[string]visit{fromUnit: {}}
+	queue := []string{fromUnit}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if cur == toUnit {
+			var path []unitConversionEdge
+			for u := toUnit; u != fromUnit; u = visited[u].prev {
+				path = append([]unitConversionEdge{visited[u].edge}, path...)
+			}
+			return path, nil
+		}
+		for _, e := range graph[cur] {
+			if _, ok := visited[e.ToUnit]; ok {
+				continue
+			}
+			visited[e.ToUnit] = visit{prev: cur, edge: e}
+			queue = append(queue, e.ToUnit)
+		}
+	}
+	return nil, ErrNoConversionPath
 }
 
-func ConvertUnit(db *sql.DB, quantity float64, fromUnit, toUnit string) (float64, error) {
-	rule, err := LoadConversionRule(db, fromUnit, toUnit)
+func ConvertUnit(db *sql.DB, quantity Decimal, fromUnit, toUnit string, item *Item, datetimeMs int64) (Decimal, error) {
+	if fromUnit == toUnit {
+		return quantity, nil
+	}
+	rules, err := LoadConversionRules(db, item, datetimeMs)
 	if err != nil {
 		return quantity, err
 	}
-	return quantity * rule.Factor, nil
+	path, err := findUnitConversionPath(buildUnitConversionGraph(rules), fromUnit, toUnit)
+	if err != nil {
+		return quantity, err
+	}
+	// apply each hop to the quantity instead of composing the factors first,
+	// so an inverse like 1/12 doesn't get truncated to 4 digits
+	result := quantity
+	for _, e := range path {
+		if e.Inverse {
+			result = result.Divide(e.Factor)
+		} else {
+			result = result.Multiply(e.Factor)
+		}
+	}
+	return result, nil
 }
 
-func ConvertCurrency(db *sql.DB, amount float64, fromCurrency, toCurrency string) float64 {
-	rule, err := LoadCurrencyConversionRule(db, fromCurrency, toCurrency)
+func convertCurrencyDirect(db *sql.DB, amount Decimal, fromCurrency, toCurrency string, datetimeMs int64) (Decimal, error) {
+	rule, err := LoadCurrencyConversionRule(db, fromCurrency, toCurrency, datetimeMs)
 	if err != nil {
-		return amount
+		return amount, err
+	}
+	if rule.FromCurrency == fromCurrency {
+		return amount.Multiply(rule.Rate), nil
+	}
+	return amount.Divide(rule.Rate), nil
+}
+
+// uses the rate effective at datetimeMs. when there is no rate between the two
+// currencies, the amount is triangulated through the base currency of the db.
+func ConvertCurrency(db *sql.DB, amount Decimal, fromCurrency, toCurrency string, datetimeMs int64) (Decimal, error) {
+	if fromCurrency == toCurrency {
+		return amount, nil
+	}
+	result, err := convertCurrencyDirect(db, amount, fromCurrency, toCurrency, datetimeMs)
+	if err == nil {
+		return result, nil
+	}
+	if err != sql.ErrNoRows {
+		return amount, err
+	}
+
+	base, err := GetBaseCurrency(db)
+	if err == ErrNoBaseCurrency {
+		return amount, ErrNoCurrencyRate
+	} else if err != nil {
+		return amount, err
+	}
+	if base == fromCurrency || base == toCurrency {
+		return amount, ErrNoCurrencyRate
+	}
+
+	inBase, err := convertCurrencyDirect(db, amount, fromCurrency, base, datetimeMs)
+	if err == sql.ErrNoRows {
+		return amount, ErrNoCurrencyRate
+	} else if err != nil {
+		return amount, err
+	}
+	result, err = convertCurrencyDirect(db, inBase, base, toCurrency, datetimeMs)
+	if err == sql.ErrNoRows {
+		return amount, ErrNoCurrencyRate
+	} else if err != nil {
+		return amount, err
 	}
-	return amount * rule.Rate
+	return result, nil
 }
@@ -0,0 +1,240 @@
+package inventory
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventTransactionApplied = "transaction_applied"
+	EventMarketPriceUpdated = "market_price_updated"
+	EventBalanceChanged     = "balance_changed"
+)
+
+// every event is published under the topic of its kind, and under
+// AccountTopic and ItemTopic of the accounts and items it touches
+const (
+	TopicTransactions = "transactions"
+	TopicMarketPrices = "market_prices"
+	TopicBalances     = "balances"
+)
+
+func AccountTopic(accUUID uuid.UUID) string {
+	return "account:" + accUUID.String()
+}
+
+func ItemTopic(itemUUID uuid.UUID) string {
+	return "item:" + itemUUID.String()
+}
+
+// a committed change in DB. Quantity and Value are the balance of
+// AccountUUID and ItemUUID after the transaction for balance_changed, Price is
+// set for market_price_updated only.
+type Event struct {
+	Kind            string
+	DB              *sql.DB
+	Topics          []string
+	DatetimeMs      int64
+	TransactionUUID uuid.UUID
+	AccountUUID     uuid.UUID
+	ItemUUID        uuid.UUID
+	Description     string
+	Quantity        Decimal
+	Value           Decimal
+	Price           Decimal
+	Unit            string
+	Currency        string
+}
+
+// runs on the goroutine that made the change, so it should hand the event
+// off instead of blocking
+type EventListener func(e *Event)
+
+type eventListener struct {
+	db *sql.DB
+	l  EventListener
+}
+
+var eventMu sync.RWMutex
+var eventListeners = map[int]eventListener{}
+var nextEventListener int
+
+// l gets every event of every database from now on until remove is called
+func AddEventListener(l EventListener) (remove func()) {
+	return AddDBEventListener(nil, l)
+}
+
+// l gets the events of changes made in db only, nil is every database
+func AddDBEventListener(db *sql.DB, l EventListener) (remove func()) {
+	eventMu.Lock()
+	defer eventMu.Unlock()
+	id := nextEventListener
+	nextEventListener++
+	eventListeners[id] = eventListener{db: db, l: l}
+	return func() {
+		eventMu.Lock()
+		defer eventMu.Unlock()
+		delete(eventListeners, id)
+	}
+}
+
+func hasEventListeners() bool {
+	eventMu.RLock()
+	defer eventMu.RUnlock()
+	return len(eventListeners) > 0
+}
+
+func publishEvent(e *Event) {
+	eventMu.RLock()
+	listeners := make([]EventListener, 0, len(eventListeners))
+	for _, l := range eventListeners {
+		if l.db == nil || l.db == e.DB {
+			listeners = append(listeners, l.l)
+		}
+	}
+	eventMu.RUnlock()
+	for _, l := range listeners {
+		l(e)
+	}
+}
+
+// transaction_applied for every transaction, each followed by balance_changed
+// for every account and item pair its lines touched. called after commit, a
+// failed lookup only loses the events it was for, the change itself stands.
+func publishTransactions(db *sql.DB, trIDs ...int64) {
+	if !hasEventListeners() {
+		return
+	}
+	for _, trID := range trIDs {
+		tr, err := GetTransactionByID(db, int(trID))
+		if err != nil {
+			continue
+		}
+
+		type balanceKey struct {
+			acc  *Account
+			item *Item
+		}
+		var balances []balanceKey
+		seen := map[[2]int]bool{}
+		topics := map[string]bool{TopicTransactions: true}
+		for _, l := range tr.TransactionLines {
+			itemID := -1
+			topics[AccountTopic(l.Account.UUID)] = true
+			if l.Item != nil {
+				itemID = l.Item.ID
+				topics[ItemTopic(l.Item.UUID)] = true
+			}
+			if !seen[[2]int{l.Account.ID, itemID}] {
+				seen[[2]int{l.Account.ID, itemID}] = true
+				balances = append(balances, balanceKey{l.Account, l.Item})
+			}
+		}
+
+		publishEvent(&Event{
+			Kind:            EventTransactionApplied,
+			DB:              db,
+			Topics:          sortedTopics(topics),
+			DatetimeMs:      tr.DatetimeMs,
+			TransactionUUID: tr.UUID,
+			Description:     tr.Description,
+		})
+
+		for _, b := range balances {
+			e := &Event{
+				Kind:            EventBalanceChanged,
+				DB:              db,
+				Topics:          []string{TopicBalances, AccountTopic(b.acc.UUID)},
+				DatetimeMs:      tr.DatetimeMs,
+				TransactionUUID: tr.UUID,
+				AccountUUID:     b.acc.UUID,
+				Quantity:        NewDecimal(0),
+				Value:           NewDecimal(0),
+			}
+			itemID := -1
+			if b.item != nil {
+				itemID = b.item.ID
+				e.ItemUUID = b.item.UUID
+				e.Unit = b.item.Unit
+				e.Topics = append(e.Topics, ItemTopic(b.item.UUID))
+			}
+			err = db.QueryRow(`
+				SELECT h.quantity, h.total_cost FROM balance_history h
+				JOIN transactions t ON h.transaction_id = t.id
+				WHERE h.account_id=? AND h.item_id=?
+				ORDER BY t.datetime_ms DESC, h.id DESC LIMIT 1`, b.acc.ID, itemID).Scan(&e.Quantity, &e.Value)
+			if err != nil {
+				continue
+			}
+			publishEvent(e)
+		}
+	}
+}
+
+func publishMarketPrices(db *sql.DB, prices ...*MarketPrice) {
+	if !hasEventListeners() {
+		return
+	}
+	for _, p := range prices {
+		publishEvent(&Event{
+			Kind:       EventMarketPriceUpdated,
+			DB:         db,
+			Topics:     []string{TopicMarketPrices, ItemTopic(p.Item.UUID)},
+			DatetimeMs: p.DatetimeMs,
+			ItemUUID:   p.Item.UUID,
+			Price:      p.Price,
+			Unit:       p.Unit,
+			Currency:   p.Currency,
+		})
+	}
+}
+
+func sortedTopics(topics map[string]bool) []string {
+	sorted := make([]string, 0, len(topics))
+	for t := range topics {
+		sorted = append(sorted, t)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// the event as a packet body, the same in every codec. uuids are raw bytes
+// and left out when nil, decimals are their text form.
+func (e *Event) Payload() map[string][]byte {
+	datetime := make([]byte, 8)
+	binary.LittleEndian.PutUint64(datetime, uint64(e.DatetimeMs))
+	payload := map[string][]byte{
+		"event":    []byte(e.Kind),
+		"datetime": datetime,
+	}
+	for key, u := range map[string]uuid.UUID{
+		"transaction": e.TransactionUUID,
+		"account":     e.AccountUUID,
+		"item":        e.ItemUUID,
+	} {
+		if u != uuid.Nil {
+			payload[key] = u[:]
+		}
+	}
+	switch e.Kind {
+	case EventBalanceChanged:
+		payload["quantity"] = []byte(e.Quantity.ToString())
+		payload["value"] = []byte(e.Value.ToString())
+	case EventMarketPriceUpdated:
+		payload["price"] = []byte(e.Price.ToString())
+	}
+	for key, s := range map[string]string{
+		"description": e.Description,
+		"unit":        e.Unit,
+		"currency":    e.Currency,
+	} {
+		if s != "" {
+			payload[key] = []byte(s)
+		}
+	}
+	return payload
+}
@@ -78,7 +78,7 @@ func (e *ExampleProtobuf) OnUnmarshalItem(receivedItemBin []byte) (*inventory.It
 }
 
 func CreatePacketToSend(e ExampleInterface) []byte {
-	itUUID, err := uuid.NewV6()
+	itUUID, err := uuid.NewV7()
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -89,7 +89,7 @@ func CreatePacketToSend(e ExampleInterface) []byte {
 		log.Fatal(err)
 	}
 
-	pktUUID, err := uuid.NewV6()
+	pktUUID, err := uuid.NewV7()
 	if err != nil {
 		log.Fatal(err)
 	}
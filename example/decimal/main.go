@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"inventory"
+	"os"
+)
+
+// how ParseDecimal reads a string and ToString writes it back, digits past
+// DECIMALPRECISION are truncated, not rounded
+var cases = []struct {
+	in   string
+	data int64
+	out  string
+	err  error
+}{
+	{"12", 120000, "12.0000", nil},
+	{"-3", -30000, "-3.0000", nil},
+	{"+7", 70000, "7.0000", nil},
+	{"0", 0, "0.0000", nil},
+	{"-0", 0, "0.0000", nil},
+	{".5", 5000, "0.5000", nil},
+	{"-0.5", -5000, "-0.5000", nil},
+	{"-.25", -2500, "-0.2500", nil},
+	{"-0.0001", -1, "-0.0001", nil},
+	{"0.02", 200, "0.0200", nil},
+	{"1.5", 15000, "1.5000", nil},
+	{"1.23456", 12345, "1.2345", nil},
+	{"-1.23456", -12345, "-1.2345", nil},
+	{"0.00009", 0, "0.0000", nil},
+	{" 42.10 ", 421000, "42.1000", nil},
+	{"12.", 120000, "12.0000", nil},
+	{"", 0, "0.0000", inventory.ErrInvalidDecimal},
+	{".", 0, "0.0000", inventory.ErrInvalidDecimal},
+	{"-", 0, "0.0000", inventory.ErrInvalidDecimal},
+	{"1,5", 0, "0.0000", inventory.ErrInvalidDecimal},
+	{"1.2.3", 0, "0.0000", inventory.ErrInvalidDecimal},
+	{"1e3", 0, "0.0000", inventory.ErrInvalidDecimal},
+	{"--1", 0, "0.0000", inventory.ErrInvalidDecimal},
+}
+
+func main() {
+	failed := 0
+	fmt.Printf("%-12s %-10s %-10s %s\n", "input", "data", "string", "error")
+	for _, c := range cases {
+		d, err := inventory.ParseDecimal(c.in)
+		ok := d.Data == c.data && d.ToString() == c.out && err == c.err
+		fmt.Printf("%-12q %-10d %-10s %v", c.in, d.Data, d.ToString(), err)
+		if !ok {
+			failed++
+			fmt.Printf(", want %d %s %v", c.data, c.out, c.err)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("%d of %d cases as expected\n", len(cases)-failed, len(cases))
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
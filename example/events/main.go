@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"inventory"
+	"inventorypb"
+	"inventoryrpc"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var names = map[uuid.UUID]string{}
+
+func name(b []byte) string {
+	u, err := uuid.FromBytes(b)
+	if err != nil {
+		return "?"
+	}
+	if n, ok := names[u]; ok {
+		return n
+	}
+	return u.String()
+}
+
+func printEvent(pkt *inventoryrpc.Packet) {
+	body := pkt.Body
+	date := time.UnixMilli(int64(binary.LittleEndian.Uint64(body["datetime"]))).UTC().Format("2006-01-02")
+	switch string(body["event"]) {
+	case inventory.EventTransactionApplied:
+		fmt.Printf("  %s %s: %s\n", date, body["event"], body["description"])
+	case inventory.EventBalanceChanged:
+		acc := name(body["account"])
+		if body["item"] != nil {
+			acc += " " + name(body["item"])
+		}
+		fmt.Printf("  %s %s: %s qty %s value %s\n", date, body["event"], acc, body["quantity"], body["value"])
+	case inventory.EventMarketPriceUpdated:
+		fmt.Printf("  %s %s: %s %s %s/%s\n", date, body["event"], name(body["item"]), body["price"], body["currency"], body["unit"])
+	}
+}
+
+// waits for n events, printing them
+func expect(events chan *inventoryrpc.Packet, n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case pkt := <-events:
+			printEvent(pkt)
+		case <-time.After(2 * time.Second):
+			log.Fatalf("got %d of %d events", i, n)
+		}
+	}
+}
+
+func main() {
+	_ = os.RemoveAll("db/")
+	if inventory.PathExists("db/") {
+		log.Fatalln("db exists")
+	}
+
+	hub := inventoryrpc.NewEventHub()
+	hub.QueueSize = 4
+	stopEvents := hub.PublishEvents(nil)
+	defer stopEvents()
+
+	server := &inventoryrpc.Server{
+		Processor: inventorypb.NewServerProcessor(),
+		Codec:     inventorypb.Codec{},
+		Events:    hub,
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	go server.Serve(l)
+	defer server.Close()
+
+	client, err := inventorypb.DialWithHello("tcp", l.Addr().String(), inventoryrpc.Hello{
+		Version:    inventoryrpc.ProtocolVersion,
+		MinVersion: inventoryrpc.MinProtocolVersion,
+		Codecs:     []string{"protobuf"},
+		Features:   []string{inventoryrpc.FeatureEvents},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+	fmt.Println("server pushes events:", client.Conn().HasFeature(inventoryrpc.FeatureEvents))
+	events := make(chan *inventoryrpc.Packet, 64)
+	client.OnEvent = func(pkt *inventoryrpc.Packet) {
+		events <- pkt
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err = client.OpenOrCreateDB(ctx, uuid.Nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mainAccs, err := client.GetMainAccounts(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	asset := &inventory.Account{UUID: mainAccs["asset"]}
+	equity := &inventory.Account{UUID: mainAccs["equity"]}
+	cash := &inventory.Account{Name: "cash", Parent: asset}
+	rawMaterial := &inventory.Account{Name: "raw material", Parent: asset}
+	steel := &inventory.Item{Name: "steel", Unit: "kg"}
+	for _, acc := range []*inventory.Account{cash, rawMaterial} {
+		acc.UUID, err = client.AddAccount(ctx, acc)
+		if err != nil {
+			log.Fatal(err)
+		}
+		names[acc.UUID] = acc.Name
+	}
+	steel.UUID, err = client.AddItem(ctx, steel)
+	if err != nil {
+		log.Fatal(err)
+	}
+	names[steel.UUID] = steel.Name
+
+	// the equity balance is not subscribed to, so its change is not pushed
+	topics, err := client.Subscribe(ctx, inventory.TopicTransactions, inventory.AccountTopic(cash.UUID), inventory.ItemTopic(steel.UUID))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("subscribed to %d topics\n", len(topics))
+
+	date := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	_, err = client.ApplyTransaction(ctx, &inventory.Transaction{
+		Description: "owner investment",
+		DatetimeMs:  date.UnixMilli(),
+		TransactionLines: []*inventory.TransactionLine{
+			inventory.CreateFinancialTrLine(equity, inventory.NewDecimal(0), inventory.NewDecimalFromStr("1000"), "USD"),
+			inventory.CreateFinancialTrLine(cash, inventory.NewDecimalFromStr("1000"), inventory.NewDecimal(0), "USD"),
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(events, 2)
+	_, err = client.ApplyTransaction(ctx, &inventory.Transaction{
+		Description: "purchase steel 100kg",
+		DatetimeMs:  date.AddDate(0, 0, 1).UnixMilli(),
+		TransactionLines: []*inventory.TransactionLine{
+			inventory.CreateInventoryTrLine(rawMaterial, steel, inventory.NewDecimalFromStr("100"), "kg", inventory.NewDecimalFromStr("5"), "USD"),
+			inventory.CreateFinancialTrLine(cash, inventory.NewDecimal(0), inventory.NewDecimalFromStr("500"), "USD"),
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(events, 3)
+	err = client.UpdateMarketPrice(ctx, &inventory.MarketPrice{
+		Item:       steel,
+		DatetimeMs: date.AddDate(0, 0, 2).UnixMilli(),
+		Price:      inventory.NewDecimalFromStr("5.5"),
+		Unit:       "kg",
+		Currency:   "USD",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(events, 1)
+
+	// the hub publishes the changes of the served database only, not those
+	// of another one the process has open
+	other, err := sql.Open("sqlite3", "file:other.db?cache=shared&mode=rwc")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.Remove("other.db")
+	defer other.Close()
+	err = inventory.InitSchema(other)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = inventory.ApplyTransaction(other, &inventory.Transaction{
+		Description: "owner investment elsewhere",
+		DatetimeMs:  date.AddDate(0, 0, 3).UnixMilli(),
+		TransactionLines: []*inventory.TransactionLine{
+			inventory.CreateFinancialTrLine(inventory.EquityAcc, inventory.NewDecimal(0), inventory.NewDecimalFromStr("100"), "USD"),
+			inventory.CreateFinancialTrLine(inventory.AssetAcc, inventory.NewDecimalFromStr("100"), inventory.NewDecimal(0), "USD"),
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	fmt.Printf("after a change in another database: %d events\n", len(events))
+
+	topics, err = client.Unsubscribe(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = client.ApplyTransaction(ctx, &inventory.Transaction{
+		Description: "owner investment",
+		DatetimeMs:  date.AddDate(0, 0, 3).UnixMilli(),
+		TransactionLines: []*inventory.TransactionLine{
+			inventory.CreateFinancialTrLine(equity, inventory.NewDecimal(0), inventory.NewDecimalFromStr("100"), "USD"),
+			inventory.CreateFinancialTrLine(cash, inventory.NewDecimalFromStr("100"), inventory.NewDecimal(0), "USD"),
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	fmt.Printf("after unsubscribing from everything (%d topics left): %d events\n", len(topics), len(events))
+
+	// a subscriber that stopped reading. once the socket buffers are full its
+	// queue fills up and the hub drops what does not fit instead of blocking
+	// the publisher, the next event it gets says how many it lost.
+	slow, err := inventoryrpc.Dial("tcp", l.Addr().String(), inventorypb.Codec{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer slow.Close()
+	reqPkt, err := inventorypb.NewRawRequestPkt(inventoryrpc.FuncSubscribe, []byte("reports"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = slow.WritePacket(reqPkt)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = slow.ReadPacket()
+	if err != nil {
+		log.Fatal(err)
+	}
+	const published = 50
+	report := bytes.Repeat([]byte(strings.Repeat("-", 63)+"\n"), 4096)
+	for i := 0; i < published; i++ {
+		err = hub.Publish("report_ready", []string{"reports"}, map[string][]byte{"report": report})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	received, dropped := 0, 0
+	for {
+		slow.NetConn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+		pkt, err := slow.ReadPacket()
+		if err != nil {
+			break
+		}
+		received++
+		dropped += inventoryrpc.DroppedEvents(pkt)
+	}
+	fmt.Printf("slow subscriber lost events: %v, received + dropped = %d of %d\n", dropped > 0, received+dropped, published)
+
+	err = client.CloseCurrDB(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"inventory"
+	"inventorypb"
+	"inventoryrpc"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// the raw decimal the messages carry
+func dec(s string) int64 {
+	return inventory.NewDecimalFromStr(s).Data
+}
+
+// prints the grpc code of a failed call
+func printStatus(name string, err error) {
+	fmt.Printf("%s: %s: %s\n", name, status.Code(err), status.Convert(err).Message())
+}
+
+func main() {
+	_ = os.RemoveAll("db/")
+	if inventory.PathExists("db/") {
+		log.Fatalln("db exists")
+	}
+
+	// every call needs the token metadata, as a RequireMeta middleware asks
+	registry := inventorypb.NewRegistry()
+	inventorypb.RegisterBuiltinFuncs(registry)
+	registry.Use(inventoryrpc.RequireMeta("token", func(value []byte) bool {
+		return bytes.Equal(value, []byte("secret"))
+	}))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	grpcServer := grpc.NewServer()
+	inventorypb.RegisterInventoryServer(grpcServer, inventorypb.NewGRPCServer(registry))
+	go grpcServer.Serve(l)
+	defer grpcServer.Stop()
+
+	clientConn, err := grpc.NewClient(l.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer clientConn.Close()
+	client := inventorypb.NewInventoryClient(clientConn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err = client.OpenOrCreateDB(ctx, &inventorypb.UUID{})
+	printStatus("OpenOrCreateDB without token", err)
+
+	ctx = metadata.AppendToOutgoingContext(ctx, "token", "secret")
+	_, err = client.PrintBalances(ctx, &emptypb.Empty{})
+	printStatus("PrintBalances before OpenOrCreateDB", err)
+
+	dbUUID, err := client.OpenOrCreateDB(ctx, &inventorypb.UUID{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("opened db", uuid.Must(uuid.FromBytes(dbUUID.UUID)))
+
+	mainAccs, err := client.GetMainAccounts(ctx, &emptypb.Empty{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	asset := mainAccs.Content["asset"]
+	equity := mainAccs.Content["equity"]
+
+	cash, err := client.AddAccount(ctx, &inventorypb.Account{Name: "cash", ParentUUID: asset})
+	if err != nil {
+		log.Fatal(err)
+	}
+	rawMaterial, err := client.AddAccount(ctx, &inventorypb.Account{Name: "raw material", ParentUUID: asset})
+	if err != nil {
+		log.Fatal(err)
+	}
+	steel, err := client.AddItem(ctx, &inventorypb.Item{Name: "steel", Unit: "kg"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// a line without an item holds the amount in quantity, debit positive
+	date := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	_, err = client.ApplyTransaction(ctx, &inventorypb.Transaction{
+		Description: "owner investment",
+		DatetimeMs:  date.UnixMilli(),
+		TransactionLines: []*inventorypb.TransactionLine{
+			{AccountUUID: equity, Quantity: dec("-1000"), Currency: "USD"},
+			{AccountUUID: cash.UUID, Quantity: dec("1000"), Currency: "USD"},
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = client.ApplyTransaction(ctx, &inventorypb.Transaction{
+		Description: "purchase steel 100kg",
+		DatetimeMs:  date.AddDate(0, 0, 1).UnixMilli(),
+		TransactionLines: []*inventorypb.TransactionLine{
+			{AccountUUID: rawMaterial.UUID, ItemUUID: steel.UUID, Quantity: dec("100"), Unit: "kg", Price: dec("5"), Currency: "USD"},
+			{AccountUUID: cash.UUID, Quantity: dec("-500"), Currency: "USD"},
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for i, price := range []string{"5.5", "6", "6.25"} {
+		_, err = client.UpdateMarketPrice(ctx, &inventorypb.MarketPrice{
+			ItemUUID:   steel.UUID,
+			DatetimeMs: date.AddDate(0, 0, 2+i).UnixMilli(),
+			Price:      dec(price),
+			Unit:       "kg",
+			Currency:   "USD",
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	unknownUUID := uuid.Must(uuid.NewV7())
+	_, err = client.UpdateMarketPrice(ctx, &inventorypb.MarketPrice{
+		ItemUUID: unknownUUID[:],
+		Price:    dec("1"),
+	})
+	printStatus("UpdateMarketPrice of an unknown item", err)
+
+	priceStream, err := client.StreamMarketPriceHistory(ctx, &inventorypb.MarketPriceHistoryQuery{
+		ItemUUID:     steel.UUID,
+		ToDatetimeMs: date.AddDate(0, 1, 0).UnixMilli(),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for {
+		p, err := priceStream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("  steel %s: %s %s/%s\n", time.UnixMilli(p.DatetimeMs).UTC().Format("2006-01-02"), inventory.NewDecimal(p.Price).ToString(), p.Currency, p.Unit)
+	}
+
+	lineStream, err := client.StreamMarketBalances(ctx, &emptypb.Empty{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	lines := 0
+	for {
+		line, err := lineStream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(line.Line)
+		lines++
+	}
+	fmt.Printf("StreamMarketBalances sent %d lines\n", lines)
+
+	// one current database for every client, concurrent opens are safe but
+	// the last one wins
+	otherUUID := uuid.Must(uuid.NewV7())
+	var wg sync.WaitGroup
+	for _, id := range [][]byte{dbUUID.UUID, otherUUID[:]} {
+		wg.Add(1)
+		go func(id []byte) {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				_, err := client.OpenOrCreateDB(ctx, &inventorypb.UUID{UUID: id})
+				if err != nil {
+					log.Fatal(err)
+				}
+				_, err = client.GetMainAccounts(ctx, &emptypb.Empty{})
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+		}(id)
+	}
+	wg.Wait()
+	_, err = client.OpenOrCreateDB(ctx, dbUUID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	currUUID, err := client.GetCurrDB(ctx, &emptypb.Empty{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("current db after concurrent opens is the one opened last:", bytes.Equal(currUUID.UUID, dbUUID.UUID))
+
+	_, err = client.CloseCurrDB(ctx, &emptypb.Empty{})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
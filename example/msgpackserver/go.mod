@@ -0,0 +1,22 @@
+module inventory-msgpackserver-example
+
+go 1.23
+
+toolchain go1.24.7
+
+replace inventory => ../..
+
+replace inventoryrpc => ../../rpc
+
+replace inventorymsgpack => ../../msgpack
+
+require (
+	github.com/google/uuid v1.6.0
+	github.com/mattn/go-sqlite3 v1.14.28
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+	inventory v0.0.0-00010101000000-000000000000
+	inventorymsgpack v0.0.0-00010101000000-000000000000
+	inventoryrpc v0.0.0-00010101000000-000000000000
+)
+
+require github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
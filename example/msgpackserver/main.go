@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"inventory"
+	"inventorymsgpack"
+	"inventoryrpc"
+	"log"
+	"net"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var conn *inventoryrpc.Conn
+
+// one call at a time, so the next packet read is the response
+func call(funcName string, arg any) (*inventoryrpc.Packet, error) {
+	reqPkt, err := inventorymsgpack.NewRequestPkt(funcName, arg)
+	if err != nil {
+		return nil, err
+	}
+	err = conn.WritePacket(reqPkt)
+	if err != nil {
+		return nil, err
+	}
+	responsePkt, err := conn.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+	codeBytes, ok := responsePkt.Body["code"]
+	if !ok || responsePkt.UUID != reqPkt.UUID {
+		return nil, errors.New("packet incomplete")
+	}
+	code := int32(binary.LittleEndian.Uint32(codeBytes))
+	if code < 0 {
+		return responsePkt, fmt.Errorf("%s: code %d: %s", funcName, code, responsePkt.Body["message"])
+	}
+	return responsePkt, nil
+}
+
+func callUUID(funcName string, arg any) (uuid.UUID, error) {
+	responsePkt, err := call(funcName, arg)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.FromBytes(responsePkt.Body["uuid"])
+}
+
+func addAccount(name string, parent uuid.UUID) (uuid.UUID, error) {
+	return callUUID("AddAccount", inventorymsgpack.NewAccount(&inventory.Account{
+		Name:   name,
+		Parent: &inventory.Account{UUID: parent},
+	}, nil))
+}
+
+func main() {
+	_ = os.RemoveAll("db/")
+	if inventory.PathExists("db/") {
+		log.Fatalln("db exists")
+	}
+
+	server := &inventoryrpc.Server{
+		Processor: inventorymsgpack.NewServerProcessor(),
+		Codec:     inventorymsgpack.Codec{},
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	go server.Serve(l)
+	defer server.Close()
+
+	conn, err = inventoryrpc.Dial("tcp", l.Addr().String(), inventorymsgpack.Codec{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	ack, err := conn.Handshake(inventoryrpc.NewHello("msgpack"), inventorymsgpack.Codec{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("handshake: version %d codec %s\n", ack.Version, ack.Codec)
+
+	_, err = callUUID("OpenOrCreateDB", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	responsePkt, err := call("GetMainAccounts", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mainAccs := map[string]uuid.UUID{}
+	for _, name := range []string{"asset", "equity", "expense"} {
+		mainAccs[name], err = uuid.FromBytes(responsePkt.Body[name])
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	cashAcc, err := addAccount("cash", mainAccs["asset"])
+	if err != nil {
+		log.Fatal(err)
+	}
+	rawMaterialAcc, err := addAccount("raw material", mainAccs["asset"])
+	if err != nil {
+		log.Fatal(err)
+	}
+	steelItem, err := callUUID("AddItem", inventorymsgpack.NewItem(&inventory.Item{
+		Name:        "steel",
+		Description: "raw material",
+		Unit:        "kg",
+	}, nil))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	date := time.Date(2025, 9, 1, 0, 0, 0, 0, time.Local)
+	_, err = callUUID("ApplyTransaction", inventorymsgpack.NewTransaction(&inventory.Transaction{
+		Description: "owner investment",
+		DatetimeMs:  date.UnixMilli(),
+		TransactionLines: []*inventory.TransactionLine{
+			inventory.CreateFinancialTrLineWithUUID(mainAccs["equity"], inventory.NewDecimal(0), inventory.NewDecimalFromIntFrac(1000, 0), "USD"),
+			inventory.CreateFinancialTrLineWithUUID(cashAcc, inventory.NewDecimalFromIntFrac(1000, 0), inventory.NewDecimal(0), "USD"),
+		},
+	}))
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = callUUID("ApplyTransaction", inventorymsgpack.NewTransaction(&inventory.Transaction{
+		Description: "purchase steel 100kg",
+		DatetimeMs:  date.AddDate(0, 0, 1).UnixMilli(),
+		TransactionLines: []*inventory.TransactionLine{
+			inventory.CreateInventoryTrLineWithUUID(rawMaterialAcc, steelItem, inventory.NewDecimalFromIntFrac(100, 0), "kg", inventory.NewDecimalFromFloat(5), "USD"),
+			inventory.CreateFinancialTrLineWithUUID(cashAcc, inventory.NewDecimal(0), inventory.NewDecimalFromIntFrac(500, 0), "USD"),
+		},
+	}))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for i, price := range []float64{5.5, 6} {
+		_, err = call("UpdateMarketPrice", inventorymsgpack.NewMarketPrice(&inventory.MarketPrice{
+			Item:       &inventory.Item{UUID: steelItem},
+			DatetimeMs: date.AddDate(0, 0, 2+i).UnixMilli(),
+			Price:      inventory.NewDecimalFromFloat(price),
+			Unit:       "kg",
+			Currency:   "USD",
+		}))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	responsePkt, err = call("GetMarketPriceHistory", &inventorymsgpack.MarketPriceHistoryQuery{
+		ItemUUID:     steelItem[:],
+		ToDatetimeMs: date.AddDate(0, 1, 0).UnixMilli(),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	var priceList inventorymsgpack.MarketPriceList
+	err = msgpack.Unmarshal(responsePkt.Body["prices"], &priceList)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, p := range inventorymsgpack.ToInvMarketPrices(&priceList) {
+		fmt.Printf("steel %s: %s %s/%s\n", time.UnixMilli(p.DatetimeMs).Format("2006-01-02"), p.Price.ToString(), p.Currency, p.Unit)
+	}
+
+	_, err = call("NoSuchFunction", nil)
+	fmt.Printf("unknown function: %v\n", err)
+	_, err = call("AddItem", nil)
+	fmt.Printf("missing arg: %v\n", err)
+
+	for _, funcName := range []string{"PrintBalances", "PrintMarketBalances"} {
+		responsePkt, err = call(funcName, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(string(responsePkt.Body["balances"]))
+	}
+
+	_, err = call("CloseCurrDB", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	conn.Close()
+
+	// a protobuf only client finds no common codec
+	pbConn, err := inventoryrpc.Dial("tcp", l.Addr().String(), inventorymsgpack.Codec{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = pbConn.Handshake(inventoryrpc.NewHello("protobuf"))
+	fmt.Printf("protobuf client: %v\n", err)
+	pbConn.Close()
+}
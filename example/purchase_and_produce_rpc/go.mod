@@ -1,6 +1,6 @@
 module inventory-basic-example
 
-go 1.23
+go 1.23.0
 
 toolchain go1.24.7
 
@@ -11,15 +11,20 @@ replace inventoryrpc => ../../rpc
 replace inventorypb => ../../pb
 
 require (
+	github.com/google/uuid v1.6.0
 	github.com/mattn/go-sqlite3 v1.14.28
+	google.golang.org/protobuf v1.36.9
 	inventory v0.0.0-00010101000000-000000000000
 	inventorypb v0.0.0-00010101000000-000000000000
+	inventoryrpc v0.0.0-00010101000000-000000000000
 )
 
 require (
-	github.com/google/uuid v1.6.0 // indirect
 	github.com/vmihailenco/msgpack/v5 v5.4.1 // indirect
 	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
-	google.golang.org/protobuf v1.36.9 // indirect
-	inventoryrpc v0.0.0-00010101000000-000000000000 // indirect
+	golang.org/x/net v0.41.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/text v0.26.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250707201910-8d1bb00bc6a7 // indirect
+	google.golang.org/grpc v1.75.1 // indirect
 )
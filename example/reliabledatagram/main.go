@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"inventory"
+	"inventorypb"
+	"inventoryrpc"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+	"google.golang.org/protobuf/proto"
+)
+
+// sends a request on conn to addr and waits for its response, requests are
+// told apart by UUID since responses come back in any order
+type datagramClient struct {
+	conn    *inventoryrpc.ReliableConn
+	addr    net.Addr
+	mu      sync.Mutex
+	pending map[uuid.UUID]chan *inventoryrpc.Packet
+}
+
+func newDatagramClient(conn *inventoryrpc.ReliableConn, addr net.Addr) *datagramClient {
+	c := &datagramClient{conn: conn, addr: addr, pending: map[uuid.UUID]chan *inventoryrpc.Packet{}}
+	go func() {
+		for {
+			data, _, err := conn.Receive()
+			if err != nil {
+				return
+			}
+			pkt, err := inventorypb.UnmarshalPkt(data)
+			if err != nil {
+				continue
+			}
+			c.mu.Lock()
+			ch, ok := c.pending[pkt.UUID]
+			delete(c.pending, pkt.UUID)
+			c.mu.Unlock()
+			if ok {
+				ch <- pkt
+			}
+		}
+	}()
+	return c
+}
+
+func (c *datagramClient) call(funcName string, arg proto.Message) (*inventoryrpc.Packet, error) {
+	pkt, err := inventorypb.NewRequestPkt(funcName, arg)
+	if err != nil {
+		return nil, err
+	}
+	data, err := inventorypb.Codec{}.Marshal(pkt)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan *inventoryrpc.Packet, 1)
+	c.mu.Lock()
+	c.pending[pkt.UUID] = ch
+	c.mu.Unlock()
+
+	err = c.conn.Send(context.Background(), data, c.addr)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case responsePkt := <-ch:
+		code, err := inventorypb.ResponseCode(responsePkt)
+		if err != nil {
+			return nil, err
+		}
+		if code < 0 {
+			return nil, fmt.Errorf("%s: %s", funcName, responsePkt.Body["message"])
+		}
+		return responsePkt, nil
+	case <-time.After(10 * time.Second):
+		return nil, fmt.Errorf("%s: no response", funcName)
+	}
+}
+
+func lossyRawTransfer() {
+	a, b := inventoryrpc.NewLossyLink(inventoryrpc.LossyConfig{
+		LossRate:      0.3,
+		DuplicateRate: 0.1,
+		MaxDelay:      20 * time.Millisecond,
+		Seed:          1,
+	})
+	sender := inventoryrpc.NewReliableConn(a)
+	receiver := inventoryrpc.NewReliableConn(b)
+	sender.RetransmitTimeout = 30 * time.Millisecond
+	defer sender.Close()
+	defer receiver.Close()
+
+	// 200 fragments, plus small messages that must not be delivered twice
+	big := bytes.Repeat([]byte("0123456789abcdef"), 15000)
+	messages := [][]byte{big}
+	for i := 0; i < 20; i++ {
+		messages = append(messages, []byte(fmt.Sprintf("message %d", i)))
+	}
+
+	var wg sync.WaitGroup
+	for _, m := range messages {
+		wg.Add(1)
+		go func(m []byte) {
+			defer wg.Done()
+			err := sender.Send(context.Background(), m, b.LocalAddr())
+			if err != nil {
+				log.Fatal(err)
+			}
+		}(m)
+	}
+	wg.Wait()
+
+	received := map[string]int{}
+	for range messages {
+		data, _, err := receiver.Receive()
+		if err != nil {
+			log.Fatal(err)
+		}
+		received[string(data)]++
+	}
+	// duplicates that were still in flight would show up here
+	time.Sleep(100 * time.Millisecond)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		receiver.StopReceiving()
+	}()
+	if data, _, err := receiver.Receive(); err == nil {
+		received[string(data)]++
+	}
+
+	for _, m := range messages {
+		if received[string(m)] != 1 {
+			log.Fatalf("%d bytes received %d times", len(m), received[string(m)])
+		}
+	}
+	stats := a.Stats()
+	fmt.Printf("lossy link: %d messages (%d bytes largest) each delivered once, %d datagrams sent, %d dropped, %d duplicated\n",
+		len(messages), len(big), stats.Sent, stats.Dropped, stats.Duplicated)
+}
+
+func serveOver(name string, clientSide, serverSide inventoryrpc.PacketConn, serverAddr net.Addr) {
+	server := &inventoryrpc.Server{
+		Processor: inventorypb.NewServerProcessor(),
+		Codec:     inventorypb.Codec{},
+	}
+	serverConn := inventoryrpc.NewReliableConn(serverSide)
+	serverConn.RetransmitTimeout = 30 * time.Millisecond
+	served := make(chan error)
+	go func() {
+		served <- server.ServeDatagram(serverConn)
+	}()
+
+	clientConn := inventoryrpc.NewReliableConn(clientSide)
+	clientConn.RetransmitTimeout = 30 * time.Millisecond
+	client := newDatagramClient(clientConn, serverAddr)
+	defer clientConn.Close()
+
+	_, err := client.call("OpenOrCreateDB", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := client.call("AddAccount", inventorypb.NewAccount(&inventory.Account{
+				Name: fmt.Sprintf("account with a long name to make the report large %d", i),
+			}, nil))
+			if err != nil {
+				log.Fatal(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	responsePkt, err := client.call("GetMainAccounts", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = client.call("CloseCurrDB", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s: 30 accounts added, %d main accounts\n", name, len(responsePkt.Body)-2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = server.Shutdown(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = <-served; err != inventoryrpc.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
+
+func main() {
+	lossyRawTransfer()
+
+	_ = os.RemoveAll("db/")
+	a, b := inventoryrpc.NewLossyLink(inventoryrpc.LossyConfig{
+		LossRate:      0.2,
+		DuplicateRate: 0.1,
+		MaxDelay:      10 * time.Millisecond,
+		Seed:          2,
+	})
+	serveOver("lossy link", a, b, b.LocalAddr())
+	_ = os.RemoveAll("db/")
+
+	serverUDP, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	clientUDP, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	serveOver("udp loopback", clientUDP, serverUDP, serverUDP.LocalAddr())
+	_ = os.RemoveAll("db/")
+}
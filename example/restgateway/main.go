@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"inventory"
+	"inventoryjson"
+	"inventoryrpc"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var baseURL string
+
+// sent as the Token header when set
+var token string
+
+// sends body as json unless it is a string already, prints the status and
+// decodes the answer into out when given
+func do(method, path string, body any, out any) int {
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(b)
+	default:
+		bodyBytes, err := json.Marshal(b)
+		if err != nil {
+			log.Fatal(err)
+		}
+		reader = bytes.NewReader(bodyBytes)
+	}
+	req, err := http.NewRequest(method, baseURL+path, reader)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Token", token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if out != nil && resp.StatusCode < 300 {
+		err = json.Unmarshal(respBytes, out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s %s: %d\n", method, path, resp.StatusCode)
+	} else {
+		fmt.Printf("%s %s: %d %s", method, path, resp.StatusCode, respBytes)
+		if len(respBytes) == 0 {
+			fmt.Println()
+		}
+	}
+	return resp.StatusCode
+}
+
+func main() {
+	_ = os.RemoveAll("db/")
+	if inventory.PathExists("db/") {
+		log.Fatalln("db exists")
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	httpServer := &http.Server{Handler: inventoryjson.NewGateway(nil)}
+	go httpServer.Serve(l)
+	defer httpServer.Close()
+	baseURL = "http://" + l.Addr().String()
+
+	var doc struct {
+		Paths map[string]any `json:"paths"`
+	}
+	do("GET", "/openapi.json", nil, &doc)
+	fmt.Printf("openapi describes %d paths\n", len(doc.Paths))
+
+	do("GET", "/balances", nil, nil)
+	do("POST", "/db", nil, nil)
+
+	var mainAccs map[string]string
+	do("GET", "/accounts", nil, &mainAccs)
+	var cash, rawMaterial, steel struct {
+		UUID string `json:"uuid"`
+	}
+	do("POST", "/accounts", &inventoryjson.Account{Name: "cash", ParentUUID: mainAccs["asset"]}, &cash)
+	do("POST", "/accounts", &inventoryjson.Account{Name: "raw material", ParentUUID: mainAccs["asset"]}, &rawMaterial)
+	do("POST", "/items", &inventoryjson.Item{Name: "steel", Unit: "kg"}, &steel)
+
+	// a line without an item holds the amount in quantity, debit positive
+	date := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	do("POST", "/transactions", &inventoryjson.Transaction{
+		Description: "owner investment",
+		DatetimeMs:  date.UnixMilli(),
+		TransactionLines: []*inventoryjson.TransactionLine{
+			{AccountUUID: mainAccs["equity"], Quantity: "-1000", Currency: "USD"},
+			{AccountUUID: cash.UUID, Quantity: "1000", Currency: "USD"},
+		},
+	}, nil)
+	do("POST", "/transactions", &inventoryjson.Transaction{
+		Description: "purchase steel 100kg",
+		DatetimeMs:  date.AddDate(0, 0, 1).UnixMilli(),
+		TransactionLines: []*inventoryjson.TransactionLine{
+			{AccountUUID: rawMaterial.UUID, ItemUUID: steel.UUID, Quantity: "100", Unit: "kg", Price: "5", Currency: "USD"},
+			{AccountUUID: cash.UUID, Quantity: "-500", Currency: "USD"},
+		},
+	}, nil)
+	do("POST", "/transactions", `{"description": "not json`, nil)
+
+	do("POST", "/market-prices", &inventoryjson.MarketPrice{
+		ItemUUID:   steel.UUID,
+		DatetimeMs: date.AddDate(0, 0, 2).UnixMilli(),
+		Price:      "5.5",
+		Unit:       "kg",
+		Currency:   "USD",
+	}, nil)
+	do("POST", "/market-prices", &inventoryjson.MarketPrice{
+		ItemUUID: "0190c1a0-0000-7000-8000-000000000000",
+		Price:    "1",
+	}, nil)
+	do("POST", "/market-prices/import?format=csv", "item,date,price,unit,currency\nsteel,2025-09-04,6,kg,USD\nsteel,2025-09-05,6.25,kg,USD\n", nil)
+	do("POST", "/market-prices/import?format=xml", "<prices/>", nil)
+
+	var prices inventoryjson.MarketPriceList
+	do("GET", "/items/"+steel.UUID+"/market-prices?to="+fmt.Sprint(date.AddDate(0, 1, 0).UnixMilli()), nil, &prices)
+	for _, p := range prices.MarketPrices {
+		fmt.Printf("  steel %s: %s %s/%s\n", time.UnixMilli(p.DatetimeMs).UTC().Format("2006-01-02"), p.Price, p.Currency, p.Unit)
+	}
+
+	var balances struct {
+		Balances string `json:"balances"`
+	}
+	do("GET", "/balances/market", nil, &balances)
+	fmt.Print(balances.Balances)
+
+	// the same functions over the framed transport with the json codec
+	server := &inventoryrpc.Server{
+		Processor: inventoryjson.NewServerProcessor(),
+		Codec:     inventoryjson.Codec{},
+	}
+	rpcListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	go server.Serve(rpcListener)
+	defer server.Close()
+	conn, err := inventoryrpc.Dial("tcp", rpcListener.Addr().String(), inventoryjson.Codec{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	ack, err := conn.Handshake(inventoryrpc.NewHello("json"), inventoryjson.Codec{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	reqPkt, err := inventoryjson.NewRequestPkt("PrintBalances", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = conn.WritePacket(reqPkt)
+	if err != nil {
+		log.Fatal(err)
+	}
+	responsePkt, err := conn.ReadPacket()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("PrintBalances over %s codec: %d bytes\n", ack.Codec, len(responsePkt.Body["balances"]))
+	conn.Close()
+
+	// headers reach middleware as Packet.Meta. a missing token is 401, a
+	// call the middleware refuses with another -3xx code is 403.
+	errReadOnly := errors.New("read only gateway")
+	registry := inventoryjson.NewRegistry()
+	inventoryjson.RegisterBuiltinFuncs(registry)
+	registry.Use(inventoryrpc.RequireMeta("token", func(value []byte) bool {
+		return string(value) == "secret"
+	}), func(next inventoryrpc.HandlerFunc) inventoryrpc.HandlerFunc {
+		return func(req *inventoryrpc.Request) error {
+			if req.Func.Name == "CloseCurrDB" {
+				return &inventoryrpc.CodeError{Code: -302, Err: errReadOnly}
+			}
+			return next(req)
+		}
+	})
+	guardedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	guardedServer := &http.Server{Handler: inventoryjson.NewGateway(registry)}
+	go guardedServer.Serve(guardedListener)
+	defer guardedServer.Close()
+	openURL := baseURL
+	baseURL = "http://" + guardedListener.Addr().String()
+	do("GET", "/db", nil, nil)
+	token = "secret"
+	do("GET", "/db", nil, nil)
+	do("DELETE", "/db", nil, nil)
+	token = ""
+	baseURL = openURL
+
+	// the gateway is single-tenant, each POST /db selects the database every
+	// later request works on. concurrent ones are safe, the last one wins.
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	answered := map[int]int{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, req := range []struct{ method, path string }{{"POST", "/db"}, {"GET", "/balances"}} {
+				httpReq, err := http.NewRequest(req.method, baseURL+req.path, nil)
+				if err != nil {
+					log.Fatal(err)
+				}
+				resp, err := http.DefaultClient.Do(httpReq)
+				if err != nil {
+					log.Fatal(err)
+				}
+				resp.Body.Close()
+				mu.Lock()
+				answered[resp.StatusCode]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("concurrent POST /db and GET /balances: %d created, %d ok\n", answered[http.StatusCreated], answered[http.StatusOK])
+
+	do("DELETE", "/db", nil, nil)
+}
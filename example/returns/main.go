@@ -0,0 +1,200 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"inventory"
+	"log"
+	"os"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type book struct {
+	db       *sql.DB
+	accounts map[string]*inventory.Account
+	steel    *inventory.Item
+	vendor   *inventory.Party
+	customer *inventory.Party
+}
+
+func openBook(path string) (*book, error) {
+	_ = os.Remove(path)
+	db, err := sql.Open("sqlite3", "file:"+path+"?cache=shared&mode=rwc")
+	if err != nil {
+		return nil, err
+	}
+	err = inventory.InitSchema(db)
+	if err != nil {
+		return nil, err
+	}
+	b := &book{db: db, accounts: map[string]*inventory.Account{}}
+	for _, a := range []struct {
+		name   string
+		parent *inventory.Account
+	}{
+		{"stock", inventory.AssetAcc},
+		{"cash", inventory.AssetAcc},
+		{"receivable", inventory.AssetAcc},
+		{"payable", inventory.LiabilityAcc},
+		{"sales", inventory.IncomeAcc},
+		{"cost of goods sold", inventory.ExpenseAcc},
+	} {
+		accUUID, err := inventory.AddAccount(db, &inventory.Account{Name: a.name, Parent: a.parent})
+		if err != nil {
+			return nil, err
+		}
+		b.accounts[a.name], err = inventory.GetAccountByUUID(db, accUUID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, err := range []error{
+		inventory.SetReceivableAccount(db, b.accounts["receivable"]),
+		inventory.SetPayableAccount(db, b.accounts["payable"]),
+		inventory.SetCOGSAccount(db, b.accounts["cost of goods sold"]),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	itUUID, err := inventory.AddItem(db, &inventory.Item{Name: "steel", Unit: "kg"})
+	if err != nil {
+		return nil, err
+	}
+	b.steel, err = inventory.GetItemByUUID(db, itUUID)
+	if err != nil {
+		return nil, err
+	}
+	b.vendor = &inventory.Party{Name: "steel mill", Kind: inventory.PartyVendor}
+	b.customer = &inventory.Party{Name: "workshop", Kind: inventory.PartyCustomer}
+	for _, p := range []*inventory.Party{b.vendor, b.customer} {
+		_, err = inventory.AddParty(db, p)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func (b *book) balance(acc string, item *inventory.Item) string {
+	paths, accMap, err := inventory.BuildAccountTree(b.db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	leaf, err := inventory.FetchLeafBalances(b.db, accMap)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, h := range leaf {
+		path := paths[h.TransactionLine.Account.ID]
+		if path[len(path)-1] != acc {
+			continue
+		}
+		lineItem := h.TransactionLine.Item
+		if (item == nil && lineItem == nil) || (item != nil && lineItem != nil && lineItem.ID == item.ID) {
+			return fmt.Sprintf("qty %.2f value %.2f", h.Quantity.ToFloat(), h.Value.ToFloat())
+		}
+	}
+	return "none"
+}
+
+func day(d int) int64 {
+	return time.Date(2025, 9, d, 0, 0, 0, 0, time.UTC).UnixMilli()
+}
+
+func main() {
+	b, err := openBook("returns.db")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.Remove("returns.db")
+	defer b.db.Close()
+
+	// the purchase invoice puts the steel on stock against the payable, it
+	// has no item line to reverse for a vendor return
+	purchase := &inventory.Invoice{
+		Type:       inventory.InvoicePurchase,
+		Number:     "P-1",
+		Party:      b.vendor,
+		DatetimeMs: day(1),
+		Currency:   "USD",
+		Lines: []*inventory.InvoiceLine{
+			{Account: b.accounts["stock"], Item: b.steel, Quantity: inventory.NewDecimalFromStr("100"), Unit: "kg", Price: inventory.NewDecimalFromStr("5")},
+		},
+	}
+	_, err = inventory.IssueInvoice(b.db, purchase)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 10kg go back to the mill, the debit on the payable is the other side
+	vendorReturn := &inventory.Return{
+		Type:       inventory.ReturnToVendor,
+		Source:     purchase.Transaction,
+		DatetimeMs: day(2),
+		Reason:     "rusty",
+		Lines:      []*inventory.ReturnLine{{Item: b.steel, Quantity: inventory.NewDecimalFromStr("10")}},
+		ExtraLines: []*inventory.TransactionLine{
+			inventory.CreateFinancialTrLine(b.accounts["payable"], inventory.NewDecimalFromStr("50"), inventory.NewDecimal(0), "USD"),
+		},
+	}
+	_, err = inventory.CreateReturn(b.db, vendorReturn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("returned 10kg to the vendor at", vendorReturn.Lines[0].UnitCost.ToString())
+	fmt.Println("  stock steel:", b.balance("stock", b.steel))
+	fmt.Println("  payable:", b.balance("payable", nil))
+
+	_, err = inventory.CreateReturn(b.db, &inventory.Return{
+		Type:       inventory.ReturnToVendor,
+		Source:     purchase.Transaction,
+		DatetimeMs: day(2),
+		Lines:      []*inventory.ReturnLine{{Item: b.steel, Quantity: inventory.NewDecimalFromStr("95")}},
+	})
+	fmt.Println("returning 95kg more:", errors.Is(err, inventory.ErrReturnExceedsSource))
+
+	// the sales invoice moves the steel to cost of goods sold at its average
+	// cost, the customer return puts it back at that cost
+	sale := &inventory.Invoice{
+		Type:       inventory.InvoiceSales,
+		Number:     "S-1",
+		Party:      b.customer,
+		DatetimeMs: day(3),
+		Currency:   "USD",
+		Lines: []*inventory.InvoiceLine{
+			{Account: b.accounts["sales"], Item: b.steel, Quantity: inventory.NewDecimalFromStr("20"), Unit: "kg", Price: inventory.NewDecimalFromStr("8"), StockAccount: b.accounts["stock"]},
+		},
+	}
+	_, err = inventory.IssueInvoice(b.db, sale)
+	if err != nil {
+		log.Fatal(err)
+	}
+	customerReturn := &inventory.Return{
+		Type:       inventory.ReturnFromCustomer,
+		Source:     sale.Transaction,
+		DatetimeMs: day(4),
+		Lines:      []*inventory.ReturnLine{{Item: b.steel, Quantity: inventory.NewDecimalFromStr("5")}},
+		ExtraLines: []*inventory.TransactionLine{
+			inventory.CreateFinancialTrLine(b.accounts["sales"], inventory.NewDecimalFromStr("40"), inventory.NewDecimal(0), "USD"),
+			inventory.CreateFinancialTrLine(b.accounts["receivable"], inventory.NewDecimal(0), inventory.NewDecimalFromStr("40"), "USD"),
+		},
+	}
+	_, err = inventory.CreateReturn(b.db, customerReturn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("customer returned 5kg at", customerReturn.Lines[0].UnitCost.ToString())
+	fmt.Println("  stock steel:", b.balance("stock", b.steel))
+	fmt.Println("  cost of goods sold steel:", b.balance("cost of goods sold", b.steel))
+	fmt.Println("  receivable:", b.balance("receivable", nil))
+
+	err = inventory.PrintReturns(b.db, day(1), day(5))
+	if err != nil {
+		log.Fatal(err)
+	}
+}
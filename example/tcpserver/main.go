@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"inventory"
+	"inventorypb"
+	"inventoryrpc"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var token = []byte("secret")
+
+func dial(network, address string) (*inventorypb.Client, error) {
+	client, err := inventorypb.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	client.Meta = map[string][]byte{"token": token}
+	return client, nil
+}
+
+func main() {
+	_ = os.RemoveAll("db/")
+	if inventory.PathExists("db/") {
+		log.Fatalln("db exists")
+	}
+	sockDir, err := os.MkdirTemp("", "inventoryrpc")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(sockDir)
+	sockPath := filepath.Join(sockDir, "inventory.sock")
+
+	metrics := inventoryrpc.NewMetrics()
+	registry := inventorypb.NewRegistry()
+	inventorypb.RegisterBuiltinFuncs(registry)
+	registry.Register(&inventorypb.ServerFunc{
+		Name:     "Greet",
+		Arg:      &inventorypb.Party{},
+		NeedsArg: true,
+		Handler: func(req *inventorypb.Request) error {
+			req.Payload["greeting"] = []byte("hello " + req.Arg.(*inventorypb.Party).Name)
+			return nil
+		},
+	})
+	registry.Use(metrics.Middleware, inventoryrpc.RequireMeta("token", func(value []byte) bool {
+		return string(value) == string(token)
+	}))
+	processor := inventorypb.NewServerProcessor()
+	processor.Registry = registry
+
+	server := &inventoryrpc.Server{
+		Processor:   processor,
+		Codec:       inventorypb.Codec{},
+		Features:    []string{"metrics"},
+		MaxConns:    4,
+		IdleTimeout: 500 * time.Millisecond,
+	}
+
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	unixListener, err := net.Listen("unix", sockPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var serveWg sync.WaitGroup
+	for _, l := range []net.Listener{tcpListener, unixListener} {
+		serveWg.Add(1)
+		go func(l net.Listener) {
+			defer serveWg.Done()
+			err := server.Serve(l)
+			if err != inventoryrpc.ErrServerClosed {
+				log.Fatal(err)
+			}
+		}(l)
+	}
+
+	ctx := context.Background()
+	client, err := dial("tcp", tcpListener.Addr().String())
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("init db over tcp")
+	dbUUID, err := client.OpenOrCreateDB(ctx, uuid.Nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("add items over unix socket, 15 calls in flight on one connection")
+	unixClient, err := dial("unix", sockPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 15; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := unixClient.AddItem(ctx, &inventory.Item{
+				Name: fmt.Sprintf("item %d", i),
+				Unit: "pcs",
+			})
+			if err != nil {
+				log.Fatal(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	unixClient.Close()
+
+	// the server is single-tenant, a database opened on one connection is
+	// the current one for all of them
+	fmt.Println("two connections open databases while a third adds items")
+	otherUUID, err := uuid.NewV7()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, id := range []uuid.UUID{dbUUID, otherUUID} {
+		c, err := dial("tcp", tcpListener.Addr().String())
+		if err != nil {
+			log.Fatal(err)
+		}
+		wg.Add(1)
+		go func(c *inventorypb.Client, id uuid.UUID) {
+			defer wg.Done()
+			defer c.Close()
+			for i := 0; i < 5; i++ {
+				_, err := c.OpenOrCreateDB(ctx, id)
+				if err != nil {
+					log.Fatal(err)
+				}
+				_, err = c.GetMainAccounts(ctx)
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+		}(c, id)
+	}
+	for i := 0; i < 5; i++ {
+		_, err := client.AddItem(ctx, &inventory.Item{Name: fmt.Sprintf("shared %d", i), Unit: "pcs"})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	wg.Wait()
+	_, err = client.OpenOrCreateDB(ctx, dbUUID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	currUUID, err := client.GetCurrDB(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("current db is the one opened last: %v\n", currUUID == dbUUID)
+
+	_, err = client.Call(ctx, "NoSuchFunction", nil)
+	fmt.Printf("unknown function: %v, is ErrNoSuchFunc: %v\n", err, errors.Is(err, inventoryrpc.ErrNoSuchFunc))
+
+	balances, err := client.PrintBalances(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(balances)
+
+	responsePkt, err := client.Call(ctx, "Greet", &inventorypb.Party{Name: "vendor"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(responsePkt.Body["greeting"]))
+
+	anonClient, err := inventorypb.Dial("tcp", tcpListener.Addr().String())
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = anonClient.GetMainAccounts(ctx)
+	fmt.Printf("without token: %v, is ErrUnauthorized: %v\n", err, errors.Is(err, inventoryrpc.ErrUnauthorized))
+	anonClient.Close()
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Nanosecond)
+	_, err = client.Call(timeoutCtx, "GetMainAccounts", nil)
+	cancel()
+	fmt.Printf("timed out call: %v\n", err)
+
+	fmt.Println("handshake")
+	hello := inventoryrpc.NewHello("json", "protobuf")
+	hello.Compression = []string{inventoryrpc.CompressionGzip}
+	hello.Features = []string{"metrics", "events"}
+	gzipClient, err := inventorypb.DialWithHello("tcp", tcpListener.Addr().String(), hello)
+	if err != nil {
+		log.Fatal(err)
+	}
+	gzipClient.Meta = map[string][]byte{"token": token}
+	balances, err = gzipClient.PrintBalances(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	conn := gzipClient.Conn()
+	fmt.Printf("version %d codec %s compression %s features %v, %d bytes of balances\n",
+		conn.Version, conn.Codec.Name(), conn.Compression, conn.Features, len(balances))
+	gzipClient.Close()
+
+	hello = inventoryrpc.NewHello("protobuf")
+	hello.Version, hello.MinVersion = 7, 5
+	_, err = inventorypb.DialWithHello("tcp", tcpListener.Addr().String(), hello)
+	fmt.Printf("newer client: %v, is ErrIncompatiblePeer: %v\n", err, errors.Is(err, inventoryrpc.ErrIncompatiblePeer))
+	_, err = inventorypb.DialWithHello("tcp", tcpListener.Addr().String(), inventoryrpc.NewHello("json"))
+	fmt.Printf("json only client: %v\n", err)
+
+	// no handshake, served with the server's Codec
+	legacyConn, err := inventoryrpc.Dial("tcp", tcpListener.Addr().String(), inventorypb.Codec{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	legacyClient := inventorypb.NewClient(legacyConn)
+	legacyClient.Meta = map[string][]byte{"token": token}
+	_, err = legacyClient.GetMainAccounts(ctx)
+	fmt.Printf("client without handshake: %v\n", err)
+	legacyClient.Close()
+
+	fmt.Println("connection limit")
+	var extra []*inventorypb.Client
+	for i := 0; i < 4; i++ {
+		c, err := dial("tcp", tcpListener.Addr().String())
+		if err != nil {
+			// the handshake is the first read on a rejected connection
+			fmt.Printf("dial %d: %v\n", i, err)
+			continue
+		}
+		extra = append(extra, c)
+	}
+	for i, c := range extra {
+		_, err = c.GetMainAccounts(ctx)
+		fmt.Printf("conn %d: %v\n", i, err)
+		c.Close()
+	}
+
+	fmt.Println("idle timeout")
+	time.Sleep(server.IdleTimeout * 2)
+	_, err = client.GetMainAccounts(ctx)
+	fmt.Printf("after idle: %v\n", err)
+	client.Close()
+
+	client, err = dial("tcp", tcpListener.Addr().String())
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = client.CloseCurrDB(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err = server.Shutdown(shutdownCtx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	serveWg.Wait()
+	_, err = client.GetMainAccounts(ctx)
+	fmt.Printf("after shutdown: %v\n", err)
+	for _, fm := range metrics.Snapshot() {
+		fmt.Printf("%-16s calls %d errors %d\n", fm.Name, fm.Calls, fm.Errors)
+	}
+	_ = os.RemoveAll("db/")
+}
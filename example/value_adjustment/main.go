@@ -0,0 +1,270 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"inventory"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// the purchase_and_produce leaf balances from before value adjustment lines
+// were added, as in example/purchase_and_produce/ref/out.txt, which prints
+// equity negated
+var reference = map[string]string{
+	"asset > cash": "qty 200.00 value 0.00",
+	"asset > inventory > finished product steel": "qty 10.00 value 100.00",
+	"asset > inventory > raw material steel":     "qty 80.00 value 400.00",
+	"asset > inventory > raw material wood":      "qty 150.00 value 450.00",
+	"asset > inventory > work in progress steel": "qty 0.00 value 0.00",
+	"equity":                      "qty -1000.00 value 0.00",
+	"expense > material purchase": "qty 800.00 value 0.00",
+	"non-financial income > incoming material steel": "qty -100.00 value -500.00",
+	"non-financial income > incoming material wood":  "qty -150.00 value -450.00",
+}
+
+type book struct {
+	db       *sql.DB
+	accounts map[string]*inventory.Account
+	items    map[string]*inventory.Item
+}
+
+func openBook(path string) (*book, error) {
+	_ = os.Remove(path)
+	db, err := sql.Open("sqlite3", "file:"+path+"?cache=shared&mode=rwc")
+	if err != nil {
+		return nil, err
+	}
+	err = inventory.InitSchema(db)
+	if err != nil {
+		return nil, err
+	}
+	b := &book{
+		db:       db,
+		accounts: map[string]*inventory.Account{"equity": inventory.EquityAcc},
+		items:    map[string]*inventory.Item{},
+	}
+	for _, a := range []struct{ name, parent string }{
+		{"non-financial income", ""},
+		{"incoming material", "non-financial income"},
+		{"inventory", "asset"},
+		{"raw material", "inventory"},
+		{"work in progress", "inventory"},
+		{"finished product", "inventory"},
+		{"cash", "asset"},
+		{"material purchase", "expense"},
+		{"cost of goods sold", "expense"},
+	} {
+		parent := b.accounts[a.parent]
+		switch a.parent {
+		case "asset":
+			parent = inventory.AssetAcc
+		case "expense":
+			parent = inventory.ExpenseAcc
+		}
+		accUUID, err := inventory.AddAccount(db, &inventory.Account{Name: a.name, Parent: parent})
+		if err != nil {
+			return nil, err
+		}
+		b.accounts[a.name], err = inventory.GetAccountByUUID(db, accUUID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range []string{"steel", "wood"} {
+		itUUID, err := inventory.AddItem(db, &inventory.Item{Name: name, Unit: "kg"})
+		if err != nil {
+			return nil, err
+		}
+		b.items[name], err = inventory.GetItemByUUID(db, itUUID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func (b *book) money(acc string, amount string) *inventory.TransactionLine {
+	return &inventory.TransactionLine{Account: b.accounts[acc], Quantity: inventory.NewDecimalFromStr(amount), Currency: "USD"}
+}
+
+func (b *book) stock(acc, item, qty, price string) *inventory.TransactionLine {
+	return inventory.CreateInventoryTrLine(b.accounts[acc], b.items[item], inventory.NewDecimalFromStr(qty), "kg", inventory.NewDecimalFromStr(price), "USD")
+}
+
+// the postings of example/purchase_and_produce
+func (b *book) purchaseAndProduce() []*inventory.Transaction {
+	date := func(day int) int64 {
+		return time.Date(2025, 9, day, 0, 0, 0, 0, time.UTC).UnixMilli()
+	}
+	return []*inventory.Transaction{
+		{Description: "Owner Investment", DatetimeMs: date(1), TransactionLines: []*inventory.TransactionLine{
+			b.money("equity", "-1000"),
+			b.money("cash", "1000"),
+		}},
+		{Description: "Purchase Steel 100kg", DatetimeMs: date(2), TransactionLines: []*inventory.TransactionLine{
+			b.stock("incoming material", "steel", "-100", "5"),
+			b.stock("raw material", "steel", "100", "5"),
+			b.money("cash", "-500"),
+			b.money("material purchase", "500"),
+		}},
+		{Description: "Purchase Wood 150kg", DatetimeMs: date(3), TransactionLines: []*inventory.TransactionLine{
+			b.stock("incoming material", "wood", "-150", "3"),
+			b.stock("raw material", "wood", "150", "3"),
+			b.money("cash", "-300"),
+			b.money("material purchase", "300"),
+		}},
+		{Description: "Use Steel to Manufacture Widgets", DatetimeMs: date(4), TransactionLines: []*inventory.TransactionLine{
+			b.stock("raw material", "steel", "-20", "5"),
+			b.stock("work in progress", "steel", "20", "5"),
+		}},
+		{Description: "Complete Widgets", DatetimeMs: date(5), TransactionLines: []*inventory.TransactionLine{
+			b.stock("work in progress", "steel", "-20", "5"),
+			b.stock("finished product", "steel", "10", "10"),
+		}},
+	}
+}
+
+// leaf balances by account path and item
+func (b *book) balances() (map[string]string, error) {
+	paths, accMap, err := inventory.BuildAccountTree(b.db)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := inventory.FetchLeafBalances(b.db, accMap)
+	if err != nil {
+		return nil, err
+	}
+	balances := map[string]string{}
+	for _, h := range leaf {
+		key := strings.Join(paths[h.TransactionLine.Account.ID], " > ")
+		if h.TransactionLine.Item != nil {
+			key += " " + h.TransactionLine.Item.Name
+		}
+		balances[key] = fmt.Sprintf("qty %.2f value %.2f", h.Quantity.ToFloat(), h.Value.ToFloat())
+	}
+	return balances, nil
+}
+
+func compare(name string, got, want map[string]string) {
+	keys := make([]string, 0, len(want))
+	for k := range want {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	same := len(got) == len(want)
+	for _, k := range keys {
+		if got[k] != want[k] {
+			fmt.Printf("  %s: %s, want %s\n", k, got[k], want[k])
+			same = false
+		}
+	}
+	fmt.Printf("%s: %v\n", name, same)
+}
+
+func main() {
+	ordered, err := openBook("ordered.db")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.Remove("ordered.db")
+	defer ordered.db.Close()
+	postings := ordered.purchaseAndProduce()
+	for _, tr := range postings {
+		_, err = inventory.ApplyTransaction(ordered.db, tr)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	orderedBalances, err := ordered.balances()
+	if err != nil {
+		log.Fatal(err)
+	}
+	compare("posted in date order, same balances as before", orderedBalances, reference)
+
+	// a value adjustment line changes the value only, e.g. freight
+	// capitalized onto the steel still in raw material
+	_, err = inventory.ApplyTransaction(ordered.db, &inventory.Transaction{
+		Description: "Freight on steel",
+		DatetimeMs:  time.Date(2025, 9, 6, 0, 0, 0, 0, time.UTC).UnixMilli(),
+		TransactionLines: []*inventory.TransactionLine{
+			inventory.CreateValueAdjustmentTrLine(ordered.accounts["raw material"], ordered.items["steel"], inventory.NewDecimalFromStr("40"), "USD"),
+			ordered.money("cash", "-40"),
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	orderedBalances, err = ordered.balances()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("after 40 USD freight on raw material steel:", orderedBalances["asset > inventory > raw material steel"])
+
+	// a zero quantity line that isn't marked as a value adjustment adds
+	// nothing, whatever its price
+	_, err = inventory.ApplyTransaction(ordered.db, &inventory.Transaction{
+		Description: "Zero quantity steel",
+		DatetimeMs:  time.Date(2025, 9, 7, 0, 0, 0, 0, time.UTC).UnixMilli(),
+		TransactionLines: []*inventory.TransactionLine{
+			ordered.stock("raw material", "steel", "0", "7"),
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	orderedBalances, err = ordered.balances()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("after a zero quantity line priced 7:", orderedBalances["asset > inventory > raw material steel"])
+
+	// half the finished steel is sold, then 100 USD of freight on the steel
+	// purchase comes in, dated back to the day after it. the 20kg that went
+	// on to production carry their share to the finished product, and the
+	// half of that sold on to cost of goods sold.
+	_, err = inventory.ApplyTransaction(ordered.db, &inventory.Transaction{
+		Description: "Sell finished steel",
+		DatetimeMs:  time.Date(2025, 9, 8, 0, 0, 0, 0, time.UTC).UnixMilli(),
+		TransactionLines: []*inventory.TransactionLine{
+			ordered.stock("finished product", "steel", "-5", "10"),
+			ordered.stock("cost of goods sold", "steel", "5", "10"),
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	lc := &inventory.LandedCost{
+		Description: "Freight on the steel purchase",
+		DatetimeMs:  time.Date(2025, 9, 3, 0, 0, 0, 0, time.UTC).UnixMilli(),
+		Method:      inventory.LandedCostByQuantity,
+		Receipts:    []*inventory.Transaction{postings[1]},
+		Charges: []*inventory.LandedCostCharge{
+			{Account: ordered.accounts["cash"], Amount: inventory.NewDecimalFromStr("100"), Currency: "USD"},
+		},
+	}
+	_, err = inventory.ApplyLandedCost(ordered.db, lc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	orderedBalances, err = ordered.balances()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("after 100 USD landed cost on the steel purchase:")
+	for _, k := range []string{
+		"asset > inventory > raw material steel",
+		"asset > inventory > work in progress steel",
+		"asset > inventory > finished product steel",
+		"expense > cost of goods sold steel",
+		"asset > cash",
+	} {
+		fmt.Printf("  %s: %s\n", k, orderedBalances[k])
+	}
+	fmt.Printf("  carried out of inventory: %s\n", lc.Allocations[0].Expensed.ToString())
+}
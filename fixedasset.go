@@ -0,0 +1,558 @@
+package inventory
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DepreciationStraightLine     = "straight_line"
+	DepreciationDecliningBalance = "declining_balance"
+)
+
+const (
+	FixedAssetActive   = "active"
+	FixedAssetDisposed = "disposed"
+)
+
+var ErrUnknownDepreciationMethod = errors.New("unknown depreciation method")
+var ErrInvalidUsefulLife = errors.New("useful life must be positive")
+var ErrInvalidSalvageValue = errors.New("salvage value must be between 0 and cost")
+var ErrAssetAlreadyRegistered = errors.New("transaction line already registered as fixed asset")
+var ErrAssetNotActive = errors.New("fixed asset is not active")
+
+type FixedAsset struct {
+	ID                             int
+	UUID                           uuid.UUID
+	Name                           string
+	TransactionLine                *TransactionLine
+	Account                        *Account
+	Item                           *Item
+	Quantity                       Decimal
+	Unit                           string
+	Cost                           Decimal
+	Currency                       string
+	SalvageValue                   Decimal
+	Method                         string
+	UsefulLifeMonths               int
+	DecliningFactor                Decimal
+	StartDatetimeMs                int64
+	ExpenseAccount                 *Account
+	AccumulatedDepreciationAccount *Account
+	Status                         string
+	DisposedDatetimeMs             int64
+	DisposalTransaction            *Transaction
+	Proceeds                       Decimal
+}
+
+type DepreciationEntry struct {
+	FixedAsset  *FixedAsset
+	Period      int
+	DatetimeMs  int64
+	Amount      Decimal
+	Accumulated Decimal
+	BookValue   Decimal
+	Transaction *Transaction
+}
+
+type FixedAssetDisposal struct {
+	FixedAsset      *FixedAsset
+	Description     string
+	DatetimeMs      int64
+	Proceeds        Decimal
+	ProceedsAccount *Account
+	GainLossAccount *Account
+}
+
+// last day of the month, period 0 being the month the asset starts in
+func depreciationPeriodEnd(startMs int64, period int) time.Time {
+	start := time.UnixMilli(startMs)
+	return time.Date(start.Year(), start.Month()+time.Month(period)+1, 0, 0, 0, 0, 0, time.Local)
+}
+
+// full monthly schedule over the useful life. declining balance switches to
+// straight line once that gives the bigger amount, so the book value reaches
+// the salvage value in the last month.
+func (a *FixedAsset) Schedule() []DepreciationEntry {
+	life := a.UsefulLifeMonths
+	if life <= 0 || a.Cost.Data-a.SalvageValue.Data <= 0 {
+		return nil
+	}
+	entries := make([]DepreciationEntry, 0, life)
+	book := a.Cost.Data
+	accumulated := int64(0)
+	for p := 0; p < life; p++ {
+		remaining := book - a.SalvageValue.Data
+		var amount int64
+		switch a.Method {
+		case DepreciationDecliningBalance:
+			amount = NewDecimal(book).Multiply(a.DecliningFactor).Data / int64(life)
+			straight := remaining / int64(life-p)
+			if straight > amount {
+				amount = straight
+			}
+		default:
+			amount = (a.Cost.Data - a.SalvageValue.Data) / int64(life)
+		}
+		if amount > remaining || p == life-1 {
+			amount = remaining
+		}
+		if amount <= 0 {
+			break
+		}
+		book -= amount
+		accumulated += amount
+		entries = append(entries, DepreciationEntry{
+			FixedAsset:  a,
+			Period:      p,
+			DatetimeMs:  depreciationPeriodEnd(a.StartDatetimeMs, p).UnixMilli(),
+			Amount:      NewDecimal(amount),
+			Accumulated: NewDecimal(accumulated),
+			BookValue:   NewDecimal(book),
+		})
+	}
+	return entries
+}
+
+func resolveAccount(db *sql.DB, acc *Account) error {
+	if acc == nil {
+		return sql.ErrNoRows
+	}
+	if acc.ID > 0 {
+		return nil
+	}
+	tmpAcc, err := GetAccountByUUID(db, acc.UUID[:])
+	if err != nil {
+		return err
+	}
+	*acc = *tmpAcc
+	return nil
+}
+
+// registers the asset acquired by a.TransactionLine (uuid is enough). account,
+// item, quantity, cost and currency are taken from the line.
+func RegisterFixedAsset(db *sql.DB, a *FixedAsset) ([]byte, error) {
+	if a.TransactionLine == nil {
+		return nil, sql.ErrNoRows
+	}
+	line, err := GetTransactionLineByUUID(db, a.TransactionLine.UUID[:])
+	if err != nil {
+		return nil, err
+	}
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM fixed_assets WHERE transaction_line_id=?`, line.ID).Scan(&count)
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrAssetAlreadyRegistered
+	}
+
+	a.TransactionLine = line
+	a.Account = line.Account
+	a.Item = line.Item
+	a.Currency = line.Currency
+	if line.Item != nil {
+		a.Quantity = line.Quantity
+		a.Unit = line.Unit
+		a.Cost = line.Quantity.Multiply(line.Price)
+	} else {
+		a.Quantity = NewDecimalFromIntFrac(1, 0)
+		a.Cost = line.Quantity
+	}
+	if a.Name == "" {
+		a.Name = line.Transaction.Description
+	}
+	if a.StartDatetimeMs == 0 {
+		a.StartDatetimeMs = line.Transaction.DatetimeMs
+	}
+	if a.SalvageValue.FracDivisor == 0 {
+		a.SalvageValue = NewDecimal(a.SalvageValue.Data)
+	}
+	switch a.Method {
+	case "":
+		a.Method = DepreciationStraightLine
+	case DepreciationStraightLine:
+	case DepreciationDecliningBalance:
+		if a.DecliningFactor.Data <= 0 {
+			a.DecliningFactor = NewDecimalFromIntFrac(2, 0)
+		}
+	default:
+		return nil, ErrUnknownDepreciationMethod
+	}
+	if a.UsefulLifeMonths <= 0 {
+		return nil, ErrInvalidUsefulLife
+	}
+	if a.SalvageValue.Data < 0 || a.SalvageValue.Data >= a.Cost.Data {
+		return nil, ErrInvalidSalvageValue
+	}
+	err = resolveAccount(db, a.ExpenseAccount)
+	if err != nil {
+		return nil, fmt.Errorf("expense account: %w", err)
+	}
+	err = resolveAccount(db, a.AccumulatedDepreciationAccount)
+	if err != nil {
+		return nil, fmt.Errorf("accumulated depreciation account: %w", err)
+	}
+
+	a.UUID, err = uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	a.Status = FixedAssetActive
+	itemID := sql.NullInt64{}
+	if a.Item != nil {
+		itemID = sql.NullInt64{Int64: int64(a.Item.ID), Valid: true}
+	}
+	res, err := db.Exec(`
+		INSERT INTO fixed_assets(uuid,name,transaction_line_id,account_id,item_id,quantity,unit,cost,currency,salvage_value,
+			method,useful_life_months,declining_factor,start_datetime_ms,expense_account_id,accumulated_account_id,status)
+		VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`,
+		a.UUID[:], a.Name, line.ID, a.Account.ID, itemID, a.Quantity, a.Unit, a.Cost, a.Currency, a.SalvageValue,
+		a.Method, a.UsefulLifeMonths, a.DecliningFactor.Data, a.StartDatetimeMs, a.ExpenseAccount.ID, a.AccumulatedDepreciationAccount.ID, a.Status)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	a.ID = int(id)
+	return a.UUID[:], nil
+}
+
+func scanFixedAssets(db *sql.DB, where string, args ...any) ([]*FixedAsset, error) {
+	rows, err := db.Query(`
+		SELECT id,uuid,name,transaction_line_id,account_id,item_id,quantity,unit,cost,currency,salvage_value,
+			method,useful_life_months,declining_factor,start_datetime_ms,expense_account_id,accumulated_account_id,
+			status,disposed_datetime_ms,disposal_transaction_id,proceeds
+		FROM fixed_assets `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	type assetRefs struct {
+		accID, expenseAccID, accumulatedAccID int
+		itemID, disposalTrID                  sql.NullInt64
+	}
+	var assets []*FixedAsset
+	var refs []assetRefs
+	for rows.Next() {
+		a := &FixedAsset{TransactionLine: &TransactionLine{}}
+		var r assetRefs
+		var assetUUID []byte
+		var unit sql.NullString
+		var decliningFactor int64
+		var disposedMs, proceeds sql.NullInt64
+		err = rows.Scan(&a.ID, &assetUUID, &a.Name, &a.TransactionLine.ID, &r.accID, &r.itemID, &a.Quantity, &unit, &a.Cost, &a.Currency, &a.SalvageValue,
+			&a.Method, &a.UsefulLifeMonths, &decliningFactor, &a.StartDatetimeMs, &r.expenseAccID, &r.accumulatedAccID,
+			&a.Status, &disposedMs, &r.disposalTrID, &proceeds)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		a.UUID, err = uuid.FromBytes(assetUUID)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		a.Unit = unit.String
+		a.DecliningFactor = NewDecimal(decliningFactor)
+		a.DisposedDatetimeMs = disposedMs.Int64
+		a.Proceeds = NewDecimal(proceeds.Int64)
+		assets = append(assets, a)
+		refs = append(refs, r)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, a := range assets {
+		r := refs[i]
+		if a.Account, err = GetAccountByID(db, r.accID); err != nil {
+			return nil, err
+		}
+		if a.ExpenseAccount, err = GetAccountByID(db, r.expenseAccID); err != nil {
+			return nil, err
+		}
+		if a.AccumulatedDepreciationAccount, err = GetAccountByID(db, r.accumulatedAccID); err != nil {
+			return nil, err
+		}
+		if r.itemID.Valid {
+			if a.Item, err = GetItemByID(db, int(r.itemID.Int64)); err != nil {
+				return nil, err
+			}
+		}
+		if r.disposalTrID.Valid {
+			a.DisposalTransaction = &Transaction{ID: int(r.disposalTrID.Int64)}
+		}
+	}
+	return assets, nil
+}
+
+func GetFixedAssets(db *sql.DB) ([]*FixedAsset, error) {
+	return scanFixedAssets(db, "")
+}
+
+func GetFixedAssetByUUID(db *sql.DB, assetUUID []byte) (*FixedAsset, error) {
+	assets, err := scanFixedAssets(db, "WHERE uuid=?", assetUUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(assets) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return assets[0], nil
+}
+
+// posts every depreciation month of assets that ended at or before datetimeMs
+// and isn't posted yet, one transaction per month
+func postDepreciationTx(db *sql.DB, tx *sql.Tx, assets []*FixedAsset, datetimeMs int64) ([]DepreciationEntry, error) {
+	var due []DepreciationEntry
+	for _, a := range assets {
+		if a.Status != FixedAssetActive {
+			continue
+		}
+		var lastPeriod sql.NullInt64
+		err := tx.QueryRow(`SELECT MAX(period) FROM fixed_asset_depreciations WHERE fixed_asset_id=?`, a.ID).Scan(&lastPeriod)
+		if err != nil {
+			return nil, err
+		}
+		next := 0
+		if lastPeriod.Valid {
+			next = int(lastPeriod.Int64) + 1
+		}
+		for _, e := range a.Schedule() {
+			if e.Period >= next && e.DatetimeMs <= datetimeMs {
+				due = append(due, e)
+			}
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].DatetimeMs < due[j].DatetimeMs })
+
+	for i := 0; i < len(due); {
+		j := i
+		transaction := &Transaction{
+			Description: fmt.Sprintf("Depreciation %s", time.UnixMilli(due[i].DatetimeMs).Format("2006-01")),
+			DatetimeMs:  due[i].DatetimeMs,
+		}
+		for ; j < len(due) && due[j].DatetimeMs == due[i].DatetimeMs; j++ {
+			e := due[j]
+			a := e.FixedAsset
+			expenseLine := CreateFinancialTrLine(a.ExpenseAccount, e.Amount, NewDecimal(0), a.Currency)
+			expenseLine.Note = a.Name
+			accumulatedLine := CreateFinancialTrLine(a.AccumulatedDepreciationAccount, NewDecimal(0), e.Amount, a.Currency)
+			accumulatedLine.Note = a.Name
+			transaction.TransactionLines = append(transaction.TransactionLines, expenseLine, accumulatedLine)
+		}
+		_, trID, err := applyTransactionTx(db, tx, transaction)
+		if err != nil {
+			return nil, err
+		}
+		for k := i; k < j; k++ {
+			e := &due[k]
+			e.Transaction = transaction
+			depUUID, err := uuid.NewV7()
+			if err != nil {
+				return nil, err
+			}
+			_, err = tx.Exec(`
+				INSERT INTO fixed_asset_depreciations(uuid,fixed_asset_id,transaction_id,period,datetime_ms,amount,accumulated,book_value)
+				VALUES(?,?,?,?,?,?,?,?)`,
+				depUUID[:], e.FixedAsset.ID, trID, e.Period, e.DatetimeMs, e.Amount, e.Accumulated, e.BookValue)
+			if err != nil {
+				return nil, err
+			}
+		}
+		i = j
+	}
+	return due, nil
+}
+
+// posts all depreciation due up to datetimeMs. months already posted are
+// skipped, so running it again posts nothing new.
+func RunDepreciation(db *sql.DB, datetimeMs int64) ([]DepreciationEntry, error) {
+	assets, err := GetFixedAssets(db)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	entries, err := postDepreciationTx(db, tx, assets, datetimeMs)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	publishTransactions(db, depreciationTransactionIDs(entries)...)
+	return entries, nil
+}
+
+// one id per posted transaction, entries of the same month share one
+func depreciationTransactionIDs(entries []DepreciationEntry) []int64 {
+	var trIDs []int64
+	for i, e := range entries {
+		if i == 0 || e.Transaction != entries[i-1].Transaction {
+			trIDs = append(trIDs, int64(e.Transaction.ID))
+		}
+	}
+	return trIDs
+}
+
+func accumulatedDepreciation(q interface {
+	QueryRow(query string, args ...any) *sql.Row
+}, assetID int, datetimeMs int64) (Decimal, error) {
+	var sum sql.NullInt64
+	err := q.QueryRow(`SELECT SUM(amount) FROM fixed_asset_depreciations WHERE fixed_asset_id=? AND datetime_ms <= ?`,
+		assetID, datetimeMs).Scan(&sum)
+	return NewDecimal(sum.Int64), err
+}
+
+// depreciates up to the disposal date, then removes the asset and its
+// accumulated depreciation and books proceeds minus book value as gain or
+// loss
+func DisposeFixedAsset(db *sql.DB, d *FixedAssetDisposal) ([]byte, error) {
+	a, err := GetFixedAssetByUUID(db, d.FixedAsset.UUID[:])
+	if err != nil {
+		return nil, err
+	}
+	if a.Status != FixedAssetActive {
+		return nil, ErrAssetNotActive
+	}
+	d.FixedAsset = a
+	if d.Proceeds.FracDivisor == 0 {
+		d.Proceeds = NewDecimal(d.Proceeds.Data)
+	}
+	if d.Proceeds.Data != 0 {
+		err = resolveAccount(db, d.ProceedsAccount)
+		if err != nil {
+			return nil, fmt.Errorf("proceeds account: %w", err)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	entries, err := postDepreciationTx(db, tx, []*FixedAsset{a}, d.DatetimeMs)
+	if err != nil {
+		return nil, err
+	}
+	accumulated, err := accumulatedDepreciation(tx, a.ID, d.DatetimeMs)
+	if err != nil {
+		return nil, err
+	}
+	book := NewDecimal(a.Cost.Data - accumulated.Data)
+	gain := NewDecimal(d.Proceeds.Data - book.Data)
+
+	description := d.Description
+	if description == "" {
+		description = "Disposal of " + a.Name
+	}
+	transaction := &Transaction{
+		Description: description,
+		DatetimeMs:  d.DatetimeMs,
+	}
+	if accumulated.Data != 0 {
+		transaction.TransactionLines = append(transaction.TransactionLines,
+			CreateFinancialTrLine(a.AccumulatedDepreciationAccount, accumulated, NewDecimal(0), a.Currency))
+	}
+	if d.Proceeds.Data != 0 {
+		transaction.TransactionLines = append(transaction.TransactionLines,
+			CreateFinancialTrLine(d.ProceedsAccount, d.Proceeds, NewDecimal(0), a.Currency))
+	}
+	if a.Item != nil {
+		price := a.Cost.Divide(a.Quantity)
+		transaction.TransactionLines = append(transaction.TransactionLines,
+			CreateInventoryTrLine(a.Account, a.Item, NewDecimal(-a.Quantity.Data), a.Unit, price, a.Currency))
+		if rest := a.Cost.Data - a.Quantity.Multiply(price).Data; rest != 0 {
+			transaction.TransactionLines = append(transaction.TransactionLines,
+				CreateValueAdjustmentTrLine(a.Account, a.Item, NewDecimal(-rest), a.Currency))
+		}
+	} else {
+		transaction.TransactionLines = append(transaction.TransactionLines,
+			CreateFinancialTrLine(a.Account, NewDecimal(0), a.Cost, a.Currency))
+	}
+	if gain.Data != 0 {
+		err = resolveAccount(db, d.GainLossAccount)
+		if err != nil {
+			return nil, fmt.Errorf("gain/loss account: %w", err)
+		}
+		// gain is credited, loss debited
+		transaction.TransactionLines = append(transaction.TransactionLines,
+			CreateFinancialTrLine(d.GainLossAccount, NewDecimal(0), gain, a.Currency))
+	}
+
+	trUUID, trID, err := applyTransactionTx(db, tx, transaction)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(`UPDATE fixed_assets SET status=?, disposed_datetime_ms=?, disposal_transaction_id=?, proceeds=? WHERE id=?`,
+		FixedAssetDisposed, d.DatetimeMs, trID, d.Proceeds, a.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	publishTransactions(db, append(depreciationTransactionIDs(entries), trID)...)
+	a.Status = FixedAssetDisposed
+	a.DisposedDatetimeMs = d.DatetimeMs
+	a.DisposalTransaction = transaction
+	a.Proceeds = d.Proceeds
+	return trUUID, nil
+}
+
+func SprintFixedAssetRegister(db *sql.DB, datetimeMs int64) (string, error) {
+	outStr := ""
+	assets, err := GetFixedAssets(db)
+	if err != nil {
+		return outStr, err
+	}
+
+	outStr += fmt.Sprintln("=== Fixed Asset Register ===")
+	for _, a := range assets {
+		if a.StartDatetimeMs > datetimeMs {
+			continue
+		}
+		accumulated, err := accumulatedDepreciation(db, a.ID, datetimeMs)
+		if err != nil {
+			return outStr, err
+		}
+		status := a.Status
+		book := NewDecimal(a.Cost.Data - accumulated.Data)
+		if a.Status == FixedAssetDisposed {
+			if a.DisposedDatetimeMs > datetimeMs {
+				status = FixedAssetActive
+			} else {
+				book = NewDecimal(0)
+			}
+		}
+		outStr += fmt.Sprintf("%s | %s | %s %d months | Cost %.2f | Salvage %.2f | Accumulated %.2f | Book %.2f %s | since %v\n",
+			a.Name, status, a.Method, a.UsefulLifeMonths, a.Cost.ToFloat(), a.SalvageValue.ToFloat(),
+			accumulated.ToFloat(), book.ToFloat(), a.Currency, time.UnixMilli(a.StartDatetimeMs).Format("2006-01-02"))
+	}
+	return outStr, nil
+}
+
+func PrintFixedAssetRegister(db *sql.DB, datetimeMs int64) error {
+	str, err := SprintFixedAssetRegister(db, datetimeMs)
+	fmt.Print(str)
+	return err
+}
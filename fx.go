@@ -0,0 +1,242 @@
+package inventory
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const SettingBaseCurrency = "base_currency"
+const SettingFXRevaluationAccount = "fx_revaluation_account"
+
+var ErrNoBaseCurrency = errors.New("base currency not set")
+var ErrNoFXRevaluationAccount = errors.New("fx revaluation account not set")
+var ErrNoGainLossAccount = errors.New("gain/loss account not given")
+
+type FXRevaluation struct {
+	ID            int
+	UUID          uuid.UUID
+	Transaction   *Transaction
+	Account       *Account
+	Currency      string
+	DatetimeMs    int64
+	Rate          Decimal
+	Balance       Decimal
+	BookValue     Decimal
+	RevaluedValue Decimal
+	Adjustment    Decimal
+}
+
+// holds the revaluation adjustments in the base currency, so the foreign
+// currency accounts keep amounts in their own currency only
+func SetFXRevaluationAccount(db *sql.DB, acc *Account) error {
+	return SetAccountSetting(db, SettingFXRevaluationAccount, acc)
+}
+
+func GetFXRevaluationAccount(db *sql.DB) (*Account, error) {
+	acc, err := GetAccountSetting(db, SettingFXRevaluationAccount)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoFXRevaluationAccount
+	}
+	return acc, err
+}
+
+type fxBalanceKey struct {
+	accountID int
+	currency  string
+}
+
+// revalues every foreign currency balance of the asset and liability accounts
+// at the rate effective at datetimeMs. the difference to the book value in the
+// base currency is posted as one transaction, on the fx revaluation account
+// against gainLossAcc. running it again for the same date posts nothing, since
+// earlier adjustments are part of the book value.
+func RunFXRevaluation(db *sql.DB, gainLossAcc *Account, datetimeMs int64) ([]FXRevaluation, []byte, error) {
+	if gainLossAcc == nil {
+		return nil, nil, ErrNoGainLossAccount
+	}
+	err := resolveAccount(db, gainLossAcc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("gain/loss account: %w", err)
+	}
+	revaluationAcc, err := GetFXRevaluationAccount(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	base, err := GetBaseCurrency(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, accMap, err := BuildAccountTree(db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT l.account_id, l.currency, l.quantity, t.datetime_ms
+		FROM transaction_lines l
+		JOIN transactions t ON l.transaction_id = t.id
+		WHERE l.item_id IS NULL AND l.currency <> ? AND l.currency <> '' AND t.datetime_ms <= ?`,
+		base, datetimeMs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	type lineAmount struct {
+		key        fxBalanceKey
+		quantity   Decimal
+		datetimeMs int64
+	}
+	var lines []lineAmount
+	for rows.Next() {
+		var l lineAmount
+		err = rows.Scan(&l.key.accountID, &l.key.currency, &l.quantity, &l.datetimeMs)
+		if err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		acc, ok := accMap[l.key.accountID]
+		if !ok || !(acc.IsChildOfOrItself(AssetAcc) || acc.IsChildOfOrItself(LiabilityAcc)) {
+			continue
+		}
+		lines = append(lines, l)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	balances := map[fxBalanceKey]*FXRevaluation{}
+	for _, l := range lines {
+		r, ok := balances[l.key]
+		if !ok {
+			r = &FXRevaluation{
+				Account:    accMap[l.key.accountID],
+				Currency:   l.key.currency,
+				DatetimeMs: datetimeMs,
+				Balance:    NewDecimal(0),
+				BookValue:  NewDecimal(0),
+			}
+			balances[l.key] = r
+		}
+		inBase, err := ConvertCurrency(db, l.quantity, l.key.currency, base, l.datetimeMs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("convert %s to %s at %v: %w", l.key.currency, base, time.UnixMilli(l.datetimeMs), err)
+		}
+		r.Balance.Data += l.quantity.Data
+		r.BookValue.Data += inBase.Data
+	}
+
+	rows, err = db.Query(`
+		SELECT account_id, currency, SUM(adjustment)
+		FROM fx_revaluations
+		WHERE datetime_ms <= ?
+		GROUP BY account_id, currency`, datetimeMs)
+	if err != nil {
+		return nil, nil, err
+	}
+	for rows.Next() {
+		var key fxBalanceKey
+		adjustment := NewDecimal(0)
+		err = rows.Scan(&key.accountID, &key.currency, &adjustment)
+		if err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		if r, ok := balances[key]; ok {
+			r.BookValue.Data += adjustment.Data
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	keys := make([]fxBalanceKey, 0, len(balances))
+	for k := range balances {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].accountID != keys[j].accountID {
+			return keys[i].accountID < keys[j].accountID
+		}
+		return keys[i].currency < keys[j].currency
+	})
+
+	var results []FXRevaluation
+	transaction := &Transaction{
+		Description: fmt.Sprintf("FX revaluation %s", time.UnixMilli(datetimeMs).Format("2006-01-02")),
+		DatetimeMs:  datetimeMs,
+	}
+	total := NewDecimal(0)
+	for _, k := range keys {
+		r := balances[k]
+		r.Rate, err = ConvertCurrency(db, NewDecimalFromIntFrac(1, 0), r.Currency, base, datetimeMs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("convert %s to %s: %w", r.Currency, base, err)
+		}
+		r.RevaluedValue, err = ConvertCurrency(db, r.Balance, r.Currency, base, datetimeMs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("convert %s to %s: %w", r.Currency, base, err)
+		}
+		r.Adjustment = NewDecimal(r.RevaluedValue.Data - r.BookValue.Data)
+		if r.Adjustment.Data == 0 {
+			continue
+		}
+		line := CreateFinancialTrLine(revaluationAcc, r.Adjustment, NewDecimal(0), base)
+		line.Note = fmt.Sprintf("%s %s", r.Account.Name, r.Currency)
+		transaction.TransactionLines = append(transaction.TransactionLines, line)
+		total.Data += r.Adjustment.Data
+		results = append(results, *r)
+	}
+	if len(results) == 0 {
+		return nil, nil, nil
+	}
+	if total.Data != 0 {
+		transaction.TransactionLines = append(transaction.TransactionLines,
+			CreateFinancialTrLine(gainLossAcc, NewDecimal(0), total, base))
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	trUUID, trID, err := applyTransactionTx(db, tx, transaction)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range results {
+		r := &results[i]
+		r.UUID, err = uuid.NewV7()
+		if err != nil {
+			return nil, nil, err
+		}
+		r.Transaction = transaction
+		res, err := tx.Exec(`
+			INSERT INTO fx_revaluations(uuid,transaction_id,account_id,currency,datetime_ms,rate,balance,book_value,revalued_value,adjustment)
+			VALUES(?,?,?,?,?,?,?,?,?,?)`,
+			r.UUID[:], trID, r.Account.ID, r.Currency, r.DatetimeMs, r.Rate, r.Balance, r.BookValue, r.RevaluedValue, r.Adjustment)
+		if err != nil {
+			return nil, nil, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, nil, err
+		}
+		r.ID = int(id)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, nil, err
+	}
+	publishTransactions(db, trID)
+	return results, trUUID, nil
+}
@@ -49,6 +49,7 @@ type Transaction struct {
 	DatetimeMs       int64
 	Year             int
 	Month            uint8
+	Party            *Party
 	TransactionLines []*TransactionLine
 }
 
@@ -63,6 +64,10 @@ type TransactionLine struct {
 	Price       Decimal
 	Currency    string
 	Note        string
+	TaxCode     *TaxCode
+	Party       *Party
+	// see CreateValueAdjustmentTrLine
+	ValueAdjustment bool
 }
 
 type BalanceHistory struct {
@@ -107,22 +112,22 @@ type MarketPrice struct {
 	Currency   string
 }
 
+// the account path and item name a balance is reported under
+func balanceKey(path []string, b BalanceHistory) string {
+	var itemName string
+	if b.TransactionLine.Item != nil {
+		itemName = b.TransactionLine.Item.Name
+	}
+	return strings.Join(path, " > ") + " " + itemName
+}
+
 func RollupBalances(balances []BalanceHistory, paths map[int][]string) map[string]BalanceHistory {
 	// fmt.Println("enter rollup balances")
 	result := map[string]BalanceHistory{}
 	for _, b := range balances {
 		path := paths[b.TransactionLine.Account.ID]
-		var itemName string
-		// var itemID int
-		if b.TransactionLine.Item != nil {
-			itemName = b.TransactionLine.Item.Name
-			// itemID = b.TransactionLine.Item.ID
-
-		} else {
-			itemName = ""
-		}
 		for i := 1; i <= len(path); i++ {
-			key := strings.Join(path[:i], " > ") + " " + itemName
+			key := balanceKey(path[:i], b)
 			agg, ok := result[key]
 			if !ok {
 				agg.Quantity = NewDecimal(0)
@@ -150,6 +155,15 @@ func RollupBalances(balances []BalanceHistory, paths map[int][]string) map[strin
 }
 
 func SprintBalances(db *sql.DB) (string, error) {
+	return sprintBalances(db, true)
+}
+
+// leaves out stock held on consignment for other parties
+func SprintBalancesExcludingConsignment(db *sql.DB) (string, error) {
+	return sprintBalances(db, false)
+}
+
+func sprintBalances(db *sql.DB, includeConsignment bool) (string, error) {
 	outStr := ""
 	// fmt.Println("building account tree")
 	paths, accMap, err := BuildAccountTree(db)
@@ -157,7 +171,7 @@ func SprintBalances(db *sql.DB) (string, error) {
 		return outStr, err
 	}
 	// fmt.Println("fetching leaf balances")
-	leaf, err := FetchLeafBalances(db, accMap)
+	leaf, err := fetchLeafBalances(db, accMap, includeConsignment)
 	if err != nil {
 		return outStr, err
 	}
@@ -173,30 +187,104 @@ func SprintBalances(db *sql.DB) (string, error) {
 	for i := range keys {
 		k := keys[i]
 		b := rolled[k]
-		normQty := NewDecimal(0)
-		normVal := NewDecimal(0)
-		if b.TransactionLine.Account != nil &&
-			(b.TransactionLine.Account.IsChildOfOrItself(LiabilityAcc) ||
-				b.TransactionLine.Account.IsChildOfOrItself(EquityAcc) ||
-				b.TransactionLine.Account.IsChildOfOrItself(IncomeAcc)) {
-			normQty.Data = -b.Quantity.Data
-			normVal.Data = -b.Value.Data
-		} else {
-			normQty = b.Quantity
-			normVal = b.Value
-		}
-		outStr += fmt.Sprintf("%s | Qty %.2f | Value %.2f | as of %v\n", k, normQty.ToFloat(), normVal.ToFloat(), time.UnixMilli(b.DatetimeMs))
+		outStr += balanceLine(k, b) + "\n"
 	}
 	return outStr, nil
 }
 
+// liability, equity and income are shown with the sign turned
+func balanceLine(key string, b BalanceHistory) string {
+	normQty := NewDecimal(0)
+	normVal := NewDecimal(0)
+	if b.TransactionLine.Account != nil &&
+		(b.TransactionLine.Account.IsChildOfOrItself(LiabilityAcc) ||
+			b.TransactionLine.Account.IsChildOfOrItself(EquityAcc) ||
+			b.TransactionLine.Account.IsChildOfOrItself(IncomeAcc)) {
+		normQty.Data = -b.Quantity.Data
+		normVal.Data = -b.Value.Data
+	} else {
+		normQty = b.Quantity
+		normVal = b.Value
+	}
+	return fmt.Sprintf("%s | Qty %.2f | Value %.2f | as of %v", key, normQty.ToFloat(), normVal.ToFloat(), time.UnixMilli(b.DatetimeMs))
+}
+
+func marketBalanceLine(key string, b BalanceHistory) string {
+	return fmt.Sprintf("%s | Qty %.2f | MarketValue %.2f %s", key, b.Quantity.ToFloat(), b.MarketValue.ToFloat(), b.Currency)
+}
+
+// the lines of SprintBalances without building the report: fn gets the
+// header, then every leaf balance as the query reads it, then the parent
+// totals sorted once all leaves are in. the lines have no newline.
+func EachBalanceLine(db *sql.DB, fn func(line string) error) error {
+	return eachBalanceLine(db, "=== Historical Cost Balances ===", balanceLine, fn)
+}
+
+// the lines of SprintMarketBalances, as EachBalanceLine
+func EachMarketBalanceLine(db *sql.DB, fn func(line string) error) error {
+	return eachBalanceLine(db, "=== Market Value Balances ===", marketBalanceLine, fn)
+}
+
+func eachBalanceLine(db *sql.DB, header string, format func(key string, b BalanceHistory) string, fn func(line string) error) error {
+	paths, accMap, err := BuildAccountTree(db)
+	if err != nil {
+		return err
+	}
+	err = fn(header)
+	if err != nil {
+		return err
+	}
+	// only the leaves are kept for the parent totals, not their lines
+	var leaf []BalanceHistory
+	sent := map[string]bool{}
+	err = eachLeafBalance(db, accMap, true, func(b BalanceHistory) error {
+		leaf = append(leaf, b)
+		key := balanceKey(paths[b.TransactionLine.Account.ID], b)
+		sent[key] = true
+		return fn(format(key, b))
+	})
+	if err != nil {
+		return err
+	}
+
+	rolled := RollupBalances(leaf, paths)
+	keys := make([]string, 0)
+	for k := range rolled {
+		if !sent[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		err = fn(format(k, rolled[k]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func PrintBalances(db *sql.DB) error {
 	str, err := SprintBalances(db)
 	fmt.Print(str)
 	return err
 }
 
+func PrintBalancesExcludingConsignment(db *sql.DB) error {
+	str, err := SprintBalancesExcludingConsignment(db)
+	fmt.Print(str)
+	return err
+}
+
 func SprintMarketBalances(db *sql.DB) (string, error) {
+	return sprintMarketBalances(db, true)
+}
+
+func SprintMarketBalancesExcludingConsignment(db *sql.DB) (string, error) {
+	return sprintMarketBalances(db, false)
+}
+
+func sprintMarketBalances(db *sql.DB, includeConsignment bool) (string, error) {
 	// fmt.Println("building account tree")
 	outStr := ""
 	paths, accMap, err := BuildAccountTree(db)
@@ -204,7 +292,7 @@ func SprintMarketBalances(db *sql.DB) (string, error) {
 		return outStr, err
 	}
 	// fmt.Println("fetching leaf balances")
-	leaf, err := FetchLeafBalances(db, accMap)
+	leaf, err := fetchLeafBalances(db, accMap, includeConsignment)
 	if err != nil {
 		return outStr, err
 	}
@@ -220,8 +308,7 @@ func SprintMarketBalances(db *sql.DB) (string, error) {
 	for i := range keys {
 		k := keys[i]
 		b := rolled[k]
-		outStr += fmt.Sprintf("%s | Qty %.2f | MarketValue %.2f %s\n",
-			k, b.Quantity.ToFloat(), b.MarketValue.ToFloat(), b.Currency)
+		outStr += marketBalanceLine(k, b) + "\n"
 	}
 
 	return outStr, nil
@@ -232,3 +319,9 @@ func PrintMarketBalances(db *sql.DB) error {
 	fmt.Print(str)
 	return err
 }
+
+func PrintMarketBalancesExcludingConsignment(db *sql.DB) error {
+	str, err := SprintMarketBalancesExcludingConsignment(db)
+	fmt.Print(str)
+	return err
+}
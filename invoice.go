@@ -0,0 +1,646 @@
+package inventory
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	InvoiceSales    = "sales"
+	InvoicePurchase = "purchase"
+)
+
+const (
+	InvoiceOpen          = "open"
+	InvoicePartiallyPaid = "partially_paid"
+	InvoicePaid          = "paid"
+)
+
+// received settles sales invoices, made settles purchase invoices
+const (
+	PaymentReceived = "received"
+	PaymentMade     = "made"
+)
+
+var ErrUnknownInvoiceType = errors.New("invoice type must be sales or purchase")
+var ErrUnknownPaymentType = errors.New("payment type must be received or made")
+var ErrEmptyInvoice = errors.New("invoice has no lines")
+var ErrInvoiceTotalNotPositive = errors.New("invoice total must be positive")
+var ErrPartyKindMismatch = errors.New("party kind doesn't allow this document")
+var ErrNoReceivableAccount = errors.New("receivable account not set")
+var ErrNoPayableAccount = errors.New("payable account not set")
+var ErrInvalidPaymentAmount = errors.New("payment amount must be positive")
+var ErrInvalidAllocationAmount = errors.New("allocation amount must be positive")
+var ErrAllocationExceedsOpen = errors.New("allocation exceeds open amount of invoice")
+var ErrAllocationExceedsPayment = errors.New("allocations exceed unallocated amount of payment")
+var ErrPaymentInvoiceMismatch = errors.New("invoice doesn't match payment party, type or currency")
+var ErrNoCOGSAccount = errors.New("cost of goods sold account not set")
+var ErrNoStockAccount = errors.New("sales item line has no stock account")
+
+const SettingCOGSAccount = "cogs_account"
+
+// a sales line with an item takes the goods out of StockAccount at its
+// average cost onto the cost of goods sold account
+type InvoiceLine struct {
+	Account      *Account
+	Item         *Item
+	Quantity     Decimal
+	Unit         string
+	Price        Decimal
+	TaxCode      *TaxCode
+	Note         string
+	StockAccount *Account
+}
+
+type Invoice struct {
+	ID            int
+	UUID          uuid.UUID
+	Type          string
+	Number        string
+	Description   string
+	Party         *Party
+	DatetimeMs    int64
+	DueDatetimeMs int64
+	Currency      string
+	Lines         []*InvoiceLine
+	Total         Decimal
+	Paid          Decimal
+	Transaction   *Transaction
+}
+
+type Payment struct {
+	ID          int
+	UUID        uuid.UUID
+	Type        string
+	Reference   string
+	Party       *Party
+	DatetimeMs  int64
+	Account     *Account
+	Amount      Decimal
+	Currency    string
+	Allocations []PaymentAllocation
+	Allocated   Decimal
+	Transaction *Transaction
+}
+
+type PaymentAllocation struct {
+	Invoice    *Invoice
+	Amount     Decimal
+	DatetimeMs int64
+}
+
+func (inv *Invoice) Open() Decimal {
+	return NewDecimal(inv.Total.Data - inv.Paid.Data)
+}
+
+func (inv *Invoice) Status() string {
+	switch {
+	case inv.Paid.Data == 0:
+		return InvoiceOpen
+	case inv.Paid.Data < inv.Total.Data:
+		return InvoicePartiallyPaid
+	default:
+		return InvoicePaid
+	}
+}
+
+// credit left on the payment, e.g. after an overpayment
+func (p *Payment) Unallocated() Decimal {
+	return NewDecimal(p.Amount.Data - p.Allocated.Data)
+}
+
+// receivable account for sales, payable account for purchases
+func controlAccount(db *sql.DB, docType string) (*Account, error) {
+	key, errNotSet := SettingReceivableAccount, ErrNoReceivableAccount
+	if docType == InvoicePurchase || docType == PaymentMade {
+		key, errNotSet = SettingPayableAccount, ErrNoPayableAccount
+	}
+	acc, err := GetAccountSetting(db, key)
+	if err == sql.ErrNoRows {
+		return nil, errNotSet
+	}
+	return acc, err
+}
+
+func checkPartyKind(p *Party, docType string) error {
+	switch {
+	case p.Kind == PartyBoth:
+	case p.Kind == PartyCustomer && (docType == InvoiceSales || docType == PaymentReceived):
+	case p.Kind == PartyVendor && (docType == InvoicePurchase || docType == PaymentMade):
+	default:
+		return fmt.Errorf("%s %s for %s: %w", docType, p.Kind, p.Name, ErrPartyKindMismatch)
+	}
+	return nil
+}
+
+func SetCOGSAccount(db *sql.DB, acc *Account) error {
+	return SetAccountSetting(db, SettingCOGSAccount, acc)
+}
+
+func GetCOGSAccount(db *sql.DB) (*Account, error) {
+	acc, err := GetAccountSetting(db, SettingCOGSAccount)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoCOGSAccount
+	}
+	return acc, err
+}
+
+// sales lines credit the amount to revenue, purchase lines debit inventory
+// or expense. quantities are given positive either way.
+func (l *InvoiceLine) transactionLine(invType, currency string) *TransactionLine {
+	quantity := l.Quantity
+	if l.Item == nil || invType == InvoiceSales {
+		quantity = l.Quantity.Multiply(l.Price)
+	}
+	if invType == InvoiceSales {
+		trLine := CreateFinancialTrLine(l.Account, NewDecimal(0), quantity, currency)
+		trLine.Note = l.Note
+		trLine.TaxCode = l.TaxCode
+		return trLine
+	}
+	trLine := &TransactionLine{
+		Account:  l.Account,
+		Item:     l.Item,
+		Quantity: quantity,
+		Unit:     l.Unit,
+		Price:    NewDecimal(0),
+		Currency: currency,
+		Note:     l.Note,
+		TaxCode:  l.TaxCode,
+	}
+	if l.Item != nil {
+		trLine.Price = l.Price
+	}
+	return trLine
+}
+
+// moves a sales line's goods from stock to cost of goods sold at the average
+// cost of the stock account at datetimeMs
+func (l *InvoiceLine) stockReliefLines(db *sql.DB, tx *sql.Tx, cogs *Account, datetimeMs int64, currency string) ([]*TransactionLine, error) {
+	if l.StockAccount == nil {
+		return nil, fmt.Errorf("%s: %w", l.Item.Name, ErrNoStockAccount)
+	}
+	err := resolveAccount(db, l.StockAccount)
+	if err != nil {
+		return nil, err
+	}
+	if l.Item.ID <= 0 {
+		item, err := GetItemByUUID(db, l.Item.UUID[:])
+		if err != nil {
+			return nil, err
+		}
+		*l.Item = *item
+	}
+	avgCost := NewDecimal(0)
+	err = tx.QueryRow(`
+		SELECT h.avg_cost FROM balance_history h
+		JOIN transactions t ON h.transaction_id = t.id
+		WHERE h.account_id=? AND h.item_id=? AND t.datetime_ms <= ?
+		ORDER BY t.datetime_ms DESC, h.id DESC LIMIT 1`, l.StockAccount.ID, l.Item.ID, datetimeMs).Scan(&avgCost)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return []*TransactionLine{
+		CreateInventoryTrLine(l.StockAccount, l.Item, NewDecimal(-l.Quantity.Data), l.Unit, avgCost, currency),
+		CreateInventoryTrLine(cogs, l.Item, l.Quantity, l.Unit, avgCost, currency),
+	}, nil
+}
+
+// posts the invoice lines, their taxes and the receivable or payable for the
+// total in one transaction. sales lines with an item also relieve stock.
+func IssueInvoice(db *sql.DB, inv *Invoice) ([]byte, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	invUUID, err := issueInvoiceTx(db, tx, inv, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	publishTransactions(db, int64(inv.Transaction.ID))
+	return invUUID, nil
+}
+
+// extra lines are posted in the same transaction but left out of the total
+func issueInvoiceTx(db *sql.DB, tx *sql.Tx, inv *Invoice, extra []*TransactionLine) ([]byte, error) {
+	if inv.Type != InvoiceSales && inv.Type != InvoicePurchase {
+		return nil, ErrUnknownInvoiceType
+	}
+	if len(inv.Lines) == 0 {
+		return nil, ErrEmptyInvoice
+	}
+	if inv.Party == nil {
+		return nil, sql.ErrNoRows
+	}
+	if _, err := resolvePartyID(db, inv.Party); err != nil {
+		return nil, err
+	}
+	if err := checkPartyKind(inv.Party, inv.Type); err != nil {
+		return nil, err
+	}
+	control, err := controlAccount(db, inv.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines, reliefLines []*TransactionLine
+	var cogs *Account
+	var sum int64
+	for _, l := range inv.Lines {
+		trLine := l.transactionLine(inv.Type, inv.Currency)
+		lines = append(lines, trLine)
+		if inv.Type == InvoiceSales && l.Item != nil && l.Quantity.Data != 0 {
+			if cogs == nil {
+				cogs, err = GetCOGSAccount(db)
+				if err != nil {
+					return nil, err
+				}
+			}
+			relief, err := l.stockReliefLines(db, tx, cogs, inv.DatetimeMs, inv.Currency)
+			if err != nil {
+				return nil, err
+			}
+			reliefLines = append(reliefLines, relief...)
+		}
+
+		// the tax lines are generated on apply, this only works out the total
+		tmp := *trLine
+		amount := lineAmount(&tmp)
+		if trLine.TaxCode != nil {
+			tc, err := resolveTaxCode(db, trLine.TaxCode)
+			if err != nil {
+				return nil, fmt.Errorf("tax code %q: %w", trLine.TaxCode.Code, err)
+			}
+			base, tax := applyLineTax(&tmp, tc)
+			amount = NewDecimal(base.Data + tax.Data)
+		}
+		sum += amount.Data
+	}
+	total := NewDecimal(-sum)
+	if inv.Type == InvoicePurchase {
+		total = NewDecimal(sum)
+	}
+	if total.Data <= 0 {
+		return nil, ErrInvoiceTotalNotPositive
+	}
+	lines = append(lines, CreateFinancialTrLine(control, NewDecimal(-sum), NewDecimal(0), inv.Currency))
+	lines = append(lines, reliefLines...)
+	lines = append(lines, extra...)
+
+	description := inv.Description
+	if description == "" {
+		description = strings.TrimSpace(fmt.Sprintf("%s invoice %s", inv.Type, inv.Number))
+	}
+	transaction := &Transaction{
+		Description:      description,
+		DatetimeMs:       inv.DatetimeMs,
+		Party:            inv.Party,
+		TransactionLines: lines,
+	}
+
+	_, trID, err := applyTransactionTx(db, tx, transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	invUUID, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	res, err := tx.Exec(`INSERT INTO invoices (uuid, type, number, description, party_id, transaction_id, datetime_ms, due_datetime_ms, currency, total)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		invUUID[:], inv.Type, sql.NullString{String: inv.Number, Valid: inv.Number != ""}, description,
+		inv.Party.ID, trID, inv.DatetimeMs, inv.DueDatetimeMs, inv.Currency, total)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	inv.ID = int(id)
+	inv.UUID = invUUID
+	inv.Description = description
+	inv.Total = total
+	inv.Paid = NewDecimal(0)
+	inv.Transaction = transaction
+	return invUUID[:], nil
+}
+
+func scanInvoices(q interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}, where string, args ...any) ([]*Invoice, error) {
+	rows, err := q.Query(`
+		SELECT i.id, i.uuid, i.type, i.number, i.description, i.datetime_ms, i.due_datetime_ms, i.currency, i.total,
+			COALESCE((SELECT SUM(a.amount) FROM payment_allocations a WHERE a.invoice_id = i.id), 0),
+			t.id, t.uuid, p.id, p.uuid, p.name, p.kind
+		FROM invoices i
+		JOIN transactions t ON i.transaction_id = t.id
+		JOIN parties p ON i.party_id = p.id
+		`+where+`
+		ORDER BY i.due_datetime_ms, i.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invoices []*Invoice
+	for rows.Next() {
+		inv := &Invoice{Party: &Party{}, Transaction: &Transaction{}, Total: NewDecimal(0), Paid: NewDecimal(0)}
+		var invUUID, trUUID, partyUUID []byte
+		var number, description, currency sql.NullString
+		var paid int64
+		err = rows.Scan(&inv.ID, &invUUID, &inv.Type, &number, &description, &inv.DatetimeMs, &inv.DueDatetimeMs, &currency, &inv.Total,
+			&paid, &inv.Transaction.ID, &trUUID, &inv.Party.ID, &partyUUID, &inv.Party.Name, &inv.Party.Kind)
+		if err != nil {
+			return nil, err
+		}
+		if inv.UUID, err = uuid.FromBytes(invUUID); err != nil {
+			return nil, err
+		}
+		if inv.Transaction.UUID, err = uuid.FromBytes(trUUID); err != nil {
+			return nil, err
+		}
+		if inv.Party.UUID, err = uuid.FromBytes(partyUUID); err != nil {
+			return nil, err
+		}
+		inv.Number = number.String
+		inv.Description = description.String
+		inv.Currency = currency.String
+		inv.Paid = NewDecimal(paid)
+		invoices = append(invoices, inv)
+	}
+	return invoices, rows.Err()
+}
+
+func GetInvoiceByUUID(db *sql.DB, invUUID []byte) (*Invoice, error) {
+	invoices, err := scanInvoices(db, "WHERE i.uuid=?", invUUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(invoices) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return invoices[0], nil
+}
+
+// invoices not fully paid, ordered by due date. party nil means every party.
+func GetOpenInvoices(db *sql.DB, party *Party) ([]*Invoice, error) {
+	where := "WHERE i.total > COALESCE((SELECT SUM(a.amount) FROM payment_allocations a WHERE a.invoice_id = i.id), 0)"
+	var args []any
+	if party != nil {
+		if _, err := resolvePartyID(db, party); err != nil {
+			return nil, err
+		}
+		where += " AND i.party_id=?"
+		args = append(args, party.ID)
+	}
+	return scanInvoices(db, where, args...)
+}
+
+func getPaymentTx(q interface {
+	QueryRow(query string, args ...any) *sql.Row
+}, payUUID []byte) (*Payment, error) {
+	p := &Payment{Party: &Party{}, Account: &Account{}, Transaction: &Transaction{}, Amount: NewDecimal(0)}
+	var reference, currency sql.NullString
+	var partyUUID, accUUID, trUUID []byte
+	var allocated int64
+	err := q.QueryRow(`
+		SELECT py.id, py.type, py.reference, py.datetime_ms, py.amount, py.currency,
+			COALESCE((SELECT SUM(a.amount) FROM payment_allocations a WHERE a.payment_id = py.id), 0),
+			p.id, p.uuid, p.name, p.kind, ac.id, ac.uuid, ac.name, t.id, t.uuid
+		FROM payments py
+		JOIN parties p ON py.party_id = p.id
+		JOIN accounts ac ON py.account_id = ac.id
+		JOIN transactions t ON py.transaction_id = t.id
+		WHERE py.uuid=?`, payUUID).
+		Scan(&p.ID, &p.Type, &reference, &p.DatetimeMs, &p.Amount, &currency, &allocated,
+			&p.Party.ID, &partyUUID, &p.Party.Name, &p.Party.Kind, &p.Account.ID, &accUUID, &p.Account.Name,
+			&p.Transaction.ID, &trUUID)
+	if err != nil {
+		return nil, err
+	}
+	if p.UUID, err = uuid.FromBytes(payUUID); err != nil {
+		return nil, err
+	}
+	if p.Party.UUID, err = uuid.FromBytes(partyUUID); err != nil {
+		return nil, err
+	}
+	if p.Account.UUID, err = uuid.FromBytes(accUUID); err != nil {
+		return nil, err
+	}
+	if p.Transaction.UUID, err = uuid.FromBytes(trUUID); err != nil {
+		return nil, err
+	}
+	p.Reference = reference.String
+	p.Currency = currency.String
+	p.Allocated = NewDecimal(allocated)
+	return p, nil
+}
+
+func GetPaymentByUUID(db *sql.DB, payUUID []byte) (*Payment, error) {
+	return getPaymentTx(db, payUUID)
+}
+
+func allocatePaymentTx(tx *sql.Tx, p *Payment, allocations []PaymentAllocation) error {
+	invType := InvoiceSales
+	if p.Type == PaymentMade {
+		invType = InvoicePurchase
+	}
+	unallocated := p.Unallocated().Data
+	for _, a := range allocations {
+		if a.Invoice == nil {
+			return sql.ErrNoRows
+		}
+		if a.Amount.Data <= 0 {
+			return ErrInvalidAllocationAmount
+		}
+		invoices, err := scanInvoices(tx, "WHERE i.uuid=?", a.Invoice.UUID[:])
+		if err != nil {
+			return err
+		}
+		if len(invoices) == 0 {
+			return fmt.Errorf("invoice %s: %w", a.Invoice.UUID, sql.ErrNoRows)
+		}
+		inv := invoices[0]
+		if inv.Type != invType || inv.Party.ID != p.Party.ID || inv.Currency != p.Currency {
+			return fmt.Errorf("invoice %s: %w", inv.Number, ErrPaymentInvoiceMismatch)
+		}
+		if a.Amount.Data > inv.Open().Data {
+			return fmt.Errorf("invoice %s open %s, allocating %s: %w",
+				inv.Number, inv.Open().ToString(), a.Amount.ToString(), ErrAllocationExceedsOpen)
+		}
+		if a.Amount.Data > unallocated {
+			return ErrAllocationExceedsPayment
+		}
+		unallocated -= a.Amount.Data
+
+		datetimeMs := a.DatetimeMs
+		if datetimeMs == 0 {
+			datetimeMs = p.DatetimeMs
+		}
+		allocUUID, err := uuid.NewV7()
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO payment_allocations (uuid, payment_id, invoice_id, amount, datetime_ms) VALUES (?, ?, ?, ?, ?)`,
+			allocUUID[:], p.ID, inv.ID, a.Amount, datetimeMs)
+		if err != nil {
+			return err
+		}
+	}
+	p.Allocated = NewDecimal(p.Amount.Data - unallocated)
+	return nil
+}
+
+// posts the payment against the receivable or payable and allocates it to
+// p.Allocations. whatever isn't allocated stays as credit for the party and
+// can be allocated later with AllocatePayment.
+func RecordPayment(db *sql.DB, p *Payment) ([]byte, error) {
+	if p.Type != PaymentReceived && p.Type != PaymentMade {
+		return nil, ErrUnknownPaymentType
+	}
+	if p.Amount.Data <= 0 {
+		return nil, ErrInvalidPaymentAmount
+	}
+	if p.Party == nil {
+		return nil, sql.ErrNoRows
+	}
+	if _, err := resolvePartyID(db, p.Party); err != nil {
+		return nil, err
+	}
+	if err := checkPartyKind(p.Party, p.Type); err != nil {
+		return nil, err
+	}
+	if err := resolveAccount(db, p.Account); err != nil {
+		return nil, err
+	}
+	control, err := controlAccount(db, p.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []*TransactionLine
+	if p.Type == PaymentReceived {
+		lines = []*TransactionLine{
+			CreateFinancialTrLine(p.Account, p.Amount, NewDecimal(0), p.Currency),
+			CreateFinancialTrLine(control, NewDecimal(0), p.Amount, p.Currency),
+		}
+	} else {
+		lines = []*TransactionLine{
+			CreateFinancialTrLine(control, p.Amount, NewDecimal(0), p.Currency),
+			CreateFinancialTrLine(p.Account, NewDecimal(0), p.Amount, p.Currency),
+		}
+	}
+	transaction := &Transaction{
+		Description:      strings.TrimSpace(fmt.Sprintf("Payment %s %s %s", p.Type, p.Party.Name, p.Reference)),
+		DatetimeMs:       p.DatetimeMs,
+		Party:            p.Party,
+		TransactionLines: lines,
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, trID, err := applyTransactionTx(db, tx, transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	payUUID, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	res, err := tx.Exec(`INSERT INTO payments (uuid, type, reference, party_id, account_id, transaction_id, datetime_ms, amount, currency)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		payUUID[:], p.Type, p.Reference, p.Party.ID, p.Account.ID, trID, p.DatetimeMs, p.Amount, p.Currency)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	p.ID = int(id)
+	p.UUID = payUUID
+	p.Allocated = NewDecimal(0)
+	p.Transaction = transaction
+
+	err = allocatePaymentTx(tx, p, p.Allocations)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	publishTransactions(db, trID)
+	return payUUID[:], nil
+}
+
+// allocates credit left on an earlier payment to open invoices
+func AllocatePayment(db *sql.DB, payment *Payment, allocations []PaymentAllocation) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	p, err := getPaymentTx(tx, payment.UUID[:])
+	if err != nil {
+		return err
+	}
+	err = allocatePaymentTx(tx, p, allocations)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	*payment = *p
+	return nil
+}
+
+func SprintOpenInvoices(db *sql.DB, party *Party, datetimeMs int64) (string, error) {
+	invoices, err := GetOpenInvoices(db, party)
+	if err != nil {
+		return "", err
+	}
+	dateFmt := "2006-01-02"
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("=== Open Invoices as of %s ===\n", time.UnixMilli(datetimeMs).Format(dateFmt)))
+	for _, inv := range invoices {
+		overdue := ""
+		if inv.DueDatetimeMs > 0 && inv.DueDatetimeMs < datetimeMs {
+			overdue = fmt.Sprintf(" | %d days overdue", (datetimeMs-inv.DueDatetimeMs)/(24*time.Hour).Milliseconds())
+		}
+		sb.WriteString(fmt.Sprintf("%-8s %-12s %-20s | due %s | %s Total %s Paid %s Open %s | %s%s\n",
+			inv.Type, inv.Number, inv.Party.Name, time.UnixMilli(inv.DueDatetimeMs).Format(dateFmt), inv.Currency,
+			inv.Total.ToString(), inv.Paid.ToString(), inv.Open().ToString(), inv.Status(), overdue))
+	}
+	return sb.String(), nil
+}
+
+func PrintOpenInvoices(db *sql.DB, party *Party, datetimeMs int64) error {
+	str, err := SprintOpenInvoices(db, party, datetimeMs)
+	if err != nil {
+		return err
+	}
+	fmt.Print(str)
+	return nil
+}
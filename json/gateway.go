@@ -0,0 +1,279 @@
+package inventoryjson
+
+import (
+	"database/sql"
+	_ "embed"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"inventoryrpc"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var ErrPacketIncomplete = errors.New("packet incomplete")
+
+// the endpoints of Gateway, also served at GET /openapi.json
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+// response codes to http statuses, see HTTPStatus
+var httpStatuses = map[int32]int{
+	0:    http.StatusOK,
+	-101: http.StatusBadRequest,
+	-102: http.StatusUnprocessableEntity,
+	-201: http.StatusBadRequest,
+	-202: http.StatusNotFound,
+	-203: http.StatusServiceUnavailable,
+	-204: http.StatusBadRequest,
+
+	inventoryrpc.CodeUnauthorized: http.StatusUnauthorized,
+}
+
+// the status a response code is answered with. a failed function is 422,
+// or 404 when err is a missing row. the codes middleware refuses a call with
+// are 403 but for CodeUnauthorized, 401. codes without a status of their own
+// are 500.
+func HTTPStatus(code int32, err error) int {
+	if code == -102 && errors.Is(err, sql.ErrNoRows) {
+		return http.StatusNotFound
+	}
+	status, ok := httpStatuses[code]
+	if ok {
+		return status
+	}
+	if code <= -300 && code > -400 {
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// what a failed call answers
+type ErrorResponse struct {
+	Code    int32  `json:"code"`
+	Message string `json:"message"`
+}
+
+// REST endpoints over a Registry, every request runs the same function a
+// packet would. the bodies are the json entities of this package. request
+// headers are passed on as Packet.Meta, so middleware like
+// inventoryrpc.RequireMeta works the same over http.
+//
+// the gateway is single-tenant: POST /db selects the database every later
+// request works on, whoever sends them, and DELETE /db closes it for all.
+type Gateway struct {
+	// nil uses DefaultRegistry
+	Registry *Registry
+
+	mux *http.ServeMux
+}
+
+type route struct {
+	pattern  string
+	function string
+	// builds the arg from the request, nil sends the body as it is
+	arg     func(r *http.Request) ([]byte, error)
+	created bool
+	// turns the response payload into the json answered, nil answers no body
+	render func(payload map[string][]byte) (any, error)
+}
+
+var routes = []route{
+	{pattern: "GET /db", function: "GetCurrDB", arg: noArg, render: renderUUID},
+	{pattern: "POST /db", function: "OpenOrCreateDB", arg: dbUUIDArg, created: true, render: renderUUID},
+	{pattern: "DELETE /db", function: "CloseCurrDB", arg: noArg},
+	{pattern: "GET /accounts", function: "GetMainAccounts", arg: noArg, render: renderMainAccounts},
+	{pattern: "POST /accounts", function: "AddAccount", created: true, render: renderUUID},
+	{pattern: "POST /items", function: "AddItem", created: true, render: renderUUID},
+	{pattern: "GET /items/{uuid}/market-prices", function: "GetMarketPriceHistory", arg: priceHistoryArg, render: renderJSON("prices")},
+	{pattern: "POST /transactions", function: "ApplyTransaction", created: true, render: renderUUID},
+	{pattern: "GET /balances", function: "PrintBalances", arg: noArg, render: renderText("balances")},
+	{pattern: "GET /balances/market", function: "PrintMarketBalances", arg: noArg, render: renderText("balances")},
+	{pattern: "POST /market-prices", function: "UpdateMarketPrice"},
+	{pattern: "POST /market-prices/import", function: "ImportMarketPrices", arg: priceImportArg, render: renderCount},
+}
+
+func NewGateway(registry *Registry) *Gateway {
+	g := &Gateway{
+		Registry: registry,
+		mux:      http.NewServeMux(),
+	}
+	for _, rt := range routes {
+		g.mux.HandleFunc(rt.pattern, g.handler(rt))
+	}
+	g.mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(OpenAPI)
+	})
+	return g
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mux.ServeHTTP(w, r)
+}
+
+func (g *Gateway) handler(rt route) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var argBytes []byte
+		var err error
+		if rt.arg == nil {
+			argBytes, err = io.ReadAll(r.Body)
+		} else {
+			argBytes, err = rt.arg(r)
+		}
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, &ErrorResponse{Code: -101, Message: err.Error()})
+			return
+		}
+		pkt, err := NewRawRequestPkt(rt.function, argBytes)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, &ErrorResponse{Code: -102, Message: err.Error()})
+			return
+		}
+		pkt.Meta = metaFromHeader(r.Header)
+
+		registry := g.Registry
+		if registry == nil {
+			registry = DefaultRegistry
+		}
+		responsePkt, message, code, err := registry.Process(pkt)
+		if code < 0 {
+			writeJSON(w, HTTPStatus(code, err), &ErrorResponse{Code: code, Message: message})
+			return
+		}
+
+		status := http.StatusOK
+		if rt.created {
+			status = http.StatusCreated
+		}
+		if rt.render == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		body, err := rt.render(responsePkt.Body)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, &ErrorResponse{Code: -102, Message: err.Error()})
+			return
+		}
+		writeJSON(w, status, body)
+	}
+}
+
+// the first value of every header, the keys lower cased like grpc metadata
+// keys, a Token header is Meta["token"]
+func metaFromHeader(header http.Header) map[string][]byte {
+	meta := map[string][]byte{}
+	for k, v := range header {
+		if len(v) > 0 {
+			meta[strings.ToLower(k)] = []byte(v[0])
+		}
+	}
+	return meta
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func noArg(r *http.Request) ([]byte, error) {
+	return nil, nil
+}
+
+// OpenOrCreateDB takes the bare uuid, an empty body creates a new db
+func dbUUIDArg(r *http.Request) ([]byte, error) {
+	var body struct {
+		UUID string `json:"uuid"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err == io.EOF || (err == nil && body.UUID == "") {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	dbUUID, err := uuid.Parse(body.UUID)
+	if err != nil {
+		return nil, err
+	}
+	return dbUUID[:], nil
+}
+
+func priceHistoryArg(r *http.Request) ([]byte, error) {
+	query := &MarketPriceHistoryQuery{
+		ItemUUID: r.PathValue("uuid"),
+	}
+	var err error
+	if from := r.URL.Query().Get("from"); from != "" {
+		query.FromDatetimeMs, err = strconv.ParseInt(from, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if to := r.URL.Query().Get("to"); to != "" {
+		query.ToDatetimeMs, err = strconv.ParseInt(to, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(query)
+}
+
+// the body is the file itself, the format is the format query parameter
+func priceImportArg(r *http.Request) ([]byte, error) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&MarketPriceImport{
+		Format: r.URL.Query().Get("format"),
+		Data:   data,
+	})
+}
+
+func renderUUID(payload map[string][]byte) (any, error) {
+	entityUUID, err := uuid.FromBytes(payload["uuid"])
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"uuid": entityUUID.String()}, nil
+}
+
+func renderMainAccounts(payload map[string][]byte) (any, error) {
+	accs := map[string]string{}
+	for _, name := range []string{"asset", "equity", "liability", "income", "expense"} {
+		accUUID, err := uuid.FromBytes(payload[name])
+		if err != nil {
+			return nil, err
+		}
+		accs[name] = accUUID.String()
+	}
+	return accs, nil
+}
+
+func renderCount(payload map[string][]byte) (any, error) {
+	countBytes := payload["count"]
+	if len(countBytes) < 4 {
+		return nil, ErrPacketIncomplete
+	}
+	return map[string]uint32{"count": binary.LittleEndian.Uint32(countBytes)}, nil
+}
+
+func renderText(key string) func(payload map[string][]byte) (any, error) {
+	return func(payload map[string][]byte) (any, error) {
+		return map[string]string{key: string(payload[key])}, nil
+	}
+}
+
+// the payload is json already
+func renderJSON(key string) func(payload map[string][]byte) (any, error) {
+	return func(payload map[string][]byte) (any, error) {
+		return json.RawMessage(payload[key]), nil
+	}
+}
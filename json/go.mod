@@ -0,0 +1,20 @@
+module inventoryjson
+
+go 1.23
+
+toolchain go1.24.7
+
+replace inventory => ..
+
+replace inventoryrpc => ../rpc
+
+require (
+	github.com/google/uuid v1.6.0
+	inventory v0.0.0-00010101000000-000000000000
+	inventoryrpc v0.0.0-00010101000000-000000000000
+)
+
+require (
+	github.com/vmihailenco/msgpack/v5 v5.4.1 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+)
@@ -0,0 +1,218 @@
+package inventoryjson
+
+// the json counterparts of the messages in pb/inventory.proto, for clients
+// that can't decode the binary codecs. uuids are in their text form, empty
+// for none, and decimals are strings like "12.5000".
+
+type Account struct {
+	UUID             string             `json:"uuid,omitempty"`
+	Name             string             `json:"name,omitempty"`
+	ParentUUID       string             `json:"parent,omitempty"`
+	TransactionLines []*TransactionLine `json:"transaction_lines,omitempty"`
+}
+
+type Item struct {
+	UUID             string             `json:"uuid,omitempty"`
+	Name             string             `json:"name,omitempty"`
+	Description      string             `json:"description,omitempty"`
+	Unit             string             `json:"unit,omitempty"`
+	TransactionLines []*TransactionLine `json:"transaction_lines,omitempty"`
+}
+
+type Transaction struct {
+	UUID             string             `json:"uuid,omitempty"`
+	Description      string             `json:"description,omitempty"`
+	DatetimeMs       int64              `json:"date,omitempty"`
+	TransactionLines []*TransactionLine `json:"transaction_lines,omitempty"`
+	PartyUUID        string             `json:"party_uuid,omitempty"`
+}
+
+type TransactionLine struct {
+	UUID            string `json:"uuid,omitempty"`
+	TransactionUUID string `json:"transaction_uuid,omitempty"`
+	AccountUUID     string `json:"account_uuid,omitempty"`
+	ItemUUID        string `json:"item_uuid,omitempty"`
+	Quantity        string `json:"quantity,omitempty"`
+	Unit            string `json:"unit,omitempty"`
+	Price           string `json:"price,omitempty"`
+	Currency        string `json:"currency,omitempty"`
+	Note            string `json:"note,omitempty"`
+	TaxCodeUUID     string `json:"tax_code_uuid,omitempty"`
+	PartyUUID       string `json:"party_uuid,omitempty"`
+}
+
+type BalanceHistoryReferences struct {
+	TransactionLineUUID string `json:"transaction_line,omitempty"`
+	TransactionUUID     string `json:"transaction_uuid,omitempty"`
+	AccountUUID         string `json:"account_uuid,omitempty"`
+	ItemUUID            string `json:"item_uuid,omitempty"`
+}
+
+type BalanceHistory struct {
+	UUID             string                   `json:"uuid,omitempty"`
+	Path             []string                 `json:"path,omitempty"`
+	References       BalanceHistoryReferences `json:"references"`
+	Unit             string                   `json:"unit,omitempty"`
+	Quantity         string                   `json:"quantity,omitempty"`
+	AvgCost          string                   `json:"avg_cost,omitempty"`
+	Value            string                   `json:"value,omitempty"`
+	DatetimeMs       int64                    `json:"date,omitempty"`
+	TransactionPrice string                   `json:"transaction_price,omitempty"`
+	MarketPrice      string                   `json:"market_price,omitempty"`
+	Currency         string                   `json:"currency,omitempty"`
+	MarketValue      string                   `json:"market_value,omitempty"`
+	Description      string                   `json:"description,omitempty"`
+}
+
+type BalanceHistoryList struct {
+	BalanceHistories []*BalanceHistory `json:"balance_histories"`
+}
+
+type UnitConversions struct {
+	FromUnit   string `json:"from_unit,omitempty"`
+	ToUnit     string `json:"to_unit,omitempty"`
+	Factor     string `json:"factor,omitempty"`
+	DatetimeMs int64  `json:"date,omitempty"`
+}
+
+type CurrencyConversions struct {
+	FromCurrency string `json:"from_currency,omitempty"`
+	ToCurrency   string `json:"to_currency,omitempty"`
+	Rate         string `json:"rate,omitempty"`
+	DatetimeMs   int64  `json:"date,omitempty"`
+}
+
+type MarketPrice struct {
+	ItemUUID   string `json:"item_uuid,omitempty"`
+	DatetimeMs int64  `json:"date,omitempty"`
+	Price      string `json:"price,omitempty"`
+	Unit       string `json:"unit,omitempty"`
+	Currency   string `json:"currency,omitempty"`
+}
+
+type MarketPriceList struct {
+	MarketPrices []*MarketPrice `json:"market_prices"`
+}
+
+type MarketPriceImport struct {
+	Format string `json:"format,omitempty"`
+	Data   []byte `json:"data,omitempty"`
+}
+
+type MarketPriceHistoryQuery struct {
+	ItemUUID       string `json:"item_uuid,omitempty"`
+	FromDatetimeMs int64  `json:"from_date,omitempty"`
+	ToDatetimeMs   int64  `json:"to_date,omitempty"`
+}
+
+type TaxCode struct {
+	UUID                  string `json:"uuid,omitempty"`
+	Code                  string `json:"code,omitempty"`
+	Name                  string `json:"name,omitempty"`
+	Rate                  string `json:"rate,omitempty"`
+	Inclusive             bool   `json:"inclusive,omitempty"`
+	PayableAccountUUID    string `json:"payable_account_uuid,omitempty"`
+	ReceivableAccountUUID string `json:"receivable_account_uuid,omitempty"`
+}
+
+type PeriodQuery struct {
+	FromDatetimeMs int64 `json:"from_date,omitempty"`
+	ToDatetimeMs   int64 `json:"to_date,omitempty"`
+}
+
+type Party struct {
+	UUID string `json:"uuid,omitempty"`
+	Name string `json:"name,omitempty"`
+	Kind string `json:"kind,omitempty"`
+}
+
+type PartyBalance struct {
+	PartyUUID string `json:"party_uuid,omitempty"`
+	Side      string `json:"side,omitempty"`
+	Currency  string `json:"currency,omitempty"`
+	Balance   string `json:"balance,omitempty"`
+}
+
+type PartyBalanceList struct {
+	PartyBalances []*PartyBalance `json:"party_balances"`
+}
+
+// empty PartyUUID means every party, balances and aging are as of ToDatetimeMs
+type PartyQuery struct {
+	PartyUUID      string `json:"party_uuid,omitempty"`
+	FromDatetimeMs int64  `json:"from_date,omitempty"`
+	ToDatetimeMs   int64  `json:"to_date,omitempty"`
+}
+
+// Template holds the description, party and lines posted on each occurrence
+type RecurringTransaction struct {
+	UUID            string       `json:"uuid,omitempty"`
+	Name            string       `json:"name,omitempty"`
+	Schedule        string       `json:"schedule,omitempty"`
+	DayOfMonth      int32        `json:"day_of_month,omitempty"`
+	Weekday         int32        `json:"weekday,omitempty"`
+	Cron            string       `json:"cron,omitempty"`
+	StartDatetimeMs int64        `json:"start_date,omitempty"`
+	EndDatetimeMs   int64        `json:"end_date,omitempty"`
+	Active          bool         `json:"active,omitempty"`
+	Template        *Transaction `json:"template,omitempty"`
+}
+
+type RecurringTransactionList struct {
+	RecurringTransactions []*RecurringTransaction `json:"recurring_transactions"`
+}
+
+// empty TransactionUUID means not posted yet
+type RecurringOccurrence struct {
+	RecurringUUID   string `json:"recurring_uuid,omitempty"`
+	DatetimeMs      int64  `json:"date,omitempty"`
+	TransactionUUID string `json:"transaction_uuid,omitempty"`
+}
+
+type RecurringOccurrenceList struct {
+	Occurrences []*RecurringOccurrence `json:"occurrences"`
+}
+
+type TemplateParam struct {
+	Name    string `json:"name,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Default string `json:"default,omitempty"`
+}
+
+type TemplateLine struct {
+	Account  string `json:"account,omitempty"`
+	Item     string `json:"item,omitempty"`
+	Quantity string `json:"quantity,omitempty"`
+	Unit     string `json:"unit,omitempty"`
+	Price    string `json:"price,omitempty"`
+	Currency string `json:"currency,omitempty"`
+	Note     string `json:"note,omitempty"`
+}
+
+type TransactionTemplate struct {
+	UUID        string           `json:"uuid,omitempty"`
+	Name        string           `json:"name,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Datetime    string           `json:"datetime,omitempty"`
+	Params      []*TemplateParam `json:"params,omitempty"`
+	Lines       []*TemplateLine  `json:"lines,omitempty"`
+}
+
+type TransactionTemplateList struct {
+	TransactionTemplates []*TransactionTemplate `json:"transaction_templates"`
+}
+
+// the template is looked up by TemplateUUID, or by TemplateName when empty
+type TemplateInstantiation struct {
+	TemplateUUID string            `json:"template_uuid,omitempty"`
+	TemplateName string            `json:"template_name,omitempty"`
+	Args         map[string]string `json:"args,omitempty"`
+}
+
+// Body values are base64 in the json, as encoding/json does for []byte
+type Packet struct {
+	UUID string            `json:"uuid"`
+	Type int16             `json:"type"`
+	Meta map[string][]byte `json:"meta,omitempty"`
+	Body map[string][]byte `json:"body,omitempty"`
+}
@@ -0,0 +1,31 @@
+package inventoryjson
+
+import (
+	"encoding/json"
+	"inventoryrpc"
+)
+
+func UnmarshalPkt(receivedPktBin []byte) (*inventoryrpc.Packet, error) {
+	var receivedPkt Packet
+	err := json.Unmarshal(receivedPktBin, &receivedPkt)
+
+	return ToInvPacket(&receivedPkt), err
+}
+
+type ConsumeProcessingResponseFunc func(responsePktByte []byte)
+
+// inventoryrpc.Codec for the json Packet, for inventoryrpc.Server and
+// inventoryrpc.Conn, and the inventoryrpc.ArgCodec of NewRegistry
+type Codec struct{}
+
+func (Codec) Name() string {
+	return "json"
+}
+
+func (Codec) Marshal(pkt *inventoryrpc.Packet) ([]byte, error) {
+	return json.Marshal(NewPacket(pkt))
+}
+
+func (Codec) Unmarshal(data []byte) (*inventoryrpc.Packet, error) {
+	return UnmarshalPkt(data)
+}
@@ -0,0 +1,171 @@
+package inventoryjson
+
+import (
+	"encoding/json"
+	"inventory"
+	"inventoryrpc"
+	"reflect"
+
+	"github.com/google/uuid"
+)
+
+type ServerFunc = inventoryrpc.ServerFunc
+type Request = inventoryrpc.Request
+type HandlerFunc = inventoryrpc.HandlerFunc
+type Middleware = inventoryrpc.Middleware
+type Registry = inventoryrpc.Registry
+
+// a Registry reading args and writing results as json, see
+// inventoryrpc.RegisterBuiltinFuncs for the built in functions
+func NewRegistry() *Registry {
+	return inventoryrpc.NewRegistry(Codec{})
+}
+
+// what a ServerProcessor without its own Registry uses, holds the built in
+// functions
+var DefaultRegistry = NewRegistry()
+
+// names registered on DefaultRegistry, in registration order
+var ServerFuncs []string
+
+// registers f on DefaultRegistry
+func RegisterServerFunc(f *ServerFunc) {
+	DefaultRegistry.Register(f)
+	ServerFuncs = DefaultRegistry.Names()
+}
+
+// registers the built in functions on r, for a registry other than
+// DefaultRegistry
+func RegisterBuiltinFuncs(r *Registry) {
+	inventoryrpc.RegisterBuiltinFuncs(r)
+}
+
+func init() {
+	inventoryrpc.RegisterBuiltinFuncs(DefaultRegistry)
+	ServerFuncs = DefaultRegistry.Names()
+}
+
+// uuid.Nil for an empty one
+func argUUID(s string) (uuid.UUID, error) {
+	if s == "" {
+		return uuid.Nil, nil
+	}
+	return uuid.Parse(s)
+}
+
+// unmarshals raw into the json type standing for the type arg points to
+// and converts it, an inventoryrpc.ArgCodec method. any other type, of a
+// function of your own, is unmarshalled as it is.
+func (Codec) DecodeArg(arg any, raw []byte) (any, error) {
+	switch arg.(type) {
+	case *inventory.Item:
+		var m Item
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return ToInvItem(&m), nil
+	case *inventory.Account:
+		var m Account
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return ToInvAccount(&m), nil
+	case *inventory.Transaction:
+		var m Transaction
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return ToInvTransaction(&m), nil
+	case *inventory.MarketPrice:
+		var m MarketPrice
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return ToInvMarketPrice(&m), nil
+	case *inventory.TaxCode:
+		var m TaxCode
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return ToInvTaxCode(&m), nil
+	case *inventory.Party:
+		var m Party
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return ToInvParty(&m), nil
+	case *inventory.RecurringTransaction:
+		var m RecurringTransaction
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		if _, err := argUUID(m.UUID); err != nil {
+			return nil, err
+		}
+		return ToInvRecurringTransaction(&m), nil
+	case *inventory.TransactionTemplate:
+		var m TransactionTemplate
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return ToInvTransactionTemplate(&m), nil
+	case *inventoryrpc.MarketPriceImport:
+		var m MarketPriceImport
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return &inventoryrpc.MarketPriceImport{Format: m.Format, Data: m.Data}, nil
+	case *inventoryrpc.MarketPriceHistoryQuery:
+		var m MarketPriceHistoryQuery
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		itemUUID, err := uuid.Parse(m.ItemUUID)
+		if err != nil {
+			return nil, err
+		}
+		return &inventoryrpc.MarketPriceHistoryQuery{ItemUUID: itemUUID, FromDatetimeMs: m.FromDatetimeMs, ToDatetimeMs: m.ToDatetimeMs}, nil
+	case *inventoryrpc.PeriodQuery:
+		var m PeriodQuery
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return &inventoryrpc.PeriodQuery{FromDatetimeMs: m.FromDatetimeMs, ToDatetimeMs: m.ToDatetimeMs}, nil
+	case *inventoryrpc.PartyQuery:
+		var m PartyQuery
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return &inventoryrpc.PartyQuery{Party: ToInvPartyRef(m.PartyUUID), FromDatetimeMs: m.FromDatetimeMs, ToDatetimeMs: m.ToDatetimeMs}, nil
+	case *inventoryrpc.TemplateInstantiation:
+		var m TemplateInstantiation
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		tmplUUID, err := argUUID(m.TemplateUUID)
+		if err != nil {
+			return nil, err
+		}
+		return &inventoryrpc.TemplateInstantiation{TemplateUUID: tmplUUID, TemplateName: m.TemplateName, Args: m.Args}, nil
+	}
+	v := reflect.New(reflect.TypeOf(arg).Elem()).Interface()
+	return v, json.Unmarshal(raw, v)
+}
+
+// the inventory lists the built in functions answer as their list type, any
+// other value as it is, an inventoryrpc.ArgCodec method
+func (Codec) EncodeResult(v any) ([]byte, error) {
+	switch v := v.(type) {
+	case []inventory.MarketPrice:
+		return json.Marshal(NewMarketPriceList(v))
+	case []inventory.PartyBalance:
+		return json.Marshal(NewPartyBalanceList(v))
+	case []*inventory.RecurringTransaction:
+		return json.Marshal(NewRecurringTransactionList(v))
+	case []inventory.RecurringOccurrence:
+		return json.Marshal(NewRecurringOccurrenceList(v))
+	case []*inventory.TransactionTemplate:
+		return json.Marshal(NewTransactionTemplateList(v))
+	}
+	return json.Marshal(v)
+}
@@ -0,0 +1,38 @@
+package inventoryjson
+
+import (
+	"encoding/json"
+	"inventoryrpc"
+)
+
+type ServerProcessor struct {
+	ProcessingChan                 chan *inventoryrpc.Packet
+	ConsumeProcessingResponseFuncs []ConsumeProcessingResponseFunc
+	// nil uses DefaultRegistry
+	Registry *Registry
+}
+
+func NewServerProcessor() *ServerProcessor {
+	return &ServerProcessor{
+		ProcessingChan: make(chan *inventoryrpc.Packet),
+	}
+}
+
+func (p *ServerProcessor) ProcessPkt(pkt *inventoryrpc.Packet) (*inventoryrpc.Packet, string, int32, error) {
+	if p.Registry != nil {
+		return p.Registry.Process(pkt)
+	}
+	return DefaultRegistry.Process(pkt)
+}
+
+func (p *ServerProcessor) PostProcessPkt(responsePkt *inventoryrpc.Packet) error {
+	responsePktJSON := NewPacket(responsePkt)
+	responsePktByte, err := json.Marshal(responsePktJSON)
+	if err != nil {
+		return err
+	}
+	for _, consumeFunc := range p.ConsumeProcessingResponseFuncs {
+		consumeFunc(responsePktByte)
+	}
+	return nil
+}
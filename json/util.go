@@ -0,0 +1,649 @@
+package inventoryjson
+
+import (
+	"encoding/json"
+	"inventory"
+	"inventoryrpc"
+
+	"github.com/google/uuid"
+)
+
+// empty for uuid.Nil
+func uuidString(u uuid.UUID) string {
+	if u == uuid.Nil {
+		return ""
+	}
+	return u.String()
+}
+
+func NewPacket(pkt *inventoryrpc.Packet) *Packet {
+	return &Packet{
+		UUID: pkt.UUID.String(),
+		Type: pkt.Type,
+		Meta: pkt.Meta,
+		Body: pkt.Body,
+	}
+}
+
+func ToInvPacket(pkt *Packet) *inventoryrpc.Packet {
+	pktUUID, _ := uuid.Parse(pkt.UUID)
+	return &inventoryrpc.Packet{
+		UUID: pktUUID,
+		Type: pkt.Type,
+		Meta: pkt.Meta,
+		Body: pkt.Body,
+	}
+}
+
+func NewTransactionLine(transactionLine *inventory.TransactionLine) *TransactionLine {
+	trLine := &TransactionLine{
+		UUID:     uuidString(transactionLine.UUID),
+		Quantity: transactionLine.Quantity.ToString(),
+		Unit:     transactionLine.Unit,
+		Price:    transactionLine.Price.ToString(),
+		Currency: transactionLine.Currency,
+		Note:     transactionLine.Note,
+	}
+	if transactionLine.Transaction != nil {
+		trLine.TransactionUUID = uuidString(transactionLine.Transaction.UUID)
+	}
+	if transactionLine.Account != nil {
+		trLine.AccountUUID = uuidString(transactionLine.Account.UUID)
+	}
+	if transactionLine.Item != nil {
+		trLine.ItemUUID = uuidString(transactionLine.Item.UUID)
+	}
+	if transactionLine.TaxCode != nil {
+		trLine.TaxCodeUUID = uuidString(transactionLine.TaxCode.UUID)
+	}
+	if transactionLine.Party != nil {
+		trLine.PartyUUID = uuidString(transactionLine.Party.UUID)
+	}
+	return trLine
+}
+
+func NewTransaction(transaction *inventory.Transaction) *Transaction {
+	var lines []*TransactionLine
+	for i := range transaction.TransactionLines {
+		lines = append(lines, NewTransactionLine(transaction.TransactionLines[i]))
+	}
+	tr := &Transaction{
+		UUID:             uuidString(transaction.UUID),
+		Description:      transaction.Description,
+		DatetimeMs:       transaction.DatetimeMs,
+		TransactionLines: lines,
+	}
+	if transaction.Party != nil {
+		tr.PartyUUID = uuidString(transaction.Party.UUID)
+	}
+	return tr
+}
+
+func NewItem(item *inventory.Item, transactionLines []*inventory.TransactionLine) *Item {
+	var lines []*TransactionLine
+	for i := range transactionLines {
+		lines = append(lines, NewTransactionLine(transactionLines[i]))
+	}
+	return &Item{
+		UUID:             uuidString(item.UUID),
+		Name:             item.Name,
+		Description:      item.Description,
+		Unit:             item.Unit,
+		TransactionLines: lines,
+	}
+}
+
+func ToInvItem(item *Item) *inventory.Item {
+	itemUUID, _ := uuid.Parse(item.UUID)
+	return &inventory.Item{
+		UUID:        itemUUID,
+		Name:        item.Name,
+		Description: item.Description,
+		Unit:        item.Unit,
+	}
+}
+
+func NewAccount(account *inventory.Account, transactionLines []*inventory.TransactionLine) *Account {
+	var lines []*TransactionLine
+	for i := range transactionLines {
+		lines = append(lines, NewTransactionLine(transactionLines[i]))
+	}
+	var parentUUID string
+	if account.Parent != nil {
+		parentUUID = uuidString(account.Parent.UUID)
+	} else {
+		parentUUID = ""
+	}
+	return &Account{
+		UUID:             uuidString(account.UUID),
+		Name:             account.Name,
+		ParentUUID:       parentUUID,
+		TransactionLines: lines,
+	}
+}
+
+func ToInvAccount(acc *Account) *inventory.Account {
+	accUUID, _ := uuid.Parse(acc.UUID)
+	parentUUID, _ := uuid.Parse(acc.ParentUUID)
+	return &inventory.Account{
+		UUID: accUUID,
+		Name: acc.Name,
+		Parent: &inventory.Account{
+			UUID: parentUUID,
+		},
+	}
+}
+
+func ToInvTransaction(tr *Transaction) *inventory.Transaction {
+	trUUID, _ := uuid.Parse(tr.UUID)
+	var trLines []*inventory.TransactionLine
+	for i := range tr.TransactionLines {
+		trl := tr.TransactionLines[i]
+		trLineUUID, _ := uuid.Parse(trl.UUID)
+		accUUID, _ := uuid.Parse(trl.AccountUUID)
+		itUUID, _ := uuid.Parse(trl.ItemUUID)
+		var acc *inventory.Account
+		if accUUID != uuid.Nil {
+			acc = &inventory.Account{
+				UUID: accUUID,
+			}
+		} else {
+			acc = nil
+		}
+		var item *inventory.Item
+		if itUUID != uuid.Nil {
+			item = &inventory.Item{
+				UUID: itUUID,
+			}
+		} else {
+			item = nil
+		}
+		var taxCode *inventory.TaxCode
+		if taxCodeUUID, _ := uuid.Parse(trl.TaxCodeUUID); taxCodeUUID != uuid.Nil {
+			taxCode = &inventory.TaxCode{
+				UUID: taxCodeUUID,
+			}
+		}
+		trLines = append(trLines, &inventory.TransactionLine{
+			UUID: trLineUUID,
+			Transaction: &inventory.Transaction{
+				UUID: trUUID,
+			},
+			Account:  acc,
+			Item:     item,
+			Quantity: inventory.NewDecimalFromStr(trl.Quantity),
+			Unit:     trl.Unit,
+			Price:    inventory.NewDecimalFromStr(trl.Price),
+			Currency: trl.Currency,
+			Note:     trl.Note,
+			TaxCode:  taxCode,
+			Party:    ToInvPartyRef(trl.PartyUUID),
+		})
+	}
+	return &inventory.Transaction{
+		UUID:             trUUID,
+		Description:      tr.Description,
+		DatetimeMs:       tr.DatetimeMs,
+		Party:            ToInvPartyRef(tr.PartyUUID),
+		TransactionLines: trLines,
+	}
+}
+
+func NewMarketPrice(p *inventory.MarketPrice) *MarketPrice {
+	var itemUUID string
+	if p.Item != nil {
+		itemUUID = uuidString(p.Item.UUID)
+	} else {
+		itemUUID = ""
+	}
+	return &MarketPrice{
+		ItemUUID:   itemUUID,
+		DatetimeMs: p.DatetimeMs,
+		Price:      p.Price.ToString(),
+		Unit:       p.Unit,
+		Currency:   p.Currency,
+	}
+}
+
+func ToInvMarketPrice(p *MarketPrice) *inventory.MarketPrice {
+	itemUUID, _ := uuid.Parse(p.ItemUUID)
+	return &inventory.MarketPrice{
+		Item: &inventory.Item{
+			UUID: itemUUID,
+		},
+		DatetimeMs: p.DatetimeMs,
+		Price:      inventory.NewDecimalFromStr(p.Price),
+		Unit:       p.Unit,
+		Currency:   p.Currency,
+	}
+}
+
+func NewMarketPriceList(prices []inventory.MarketPrice) *MarketPriceList {
+	var list []*MarketPrice
+	for i := range prices {
+		list = append(list, NewMarketPrice(&prices[i]))
+	}
+	return &MarketPriceList{
+		MarketPrices: list,
+	}
+}
+
+func ToInvMarketPrices(list *MarketPriceList) []*inventory.MarketPrice {
+	var prices []*inventory.MarketPrice
+	for i := range list.MarketPrices {
+		prices = append(prices, ToInvMarketPrice(list.MarketPrices[i]))
+	}
+	return prices
+}
+
+func NewBalanceHistory(b *inventory.BalanceHistory) *BalanceHistory {
+	balance := &BalanceHistory{
+		UUID:             uuidString(b.UUID),
+		Path:             b.Path,
+		Unit:             b.Unit,
+		Quantity:         b.Quantity.ToString(),
+		AvgCost:          b.AvgCost.ToString(),
+		Value:            b.Value.ToString(),
+		DatetimeMs:       b.DatetimeMs,
+		TransactionPrice: b.TransactionPrice.ToString(),
+		MarketPrice:      b.MarketPrice.ToString(),
+		Currency:         b.Currency,
+		MarketValue:      b.MarketValue.ToString(),
+		Description:      b.Description,
+	}
+	if trLine := b.TransactionLine; trLine != nil {
+		balance.References.TransactionLineUUID = uuidString(trLine.UUID)
+		if trLine.Transaction != nil {
+			balance.References.TransactionUUID = uuidString(trLine.Transaction.UUID)
+		}
+		if trLine.Account != nil {
+			balance.References.AccountUUID = uuidString(trLine.Account.UUID)
+		}
+		if trLine.Item != nil {
+			balance.References.ItemUUID = uuidString(trLine.Item.UUID)
+		}
+	}
+	return balance
+}
+
+// the transaction line only holds the referenced uuids
+func ToInvBalanceHistory(b *BalanceHistory) *inventory.BalanceHistory {
+	balanceUUID, _ := uuid.Parse(b.UUID)
+	trLineUUID, _ := uuid.Parse(b.References.TransactionLineUUID)
+	trLine := &inventory.TransactionLine{
+		UUID: trLineUUID,
+	}
+	if trUUID, _ := uuid.Parse(b.References.TransactionUUID); trUUID != uuid.Nil {
+		trLine.Transaction = &inventory.Transaction{UUID: trUUID}
+	}
+	if accUUID, _ := uuid.Parse(b.References.AccountUUID); accUUID != uuid.Nil {
+		trLine.Account = &inventory.Account{UUID: accUUID}
+	}
+	if itemUUID, _ := uuid.Parse(b.References.ItemUUID); itemUUID != uuid.Nil {
+		trLine.Item = &inventory.Item{UUID: itemUUID}
+	}
+	return &inventory.BalanceHistory{
+		UUID:             balanceUUID,
+		Path:             b.Path,
+		TransactionLine:  trLine,
+		Unit:             b.Unit,
+		Quantity:         inventory.NewDecimalFromStr(b.Quantity),
+		AvgCost:          inventory.NewDecimalFromStr(b.AvgCost),
+		Value:            inventory.NewDecimalFromStr(b.Value),
+		DatetimeMs:       b.DatetimeMs,
+		TransactionPrice: inventory.NewDecimalFromStr(b.TransactionPrice),
+		MarketPrice:      inventory.NewDecimalFromStr(b.MarketPrice),
+		Currency:         b.Currency,
+		MarketValue:      inventory.NewDecimalFromStr(b.MarketValue),
+		Description:      b.Description,
+	}
+}
+
+func NewBalanceHistoryList(balances []inventory.BalanceHistory) *BalanceHistoryList {
+	var list []*BalanceHistory
+	for i := range balances {
+		list = append(list, NewBalanceHistory(&balances[i]))
+	}
+	return &BalanceHistoryList{
+		BalanceHistories: list,
+	}
+}
+
+func ToInvBalanceHistories(list *BalanceHistoryList) []inventory.BalanceHistory {
+	var balances []inventory.BalanceHistory
+	for _, b := range list.BalanceHistories {
+		balances = append(balances, *ToInvBalanceHistory(b))
+	}
+	return balances
+}
+
+func NewUnitConversions(c *inventory.UnitConversions) *UnitConversions {
+	return &UnitConversions{
+		FromUnit:   c.FromUnit,
+		ToUnit:     c.ToUnit,
+		Factor:     c.Factor.ToString(),
+		DatetimeMs: c.DatetimeMs,
+	}
+}
+
+func ToInvUnitConversions(c *UnitConversions) *inventory.UnitConversions {
+	return &inventory.UnitConversions{
+		FromUnit:   c.FromUnit,
+		ToUnit:     c.ToUnit,
+		Factor:     inventory.NewDecimalFromStr(c.Factor),
+		DatetimeMs: c.DatetimeMs,
+	}
+}
+
+func NewCurrencyConversions(c *inventory.CurrencyConversion) *CurrencyConversions {
+	return &CurrencyConversions{
+		FromCurrency: c.FromCurrency,
+		ToCurrency:   c.ToCurrency,
+		Rate:         c.Rate.ToString(),
+		DatetimeMs:   c.DatetimeMs,
+	}
+}
+
+func ToInvCurrencyConversion(c *CurrencyConversions) *inventory.CurrencyConversion {
+	return &inventory.CurrencyConversion{
+		FromCurrency: c.FromCurrency,
+		ToCurrency:   c.ToCurrency,
+		Rate:         inventory.NewDecimalFromStr(c.Rate),
+		DatetimeMs:   c.DatetimeMs,
+	}
+}
+
+func NewTaxCode(tc *inventory.TaxCode) *TaxCode {
+	taxCode := &TaxCode{
+		UUID:      uuidString(tc.UUID),
+		Code:      tc.Code,
+		Name:      tc.Name,
+		Rate:      tc.Rate.ToString(),
+		Inclusive: tc.Inclusive,
+	}
+	if tc.PayableAccount != nil {
+		taxCode.PayableAccountUUID = uuidString(tc.PayableAccount.UUID)
+	}
+	if tc.ReceivableAccount != nil {
+		taxCode.ReceivableAccountUUID = uuidString(tc.ReceivableAccount.UUID)
+	}
+	return taxCode
+}
+
+func ToInvTaxCode(tc *TaxCode) *inventory.TaxCode {
+	tcUUID, _ := uuid.Parse(tc.UUID)
+	taxCode := &inventory.TaxCode{
+		UUID:      tcUUID,
+		Code:      tc.Code,
+		Name:      tc.Name,
+		Rate:      inventory.NewDecimalFromStr(tc.Rate),
+		Inclusive: tc.Inclusive,
+	}
+	if accUUID, _ := uuid.Parse(tc.PayableAccountUUID); accUUID != uuid.Nil {
+		taxCode.PayableAccount = &inventory.Account{UUID: accUUID}
+	}
+	if accUUID, _ := uuid.Parse(tc.ReceivableAccountUUID); accUUID != uuid.Nil {
+		taxCode.ReceivableAccount = &inventory.Account{UUID: accUUID}
+	}
+	return taxCode
+}
+
+func NewParty(p *inventory.Party) *Party {
+	return &Party{
+		UUID: uuidString(p.UUID),
+		Name: p.Name,
+		Kind: p.Kind,
+	}
+}
+
+func ToInvParty(p *Party) *inventory.Party {
+	partyUUID, _ := uuid.Parse(p.UUID)
+	return &inventory.Party{
+		UUID: partyUUID,
+		Name: p.Name,
+		Kind: p.Kind,
+	}
+}
+
+// nil for an empty or nil uuid
+func ToInvPartyRef(partyUUIDStr string) *inventory.Party {
+	partyUUID, _ := uuid.Parse(partyUUIDStr)
+	if partyUUID == uuid.Nil {
+		return nil
+	}
+	return &inventory.Party{
+		UUID: partyUUID,
+	}
+}
+
+func NewPartyBalanceList(balances []inventory.PartyBalance) *PartyBalanceList {
+	var list []*PartyBalance
+	for _, b := range balances {
+		list = append(list, &PartyBalance{
+			PartyUUID: uuidString(b.Party.UUID),
+			Side:      b.Side,
+			Currency:  b.Currency,
+			Balance:   b.Balance.ToString(),
+		})
+	}
+	return &PartyBalanceList{
+		PartyBalances: list,
+	}
+}
+
+func NewRecurringTransaction(r *inventory.RecurringTransaction) *RecurringTransaction {
+	rec := &RecurringTransaction{
+		UUID:            uuidString(r.UUID),
+		Name:            r.Name,
+		Schedule:        r.Schedule,
+		DayOfMonth:      int32(r.DayOfMonth),
+		Weekday:         int32(r.Weekday),
+		Cron:            r.Cron,
+		StartDatetimeMs: r.StartDatetimeMs,
+		EndDatetimeMs:   r.EndDatetimeMs,
+		Active:          r.Active,
+	}
+	if r.Template != nil {
+		rec.Template = NewTransaction(r.Template)
+	}
+	return rec
+}
+
+func ToInvRecurringTransaction(r *RecurringTransaction) *inventory.RecurringTransaction {
+	recUUID, _ := uuid.Parse(r.UUID)
+	rec := &inventory.RecurringTransaction{
+		UUID:            recUUID,
+		Name:            r.Name,
+		Schedule:        r.Schedule,
+		DayOfMonth:      int(r.DayOfMonth),
+		Weekday:         int(r.Weekday),
+		Cron:            r.Cron,
+		StartDatetimeMs: r.StartDatetimeMs,
+		EndDatetimeMs:   r.EndDatetimeMs,
+		Active:          r.Active,
+	}
+	if r.Template != nil {
+		rec.Template = ToInvTransaction(r.Template)
+	}
+	return rec
+}
+
+func ToInvPartyBalances(list *PartyBalanceList) []inventory.PartyBalance {
+	var balances []inventory.PartyBalance
+	for _, b := range list.PartyBalances {
+		balances = append(balances, inventory.PartyBalance{
+			Party:    ToInvPartyRef(b.PartyUUID),
+			Side:     b.Side,
+			Currency: b.Currency,
+			Balance:  inventory.NewDecimalFromStr(b.Balance),
+		})
+	}
+	return balances
+}
+
+func NewRecurringTransactionList(recs []*inventory.RecurringTransaction) *RecurringTransactionList {
+	var list []*RecurringTransaction
+	for _, r := range recs {
+		list = append(list, NewRecurringTransaction(r))
+	}
+	return &RecurringTransactionList{
+		RecurringTransactions: list,
+	}
+}
+
+func NewRecurringOccurrenceList(occurrences []inventory.RecurringOccurrence) *RecurringOccurrenceList {
+	var list []*RecurringOccurrence
+	for _, o := range occurrences {
+		occ := &RecurringOccurrence{
+			RecurringUUID: uuidString(o.Recurring.UUID),
+			DatetimeMs:    o.DatetimeMs,
+		}
+		if o.Transaction != nil {
+			occ.TransactionUUID = uuidString(o.Transaction.UUID)
+		}
+		list = append(list, occ)
+	}
+	return &RecurringOccurrenceList{
+		Occurrences: list,
+	}
+}
+
+func ToInvRecurringTransactions(list *RecurringTransactionList) []*inventory.RecurringTransaction {
+	var recs []*inventory.RecurringTransaction
+	for _, r := range list.RecurringTransactions {
+		recs = append(recs, ToInvRecurringTransaction(r))
+	}
+	return recs
+}
+
+func ToInvRecurringOccurrences(list *RecurringOccurrenceList) []inventory.RecurringOccurrence {
+	var occurrences []inventory.RecurringOccurrence
+	for _, o := range list.Occurrences {
+		recUUID, _ := uuid.Parse(o.RecurringUUID)
+		occ := inventory.RecurringOccurrence{
+			Recurring:  &inventory.RecurringTransaction{UUID: recUUID},
+			DatetimeMs: o.DatetimeMs,
+		}
+		if trUUID, _ := uuid.Parse(o.TransactionUUID); trUUID != uuid.Nil {
+			occ.Transaction = &inventory.Transaction{UUID: trUUID}
+		}
+		occurrences = append(occurrences, occ)
+	}
+	return occurrences
+}
+
+func NewTransactionTemplate(t *inventory.TransactionTemplate) *TransactionTemplate {
+	tmpl := &TransactionTemplate{
+		UUID:        uuidString(t.UUID),
+		Name:        t.Name,
+		Description: t.Description,
+		Datetime:    t.Datetime,
+	}
+	for _, p := range t.Params {
+		tmpl.Params = append(tmpl.Params, &TemplateParam{
+			Name:    p.Name,
+			Type:    p.Type,
+			Default: p.Default,
+		})
+	}
+	for _, l := range t.Lines {
+		tmpl.Lines = append(tmpl.Lines, &TemplateLine{
+			Account:  l.Account,
+			Item:     l.Item,
+			Quantity: l.Quantity,
+			Unit:     l.Unit,
+			Price:    l.Price,
+			Currency: l.Currency,
+			Note:     l.Note,
+		})
+	}
+	return tmpl
+}
+
+func ToInvTransactionTemplate(t *TransactionTemplate) *inventory.TransactionTemplate {
+	tmplUUID, _ := uuid.Parse(t.UUID)
+	tmpl := &inventory.TransactionTemplate{
+		UUID:        tmplUUID,
+		Name:        t.Name,
+		Description: t.Description,
+		Datetime:    t.Datetime,
+	}
+	for _, p := range t.Params {
+		tmpl.Params = append(tmpl.Params, inventory.TemplateParam{
+			Name:    p.Name,
+			Type:    p.Type,
+			Default: p.Default,
+		})
+	}
+	for _, l := range t.Lines {
+		tmpl.Lines = append(tmpl.Lines, &inventory.TemplateLine{
+			Account:  l.Account,
+			Item:     l.Item,
+			Quantity: l.Quantity,
+			Unit:     l.Unit,
+			Price:    l.Price,
+			Currency: l.Currency,
+			Note:     l.Note,
+		})
+	}
+	return tmpl
+}
+
+func NewTransactionTemplateList(templates []*inventory.TransactionTemplate) *TransactionTemplateList {
+	var list []*TransactionTemplate
+	for _, t := range templates {
+		list = append(list, NewTransactionTemplate(t))
+	}
+	return &TransactionTemplateList{
+		TransactionTemplates: list,
+	}
+}
+
+func ToInvTransactionTemplates(list *TransactionTemplateList) []*inventory.TransactionTemplate {
+	var templates []*inventory.TransactionTemplate
+	for _, t := range list.TransactionTemplates {
+		templates = append(templates, ToInvTransactionTemplate(t))
+	}
+	return templates
+}
+
+// request packet calling reqFunc with params, nil params sends no arg
+func NewRequestPkt(reqFunc string, params any) (*inventoryrpc.Packet, error) {
+	if params == nil {
+		return NewRawRequestPkt(reqFunc, nil)
+	}
+	paramBin, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	return NewRawRequestPkt(reqFunc, paramBin)
+}
+
+// for functions taking a non message arg, OpenOrCreateDB takes a bare uuid
+func NewRawRequestPkt(reqFunc string, paramBin []byte) (*inventoryrpc.Packet, error) {
+	pktUUID, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+
+	return &inventoryrpc.Packet{
+		UUID: pktUUID,
+		Type: inventoryrpc.TypeReq,
+		Meta: nil,
+		Body: map[string][]byte{
+			"function": []byte(reqFunc),
+			"arg":      paramBin,
+		},
+	}, nil
+}
+
+func CreateRequest(reqFunc string, params any) (uuid.UUID, []byte, error) {
+	pkt, err := NewRequestPkt(reqFunc, params)
+	if err != nil {
+		return uuid.UUID{}, nil, err
+	}
+
+	pktBin, err := json.Marshal(NewPacket(pkt))
+
+	return pkt.UUID, pktBin, err
+}
@@ -0,0 +1,562 @@
+package inventory
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/google/uuid"
+)
+
+const (
+	LandedCostByQuantity = "quantity"
+	LandedCostByWeight   = "weight"
+	LandedCostByValue    = "value"
+	LandedCostManual     = "manual"
+)
+
+var ErrUnknownAllocationMethod = errors.New("unknown allocation method")
+var ErrNoLandedCostCharge = errors.New("landed cost has no charge")
+var ErrMixedCurrency = errors.New("mixed currencies")
+var ErrNoReceivingLine = errors.New("no receiving line to allocate to")
+var ErrAllocationMismatch = errors.New("allocated amounts don't match the charges")
+var ErrNoCounterLine = errors.New("outgoing stock has no line to carry its landed cost to")
+
+// cost added on top of received goods, Account is credited, e.g. cash or a
+// payable to the forwarder
+type LandedCostCharge struct {
+	Account     *Account
+	Amount      Decimal
+	Currency    string
+	Description string
+}
+
+// share of the landed cost of one receiving line. for manual allocation the
+// caller fills TransactionLine (uuid is enough) and Amount. Expensed is the
+// part of Amount that followed consumed or sold stock out of inventory.
+type LandedCostAllocation struct {
+	ID              int
+	UUID            uuid.UUID
+	TransactionLine *TransactionLine
+	Basis           Decimal
+	Amount          Decimal
+	Expensed        Decimal
+}
+
+type LandedCost struct {
+	ID          int
+	UUID        uuid.UUID
+	Description string
+	DatetimeMs  int64
+	Method      string
+	WeightUnit  string
+	Receipts    []*Transaction
+	Charges     []*LandedCostCharge
+	Allocations []*LandedCostAllocation
+	Transaction *Transaction
+}
+
+// incoming item lines of the receipts, i.e. positive quantity on an asset
+// account
+func loadReceivingLines(db *sql.DB, receipts []*Transaction) ([]*TransactionLine, error) {
+	_, accMap, err := BuildAccountTree(db)
+	if err != nil {
+		return nil, err
+	}
+	var lines []*TransactionLine
+	for i, r := range receipts {
+		tr, err := GetTransactionByUUID(db, r.UUID[:])
+		if err != nil {
+			return nil, fmt.Errorf("receipt %s: %w", r.UUID, err)
+		}
+		receipts[i] = tr
+		for _, l := range tr.TransactionLines {
+			acc, ok := accMap[l.Account.ID]
+			if l.Item == nil || l.Quantity.Data <= 0 || !ok || !acc.IsChildOfOrItself(AssetAcc) {
+				continue
+			}
+			lines = append(lines, l)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, ErrNoReceivingLine
+	}
+	return lines, nil
+}
+
+// total * basis / sumBasis, the last share takes the rounding remainder
+func splitProportionally(total Decimal, bases []Decimal) []Decimal {
+	sum := big.NewInt(0)
+	for _, b := range bases {
+		sum.Add(sum, big.NewInt(b.Data))
+	}
+	shares := make([]Decimal, len(bases))
+	allocated := int64(0)
+	for i, b := range bases {
+		if i == len(bases)-1 {
+			shares[i] = NewDecimal(total.Data - allocated)
+			break
+		}
+		share := big.NewInt(total.Data)
+		share.Mul(share, big.NewInt(b.Data))
+		share.Quo(share, sum)
+		shares[i] = NewDecimal(share.Int64())
+		allocated += shares[i].Data
+	}
+	return shares
+}
+
+func allocateLandedCost(db *sql.DB, lc *LandedCost, lines []*TransactionLine, total Decimal) error {
+	if lc.Method == LandedCostManual {
+		byUUID := map[uuid.UUID]*TransactionLine{}
+		for _, l := range lines {
+			byUUID[l.UUID] = l
+		}
+		sum := int64(0)
+		for _, a := range lc.Allocations {
+			if a.TransactionLine == nil {
+				return ErrNoReceivingLine
+			}
+			l, ok := byUUID[a.TransactionLine.UUID]
+			if !ok {
+				return fmt.Errorf("%w: line %s", ErrNoReceivingLine, a.TransactionLine.UUID)
+			}
+			a.TransactionLine = l
+			a.Basis = a.Amount
+			sum += a.Amount.Data
+		}
+		if sum != total.Data {
+			return ErrAllocationMismatch
+		}
+		return nil
+	}
+
+	bases := make([]Decimal, len(lines))
+	for i, l := range lines {
+		switch lc.Method {
+		case LandedCostByQuantity:
+			bases[i] = l.Quantity
+		case LandedCostByValue:
+			bases[i] = l.Quantity.Multiply(l.Price)
+		case LandedCostByWeight:
+			weightUnit := lc.WeightUnit
+			if weightUnit == "" {
+				weightUnit = "kg"
+			}
+			unit := l.Unit
+			if unit == "" {
+				unit = l.Item.Unit
+			}
+			weight, err := ConvertUnit(db, l.Quantity, unit, weightUnit, l.Item, l.Transaction.DatetimeMs)
+			if err != nil {
+				return fmt.Errorf("weight of %s: %w", l.Item.Name, err)
+			}
+			bases[i] = weight
+		default:
+			return ErrUnknownAllocationMethod
+		}
+	}
+	sum := int64(0)
+	for _, b := range bases {
+		sum += b.Data
+	}
+	if sum <= 0 {
+		return ErrNoReceivingLine
+	}
+
+	lc.Allocations = nil
+	for i, share := range splitProportionally(total, bases) {
+		lc.Allocations = append(lc.Allocations, &LandedCostAllocation{
+			TransactionLine: lines[i],
+			Basis:           bases[i],
+			Amount:          share,
+		})
+	}
+	return nil
+}
+
+type stockPool struct {
+	accountID, itemID int
+}
+
+// a balance_history row of an item, qty is the quantity after it and
+// prevQty the one before
+type stockRow struct {
+	trID    int
+	pool    stockPool
+	qty     int64
+	prevQty int64
+}
+
+// follows the landed cost share of received stock through the lines that
+// took the stock out, the way it would have gone had it been in the cost
+// from the start. an outgoing line takes the share of the quantity it takes,
+// which moves on to its counter lines in the same transaction: stock lines
+// carry it further, other lines keep it.
+type landedCostCarry struct {
+	db     *sql.DB
+	accMap map[int]*Account
+	rows   []stockRow
+	trs    map[int]*Transaction
+
+	// where the shares ended up, in the order first reached, with a line of
+	// the account and item each is posted on
+	order  []stockPool
+	amount map[stockPool]int64
+	lines  map[stockPool]*TransactionLine
+}
+
+func newLandedCostCarry(db *sql.DB) (*landedCostCarry, error) {
+	_, accMap, err := BuildAccountTree(db)
+	if err != nil {
+		return nil, err
+	}
+	c := &landedCostCarry{
+		db:     db,
+		accMap: accMap,
+		trs:    map[int]*Transaction{},
+		amount: map[stockPool]int64{},
+		lines:  map[stockPool]*TransactionLine{},
+	}
+	rows, err := db.Query(`
+		SELECT h.transaction_id, h.account_id, h.item_id, h.quantity
+		FROM balance_history h
+		JOIN transactions t ON h.transaction_id = t.id
+		WHERE h.item_id > 0
+		ORDER BY t.datetime_ms, h.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	last := map[stockPool]int64{}
+	for rows.Next() {
+		var r stockRow
+		err = rows.Scan(&r.trID, &r.pool.accountID, &r.pool.itemID, &r.qty)
+		if err != nil {
+			return nil, err
+		}
+		r.prevQty = last[r.pool]
+		last[r.pool] = r.qty
+		c.rows = append(c.rows, r)
+	}
+	return c, rows.Err()
+}
+
+func (c *landedCostCarry) isStock(l *TransactionLine) bool {
+	acc, ok := c.accMap[l.Account.ID]
+	return l.Item != nil && ok && acc.IsChildOfOrItself(AssetAcc)
+}
+
+func (c *landedCostCarry) transaction(trID int) (*Transaction, error) {
+	tr, ok := c.trs[trID]
+	if ok {
+		return tr, nil
+	}
+	tr, err := GetTransactionByID(c.db, trID)
+	if err != nil {
+		return nil, err
+	}
+	c.trs[trID] = tr
+	return tr, nil
+}
+
+func linePool(l *TransactionLine) stockPool {
+	if l.Item == nil {
+		return stockPool{l.Account.ID, -1}
+	}
+	return stockPool{l.Account.ID, l.Item.ID}
+}
+
+func (c *landedCostCarry) add(l *TransactionLine, amount int64) {
+	k := linePool(l)
+	if _, ok := c.lines[k]; !ok {
+		c.order = append(c.order, k)
+		c.lines[k] = l
+	}
+	c.amount[k] += amount
+}
+
+// the lines of tr that took the stock an outgoing row of pool gave up with
+// the weights to split its share by. incoming stock of the same item comes
+// first, then any incoming stock, then the debits.
+func counterLines(tr *Transaction, pool stockPool) ([]*TransactionLine, []Decimal) {
+	var sameItem, items, debits []*TransactionLine
+	for _, l := range tr.TransactionLines {
+		switch {
+		case l.Quantity.Data <= 0 || linePool(l) == pool:
+		case l.Item == nil:
+			debits = append(debits, l)
+		case l.Item.ID == pool.itemID:
+			sameItem = append(sameItem, l)
+		default:
+			items = append(items, l)
+		}
+	}
+	counters := sameItem
+	if len(counters) == 0 {
+		counters = items
+	}
+	if len(counters) == 0 {
+		weights := make([]Decimal, len(debits))
+		for i, l := range debits {
+			weights[i] = l.Quantity
+		}
+		return debits, weights
+	}
+	byValue := true
+	weights := make([]Decimal, len(counters))
+	for i, l := range counters {
+		weights[i] = l.Quantity.Multiply(l.Price)
+		byValue = byValue && weights[i].Data > 0
+	}
+	if !byValue {
+		for i, l := range counters {
+			weights[i] = l.Quantity
+		}
+	}
+	return counters, weights
+}
+
+// carries the share of a from its receiving line to where the stock is now
+// or to where it left inventory, which is a.Expensed
+func (c *landedCostCarry) carry(a *LandedCostAllocation) error {
+	receipt := a.TransactionLine
+	value := map[stockPool]int64{}
+	refs := map[stockPool]*TransactionLine{}
+	received := false
+	for _, r := range c.rows {
+		if !received {
+			if r.trID == receipt.Transaction.ID && r.pool == linePool(receipt) {
+				value[r.pool] = a.Amount.Data
+				refs[r.pool] = receipt
+				received = true
+			}
+			continue
+		}
+		out := r.prevQty - r.qty
+		if value[r.pool] == 0 || out <= 0 || r.prevQty <= 0 {
+			continue
+		}
+		share := big.NewInt(value[r.pool])
+		if out < r.prevQty {
+			share.Mul(share, big.NewInt(out))
+			share.Quo(share, big.NewInt(r.prevQty))
+		}
+		value[r.pool] -= share.Int64()
+
+		tr, err := c.transaction(r.trID)
+		if err != nil {
+			return err
+		}
+		counters, weights := counterLines(tr, r.pool)
+		if len(counters) == 0 {
+			return fmt.Errorf("%w: %q", ErrNoCounterLine, tr.Description)
+		}
+		for i, part := range splitProportionally(NewDecimal(share.Int64()), weights) {
+			l := counters[i]
+			if c.isStock(l) {
+				value[linePool(l)] += part.Data
+				refs[linePool(l)] = l
+				continue
+			}
+			c.add(l, part.Data)
+			a.Expensed.Data += part.Data
+		}
+	}
+	if !received {
+		return fmt.Errorf("%w: line %s has no balance", ErrNoReceivingLine, receipt.UUID)
+	}
+	for _, r := range c.rows {
+		if v := value[r.pool]; v != 0 {
+			c.add(refs[r.pool], v)
+			value[r.pool] = 0
+		}
+	}
+	return nil
+}
+
+// allocates the charges onto the receiving lines of lc.Receipts and
+// capitalizes them into the cost of the received items at lc.DatetimeMs.
+// the share of stock consumed or moved since the receipt follows it to the
+// counter lines that took it, see landedCostCarry. balances recorded after
+// that date for the same account and item are shifted too.
+func ApplyLandedCost(db *sql.DB, lc *LandedCost) ([]byte, error) {
+	if len(lc.Charges) == 0 {
+		return nil, ErrNoLandedCostCharge
+	}
+	total := NewDecimal(0)
+	currency := lc.Charges[0].Currency
+	for _, c := range lc.Charges {
+		if c.Currency != currency {
+			return nil, ErrMixedCurrency
+		}
+		total.Data += c.Amount.Data
+	}
+	if total.Data <= 0 {
+		return nil, ErrNoLandedCostCharge
+	}
+
+	lines, err := loadReceivingLines(db, lc.Receipts)
+	if err != nil {
+		return nil, err
+	}
+	err = allocateLandedCost(db, lc, lines, total)
+	if err != nil {
+		return nil, err
+	}
+	carry, err := newLandedCostCarry(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range lc.Allocations {
+		a.Expensed = NewDecimal(0)
+		err = carry.carry(a)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	transaction := &Transaction{
+		Description: lc.Description,
+		DatetimeMs:  lc.DatetimeMs,
+	}
+	for _, k := range carry.order {
+		amount, l := NewDecimal(carry.amount[k]), carry.lines[k]
+		switch {
+		case amount.Data == 0:
+		case l.Item != nil:
+			transaction.TransactionLines = append(transaction.TransactionLines,
+				CreateValueAdjustmentTrLine(l.Account, l.Item, amount, currency))
+		default:
+			transaction.TransactionLines = append(transaction.TransactionLines,
+				CreateFinancialTrLine(l.Account, amount, NewDecimal(0), currency))
+		}
+	}
+	for _, c := range lc.Charges {
+		line := CreateFinancialTrLine(c.Account, NewDecimal(0), c.Amount, currency)
+		line.Note = c.Description
+		transaction.TransactionLines = append(transaction.TransactionLines, line)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	trUUID, trID, err := applyTransactionTx(db, tx, transaction)
+	if err != nil {
+		return nil, err
+	}
+	// the landed cost is usually dated back to the receipts, the balances
+	// recorded since take it in too
+	for _, l := range transaction.TransactionLines {
+		itemID, value := -1, l.Quantity.Multiply(l.Price)
+		if l.Item != nil {
+			itemID = l.Item.ID
+		}
+		if l.ValueAdjustment {
+			value = l.Price
+		}
+		err = shiftLaterBalancesTx(tx, itemID, l.Account.ID, lc.DatetimeMs, l.Quantity, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	lc.Transaction = transaction
+
+	lc.UUID, err = uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	res, err := tx.Exec(`
+		INSERT INTO landed_costs(uuid,transaction_id,description,datetime_ms,method,weight_unit,amount,currency)
+		VALUES(?,?,?,?,?,?,?,?)`,
+		lc.UUID[:], trID, lc.Description, lc.DatetimeMs, lc.Method, lc.WeightUnit, total, currency)
+	if err != nil {
+		return nil, err
+	}
+	lcID, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	lc.ID = int(lcID)
+
+	for _, a := range lc.Allocations {
+		a.UUID, err = uuid.NewV7()
+		if err != nil {
+			return nil, err
+		}
+		res, err = tx.Exec(`
+			INSERT INTO landed_cost_allocations(uuid,landed_cost_id,receipt_transaction_id,receipt_line_id,basis,amount,expensed)
+			VALUES(?,?,?,?,?,?,?)`,
+			a.UUID[:], lcID, a.TransactionLine.Transaction.ID, a.TransactionLine.ID, a.Basis, a.Amount, a.Expensed)
+		if err != nil {
+			return nil, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		a.ID = int(id)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	publishTransactions(db, trID)
+	return trUUID, nil
+}
+
+// a line posted at datetimeMs also changes every balance of its account and
+// item recorded after that
+func shiftLaterBalancesTx(tx *sql.Tx, itemID, accountID int, datetimeMs int64, qty, value Decimal) error {
+	if qty.Data == 0 && value.Data == 0 {
+		return nil
+	}
+	rows, err := tx.Query(`
+		SELECT h.id, h.quantity, h.total_cost
+		FROM balance_history h
+		JOIN transactions t ON h.transaction_id = t.id
+		WHERE h.item_id=? AND h.account_id=? AND t.datetime_ms > ?`,
+		itemID, accountID, datetimeMs)
+	if err != nil {
+		return err
+	}
+	type laterBalance struct {
+		id        int
+		qty       Decimal
+		totalCost Decimal
+	}
+	var later []laterBalance
+	for rows.Next() {
+		b := laterBalance{qty: NewDecimal(0), totalCost: NewDecimal(0)}
+		err = rows.Scan(&b.id, &b.qty, &b.totalCost)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		later = append(later, b)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, b := range later {
+		newQty := NewDecimal(b.qty.Data + qty.Data)
+		newTotal := NewDecimal(b.totalCost.Data + value.Data)
+		avgCost := NewDecimal(0)
+		if newQty.Data != 0 {
+			avgCost = newTotal.Divide(newQty)
+		}
+		_, err = tx.Exec(`UPDATE balance_history SET quantity=?, total_cost=?, avg_cost=? WHERE id=?`,
+			newQty, newTotal, avgCost, b.id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
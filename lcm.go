@@ -0,0 +1,279 @@
+package inventory
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const SettingWriteDownAccount = "write_down_account"
+
+var ErrNoWriteDownAccount = errors.New("write down account not set")
+
+// one (account, item) adjusted by a lower of cost or market run. Cost is the
+// historical cost without any write down, CarryingValue the total cost before
+// the run. Adjustment is negative for a write down and positive for a write
+// back.
+type WriteDown struct {
+	ID            int
+	UUID          uuid.UUID
+	Transaction   *Transaction
+	Account       *Account
+	Item          *Item
+	DatetimeMs    int64
+	Quantity      Decimal
+	Cost          Decimal
+	CarryingValue Decimal
+	MarketPrice   Decimal
+	MarketValue   Decimal
+	Adjustment    Decimal
+	Currency      string
+}
+
+func SetWriteDownAccount(db *sql.DB, acc *Account) error {
+	return SetAccountSetting(db, SettingWriteDownAccount, acc)
+}
+
+func GetWriteDownAccount(db *sql.DB) (*Account, error) {
+	acc, err := GetAccountSetting(db, SettingWriteDownAccount)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoWriteDownAccount
+	}
+	return acc, err
+}
+
+type lcmKey struct {
+	accountID int
+	itemID    int
+}
+
+// market price of item effective at datetimeMs, per unit of the item and in
+// the base currency when one is set
+func marketPricePerItemUnit(db *sql.DB, item *Item, datetimeMs int64, baseCurrency string) (Decimal, string, bool, error) {
+	var price Decimal
+	var unit, currency sql.NullString
+	err := db.QueryRow(`
+		SELECT price,unit,currency
+		FROM market_prices
+		WHERE item_id=? AND datetime_ms <= ?
+		ORDER BY datetime_ms DESC, id DESC
+		LIMIT 1`, item.ID, datetimeMs).Scan(&price, &unit, &currency)
+	if err == sql.ErrNoRows {
+		return price, "", false, nil
+	} else if err != nil {
+		return price, "", false, err
+	}
+
+	if unit.String != "" && unit.String != item.Unit {
+		// price is per market unit, find how many item units that is
+		perUnit, err := ConvertUnit(db, NewDecimalFromIntFrac(1, 0), unit.String, item.Unit, item, datetimeMs)
+		if err != nil {
+			return price, "", false, fmt.Errorf("price unit %s of %s: %w", unit.String, item.Name, err)
+		}
+		price = price.Divide(perUnit)
+	}
+	if baseCurrency != "" && currency.String != "" && currency.String != baseCurrency {
+		price, err = ConvertCurrency(db, price, currency.String, baseCurrency, datetimeMs)
+		if err != nil {
+			return price, "", false, err
+		}
+		return price, baseCurrency, true, nil
+	}
+	return price, currency.String, true, nil
+}
+
+// compares the market value with the historical cost of every item held in an
+// asset account at datetimeMs and writes the carrying value down to the lower
+// of the two against the write down account. earlier write downs are written
+// back when the market price recovers, never above historical cost.
+func RunLowerOfCostOrMarket(db *sql.DB, datetimeMs int64) ([]WriteDown, []byte, error) {
+	lossAcc, err := GetWriteDownAccount(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	baseCurrency, err := GetBaseCurrency(db)
+	if err != nil && err != ErrNoBaseCurrency {
+		return nil, nil, err
+	}
+	_, accMap, err := BuildAccountTree(db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the allowance is what earlier runs took off the carrying value, negative
+	// after a write down. stock going out carries its share of the allowance
+	// with it at average cost, so the allowance left shrinks with the
+	// quantity and Cost stays the historical cost of what is still held.
+	rows, err := db.Query(`
+		SELECT h.account_id, h.item_id, h.quantity, h.total_cost, a.adjustment
+		FROM balance_history h
+		JOIN transactions t ON h.transaction_id = t.id
+		LEFT JOIN lcm_adjustments a ON a.transaction_id = h.transaction_id
+			AND a.account_id = h.account_id AND a.item_id = h.item_id
+		WHERE h.item_id > 0 AND t.datetime_ms <= ?
+		ORDER BY t.datetime_ms ASC, h.id ASC`, datetimeMs)
+	if err != nil {
+		return nil, nil, err
+	}
+	balances := map[lcmKey]*WriteDown{}
+	allowances := map[lcmKey]int64{}
+	for rows.Next() {
+		var key lcmKey
+		var adjustment sql.NullInt64
+		qty, totalCost := NewDecimal(0), NewDecimal(0)
+		err = rows.Scan(&key.accountID, &key.itemID, &qty, &totalCost, &adjustment)
+		if err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		acc, ok := accMap[key.accountID]
+		if !ok || !acc.IsChildOfOrItself(AssetAcc) {
+			continue
+		}
+		allowance := allowances[key]
+		if prev, ok := balances[key]; ok && qty.Data < prev.Quantity.Data {
+			allowance = prorateAllowance(allowance, qty, prev.Quantity)
+		}
+		allowance += adjustment.Int64
+		if allowance > 0 {
+			// a write back never goes past the write downs
+			allowance = 0
+		}
+		allowances[key] = allowance
+		balances[key] = &WriteDown{
+			Account:       acc,
+			DatetimeMs:    datetimeMs,
+			Quantity:      qty,
+			CarryingValue: totalCost,
+			Cost:          NewDecimal(totalCost.Data - allowance),
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	keys := make([]lcmKey, 0, len(balances))
+	for k := range balances {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].accountID != keys[j].accountID {
+			return keys[i].accountID < keys[j].accountID
+		}
+		return keys[i].itemID < keys[j].itemID
+	})
+
+	transaction := &Transaction{
+		Description: fmt.Sprintf("Lower of cost or market %s", time.UnixMilli(datetimeMs).Format("2006-01-02")),
+		DatetimeMs:  datetimeMs,
+	}
+	items := map[int]*Item{}
+	totals := map[string]int64{}
+	var currencies []string
+	var results []WriteDown
+	for _, k := range keys {
+		w := balances[k]
+		if w.Quantity.Data <= 0 {
+			continue
+		}
+		item, ok := items[k.itemID]
+		if !ok {
+			item, err = GetItemByID(db, k.itemID)
+			if err != nil {
+				return nil, nil, err
+			}
+			items[k.itemID] = item
+		}
+		w.Item = item
+
+		price, currency, ok, err := marketPricePerItemUnit(db, item, datetimeMs, baseCurrency)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			continue
+		}
+		w.MarketPrice = price
+		w.MarketValue = w.Quantity.Multiply(price)
+		w.Currency = currency
+
+		target := w.Cost
+		if w.MarketValue.Data < target.Data {
+			target = w.MarketValue
+		}
+		w.Adjustment = NewDecimal(target.Data - w.CarryingValue.Data)
+		if w.Adjustment.Data == 0 {
+			continue
+		}
+		transaction.TransactionLines = append(transaction.TransactionLines,
+			CreateValueAdjustmentTrLine(w.Account, item, w.Adjustment, currency))
+		if _, ok := totals[currency]; !ok {
+			currencies = append(currencies, currency)
+		}
+		totals[currency] += w.Adjustment.Data
+		results = append(results, *w)
+	}
+	if len(results) == 0 {
+		return nil, nil, nil
+	}
+	for _, currency := range currencies {
+		if totals[currency] == 0 {
+			continue
+		}
+		// write down debits the loss account, write back credits it
+		transaction.TransactionLines = append(transaction.TransactionLines,
+			CreateFinancialTrLine(lossAcc, NewDecimal(0), NewDecimal(totals[currency]), currency))
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	trUUID, trID, err := applyTransactionTx(db, tx, transaction)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range results {
+		w := &results[i]
+		w.UUID, err = uuid.NewV7()
+		if err != nil {
+			return nil, nil, err
+		}
+		w.Transaction = transaction
+		res, err := tx.Exec(`
+			INSERT INTO lcm_adjustments(uuid,transaction_id,account_id,item_id,datetime_ms,quantity,cost,carrying_value,market_price,market_value,adjustment,currency)
+			VALUES(?,?,?,?,?,?,?,?,?,?,?,?)`,
+			w.UUID[:], trID, w.Account.ID, w.Item.ID, w.DatetimeMs, w.Quantity, w.Cost, w.CarryingValue, w.MarketPrice, w.MarketValue, w.Adjustment, w.Currency)
+		if err != nil {
+			return nil, nil, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, nil, err
+		}
+		w.ID = int(id)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, nil, err
+	}
+	publishTransactions(db, trID)
+	return results, trUUID, nil
+}
+
+// the part of allowance left with qty of the prevQty held before
+func prorateAllowance(allowance int64, qty, prevQty Decimal) int64 {
+	if qty.Data <= 0 || prevQty.Data <= 0 {
+		return 0
+	}
+	return allowance * qty.Data / prevQty.Data
+}
@@ -0,0 +1,269 @@
+package inventory
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MarketPriceFormatCSV  = "csv"
+	MarketPriceFormatJSON = "json"
+)
+
+var ErrUnknownImportFormat = errors.New("unknown import format")
+var ErrMissingColumn = errors.New("missing column")
+var ErrNegativePrice = errors.New("price must not be negative")
+var ErrDuplicateMarketPrice = errors.New("duplicate price for item and date")
+
+// one row of a price list. Item is either the item uuid or its name, Date is
+// either YYYY-MM-DD, RFC3339 or unix milliseconds.
+type MarketPriceRecord struct {
+	Item     string      `json:"item"`
+	Date     string      `json:"date"`
+	Price    json.Number `json:"price"`
+	Unit     string      `json:"unit"`
+	Currency string      `json:"currency"`
+}
+
+type MarketPriceImportError struct {
+	Row int
+	Err error
+}
+
+func (e *MarketPriceImportError) Error() string {
+	return fmt.Sprintf("row %d: %s", e.Row, e.Err.Error())
+}
+
+func (e *MarketPriceImportError) Unwrap() error {
+	return e.Err
+}
+
+func ParseMarketPriceRecords(r io.Reader, format string) ([]MarketPriceRecord, error) {
+	switch format {
+	case MarketPriceFormatCSV:
+		return parseMarketPriceCSV(r)
+	case MarketPriceFormatJSON:
+		var records []MarketPriceRecord
+		err := json.NewDecoder(r).Decode(&records)
+		return records, err
+	}
+	return nil, ErrUnknownImportFormat
+}
+
+// first row is the header, columns may come in any order
+func parseMarketPriceCSV(r io.Reader) ([]MarketPriceRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	cols := map[string]int{}
+	for i, h := range header {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, c := range []string{"item", "date", "price"} {
+		if _, ok := cols[c]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingColumn, c)
+		}
+	}
+	get := func(row []string, col string) string {
+		i, ok := cols[col]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	var records []MarketPriceRecord
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, MarketPriceRecord{
+			Item:     get(row, "item"),
+			Date:     get(row, "date"),
+			Price:    json.Number(get(row, "price")),
+			Unit:     get(row, "unit"),
+			Currency: get(row, "currency"),
+		})
+	}
+	return records, nil
+}
+
+func ParseDatetimeMs(str string) (int64, error) {
+	str = strings.TrimSpace(str)
+	if ms, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return ms, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", str, time.Local); err == nil {
+		return t.UnixMilli(), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04:05", str, time.Local); err == nil {
+		return t.UnixMilli(), nil
+	}
+	t, err := time.Parse(time.RFC3339, str)
+	if err != nil {
+		return 0, err
+	}
+	return t.UnixMilli(), nil
+}
+
+func findItemForImport(db *sql.DB, ref string) (*Item, error) {
+	if itUUID, err := uuid.Parse(ref); err == nil {
+		return GetItemByUUID(db, itUUID[:])
+	}
+	return GetItemByName(db, ref)
+}
+
+// validates every record, resolving items and falling back to the item unit
+// and the base currency. all problems are returned together.
+func ValidateMarketPriceRecords(db *sql.DB, records []MarketPriceRecord) ([]*MarketPrice, error) {
+	baseCurrency, err := GetBaseCurrency(db)
+	if err != nil && err != ErrNoBaseCurrency {
+		return nil, err
+	}
+
+	var errs []error
+	var prices []*MarketPrice
+	items := map[string]*Item{}
+	seen := map[string]bool{}
+	for i, rec := range records {
+		row := i + 1
+		item, ok := items[rec.Item]
+		if !ok {
+			item, err = findItemForImport(db, rec.Item)
+			if err == sql.ErrNoRows {
+				errs = append(errs, &MarketPriceImportError{Row: row, Err: fmt.Errorf("item %q not found", rec.Item)})
+				continue
+			} else if err != nil {
+				errs = append(errs, &MarketPriceImportError{Row: row, Err: err})
+				continue
+			}
+			items[rec.Item] = item
+		}
+		datetimeMs, err := ParseDatetimeMs(rec.Date)
+		if err != nil {
+			errs = append(errs, &MarketPriceImportError{Row: row, Err: fmt.Errorf("invalid date %q", rec.Date)})
+			continue
+		}
+		price, err := ParseDecimal(rec.Price.String())
+		if err != nil {
+			errs = append(errs, &MarketPriceImportError{Row: row, Err: fmt.Errorf("invalid price %q", rec.Price.String())})
+			continue
+		}
+		if price.Data < 0 {
+			errs = append(errs, &MarketPriceImportError{Row: row, Err: ErrNegativePrice})
+			continue
+		}
+		unit := rec.Unit
+		if unit == "" {
+			unit = item.Unit
+		}
+		currency := rec.Currency
+		if currency == "" {
+			currency = baseCurrency
+		}
+		if currency == "" {
+			errs = append(errs, &MarketPriceImportError{Row: row, Err: errors.New("currency missing and base currency not set")})
+			continue
+		}
+		key := fmt.Sprintf("%d:%d", item.ID, datetimeMs)
+		if seen[key] {
+			errs = append(errs, &MarketPriceImportError{Row: row, Err: ErrDuplicateMarketPrice})
+			continue
+		}
+		seen[key] = true
+
+		prices = append(prices, &MarketPrice{
+			Item:       item,
+			DatetimeMs: datetimeMs,
+			Price:      price,
+			Unit:       unit,
+			Currency:   currency,
+		})
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return prices, nil
+}
+
+// all or nothing, returns the number of prices inserted
+func ImportMarketPrices(db *sql.DB, r io.Reader, format string) (int, error) {
+	records, err := ParseMarketPriceRecords(r, format)
+	if err != nil {
+		return 0, err
+	}
+	prices, err := ValidateMarketPriceRecords(db, records)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, p := range prices {
+		_, err = tx.Exec(`
+			INSERT INTO market_prices(item_id,datetime_ms,price,currency,unit)
+			VALUES(?,?,?,?,?)
+		`, p.Item.ID, p.DatetimeMs, p.Price, p.Currency, p.Unit)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	publishMarketPrices(db, prices...)
+	return len(prices), nil
+}
+
+// prices of item with fromMs <= date <= toMs, oldest first
+func GetMarketPriceHistory(db *sql.DB, item *Item, fromMs, toMs int64) ([]MarketPrice, error) {
+	itemID, err := resolveItemID(db, item)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`
+		SELECT id,datetime_ms,price,unit,currency
+		FROM market_prices
+		WHERE item_id=? AND datetime_ms >= ? AND datetime_ms <= ?
+		ORDER BY datetime_ms ASC, id ASC`, itemID, fromMs, toMs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prices []MarketPrice
+	for rows.Next() {
+		p := MarketPrice{Item: item}
+		var unit, currency sql.NullString
+		err = rows.Scan(&p.ID, &p.DatetimeMs, &p.Price, &unit, &currency)
+		if err != nil {
+			return nil, err
+		}
+		p.Unit = unit.String
+		p.Currency = currency.String
+		prices = append(prices, p)
+	}
+	return prices, rows.Err()
+}
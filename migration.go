@@ -0,0 +1,150 @@
+package inventory
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// databases created before schema versioning are version 0. migrations[i]
+// brings a database from version i to i+1, InitSchema creates the latest
+// schema and marks it len(migrations).
+var migrations = []func(tx *sql.Tx) error{
+	migrateUnitConversionItems,
+	migrateMarketPriceItemIDs,
+	migrateTaxCodes,
+	migrateParties,
+	migrateValueAdjustmentLines,
+}
+
+var ErrSchemaTooNew = errors.New("database schema is newer than this build")
+
+func SchemaVersion(db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRow("PRAGMA user_version").Scan(&version)
+	return version, err
+}
+
+func setSchemaVersion(db interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}, version int) error {
+	// pragmas take no placeholders
+	_, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", version))
+	return err
+}
+
+// runs the migrations the database is missing, each in its own transaction,
+// then creates the tables added to the schema since the database was made
+func MigrateSchema(db *sql.DB) error {
+	version, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return ErrSchemaTooNew
+	}
+	for ; version < len(migrations); version++ {
+		err = migrate(db, version)
+		if err != nil {
+			return fmt.Errorf("migrating schema to version %d: %w", version+1, err)
+		}
+	}
+	_, err = db.Exec(schema)
+	return err
+}
+
+func migrate(db *sql.DB, version int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = migrations[version](tx)
+	if err != nil {
+		return err
+	}
+	err = setSchemaVersion(tx, version+1)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// empty when the table or the column doesn't exist
+func columnType(tx *sql.Tx, table, column string) (string, error) {
+	var typ string
+	err := tx.QueryRow(`SELECT type FROM pragma_table_info(?) WHERE name=?`, table, column).Scan(&typ)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return typ, err
+}
+
+// a table the database doesn't have yet is left to MigrateSchema, which
+// creates it with the column. databases created by a build in between may
+// have the column already.
+func addColumn(tx *sql.Tx, table, column, decl string) error {
+	var tables int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=?`, table).Scan(&tables)
+	if err != nil || tables == 0 {
+		return err
+	}
+	typ, err := columnType(tx, table, column)
+	if err != nil || typ != "" {
+		return err
+	}
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, decl))
+	return err
+}
+
+// unit conversions can be specific to an item
+func migrateUnitConversionItems(tx *sql.Tx) error {
+	return addColumn(tx, "unit_conversions", "item_id", "INTEGER REFERENCES items(id) ON DELETE CASCADE")
+}
+
+// market prices were keyed by the item uuid in a BLOB column, they are keyed
+// by the integer item id. sqlite can't change a column type, the table is
+// copied instead.
+func migrateMarketPriceItemIDs(tx *sql.Tx) error {
+	typ, err := columnType(tx, "market_prices", "item_id")
+	if err != nil || typ != "BLOB" {
+		return err
+	}
+	_, err = tx.Exec(`
+CREATE TABLE market_prices_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    item_id INTEGER NOT NULL,
+    datetime_ms INTEGER NOT NULL,
+    price BIGINT,
+    unit TEXT,
+    currency TEXT,
+    FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE
+);
+INSERT INTO market_prices_new (id,item_id,datetime_ms,price,unit,currency)
+    SELECT id, CAST(item_id AS INTEGER), datetime_ms, price, unit, currency FROM market_prices;
+DROP TABLE market_prices;
+ALTER TABLE market_prices_new RENAME TO market_prices;
+`)
+	return err
+}
+
+// lines name the tax code they were taxed with
+func migrateTaxCodes(tx *sql.Tx) error {
+	return addColumn(tx, "transaction_lines", "tax_code_id", "INTEGER")
+}
+
+// transactions and their lines name the party of a receivable or payable
+func migrateParties(tx *sql.Tx) error {
+	err := addColumn(tx, "transactions", "party_id", "INTEGER")
+	if err != nil {
+		return err
+	}
+	return addColumn(tx, "transaction_lines", "party_id", "INTEGER")
+}
+
+// value adjustments are marked instead of told apart by their zero quantity.
+// lines written before are left unmarked, their balances are already posted.
+func migrateValueAdjustmentLines(tx *sql.Tx) error {
+	return addColumn(tx, "transaction_lines", "value_adjustment", "INTEGER NOT NULL DEFAULT 0")
+}
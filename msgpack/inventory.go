@@ -1,44 +1,43 @@
 package inventorymsgpack
 
-import (
-	"inventory"
-	"inventoryrpc"
-
-	"github.com/google/uuid"
-)
+// the msgpack counterparts of the messages in pb/inventory.proto. decimals
+// are the raw inventory.Decimal Data, as in protobuf.
 
 type Account struct {
-	UUID             []byte            `msgpack:"uuid,omitempty"`
-	Name             string            `msgpack:"name,omitempty"`
-	ParentUUID       []byte            `msgpack:"parent,omitempty"`
-	TransactionLines []TransactionLine `msgpack:"transaction_lines,omitempty"`
+	UUID             []byte             `msgpack:"uuid,omitempty"`
+	Name             string             `msgpack:"name,omitempty"`
+	ParentUUID       []byte             `msgpack:"parent,omitempty"`
+	TransactionLines []*TransactionLine `msgpack:"transaction_lines,omitempty"`
 }
 
 type Item struct {
-	UUID             []byte            `msgpack:"uuid,omitempty"`
-	Name             string            `msgpack:"name,omitempty"`
-	Description      string            `msgpack:"description,omitempty"`
-	Unit             string            `msgpack:"unit,omitempty"`
-	TransactionLines []TransactionLine `msgpack:"transaction_lines,omitempty"`
+	UUID             []byte             `msgpack:"uuid,omitempty"`
+	Name             string             `msgpack:"name,omitempty"`
+	Description      string             `msgpack:"description,omitempty"`
+	Unit             string             `msgpack:"unit,omitempty"`
+	TransactionLines []*TransactionLine `msgpack:"transaction_lines,omitempty"`
 }
 
 type Transaction struct {
-	UUID             []byte            `msgpack:"uuid,omitempty"`
-	Description      string            `msgpack:"description,omitempty"`
-	DatetimeMs       int64             `msgpack:"date,omitempty"`
-	TransactionLines []TransactionLine `msgpack:"transaction_lines,omitempty"`
+	UUID             []byte             `msgpack:"uuid,omitempty"`
+	Description      string             `msgpack:"description,omitempty"`
+	DatetimeMs       int64              `msgpack:"date,omitempty"`
+	TransactionLines []*TransactionLine `msgpack:"transaction_lines,omitempty"`
+	PartyUUID        []byte             `msgpack:"party_uuid,omitempty"`
 }
 
 type TransactionLine struct {
-	UUID            []byte  `msgpack:"uuid,omitempty"`
-	TransactionUUID []byte  `msgpack:"transaction_uuid,omitempty"`
-	AccountUUID     []byte  `msgpack:"account_uuid,omitempty"`
-	ItemUUID        []byte  `msgpack:"item_uuid,omitempty"`
-	Quantity        float64 `msgpack:"quantity,omitempty"`
-	Unit            string  `msgpack:"unit,omitempty"`
-	Price           float64 `msgpack:"price,omitempty"`
-	Currency        string  `msgpack:"currency,omitempty"`
-	Note            string  `msgpack:"note,omitempty"`
+	UUID            []byte `msgpack:"uuid,omitempty"`
+	TransactionUUID []byte `msgpack:"transaction_uuid,omitempty"`
+	AccountUUID     []byte `msgpack:"account_uuid,omitempty"`
+	ItemUUID        []byte `msgpack:"item_uuid,omitempty"`
+	Quantity        int64  `msgpack:"quantity,omitempty"`
+	Unit            string `msgpack:"unit,omitempty"`
+	Price           int64  `msgpack:"price,omitempty"`
+	Currency        string `msgpack:"currency,omitempty"`
+	Note            string `msgpack:"note,omitempty"`
+	TaxCodeUUID     []byte `msgpack:"tax_code_uuid,omitempty"`
+	PartyUUID       []byte `msgpack:"party_uuid,omitempty"`
 }
 
 type BalanceHistoryReferences struct {
@@ -53,37 +52,160 @@ type BalanceHistory struct {
 	Path             []string                 `msgpack:"path,omitempty"`
 	References       BalanceHistoryReferences `msgpack:"references,omitempty"`
 	Unit             string                   `msgpack:"unit,omitempty"`
-	Quantity         float64                  `msgpack:"quantity,omitempty"`
-	AvgCost          float64                  `msgpack:"avg_cost,omitempty"`
-	Value            float64                  `msgpack:"value,omitempty"`
+	Quantity         int64                    `msgpack:"quantity,omitempty"`
+	AvgCost          int64                    `msgpack:"avg_cost,omitempty"`
+	Value            int64                    `msgpack:"value,omitempty"`
 	DatetimeMs       int64                    `msgpack:"date,omitempty"`
-	TransactionPrice float64                  `msgpack:"transaction_price,omitempty"`
-	MarketPrice      float64                  `msgpack:"market_price,omitempty"`
+	TransactionPrice int64                    `msgpack:"transaction_price,omitempty"`
+	MarketPrice      int64                    `msgpack:"market_price,omitempty"`
 	Currency         string                   `msgpack:"currency,omitempty"`
-	MarketValue      float64                  `msgpack:"market_value,omitempty"`
+	MarketValue      int64                    `msgpack:"market_value,omitempty"`
 	Description      string                   `msgpack:"description,omitempty"`
 }
 
+type BalanceHistoryList struct {
+	BalanceHistories []*BalanceHistory `msgpack:"balance_histories,omitempty"`
+}
+
 type UnitConversions struct {
-	FromUnit   string  `msgpack:"from_unit,omitempty"`
-	ToUnit     string  `msgpack:"to_unit,omitempty"`
-	Factor     float64 `msgpack:"factor,omitempty"`
-	DatetimeMs int64   `msgpack:"date,omitempty"`
+	FromUnit   string `msgpack:"from_unit,omitempty"`
+	ToUnit     string `msgpack:"to_unit,omitempty"`
+	Factor     int64  `msgpack:"factor,omitempty"`
+	DatetimeMs int64  `msgpack:"date,omitempty"`
 }
 
 type CurrencyConversions struct {
-	FromCurrency string  `msgpack:"from_currency,omitempty"`
-	ToCurrency   string  `msgpack:"to_currency,omitempty"`
-	Rate         float64 `msgpack:"rate,omitempty"`
-	DatetimeMs   int64   `msgpack:"date,omitempty"`
+	FromCurrency string `msgpack:"from_currency,omitempty"`
+	ToCurrency   string `msgpack:"to_currency,omitempty"`
+	Rate         int64  `msgpack:"rate,omitempty"`
+	DatetimeMs   int64  `msgpack:"date,omitempty"`
+}
+
+type MarketPrice struct {
+	ItemUUID   []byte `msgpack:"item_uuid,omitempty"`
+	DatetimeMs int64  `msgpack:"date,omitempty"`
+	Price      int64  `msgpack:"price,omitempty"`
+	Unit       string `msgpack:"unit,omitempty"`
+	Currency   string `msgpack:"currency,omitempty"`
+}
+
+type MarketPriceList struct {
+	MarketPrices []*MarketPrice `msgpack:"market_prices,omitempty"`
+}
+
+type MarketPriceImport struct {
+	Format string `msgpack:"format,omitempty"`
+	Data   []byte `msgpack:"data,omitempty"`
+}
+
+type MarketPriceHistoryQuery struct {
+	ItemUUID       []byte `msgpack:"item_uuid,omitempty"`
+	FromDatetimeMs int64  `msgpack:"from_date,omitempty"`
+	ToDatetimeMs   int64  `msgpack:"to_date,omitempty"`
+}
+
+type TaxCode struct {
+	UUID                  []byte `msgpack:"uuid,omitempty"`
+	Code                  string `msgpack:"code,omitempty"`
+	Name                  string `msgpack:"name,omitempty"`
+	Rate                  int64  `msgpack:"rate,omitempty"`
+	Inclusive             bool   `msgpack:"inclusive,omitempty"`
+	PayableAccountUUID    []byte `msgpack:"payable_account_uuid,omitempty"`
+	ReceivableAccountUUID []byte `msgpack:"receivable_account_uuid,omitempty"`
+}
+
+type PeriodQuery struct {
+	FromDatetimeMs int64 `msgpack:"from_date,omitempty"`
+	ToDatetimeMs   int64 `msgpack:"to_date,omitempty"`
+}
+
+type Party struct {
+	UUID []byte `msgpack:"uuid,omitempty"`
+	Name string `msgpack:"name,omitempty"`
+	Kind string `msgpack:"kind,omitempty"`
+}
+
+type PartyBalance struct {
+	PartyUUID []byte `msgpack:"party_uuid,omitempty"`
+	Side      string `msgpack:"side,omitempty"`
+	Currency  string `msgpack:"currency,omitempty"`
+	Balance   int64  `msgpack:"balance,omitempty"`
+}
+
+type PartyBalanceList struct {
+	PartyBalances []*PartyBalance `msgpack:"party_balances,omitempty"`
 }
 
-type MarketPrices struct {
-	ItemUUID   []byte  `msgpack:"item_uuid,omitempty"`
-	DatetimeMs int64   `msgpack:"date,omitempty"`
-	Price      float64 `msgpack:"price,omitempty"`
-	Unit       string  `msgpack:"unit,omitempty"`
-	Currency   string  `msgpack:"currency,omitempty"`
+// empty PartyUUID means every party, balances and aging are as of ToDatetimeMs
+type PartyQuery struct {
+	PartyUUID      []byte `msgpack:"party_uuid,omitempty"`
+	FromDatetimeMs int64  `msgpack:"from_date,omitempty"`
+	ToDatetimeMs   int64  `msgpack:"to_date,omitempty"`
+}
+
+// Template holds the description, party and lines posted on each occurrence
+type RecurringTransaction struct {
+	UUID            []byte       `msgpack:"uuid,omitempty"`
+	Name            string       `msgpack:"name,omitempty"`
+	Schedule        string       `msgpack:"schedule,omitempty"`
+	DayOfMonth      int32        `msgpack:"day_of_month,omitempty"`
+	Weekday         int32        `msgpack:"weekday,omitempty"`
+	Cron            string       `msgpack:"cron,omitempty"`
+	StartDatetimeMs int64        `msgpack:"start_date,omitempty"`
+	EndDatetimeMs   int64        `msgpack:"end_date,omitempty"`
+	Active          bool         `msgpack:"active,omitempty"`
+	Template        *Transaction `msgpack:"template,omitempty"`
+}
+
+type RecurringTransactionList struct {
+	RecurringTransactions []*RecurringTransaction `msgpack:"recurring_transactions,omitempty"`
+}
+
+// empty TransactionUUID means not posted yet
+type RecurringOccurrence struct {
+	RecurringUUID   []byte `msgpack:"recurring_uuid,omitempty"`
+	DatetimeMs      int64  `msgpack:"date,omitempty"`
+	TransactionUUID []byte `msgpack:"transaction_uuid,omitempty"`
+}
+
+type RecurringOccurrenceList struct {
+	Occurrences []*RecurringOccurrence `msgpack:"occurrences,omitempty"`
+}
+
+type TemplateParam struct {
+	Name    string `msgpack:"name,omitempty"`
+	Type    string `msgpack:"type,omitempty"`
+	Default string `msgpack:"default,omitempty"`
+}
+
+type TemplateLine struct {
+	Account  string `msgpack:"account,omitempty"`
+	Item     string `msgpack:"item,omitempty"`
+	Quantity string `msgpack:"quantity,omitempty"`
+	Unit     string `msgpack:"unit,omitempty"`
+	Price    string `msgpack:"price,omitempty"`
+	Currency string `msgpack:"currency,omitempty"`
+	Note     string `msgpack:"note,omitempty"`
+}
+
+type TransactionTemplate struct {
+	UUID        []byte           `msgpack:"uuid,omitempty"`
+	Name        string           `msgpack:"name,omitempty"`
+	Description string           `msgpack:"description,omitempty"`
+	Datetime    string           `msgpack:"datetime,omitempty"`
+	Params      []*TemplateParam `msgpack:"params,omitempty"`
+	Lines       []*TemplateLine  `msgpack:"lines,omitempty"`
+}
+
+type TransactionTemplateList struct {
+	TransactionTemplates []*TransactionTemplate `msgpack:"transaction_templates,omitempty"`
+}
+
+// the template is looked up by TemplateUUID, or by TemplateName when empty
+type TemplateInstantiation struct {
+	TemplateUUID []byte            `msgpack:"template_uuid,omitempty"`
+	TemplateName string            `msgpack:"template_name,omitempty"`
+	Args         map[string]string `msgpack:"args,omitempty"`
 }
 
 type Packet struct {
@@ -92,67 +214,3 @@ type Packet struct {
 	Meta map[string][]byte `msgpack:"meta,omitempty"`
 	Body map[string][]byte `msgpack:"body,omitempty"`
 }
-
-func NewPacket(pkt *inventoryrpc.Packet) *Packet {
-	return &Packet{
-		UUID: pkt.UUID[:],
-		Type: pkt.Type,
-		Meta: pkt.Meta,
-		Body: pkt.Body,
-	}
-}
-
-func ToInvPacket(pkt *Packet) *inventoryrpc.Packet {
-	pktUUID, _ := uuid.FromBytes(pkt.UUID)
-	return &inventoryrpc.Packet{
-		UUID: pktUUID,
-		Type: pkt.Type,
-		Meta: pkt.Meta,
-		Body: pkt.Body,
-	}
-}
-
-func NewTransactionLine(transactionLine inventory.TransactionLine) *TransactionLine {
-	trLine := &TransactionLine{
-		UUID:     transactionLine.UUID[:],
-		Quantity: transactionLine.Quantity,
-		Unit:     transactionLine.Unit,
-		Price:    transactionLine.Price,
-		Currency: transactionLine.Currency,
-		Note:     transactionLine.Note,
-	}
-	if transactionLine.Transaction != nil {
-		trLine.TransactionUUID = transactionLine.Transaction.UUID[:]
-	}
-	if transactionLine.Account != nil {
-		trLine.AccountUUID = transactionLine.Account.UUID[:]
-	}
-	if transactionLine.Item != nil {
-		trLine.ItemUUID = transactionLine.Item.UUID[:]
-	}
-	return trLine
-}
-
-func NewItem(item *inventory.Item, transactionLines []inventory.TransactionLine) *Item {
-	var lines []TransactionLine
-	for i := range transactionLines {
-		lines = append(lines, *NewTransactionLine(transactionLines[i]))
-	}
-	return &Item{
-		UUID:             item.UUID[:],
-		Name:             item.Name,
-		Description:      item.Description,
-		Unit:             item.Unit,
-		TransactionLines: lines,
-	}
-}
-
-func ToInvItem(item *Item) *inventory.Item {
-	itemUUID, _ := uuid.FromBytes(item.UUID)
-	return &inventory.Item{
-		UUID:        itemUUID,
-		Name:        item.Name,
-		Description: item.Description,
-		Unit:        item.Unit,
-	}
-}
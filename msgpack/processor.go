@@ -0,0 +1,32 @@
+package inventorymsgpack
+
+import (
+	"inventoryrpc"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func UnmarshalPkt(receivedPktBin []byte) (*inventoryrpc.Packet, error) {
+	var receivedPkt Packet
+	err := msgpack.Unmarshal(receivedPktBin, &receivedPkt)
+
+	return ToInvPacket(&receivedPkt), err
+}
+
+type ConsumeProcessingResponseFunc func(responsePktByte []byte)
+
+// inventoryrpc.Codec for the msgpack Packet, for inventoryrpc.Server and
+// inventoryrpc.Conn, and the inventoryrpc.ArgCodec of NewRegistry
+type Codec struct{}
+
+func (Codec) Name() string {
+	return "msgpack"
+}
+
+func (Codec) Marshal(pkt *inventoryrpc.Packet) ([]byte, error) {
+	return msgpack.Marshal(NewPacket(pkt))
+}
+
+func (Codec) Unmarshal(data []byte) (*inventoryrpc.Packet, error) {
+	return UnmarshalPkt(data)
+}
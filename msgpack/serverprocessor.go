@@ -0,0 +1,39 @@
+package inventorymsgpack
+
+import (
+	"inventoryrpc"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type ServerProcessor struct {
+	ProcessingChan                 chan *inventoryrpc.Packet
+	ConsumeProcessingResponseFuncs []ConsumeProcessingResponseFunc
+	// nil uses DefaultRegistry
+	Registry *Registry
+}
+
+func NewServerProcessor() *ServerProcessor {
+	return &ServerProcessor{
+		ProcessingChan: make(chan *inventoryrpc.Packet),
+	}
+}
+
+func (p *ServerProcessor) ProcessPkt(pkt *inventoryrpc.Packet) (*inventoryrpc.Packet, string, int32, error) {
+	if p.Registry != nil {
+		return p.Registry.Process(pkt)
+	}
+	return DefaultRegistry.Process(pkt)
+}
+
+func (p *ServerProcessor) PostProcessPkt(responsePkt *inventoryrpc.Packet) error {
+	responsePktMsgpack := NewPacket(responsePkt)
+	responsePktByte, err := msgpack.Marshal(responsePktMsgpack)
+	if err != nil {
+		return err
+	}
+	for _, consumeFunc := range p.ConsumeProcessingResponseFuncs {
+		consumeFunc(responsePktByte)
+	}
+	return nil
+}
@@ -0,0 +1,641 @@
+package inventorymsgpack
+
+import (
+	"inventory"
+	"inventoryrpc"
+
+	"github.com/google/uuid"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func NewPacket(pkt *inventoryrpc.Packet) *Packet {
+	return &Packet{
+		UUID: pkt.UUID[:],
+		Type: pkt.Type,
+		Meta: pkt.Meta,
+		Body: pkt.Body,
+	}
+}
+
+func ToInvPacket(pkt *Packet) *inventoryrpc.Packet {
+	pktUUID, _ := uuid.FromBytes(pkt.UUID)
+	return &inventoryrpc.Packet{
+		UUID: pktUUID,
+		Type: pkt.Type,
+		Meta: pkt.Meta,
+		Body: pkt.Body,
+	}
+}
+
+func NewTransactionLine(transactionLine *inventory.TransactionLine) *TransactionLine {
+	trLine := &TransactionLine{
+		UUID:     transactionLine.UUID[:],
+		Quantity: transactionLine.Quantity.Data,
+		Unit:     transactionLine.Unit,
+		Price:    transactionLine.Price.Data,
+		Currency: transactionLine.Currency,
+		Note:     transactionLine.Note,
+	}
+	if transactionLine.Transaction != nil {
+		trLine.TransactionUUID = transactionLine.Transaction.UUID[:]
+	}
+	if transactionLine.Account != nil {
+		trLine.AccountUUID = transactionLine.Account.UUID[:]
+	}
+	if transactionLine.Item != nil {
+		trLine.ItemUUID = transactionLine.Item.UUID[:]
+	}
+	if transactionLine.TaxCode != nil {
+		trLine.TaxCodeUUID = transactionLine.TaxCode.UUID[:]
+	}
+	if transactionLine.Party != nil {
+		trLine.PartyUUID = transactionLine.Party.UUID[:]
+	}
+	return trLine
+}
+
+func NewTransaction(transaction *inventory.Transaction) *Transaction {
+	var lines []*TransactionLine
+	for i := range transaction.TransactionLines {
+		lines = append(lines, NewTransactionLine(transaction.TransactionLines[i]))
+	}
+	tr := &Transaction{
+		UUID:             transaction.UUID[:],
+		Description:      transaction.Description,
+		DatetimeMs:       transaction.DatetimeMs,
+		TransactionLines: lines,
+	}
+	if transaction.Party != nil {
+		tr.PartyUUID = transaction.Party.UUID[:]
+	}
+	return tr
+}
+
+func NewItem(item *inventory.Item, transactionLines []*inventory.TransactionLine) *Item {
+	var lines []*TransactionLine
+	for i := range transactionLines {
+		lines = append(lines, NewTransactionLine(transactionLines[i]))
+	}
+	return &Item{
+		UUID:             item.UUID[:],
+		Name:             item.Name,
+		Description:      item.Description,
+		Unit:             item.Unit,
+		TransactionLines: lines,
+	}
+}
+
+func ToInvItem(item *Item) *inventory.Item {
+	itemUUID, _ := uuid.FromBytes(item.UUID)
+	return &inventory.Item{
+		UUID:        itemUUID,
+		Name:        item.Name,
+		Description: item.Description,
+		Unit:        item.Unit,
+	}
+}
+
+func NewAccount(account *inventory.Account, transactionLines []*inventory.TransactionLine) *Account {
+	var lines []*TransactionLine
+	for i := range transactionLines {
+		lines = append(lines, NewTransactionLine(transactionLines[i]))
+	}
+	var parentUUID []byte
+	if account.Parent != nil {
+		parentUUID = account.Parent.UUID[:]
+	} else {
+		parentUUID = nil
+	}
+	return &Account{
+		UUID:             account.UUID[:],
+		Name:             account.Name,
+		ParentUUID:       parentUUID,
+		TransactionLines: lines,
+	}
+}
+
+func ToInvAccount(acc *Account) *inventory.Account {
+	accUUID, _ := uuid.FromBytes(acc.UUID)
+	parentUUID, _ := uuid.FromBytes(acc.ParentUUID)
+	return &inventory.Account{
+		UUID: accUUID,
+		Name: acc.Name,
+		Parent: &inventory.Account{
+			UUID: parentUUID,
+		},
+	}
+}
+
+func ToInvTransaction(tr *Transaction) *inventory.Transaction {
+	trUUID, _ := uuid.FromBytes(tr.UUID)
+	var trLines []*inventory.TransactionLine
+	for i := range tr.TransactionLines {
+		trl := tr.TransactionLines[i]
+		trLineUUID, _ := uuid.FromBytes(trl.UUID)
+		accUUID, _ := uuid.FromBytes(trl.AccountUUID)
+		itUUID, _ := uuid.FromBytes(trl.ItemUUID)
+		var acc *inventory.Account
+		if accUUID != uuid.Nil {
+			acc = &inventory.Account{
+				UUID: accUUID,
+			}
+		} else {
+			acc = nil
+		}
+		var item *inventory.Item
+		if itUUID != uuid.Nil {
+			item = &inventory.Item{
+				UUID: itUUID,
+			}
+		} else {
+			item = nil
+		}
+		var taxCode *inventory.TaxCode
+		if taxCodeUUID, _ := uuid.FromBytes(trl.TaxCodeUUID); taxCodeUUID != uuid.Nil {
+			taxCode = &inventory.TaxCode{
+				UUID: taxCodeUUID,
+			}
+		}
+		trLines = append(trLines, &inventory.TransactionLine{
+			UUID: trLineUUID,
+			Transaction: &inventory.Transaction{
+				UUID: trUUID,
+			},
+			Account:  acc,
+			Item:     item,
+			Quantity: inventory.NewDecimal(trl.Quantity),
+			Unit:     trl.Unit,
+			Price:    inventory.NewDecimal(trl.Price),
+			Currency: trl.Currency,
+			Note:     trl.Note,
+			TaxCode:  taxCode,
+			Party:    ToInvPartyRef(trl.PartyUUID),
+		})
+	}
+	return &inventory.Transaction{
+		UUID:             trUUID,
+		Description:      tr.Description,
+		DatetimeMs:       tr.DatetimeMs,
+		Party:            ToInvPartyRef(tr.PartyUUID),
+		TransactionLines: trLines,
+	}
+}
+
+func NewMarketPrice(p *inventory.MarketPrice) *MarketPrice {
+	var itemUUID []byte
+	if p.Item != nil {
+		itemUUID = p.Item.UUID[:]
+	} else {
+		itemUUID = nil
+	}
+	return &MarketPrice{
+		ItemUUID:   itemUUID,
+		DatetimeMs: p.DatetimeMs,
+		Price:      p.Price.Data,
+		Unit:       p.Unit,
+		Currency:   p.Currency,
+	}
+}
+
+func ToInvMarketPrice(p *MarketPrice) *inventory.MarketPrice {
+	itemUUID, _ := uuid.FromBytes(p.ItemUUID)
+	return &inventory.MarketPrice{
+		Item: &inventory.Item{
+			UUID: itemUUID,
+		},
+		DatetimeMs: p.DatetimeMs,
+		Price:      inventory.NewDecimal(p.Price),
+		Unit:       p.Unit,
+		Currency:   p.Currency,
+	}
+}
+
+func NewMarketPriceList(prices []inventory.MarketPrice) *MarketPriceList {
+	var list []*MarketPrice
+	for i := range prices {
+		list = append(list, NewMarketPrice(&prices[i]))
+	}
+	return &MarketPriceList{
+		MarketPrices: list,
+	}
+}
+
+func ToInvMarketPrices(list *MarketPriceList) []*inventory.MarketPrice {
+	var prices []*inventory.MarketPrice
+	for i := range list.MarketPrices {
+		prices = append(prices, ToInvMarketPrice(list.MarketPrices[i]))
+	}
+	return prices
+}
+
+func NewBalanceHistory(b *inventory.BalanceHistory) *BalanceHistory {
+	balance := &BalanceHistory{
+		UUID:             b.UUID[:],
+		Path:             b.Path,
+		Unit:             b.Unit,
+		Quantity:         b.Quantity.Data,
+		AvgCost:          b.AvgCost.Data,
+		Value:            b.Value.Data,
+		DatetimeMs:       b.DatetimeMs,
+		TransactionPrice: b.TransactionPrice.Data,
+		MarketPrice:      b.MarketPrice.Data,
+		Currency:         b.Currency,
+		MarketValue:      b.MarketValue.Data,
+		Description:      b.Description,
+	}
+	if trLine := b.TransactionLine; trLine != nil {
+		balance.References.TransactionLineUUID = trLine.UUID[:]
+		if trLine.Transaction != nil {
+			balance.References.TransactionUUID = trLine.Transaction.UUID[:]
+		}
+		if trLine.Account != nil {
+			balance.References.AccountUUID = trLine.Account.UUID[:]
+		}
+		if trLine.Item != nil {
+			balance.References.ItemUUID = trLine.Item.UUID[:]
+		}
+	}
+	return balance
+}
+
+// the transaction line only holds the referenced uuids
+func ToInvBalanceHistory(b *BalanceHistory) *inventory.BalanceHistory {
+	balanceUUID, _ := uuid.FromBytes(b.UUID)
+	trLineUUID, _ := uuid.FromBytes(b.References.TransactionLineUUID)
+	trLine := &inventory.TransactionLine{
+		UUID: trLineUUID,
+	}
+	if trUUID, _ := uuid.FromBytes(b.References.TransactionUUID); trUUID != uuid.Nil {
+		trLine.Transaction = &inventory.Transaction{UUID: trUUID}
+	}
+	if accUUID, _ := uuid.FromBytes(b.References.AccountUUID); accUUID != uuid.Nil {
+		trLine.Account = &inventory.Account{UUID: accUUID}
+	}
+	if itemUUID, _ := uuid.FromBytes(b.References.ItemUUID); itemUUID != uuid.Nil {
+		trLine.Item = &inventory.Item{UUID: itemUUID}
+	}
+	return &inventory.BalanceHistory{
+		UUID:             balanceUUID,
+		Path:             b.Path,
+		TransactionLine:  trLine,
+		Unit:             b.Unit,
+		Quantity:         inventory.NewDecimal(b.Quantity),
+		AvgCost:          inventory.NewDecimal(b.AvgCost),
+		Value:            inventory.NewDecimal(b.Value),
+		DatetimeMs:       b.DatetimeMs,
+		TransactionPrice: inventory.NewDecimal(b.TransactionPrice),
+		MarketPrice:      inventory.NewDecimal(b.MarketPrice),
+		Currency:         b.Currency,
+		MarketValue:      inventory.NewDecimal(b.MarketValue),
+		Description:      b.Description,
+	}
+}
+
+func NewBalanceHistoryList(balances []inventory.BalanceHistory) *BalanceHistoryList {
+	var list []*BalanceHistory
+	for i := range balances {
+		list = append(list, NewBalanceHistory(&balances[i]))
+	}
+	return &BalanceHistoryList{
+		BalanceHistories: list,
+	}
+}
+
+func ToInvBalanceHistories(list *BalanceHistoryList) []inventory.BalanceHistory {
+	var balances []inventory.BalanceHistory
+	for _, b := range list.BalanceHistories {
+		balances = append(balances, *ToInvBalanceHistory(b))
+	}
+	return balances
+}
+
+func NewUnitConversions(c *inventory.UnitConversions) *UnitConversions {
+	return &UnitConversions{
+		FromUnit:   c.FromUnit,
+		ToUnit:     c.ToUnit,
+		Factor:     c.Factor.Data,
+		DatetimeMs: c.DatetimeMs,
+	}
+}
+
+func ToInvUnitConversions(c *UnitConversions) *inventory.UnitConversions {
+	return &inventory.UnitConversions{
+		FromUnit:   c.FromUnit,
+		ToUnit:     c.ToUnit,
+		Factor:     inventory.NewDecimal(c.Factor),
+		DatetimeMs: c.DatetimeMs,
+	}
+}
+
+func NewCurrencyConversions(c *inventory.CurrencyConversion) *CurrencyConversions {
+	return &CurrencyConversions{
+		FromCurrency: c.FromCurrency,
+		ToCurrency:   c.ToCurrency,
+		Rate:         c.Rate.Data,
+		DatetimeMs:   c.DatetimeMs,
+	}
+}
+
+func ToInvCurrencyConversion(c *CurrencyConversions) *inventory.CurrencyConversion {
+	return &inventory.CurrencyConversion{
+		FromCurrency: c.FromCurrency,
+		ToCurrency:   c.ToCurrency,
+		Rate:         inventory.NewDecimal(c.Rate),
+		DatetimeMs:   c.DatetimeMs,
+	}
+}
+
+func NewTaxCode(tc *inventory.TaxCode) *TaxCode {
+	taxCode := &TaxCode{
+		UUID:      tc.UUID[:],
+		Code:      tc.Code,
+		Name:      tc.Name,
+		Rate:      tc.Rate.Data,
+		Inclusive: tc.Inclusive,
+	}
+	if tc.PayableAccount != nil {
+		taxCode.PayableAccountUUID = tc.PayableAccount.UUID[:]
+	}
+	if tc.ReceivableAccount != nil {
+		taxCode.ReceivableAccountUUID = tc.ReceivableAccount.UUID[:]
+	}
+	return taxCode
+}
+
+func ToInvTaxCode(tc *TaxCode) *inventory.TaxCode {
+	tcUUID, _ := uuid.FromBytes(tc.UUID)
+	taxCode := &inventory.TaxCode{
+		UUID:      tcUUID,
+		Code:      tc.Code,
+		Name:      tc.Name,
+		Rate:      inventory.NewDecimal(tc.Rate),
+		Inclusive: tc.Inclusive,
+	}
+	if accUUID, _ := uuid.FromBytes(tc.PayableAccountUUID); accUUID != uuid.Nil {
+		taxCode.PayableAccount = &inventory.Account{UUID: accUUID}
+	}
+	if accUUID, _ := uuid.FromBytes(tc.ReceivableAccountUUID); accUUID != uuid.Nil {
+		taxCode.ReceivableAccount = &inventory.Account{UUID: accUUID}
+	}
+	return taxCode
+}
+
+func NewParty(p *inventory.Party) *Party {
+	return &Party{
+		UUID: p.UUID[:],
+		Name: p.Name,
+		Kind: p.Kind,
+	}
+}
+
+func ToInvParty(p *Party) *inventory.Party {
+	partyUUID, _ := uuid.FromBytes(p.UUID)
+	return &inventory.Party{
+		UUID: partyUUID,
+		Name: p.Name,
+		Kind: p.Kind,
+	}
+}
+
+// nil for an empty or nil uuid
+func ToInvPartyRef(partyUUIDBytes []byte) *inventory.Party {
+	partyUUID, _ := uuid.FromBytes(partyUUIDBytes)
+	if partyUUID == uuid.Nil {
+		return nil
+	}
+	return &inventory.Party{
+		UUID: partyUUID,
+	}
+}
+
+func NewPartyBalanceList(balances []inventory.PartyBalance) *PartyBalanceList {
+	var list []*PartyBalance
+	for _, b := range balances {
+		list = append(list, &PartyBalance{
+			PartyUUID: b.Party.UUID[:],
+			Side:      b.Side,
+			Currency:  b.Currency,
+			Balance:   b.Balance.Data,
+		})
+	}
+	return &PartyBalanceList{
+		PartyBalances: list,
+	}
+}
+
+func NewRecurringTransaction(r *inventory.RecurringTransaction) *RecurringTransaction {
+	rec := &RecurringTransaction{
+		UUID:            r.UUID[:],
+		Name:            r.Name,
+		Schedule:        r.Schedule,
+		DayOfMonth:      int32(r.DayOfMonth),
+		Weekday:         int32(r.Weekday),
+		Cron:            r.Cron,
+		StartDatetimeMs: r.StartDatetimeMs,
+		EndDatetimeMs:   r.EndDatetimeMs,
+		Active:          r.Active,
+	}
+	if r.Template != nil {
+		rec.Template = NewTransaction(r.Template)
+	}
+	return rec
+}
+
+func ToInvRecurringTransaction(r *RecurringTransaction) *inventory.RecurringTransaction {
+	recUUID, _ := uuid.FromBytes(r.UUID)
+	rec := &inventory.RecurringTransaction{
+		UUID:            recUUID,
+		Name:            r.Name,
+		Schedule:        r.Schedule,
+		DayOfMonth:      int(r.DayOfMonth),
+		Weekday:         int(r.Weekday),
+		Cron:            r.Cron,
+		StartDatetimeMs: r.StartDatetimeMs,
+		EndDatetimeMs:   r.EndDatetimeMs,
+		Active:          r.Active,
+	}
+	if r.Template != nil {
+		rec.Template = ToInvTransaction(r.Template)
+	}
+	return rec
+}
+
+func ToInvPartyBalances(list *PartyBalanceList) []inventory.PartyBalance {
+	var balances []inventory.PartyBalance
+	for _, b := range list.PartyBalances {
+		balances = append(balances, inventory.PartyBalance{
+			Party:    ToInvPartyRef(b.PartyUUID),
+			Side:     b.Side,
+			Currency: b.Currency,
+			Balance:  inventory.NewDecimal(b.Balance),
+		})
+	}
+	return balances
+}
+
+func NewRecurringTransactionList(recs []*inventory.RecurringTransaction) *RecurringTransactionList {
+	var list []*RecurringTransaction
+	for _, r := range recs {
+		list = append(list, NewRecurringTransaction(r))
+	}
+	return &RecurringTransactionList{
+		RecurringTransactions: list,
+	}
+}
+
+func NewRecurringOccurrenceList(occurrences []inventory.RecurringOccurrence) *RecurringOccurrenceList {
+	var list []*RecurringOccurrence
+	for _, o := range occurrences {
+		occ := &RecurringOccurrence{
+			RecurringUUID: o.Recurring.UUID[:],
+			DatetimeMs:    o.DatetimeMs,
+		}
+		if o.Transaction != nil {
+			occ.TransactionUUID = o.Transaction.UUID[:]
+		}
+		list = append(list, occ)
+	}
+	return &RecurringOccurrenceList{
+		Occurrences: list,
+	}
+}
+
+func ToInvRecurringTransactions(list *RecurringTransactionList) []*inventory.RecurringTransaction {
+	var recs []*inventory.RecurringTransaction
+	for _, r := range list.RecurringTransactions {
+		recs = append(recs, ToInvRecurringTransaction(r))
+	}
+	return recs
+}
+
+func ToInvRecurringOccurrences(list *RecurringOccurrenceList) []inventory.RecurringOccurrence {
+	var occurrences []inventory.RecurringOccurrence
+	for _, o := range list.Occurrences {
+		recUUID, _ := uuid.FromBytes(o.RecurringUUID)
+		occ := inventory.RecurringOccurrence{
+			Recurring:  &inventory.RecurringTransaction{UUID: recUUID},
+			DatetimeMs: o.DatetimeMs,
+		}
+		if trUUID, _ := uuid.FromBytes(o.TransactionUUID); trUUID != uuid.Nil {
+			occ.Transaction = &inventory.Transaction{UUID: trUUID}
+		}
+		occurrences = append(occurrences, occ)
+	}
+	return occurrences
+}
+
+func NewTransactionTemplate(t *inventory.TransactionTemplate) *TransactionTemplate {
+	tmpl := &TransactionTemplate{
+		UUID:        t.UUID[:],
+		Name:        t.Name,
+		Description: t.Description,
+		Datetime:    t.Datetime,
+	}
+	for _, p := range t.Params {
+		tmpl.Params = append(tmpl.Params, &TemplateParam{
+			Name:    p.Name,
+			Type:    p.Type,
+			Default: p.Default,
+		})
+	}
+	for _, l := range t.Lines {
+		tmpl.Lines = append(tmpl.Lines, &TemplateLine{
+			Account:  l.Account,
+			Item:     l.Item,
+			Quantity: l.Quantity,
+			Unit:     l.Unit,
+			Price:    l.Price,
+			Currency: l.Currency,
+			Note:     l.Note,
+		})
+	}
+	return tmpl
+}
+
+func ToInvTransactionTemplate(t *TransactionTemplate) *inventory.TransactionTemplate {
+	tmplUUID, _ := uuid.FromBytes(t.UUID)
+	tmpl := &inventory.TransactionTemplate{
+		UUID:        tmplUUID,
+		Name:        t.Name,
+		Description: t.Description,
+		Datetime:    t.Datetime,
+	}
+	for _, p := range t.Params {
+		tmpl.Params = append(tmpl.Params, inventory.TemplateParam{
+			Name:    p.Name,
+			Type:    p.Type,
+			Default: p.Default,
+		})
+	}
+	for _, l := range t.Lines {
+		tmpl.Lines = append(tmpl.Lines, &inventory.TemplateLine{
+			Account:  l.Account,
+			Item:     l.Item,
+			Quantity: l.Quantity,
+			Unit:     l.Unit,
+			Price:    l.Price,
+			Currency: l.Currency,
+			Note:     l.Note,
+		})
+	}
+	return tmpl
+}
+
+func NewTransactionTemplateList(templates []*inventory.TransactionTemplate) *TransactionTemplateList {
+	var list []*TransactionTemplate
+	for _, t := range templates {
+		list = append(list, NewTransactionTemplate(t))
+	}
+	return &TransactionTemplateList{
+		TransactionTemplates: list,
+	}
+}
+
+func ToInvTransactionTemplates(list *TransactionTemplateList) []*inventory.TransactionTemplate {
+	var templates []*inventory.TransactionTemplate
+	for _, t := range list.TransactionTemplates {
+		templates = append(templates, ToInvTransactionTemplate(t))
+	}
+	return templates
+}
+
+// request packet calling reqFunc with params, nil params sends no arg
+func NewRequestPkt(reqFunc string, params any) (*inventoryrpc.Packet, error) {
+	if params == nil {
+		return NewRawRequestPkt(reqFunc, nil)
+	}
+	paramBin, err := msgpack.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	return NewRawRequestPkt(reqFunc, paramBin)
+}
+
+// for functions taking a non message arg, OpenOrCreateDB takes a bare uuid
+func NewRawRequestPkt(reqFunc string, paramBin []byte) (*inventoryrpc.Packet, error) {
+	pktUUID, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+
+	return &inventoryrpc.Packet{
+		UUID: pktUUID,
+		Type: inventoryrpc.TypeReq,
+		Meta: nil,
+		Body: map[string][]byte{
+			"function": []byte(reqFunc),
+			"arg":      paramBin,
+		},
+	}, nil
+}
+
+func CreateRequest(reqFunc string, params any) (uuid.UUID, []byte, error) {
+	pkt, err := NewRequestPkt(reqFunc, params)
+	if err != nil {
+		return uuid.UUID{}, nil, err
+	}
+
+	pktBin, err := msgpack.Marshal(NewPacket(pkt))
+
+	return pkt.UUID, pktBin, err
+}
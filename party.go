@@ -0,0 +1,494 @@
+package inventory
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	PartyCustomer = "customer"
+	PartyVendor   = "vendor"
+	PartyBoth     = "both"
+)
+
+// receivables are debit balances owed to us, payables credit balances we owe
+const (
+	PartySideReceivable = "receivable"
+	PartySidePayable    = "payable"
+)
+
+const (
+	SettingReceivableAccount = "receivable_account"
+	SettingPayableAccount    = "payable_account"
+)
+
+var ErrUnknownPartyKind = errors.New("party kind must be customer, vendor or both")
+var ErrNoPartyControlAccount = errors.New("neither receivable nor payable account is set")
+
+type Party struct {
+	ID   int
+	UUID uuid.UUID
+	Name string
+	Kind string
+}
+
+// a line posted to the receivable or payable account (or below it) for a
+// party. Amount is positive when it increases what is owed.
+type PartyLine struct {
+	Party       *Party
+	Side        string
+	Account     *Account
+	Transaction *Transaction
+	LineID      int
+	Note        string
+	Currency    string
+	Amount      Decimal
+	Balance     Decimal
+}
+
+type PartyBalance struct {
+	Party    *Party
+	Side     string
+	Currency string
+	Balance  Decimal
+}
+
+// open amounts by age in days, current being up to 30
+type AgingRow struct {
+	Party    *Party
+	Side     string
+	Currency string
+	Current  Decimal
+	Over30   Decimal
+	Over60   Decimal
+	Over90   Decimal
+	Total    Decimal
+}
+
+type PartyStatement struct {
+	Party          *Party
+	FromDatetimeMs int64
+	ToDatetimeMs   int64
+	Opening        []PartyBalance
+	Lines          []PartyLine
+	Closing        []PartyBalance
+}
+
+func AddParty(db *sql.DB, p *Party) ([]byte, error) {
+	switch p.Kind {
+	case PartyCustomer, PartyVendor, PartyBoth:
+	default:
+		return nil, ErrUnknownPartyKind
+	}
+	partyUUID, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	res, err := db.Exec(`INSERT INTO parties (uuid, name, kind) VALUES (?, ?, ?)`, partyUUID[:], p.Name, p.Kind)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	p.ID = int(id)
+	p.UUID = partyUUID
+	return partyUUID[:], nil
+}
+
+func scanParties(db *sql.DB, where string, args ...any) ([]*Party, error) {
+	rows, err := db.Query(`SELECT id, uuid, name, kind FROM parties `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parties []*Party
+	for rows.Next() {
+		var p Party
+		var partyUUID []byte
+		err = rows.Scan(&p.ID, &partyUUID, &p.Name, &p.Kind)
+		if err != nil {
+			return nil, err
+		}
+		p.UUID, err = uuid.FromBytes(partyUUID)
+		if err != nil {
+			return nil, err
+		}
+		parties = append(parties, &p)
+	}
+	return parties, rows.Err()
+}
+
+func GetParties(db *sql.DB) ([]*Party, error) {
+	return scanParties(db, "")
+}
+
+func GetPartyByID(db *sql.DB, id int) (*Party, error) {
+	parties, err := scanParties(db, "WHERE id=?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(parties) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return parties[0], nil
+}
+
+func GetPartyByUUID(db *sql.DB, partyUUID []byte) (*Party, error) {
+	parties, err := scanParties(db, "WHERE uuid=?", partyUUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(parties) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return parties[0], nil
+}
+
+// fills in p from its uuid when the id isn't known yet
+func resolvePartyID(db *sql.DB, p *Party) (sql.NullInt64, error) {
+	if p == nil {
+		return sql.NullInt64{}, nil
+	}
+	if p.ID <= 0 {
+		tmp, err := GetPartyByUUID(db, p.UUID[:])
+		if err != nil {
+			return sql.NullInt64{}, fmt.Errorf("party %s: %w", p.UUID, err)
+		}
+		*p = *tmp
+	}
+	return sql.NullInt64{Int64: int64(p.ID), Valid: true}, nil
+}
+
+func SetReceivableAccount(db *sql.DB, acc *Account) error {
+	return SetAccountSetting(db, SettingReceivableAccount, acc)
+}
+
+func SetPayableAccount(db *sql.DB, acc *Account) error {
+	return SetAccountSetting(db, SettingPayableAccount, acc)
+}
+
+// nil when not set
+func getOptionalAccountSetting(db *sql.DB, key string) (*Account, error) {
+	acc, err := GetAccountSetting(db, key)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return acc, err
+}
+
+// party lines up to toMs ordered by date. party nil means every party.
+func getPartyLines(db *sql.DB, party *Party, toMs int64) ([]PartyLine, error) {
+	receivable, err := getOptionalAccountSetting(db, SettingReceivableAccount)
+	if err != nil {
+		return nil, err
+	}
+	payable, err := getOptionalAccountSetting(db, SettingPayableAccount)
+	if err != nil {
+		return nil, err
+	}
+	if receivable == nil && payable == nil {
+		return nil, ErrNoPartyControlAccount
+	}
+
+	_, accMap, err := BuildAccountTree(db)
+	if err != nil {
+		return nil, err
+	}
+	var receivableAcc, payableAcc *Account
+	if receivable != nil {
+		receivableAcc = accMap[receivable.ID]
+	}
+	if payable != nil {
+		payableAcc = accMap[payable.ID]
+	}
+
+	parties, err := GetParties(db)
+	if err != nil {
+		return nil, err
+	}
+	partyMap := map[int]*Party{}
+	for _, p := range parties {
+		partyMap[p.ID] = p
+	}
+
+	query := `
+		SELECT COALESCE(l.party_id, t.party_id), l.id, l.account_id, l.currency, l.quantity, l.note,
+			t.id, t.uuid, t.description, t.datetime_ms
+		FROM transaction_lines l
+		JOIN transactions t ON l.transaction_id = t.id
+		WHERE l.item_id IS NULL AND COALESCE(l.party_id, t.party_id) IS NOT NULL AND t.datetime_ms <= ?`
+	args := []any{toMs}
+	if party != nil {
+		_, err = resolvePartyID(db, party)
+		if err != nil {
+			return nil, err
+		}
+		query += ` AND COALESCE(l.party_id, t.party_id) = ?`
+		args = append(args, party.ID)
+	}
+	query += ` ORDER BY t.datetime_ms, l.id`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []PartyLine
+	for rows.Next() {
+		var partyID, accID int
+		var currency, note, desc sql.NullString
+		var trUUID []byte
+		l := PartyLine{Transaction: &Transaction{}, Amount: NewDecimal(0)}
+		err = rows.Scan(&partyID, &l.LineID, &accID, &currency, &l.Amount, &note,
+			&l.Transaction.ID, &trUUID, &desc, &l.Transaction.DatetimeMs)
+		if err != nil {
+			return nil, err
+		}
+		l.Account = accMap[accID]
+		switch {
+		case l.Account == nil:
+			continue
+		case l.Account.IsChildOfOrItself(receivableAcc):
+			l.Side = PartySideReceivable
+		case l.Account.IsChildOfOrItself(payableAcc):
+			l.Side = PartySidePayable
+			l.Amount = NewDecimal(-l.Amount.Data)
+		default:
+			continue
+		}
+		l.Transaction.UUID, err = uuid.FromBytes(trUUID)
+		if err != nil {
+			return nil, err
+		}
+		l.Transaction.Description = desc.String
+		l.Party = partyMap[partyID]
+		l.Currency = currency.String
+		l.Note = note.String
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+type partyBalanceKey struct {
+	partyID  int
+	side     string
+	currency string
+}
+
+func sumPartyBalances(lines []PartyLine) []PartyBalance {
+	var keys []partyBalanceKey
+	balances := map[partyBalanceKey]*PartyBalance{}
+	for _, l := range lines {
+		key := partyBalanceKey{l.Party.ID, l.Side, l.Currency}
+		b, ok := balances[key]
+		if !ok {
+			b = &PartyBalance{Party: l.Party, Side: l.Side, Currency: l.Currency, Balance: NewDecimal(0)}
+			balances[key] = b
+			keys = append(keys, key)
+		}
+		b.Balance.Data += l.Amount.Data
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].partyID != keys[j].partyID {
+			return keys[i].partyID < keys[j].partyID
+		}
+		if keys[i].side != keys[j].side {
+			return keys[i].side < keys[j].side
+		}
+		return keys[i].currency < keys[j].currency
+	})
+	var result []PartyBalance
+	for _, key := range keys {
+		result = append(result, *balances[key])
+	}
+	return result
+}
+
+// subledger balances as of datetimeMs. party nil means every party.
+func GetPartyBalances(db *sql.DB, party *Party, datetimeMs int64) ([]PartyBalance, error) {
+	lines, err := getPartyLines(db, party, datetimeMs)
+	if err != nil {
+		return nil, err
+	}
+	return sumPartyBalances(lines), nil
+}
+
+// settlements are applied to the oldest open amounts first, unapplied
+// credit shows as a negative current amount
+func GetAgingReport(db *sql.DB, party *Party, datetimeMs int64) ([]AgingRow, error) {
+	lines, err := getPartyLines(db, party, datetimeMs)
+	if err != nil {
+		return nil, err
+	}
+
+	type openItem struct {
+		datetimeMs int64
+		amount     int64
+	}
+	type aging struct {
+		row    *AgingRow
+		open   []openItem
+		credit int64
+	}
+	var keys []partyBalanceKey
+	agings := map[partyBalanceKey]*aging{}
+	for _, l := range lines {
+		key := partyBalanceKey{l.Party.ID, l.Side, l.Currency}
+		a, ok := agings[key]
+		if !ok {
+			a = &aging{row: &AgingRow{Party: l.Party, Side: l.Side, Currency: l.Currency,
+				Current: NewDecimal(0), Over30: NewDecimal(0), Over60: NewDecimal(0), Over90: NewDecimal(0), Total: NewDecimal(0)}}
+			agings[key] = a
+			keys = append(keys, key)
+		}
+		amount := l.Amount.Data
+		if amount > 0 {
+			applied := min(amount, a.credit)
+			a.credit -= applied
+			amount -= applied
+			if amount > 0 {
+				a.open = append(a.open, openItem{l.Transaction.DatetimeMs, amount})
+			}
+			continue
+		}
+		settle := -amount
+		for settle > 0 && len(a.open) > 0 {
+			applied := min(settle, a.open[0].amount)
+			a.open[0].amount -= applied
+			settle -= applied
+			if a.open[0].amount == 0 {
+				a.open = a.open[1:]
+			}
+		}
+		a.credit += settle
+	}
+
+	var rows []AgingRow
+	for _, key := range keys {
+		a := agings[key]
+		for _, item := range a.open {
+			days := (datetimeMs - item.datetimeMs) / (24 * time.Hour).Milliseconds()
+			switch {
+			case days > 90:
+				a.row.Over90.Data += item.amount
+			case days > 60:
+				a.row.Over60.Data += item.amount
+			case days > 30:
+				a.row.Over30.Data += item.amount
+			default:
+				a.row.Current.Data += item.amount
+			}
+			a.row.Total.Data += item.amount
+		}
+		a.row.Current.Data -= a.credit
+		a.row.Total.Data -= a.credit
+		rows = append(rows, *a.row)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Side != rows[j].Side {
+			return rows[i].Side > rows[j].Side
+		}
+		return rows[i].Party.Name < rows[j].Party.Name
+	})
+	return rows, nil
+}
+
+func SprintAgingReport(db *sql.DB, party *Party, datetimeMs int64) (string, error) {
+	rows, err := GetAgingReport(db, party, datetimeMs)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("=== Aging Report as of %s ===\n", time.UnixMilli(datetimeMs).Format("2006-01-02")))
+	sb.WriteString(fmt.Sprintf("%-10s %-20s %-4s %14s %14s %14s %14s %14s\n",
+		"Side", "Party", "Cur", "Current", "31-60", "61-90", "90+", "Total"))
+	for _, r := range rows {
+		sb.WriteString(fmt.Sprintf("%-10s %-20s %-4s %14s %14s %14s %14s %14s\n",
+			r.Side, r.Party.Name, r.Currency, r.Current.ToString(), r.Over30.ToString(),
+			r.Over60.ToString(), r.Over90.ToString(), r.Total.ToString()))
+	}
+	return sb.String(), nil
+}
+
+func PrintAgingReport(db *sql.DB, party *Party, datetimeMs int64) error {
+	str, err := SprintAgingReport(db, party, datetimeMs)
+	if err != nil {
+		return err
+	}
+	fmt.Print(str)
+	return nil
+}
+
+// opening balances before fromMs, the lines in between with a running
+// balance per side and currency, and closing balances at toMs
+func GetPartyStatement(db *sql.DB, party *Party, fromMs, toMs int64) (*PartyStatement, error) {
+	if party == nil {
+		return nil, sql.ErrNoRows
+	}
+	lines, err := getPartyLines(db, party, toMs)
+	if err != nil {
+		return nil, err
+	}
+	st := &PartyStatement{Party: party, FromDatetimeMs: fromMs, ToDatetimeMs: toMs}
+	var before []PartyLine
+	running := map[partyBalanceKey]int64{}
+	for _, l := range lines {
+		key := partyBalanceKey{l.Party.ID, l.Side, l.Currency}
+		running[key] += l.Amount.Data
+		if l.Transaction.DatetimeMs < fromMs {
+			before = append(before, l)
+			continue
+		}
+		l.Balance = NewDecimal(running[key])
+		st.Lines = append(st.Lines, l)
+	}
+	st.Opening = sumPartyBalances(before)
+	st.Closing = sumPartyBalances(lines)
+	return st, nil
+}
+
+func SprintPartyStatement(db *sql.DB, party *Party, fromMs, toMs int64) (string, error) {
+	st, err := GetPartyStatement(db, party, fromMs, toMs)
+	if err != nil {
+		return "", err
+	}
+	dateFmt := "2006-01-02"
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("=== Statement %s %s to %s ===\n", st.Party.Name,
+		time.UnixMilli(fromMs).Format(dateFmt), time.UnixMilli(toMs).Format(dateFmt)))
+	for _, b := range st.Opening {
+		sb.WriteString(fmt.Sprintf("Opening %s %s: %s\n", b.Side, b.Currency, b.Balance.ToString()))
+	}
+	for _, l := range st.Lines {
+		desc := l.Transaction.Description
+		if l.Note != "" {
+			desc += " - " + l.Note
+		}
+		sb.WriteString(fmt.Sprintf("%s | %-10s | %-30s | %4s %14s | Balance %14s\n",
+			time.UnixMilli(l.Transaction.DatetimeMs).Format(dateFmt), l.Side, desc, l.Currency,
+			l.Amount.ToString(), l.Balance.ToString()))
+	}
+	for _, b := range st.Closing {
+		sb.WriteString(fmt.Sprintf("Closing %s %s: %s\n", b.Side, b.Currency, b.Balance.ToString()))
+	}
+	return sb.String(), nil
+}
+
+func PrintPartyStatement(db *sql.DB, party *Party, fromMs, toMs int64) error {
+	str, err := SprintPartyStatement(db, party, fromMs, toMs)
+	if err != nil {
+		return err
+	}
+	fmt.Print(str)
+	return nil
+}
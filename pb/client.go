@@ -0,0 +1,217 @@
+package inventorypb
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"inventoryrpc"
+	"sync"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+)
+
+var ErrClientClosed = errors.New("client closed")
+var ErrPacketIncomplete = errors.New("packet incomplete")
+var ErrUnmarshallArg = errors.New("error unmarshall")
+var ErrExecFunc = errors.New("error execute function")
+
+// response codes to the errors they stand for, see ServerProcessor.ProcessPkt
+var responseCodeErrs = map[int32]error{
+	-101: ErrUnmarshallArg,
+	-102: ErrExecFunc,
+	-201: inventoryrpc.ErrReqHasNoFunc,
+	-202: inventoryrpc.ErrNoSuchFunc,
+	-203: inventoryrpc.ErrCurrDbNil,
+	-204: inventoryrpc.ErrReqHasNoArg,
+
+	inventoryrpc.CodeUnauthorized: inventoryrpc.ErrUnauthorized,
+}
+
+// a negative response code. errors.Is matches it against the error of its
+// code, inventoryrpc.ErrNoSuchFunc for -202 for example.
+type ResponseError struct {
+	Function string
+	Code     int32
+	Message  string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s: code %d: %s", e.Function, e.Code, e.Message)
+}
+
+func (e *ResponseError) Unwrap() error {
+	return responseCodeErrs[e.Code]
+}
+
+func ResponseCode(pkt *inventoryrpc.Packet) (int32, error) {
+	codeBytes, ok := pkt.Body["code"]
+	if !ok || len(codeBytes) < 4 {
+		return 0, ErrPacketIncomplete
+	}
+	return int32(binary.LittleEndian.Uint32(codeBytes)), nil
+}
+
+// sends requests over one connection and matches the responses to them by
+// Packet.UUID, so any number of calls can be in flight from many goroutines
+type Client struct {
+	// sent as Packet.Meta with every request, credentials for inventoryrpc.RequireMeta
+	// for example. set it before the first call.
+	Meta map[string][]byte
+	// gets the TypeEvent packets of Subscribe. it runs on the read loop, so
+	// responses wait while it does, set it before the first call.
+	OnEvent func(pkt *inventoryrpc.Packet)
+
+	conn *inventoryrpc.Conn
+
+	mu      sync.Mutex
+	pending map[uuid.UUID]chan *inventoryrpc.Packet
+	err     error
+	done    chan struct{}
+}
+
+// network is "tcp" or "unix". handshakes for protobuf without compression.
+func Dial(network, address string) (*Client, error) {
+	return DialWithHello(network, address, inventoryrpc.NewHello(Codec{}.Name()))
+}
+
+// the handshake offers hello, its Codecs may only name protobuf. a server
+// refusing it returns an error matching inventoryrpc.ErrIncompatiblePeer.
+func DialWithHello(network, address string, hello inventoryrpc.Hello) (*Client, error) {
+	conn, err := inventoryrpc.Dial(network, address, Codec{})
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Handshake(hello, Codec{})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// version, compression and features agreed on in the handshake
+func (c *Client) Conn() *inventoryrpc.Conn {
+	return c.conn
+}
+
+// takes over reading from conn
+func NewClient(conn *inventoryrpc.Conn) *Client {
+	c := &Client{
+		conn:    conn,
+		pending: map[uuid.UUID]chan *inventoryrpc.Packet{},
+		done:    make(chan struct{}),
+	}
+	go c.readLoop()
+	return c
+}
+
+func (c *Client) readLoop() {
+	var err error
+	for {
+		var pkt *inventoryrpc.Packet
+		pkt, err = c.conn.ReadPacket()
+		if err != nil {
+			break
+		}
+		if pkt.Type == inventoryrpc.TypeEvent && c.OnEvent != nil {
+			c.OnEvent(pkt)
+			continue
+		}
+		if pkt.Type != inventoryrpc.TypeResp {
+			continue
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[pkt.UUID]
+		delete(c.pending, pkt.UUID)
+		c.mu.Unlock()
+		// a response nobody waits for anymore, its call was cancelled
+		if ok {
+			ch <- pkt
+		}
+	}
+
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = fmt.Errorf("%w: %v", ErrClientClosed, err)
+	}
+	c.mu.Unlock()
+	close(c.done)
+}
+
+// sends funcName with arg (nil for none) and waits for the response or for
+// ctx to end. a negative response code comes back as *ResponseError along
+// with the response packet.
+func (c *Client) Call(ctx context.Context, funcName string, arg proto.Message) (*inventoryrpc.Packet, error) {
+	argBytes, err := proto.Marshal(arg)
+	if err != nil {
+		return nil, err
+	}
+	return c.CallRaw(ctx, funcName, argBytes)
+}
+
+// Call with the arg already encoded
+func (c *Client) CallRaw(ctx context.Context, funcName string, arg []byte) (*inventoryrpc.Packet, error) {
+	reqPkt, err := NewRawRequestPkt(funcName, arg)
+	if err != nil {
+		return nil, err
+	}
+	reqPkt.Meta = c.Meta
+
+	ch := make(chan *inventoryrpc.Packet, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		err = c.err
+		c.mu.Unlock()
+		return nil, err
+	}
+	c.pending[reqPkt.UUID] = ch
+	c.mu.Unlock()
+
+	err = c.conn.WritePacket(reqPkt)
+	if err != nil {
+		c.forget(reqPkt.UUID)
+		return nil, err
+	}
+
+	select {
+	case responsePkt := <-ch:
+		code, err := ResponseCode(responsePkt)
+		if err != nil {
+			return responsePkt, err
+		}
+		if code < 0 {
+			return responsePkt, &ResponseError{
+				Function: funcName,
+				Code:     code,
+				Message:  string(responsePkt.Body["message"]),
+			}
+		}
+		return responsePkt, nil
+	case <-ctx.Done():
+		c.forget(reqPkt.UUID)
+		return nil, ctx.Err()
+	case <-c.done:
+		c.mu.Lock()
+		err = c.err
+		c.mu.Unlock()
+		return nil, err
+	}
+}
+
+func (c *Client) forget(UUID uuid.UUID) {
+	c.mu.Lock()
+	delete(c.pending, UUID)
+	c.mu.Unlock()
+}
+
+// calls still waiting return ErrClientClosed
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = ErrClientClosed
+	}
+	c.mu.Unlock()
+	return c.conn.Close()
+}
@@ -0,0 +1,283 @@
+package inventorypb
+
+import (
+	"context"
+	"encoding/binary"
+	"inventory"
+	"inventoryrpc"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+)
+
+// one method per ServerFuncs entry, taking and returning inventory types
+
+func payloadBytes(pkt *inventoryrpc.Packet, key string) ([]byte, error) {
+	b, ok := pkt.Body[key]
+	if !ok {
+		return nil, ErrPacketIncomplete
+	}
+	return b, nil
+}
+
+func (c *Client) callUUID(ctx context.Context, funcName string, arg proto.Message) (uuid.UUID, error) {
+	pkt, err := c.Call(ctx, funcName, arg)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	b, err := payloadBytes(pkt, "uuid")
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.FromBytes(b)
+}
+
+func (c *Client) callString(ctx context.Context, funcName string, arg proto.Message, key string) (string, error) {
+	pkt, err := c.Call(ctx, funcName, arg)
+	if err != nil {
+		return "", err
+	}
+	b, err := payloadBytes(pkt, key)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// unmarshals the payload under key into result
+func (c *Client) callMessage(ctx context.Context, funcName string, arg proto.Message, key string, result proto.Message) error {
+	pkt, err := c.Call(ctx, funcName, arg)
+	if err != nil {
+		return err
+	}
+	b, err := payloadBytes(pkt, key)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(b, result)
+}
+
+func (c *Client) GetCurrDB(ctx context.Context) (uuid.UUID, error) {
+	return c.callUUID(ctx, "GetCurrDB", nil)
+}
+
+// uuid.Nil creates a new database
+func (c *Client) OpenOrCreateDB(ctx context.Context, dbUUID uuid.UUID) (uuid.UUID, error) {
+	var arg []byte
+	if dbUUID != uuid.Nil {
+		arg = dbUUID[:]
+	}
+	pkt, err := c.CallRaw(ctx, "OpenOrCreateDB", arg)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	b, err := payloadBytes(pkt, "uuid")
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.FromBytes(b)
+}
+
+func (c *Client) AddItem(ctx context.Context, item *inventory.Item) (uuid.UUID, error) {
+	return c.callUUID(ctx, "AddItem", NewItem(item, nil))
+}
+
+func (c *Client) AddAccount(ctx context.Context, acc *inventory.Account) (uuid.UUID, error) {
+	return c.callUUID(ctx, "AddAccount", NewAccount(acc, nil))
+}
+
+func (c *Client) ApplyTransaction(ctx context.Context, tr *inventory.Transaction) (uuid.UUID, error) {
+	return c.callUUID(ctx, "ApplyTransaction", NewTransaction(tr))
+}
+
+// asset, equity, liability, income and expense by name
+func (c *Client) GetMainAccounts(ctx context.Context) (map[string]uuid.UUID, error) {
+	pkt, err := c.Call(ctx, "GetMainAccounts", nil)
+	if err != nil {
+		return nil, err
+	}
+	accs := map[string]uuid.UUID{}
+	for k, v := range pkt.Body {
+		if k == "code" || k == "message" {
+			continue
+		}
+		accUUID, err := uuid.FromBytes(v)
+		if err != nil {
+			return nil, err
+		}
+		accs[k] = accUUID
+	}
+	return accs, nil
+}
+
+func (c *Client) UpdateMarketPrice(ctx context.Context, price *inventory.MarketPrice) error {
+	_, err := c.Call(ctx, "UpdateMarketPrice", NewMarketPrice(price))
+	return err
+}
+
+// returns the number of prices imported
+func (c *Client) ImportMarketPrices(ctx context.Context, data []byte, format string) (int, error) {
+	pkt, err := c.Call(ctx, "ImportMarketPrices", &MarketPriceImport{Format: format, Data: data})
+	if err != nil {
+		return 0, err
+	}
+	b, err := payloadBytes(pkt, "count")
+	if err != nil {
+		return 0, err
+	}
+	if len(b) < 4 {
+		return 0, ErrPacketIncomplete
+	}
+	return int(binary.LittleEndian.Uint32(b)), nil
+}
+
+func (c *Client) GetMarketPriceHistory(ctx context.Context, item *inventory.Item, fromMs, toMs int64) ([]*inventory.MarketPrice, error) {
+	var list MarketPriceList
+	err := c.callMessage(ctx, "GetMarketPriceHistory", &MarketPriceHistoryQuery{
+		ItemUUID:       item.UUID[:],
+		FromDatetimeMs: fromMs,
+		ToDatetimeMs:   toMs,
+	}, "prices", &list)
+	if err != nil {
+		return nil, err
+	}
+	return ToInvMarketPrices(&list), nil
+}
+
+func (c *Client) AddTaxCode(ctx context.Context, tc *inventory.TaxCode) (uuid.UUID, error) {
+	return c.callUUID(ctx, "AddTaxCode", NewTaxCode(tc))
+}
+
+func (c *Client) PrintTaxSummary(ctx context.Context, fromMs, toMs int64) (string, error) {
+	return c.callString(ctx, "PrintTaxSummary", &PeriodQuery{FromDatetimeMs: fromMs, ToDatetimeMs: toMs}, "summary")
+}
+
+func (c *Client) AddParty(ctx context.Context, party *inventory.Party) (uuid.UUID, error) {
+	return c.callUUID(ctx, "AddParty", NewParty(party))
+}
+
+func partyQuery(party *inventory.Party, fromMs, toMs int64) *PartyQuery {
+	query := &PartyQuery{FromDatetimeMs: fromMs, ToDatetimeMs: toMs}
+	if party != nil {
+		query.PartyUUID = party.UUID[:]
+	}
+	return query
+}
+
+// nil party is every party
+func (c *Client) GetPartyBalances(ctx context.Context, party *inventory.Party, toMs int64) ([]inventory.PartyBalance, error) {
+	var list PartyBalanceList
+	err := c.callMessage(ctx, "GetPartyBalances", partyQuery(party, 0, toMs), "balances", &list)
+	if err != nil {
+		return nil, err
+	}
+	return ToInvPartyBalances(&list), nil
+}
+
+func (c *Client) PrintAgingReport(ctx context.Context, party *inventory.Party, toMs int64) (string, error) {
+	return c.callString(ctx, "PrintAgingReport", partyQuery(party, 0, toMs), "report")
+}
+
+func (c *Client) PrintPartyStatement(ctx context.Context, party *inventory.Party, fromMs, toMs int64) (string, error) {
+	return c.callString(ctx, "PrintPartyStatement", partyQuery(party, fromMs, toMs), "statement")
+}
+
+func (c *Client) AddRecurringTransaction(ctx context.Context, r *inventory.RecurringTransaction) (uuid.UUID, error) {
+	return c.callUUID(ctx, "AddRecurringTransaction", NewRecurringTransaction(r))
+}
+
+func (c *Client) GetRecurringTransactions(ctx context.Context) ([]*inventory.RecurringTransaction, error) {
+	var list RecurringTransactionList
+	err := c.callMessage(ctx, "GetRecurringTransactions", nil, "recurring", &list)
+	if err != nil {
+		return nil, err
+	}
+	return ToInvRecurringTransactions(&list), nil
+}
+
+func (c *Client) SetRecurringTransactionActive(ctx context.Context, recUUID uuid.UUID, active bool) error {
+	_, err := c.Call(ctx, "SetRecurringTransactionActive", &RecurringTransaction{UUID: recUUID[:], Active: active})
+	return err
+}
+
+func (c *Client) PreviewRecurringTransactions(ctx context.Context, untilMs int64) ([]inventory.RecurringOccurrence, error) {
+	var list RecurringOccurrenceList
+	err := c.callMessage(ctx, "PreviewRecurringTransactions", &PeriodQuery{ToDatetimeMs: untilMs}, "occurrences", &list)
+	if err != nil {
+		return nil, err
+	}
+	return ToInvRecurringOccurrences(&list), nil
+}
+
+// nowMs 0 posts what is due by the server's clock
+func (c *Client) RunRecurringTransactions(ctx context.Context, nowMs int64) ([]inventory.RecurringOccurrence, error) {
+	var arg proto.Message
+	if nowMs != 0 {
+		arg = &PeriodQuery{ToDatetimeMs: nowMs}
+	}
+	var list RecurringOccurrenceList
+	err := c.callMessage(ctx, "RunRecurringTransactions", arg, "occurrences", &list)
+	if err != nil {
+		return nil, err
+	}
+	return ToInvRecurringOccurrences(&list), nil
+}
+
+func (c *Client) AddTransactionTemplate(ctx context.Context, t *inventory.TransactionTemplate) (uuid.UUID, error) {
+	return c.callUUID(ctx, "AddTransactionTemplate", NewTransactionTemplate(t))
+}
+
+func (c *Client) GetTransactionTemplates(ctx context.Context) ([]*inventory.TransactionTemplate, error) {
+	var list TransactionTemplateList
+	err := c.callMessage(ctx, "GetTransactionTemplates", nil, "templates", &list)
+	if err != nil {
+		return nil, err
+	}
+	return ToInvTransactionTemplates(&list), nil
+}
+
+// t is looked up by UUID, or by Name when UUID is nil
+func (c *Client) ApplyTransactionTemplate(ctx context.Context, t *inventory.TransactionTemplate, args map[string]string) (uuid.UUID, error) {
+	inst := &TemplateInstantiation{TemplateName: t.Name, Args: args}
+	if t.UUID != uuid.Nil {
+		inst.TemplateUUID = t.UUID[:]
+	}
+	return c.callUUID(ctx, "ApplyTransactionTemplate", inst)
+}
+
+func (c *Client) PrintBalances(ctx context.Context) (string, error) {
+	return c.callString(ctx, "PrintBalances", nil, "balances")
+}
+
+func (c *Client) PrintMarketBalances(ctx context.Context) (string, error) {
+	return c.callString(ctx, "PrintMarketBalances", nil, "balances")
+}
+
+func (c *Client) CloseCurrDB(ctx context.Context) error {
+	_, err := c.Call(ctx, "CloseCurrDB", nil)
+	return err
+}
+
+func (c *Client) callTopics(ctx context.Context, funcName string, topics []string) ([]string, error) {
+	pkt, err := c.CallRaw(ctx, funcName, []byte(strings.Join(topics, "\n")))
+	if err != nil {
+		return nil, err
+	}
+	if len(pkt.Body["topics"]) == 0 {
+		return nil, nil
+	}
+	return strings.Split(string(pkt.Body["topics"]), "\n"), nil
+}
+
+// topics like inventory.TopicTransactions or inventory.AccountTopic, the
+// events come to OnEvent. returns every topic subscribed after the call. the
+// server needs an EventHub, see Conn().HasFeature(inventoryrpc.FeatureEvents).
+func (c *Client) Subscribe(ctx context.Context, topics ...string) ([]string, error) {
+	return c.callTopics(ctx, inventoryrpc.FuncSubscribe, topics)
+}
+
+// no topics drops all of them
+func (c *Client) Unsubscribe(ctx context.Context, topics ...string) ([]string, error) {
+	return c.callTopics(ctx, inventoryrpc.FuncUnsubscribe, topics)
+}
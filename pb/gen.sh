@@ -5,6 +5,6 @@ if [ ! -f gen.sh ]; then
     exit 1
 fi
 
-protoc -I . --go_out=. inventory.proto 
-mv inventory/inventorypb/inventory.pb.go .
+protoc -I . --go_out=. --go-grpc_out=. inventory.proto
+mv inventory/inventorypb/inventory.pb.go inventory/inventorypb/inventory_grpc.pb.go .
 rm -r inventory
\ No newline at end of file
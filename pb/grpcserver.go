@@ -0,0 +1,429 @@
+package inventorypb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"inventoryrpc"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// response codes to grpc codes, see GRPCCode
+var grpcCodes = map[int32]codes.Code{
+	0:    codes.OK,
+	-101: codes.InvalidArgument,
+	-102: codes.FailedPrecondition,
+	-201: codes.InvalidArgument,
+	-202: codes.Unimplemented,
+	-203: codes.FailedPrecondition,
+	-204: codes.InvalidArgument,
+
+	inventoryrpc.CodeUnauthorized: codes.Unauthenticated,
+}
+
+// the grpc code a response code is answered with. a failed function is
+// FailedPrecondition, or NotFound when err is a missing row. codes without a
+// grpc code of their own are Internal.
+func GRPCCode(code int32, err error) codes.Code {
+	if code == -102 && errors.Is(err, sql.ErrNoRows) {
+		return codes.NotFound
+	}
+	c, ok := grpcCodes[code]
+	if !ok {
+		return codes.Internal
+	}
+	return c
+}
+
+// the Inventory grpc service over a Registry, every rpc runs the same
+// function a packet would. incoming metadata is passed on as Packet.Meta, so
+// middleware like inventoryrpc.RequireMeta works the same over grpc.
+//
+// like inventoryrpc.Server it is single-tenant: every rpc works on
+// inventory.CurrentDB, the database the last OpenOrCreateDB selected,
+// whichever client called it.
+type GRPCServer struct {
+	UnimplementedInventoryServer
+
+	// nil uses DefaultRegistry
+	Registry *Registry
+}
+
+func NewGRPCServer(registry *Registry) *GRPCServer {
+	return &GRPCServer{Registry: registry}
+}
+
+// the first value of every metadata key
+func metaFromContext(ctx context.Context) map[string][]byte {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+	meta := map[string][]byte{}
+	for k, v := range md {
+		if len(v) > 0 {
+			meta[k] = []byte(v[0])
+		}
+	}
+	return meta
+}
+
+func (s *GRPCServer) registry() *Registry {
+	if s.Registry == nil {
+		return DefaultRegistry
+	}
+	return s.Registry
+}
+
+func (s *GRPCServer) process(ctx context.Context, pkt *inventoryrpc.Packet) (map[string][]byte, error) {
+	pkt.Meta = metaFromContext(ctx)
+	responsePkt, message, code, err := s.registry().Process(pkt)
+	if code < 0 {
+		return nil, status.Error(GRPCCode(code, err), message)
+	}
+	return responsePkt.Body, nil
+}
+
+func (s *GRPCServer) call(ctx context.Context, funcName string, arg proto.Message) (map[string][]byte, error) {
+	pkt, err := NewRequestPkt(funcName, arg)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return s.process(ctx, pkt)
+}
+
+func grpcPayloadBytes(payload map[string][]byte, key string) ([]byte, error) {
+	b, ok := payload[key]
+	if !ok {
+		return nil, status.Error(codes.Internal, ErrPacketIncomplete.Error())
+	}
+	return b, nil
+}
+
+func (s *GRPCServer) callUUID(ctx context.Context, funcName string, arg proto.Message) (*UUID, error) {
+	payload, err := s.call(ctx, funcName, arg)
+	if err != nil {
+		return nil, err
+	}
+	b, err := grpcPayloadBytes(payload, "uuid")
+	if err != nil {
+		return nil, err
+	}
+	return &UUID{UUID: b}, nil
+}
+
+func (s *GRPCServer) callEmpty(ctx context.Context, funcName string, arg proto.Message) (*emptypb.Empty, error) {
+	_, err := s.call(ctx, funcName, arg)
+	if err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *GRPCServer) callReport(ctx context.Context, funcName string, arg proto.Message, key string) (*Report, error) {
+	payload, err := s.call(ctx, funcName, arg)
+	if err != nil {
+		return nil, err
+	}
+	b, err := grpcPayloadBytes(payload, key)
+	if err != nil {
+		return nil, err
+	}
+	return &Report{Text: string(b)}, nil
+}
+
+// unmarshals the payload under key into result
+func (s *GRPCServer) callMessage(ctx context.Context, funcName string, arg proto.Message, key string, result proto.Message) error {
+	payload, err := s.call(ctx, funcName, arg)
+	if err != nil {
+		return err
+	}
+	b, err := grpcPayloadBytes(payload, key)
+	if err != nil {
+		return err
+	}
+	err = proto.Unmarshal(b, result)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+func (s *GRPCServer) GetCurrDB(ctx context.Context, _ *emptypb.Empty) (*UUID, error) {
+	return s.callUUID(ctx, "GetCurrDB", nil)
+}
+
+func (s *GRPCServer) OpenOrCreateDB(ctx context.Context, dbUUID *UUID) (*UUID, error) {
+	pkt, err := NewRawRequestPkt("OpenOrCreateDB", dbUUID.UUID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	payload, err := s.process(ctx, pkt)
+	if err != nil {
+		return nil, err
+	}
+	b, err := grpcPayloadBytes(payload, "uuid")
+	if err != nil {
+		return nil, err
+	}
+	return &UUID{UUID: b}, nil
+}
+
+func (s *GRPCServer) AddItem(ctx context.Context, item *Item) (*UUID, error) {
+	return s.callUUID(ctx, "AddItem", item)
+}
+
+func (s *GRPCServer) AddAccount(ctx context.Context, acc *Account) (*UUID, error) {
+	return s.callUUID(ctx, "AddAccount", acc)
+}
+
+func (s *GRPCServer) ApplyTransaction(ctx context.Context, tr *Transaction) (*UUID, error) {
+	return s.callUUID(ctx, "ApplyTransaction", tr)
+}
+
+func (s *GRPCServer) GetMainAccounts(ctx context.Context, _ *emptypb.Empty) (*MapOfBytes, error) {
+	payload, err := s.call(ctx, "GetMainAccounts", nil)
+	if err != nil {
+		return nil, err
+	}
+	accs := map[string][]byte{}
+	for k, v := range payload {
+		if k == "code" || k == "message" {
+			continue
+		}
+		accs[k] = v
+	}
+	return NewMapOfBytes(accs), nil
+}
+
+func (s *GRPCServer) UpdateMarketPrice(ctx context.Context, price *MarketPrice) (*emptypb.Empty, error) {
+	return s.callEmpty(ctx, "UpdateMarketPrice", price)
+}
+
+func (s *GRPCServer) ImportMarketPrices(ctx context.Context, priceImport *MarketPriceImport) (*Count, error) {
+	payload, err := s.call(ctx, "ImportMarketPrices", priceImport)
+	if err != nil {
+		return nil, err
+	}
+	b, err := grpcPayloadBytes(payload, "count")
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 4 {
+		return nil, status.Error(codes.Internal, ErrPacketIncomplete.Error())
+	}
+	return &Count{Count: int32(binary.LittleEndian.Uint32(b))}, nil
+}
+
+func (s *GRPCServer) GetMarketPriceHistory(ctx context.Context, query *MarketPriceHistoryQuery) (*MarketPriceList, error) {
+	list := &MarketPriceList{}
+	err := s.callMessage(ctx, "GetMarketPriceHistory", query, "prices", list)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *GRPCServer) AddTaxCode(ctx context.Context, taxCode *TaxCode) (*UUID, error) {
+	return s.callUUID(ctx, "AddTaxCode", taxCode)
+}
+
+func (s *GRPCServer) PrintTaxSummary(ctx context.Context, query *PeriodQuery) (*Report, error) {
+	return s.callReport(ctx, "PrintTaxSummary", query, "summary")
+}
+
+func (s *GRPCServer) AddParty(ctx context.Context, party *Party) (*UUID, error) {
+	return s.callUUID(ctx, "AddParty", party)
+}
+
+func (s *GRPCServer) GetPartyBalances(ctx context.Context, query *PartyQuery) (*PartyBalanceList, error) {
+	list := &PartyBalanceList{}
+	err := s.callMessage(ctx, "GetPartyBalances", query, "balances", list)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *GRPCServer) PrintAgingReport(ctx context.Context, query *PartyQuery) (*Report, error) {
+	return s.callReport(ctx, "PrintAgingReport", query, "report")
+}
+
+func (s *GRPCServer) PrintPartyStatement(ctx context.Context, query *PartyQuery) (*Report, error) {
+	return s.callReport(ctx, "PrintPartyStatement", query, "statement")
+}
+
+func (s *GRPCServer) AddRecurringTransaction(ctx context.Context, rec *RecurringTransaction) (*UUID, error) {
+	return s.callUUID(ctx, "AddRecurringTransaction", rec)
+}
+
+func (s *GRPCServer) GetRecurringTransactions(ctx context.Context, _ *emptypb.Empty) (*RecurringTransactionList, error) {
+	list := &RecurringTransactionList{}
+	err := s.callMessage(ctx, "GetRecurringTransactions", nil, "recurring", list)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *GRPCServer) SetRecurringTransactionActive(ctx context.Context, rec *RecurringTransaction) (*emptypb.Empty, error) {
+	return s.callEmpty(ctx, "SetRecurringTransactionActive", rec)
+}
+
+func (s *GRPCServer) PreviewRecurringTransactions(ctx context.Context, query *PeriodQuery) (*RecurringOccurrenceList, error) {
+	list := &RecurringOccurrenceList{}
+	err := s.callMessage(ctx, "PreviewRecurringTransactions", query, "occurrences", list)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *GRPCServer) RunRecurringTransactions(ctx context.Context, query *PeriodQuery) (*RecurringOccurrenceList, error) {
+	// a zero query is sent as no arg, which posts everything due by now
+	list := &RecurringOccurrenceList{}
+	err := s.callMessage(ctx, "RunRecurringTransactions", query, "occurrences", list)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *GRPCServer) AddTransactionTemplate(ctx context.Context, tmpl *TransactionTemplate) (*UUID, error) {
+	return s.callUUID(ctx, "AddTransactionTemplate", tmpl)
+}
+
+func (s *GRPCServer) GetTransactionTemplates(ctx context.Context, _ *emptypb.Empty) (*TransactionTemplateList, error) {
+	list := &TransactionTemplateList{}
+	err := s.callMessage(ctx, "GetTransactionTemplates", nil, "templates", list)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *GRPCServer) ApplyTransactionTemplate(ctx context.Context, inst *TemplateInstantiation) (*UUID, error) {
+	return s.callUUID(ctx, "ApplyTransactionTemplate", inst)
+}
+
+func (s *GRPCServer) PrintBalances(ctx context.Context, _ *emptypb.Empty) (*Report, error) {
+	return s.callReport(ctx, "PrintBalances", nil, "balances")
+}
+
+func (s *GRPCServer) PrintMarketBalances(ctx context.Context, _ *emptypb.Empty) (*Report, error) {
+	return s.callReport(ctx, "PrintMarketBalances", nil, "balances")
+}
+
+func (s *GRPCServer) CloseCurrDB(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	return s.callEmpty(ctx, "CloseCurrDB", nil)
+}
+
+func (s *GRPCServer) StreamMarketPriceHistory(query *MarketPriceHistoryQuery, stream grpc.ServerStreamingServer[MarketPrice]) error {
+	list, err := s.GetMarketPriceHistory(stream.Context(), query)
+	if err != nil {
+		return err
+	}
+	for _, price := range list.MarketPrices {
+		err = stream.Send(price)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *GRPCServer) StreamPartyBalances(query *PartyQuery, stream grpc.ServerStreamingServer[PartyBalance]) error {
+	list, err := s.GetPartyBalances(stream.Context(), query)
+	if err != nil {
+		return err
+	}
+	for _, balance := range list.PartyBalances {
+		err = stream.Send(balance)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *GRPCServer) StreamRecurringPreview(query *PeriodQuery, stream grpc.ServerStreamingServer[RecurringOccurrence]) error {
+	list, err := s.PreviewRecurringTransactions(stream.Context(), query)
+	if err != nil {
+		return err
+	}
+	for _, occurrence := range list.Occurrences {
+		err = stream.Send(occurrence)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sends the report one line at a time, without the line breaks
+func sendReportLines(report *Report, stream grpc.ServerStreamingServer[ReportLine]) error {
+	for _, line := range strings.Split(strings.TrimSuffix(report.Text, "\n"), "\n") {
+		err := stream.Send(&ReportLine{Line: line})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sends every "line" the function streams as it is read, see
+// inventory.EachBalanceLine
+func (s *GRPCServer) streamLines(funcName string, stream grpc.ServerStreamingServer[ReportLine]) error {
+	pkt, err := NewRequestPkt(funcName, nil)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	pkt.Meta = metaFromContext(stream.Context())
+	_, message, code, err := s.registry().ProcessStream(pkt, func(payload map[string][]byte) error {
+		return stream.Send(&ReportLine{Line: string(payload["line"])})
+	})
+	if code < 0 {
+		return status.Error(GRPCCode(code, err), message)
+	}
+	return nil
+}
+
+func (s *GRPCServer) StreamBalances(_ *emptypb.Empty, stream grpc.ServerStreamingServer[ReportLine]) error {
+	return s.streamLines("PrintBalances", stream)
+}
+
+func (s *GRPCServer) StreamMarketBalances(_ *emptypb.Empty, stream grpc.ServerStreamingServer[ReportLine]) error {
+	return s.streamLines("PrintMarketBalances", stream)
+}
+
+func (s *GRPCServer) StreamTaxSummary(query *PeriodQuery, stream grpc.ServerStreamingServer[ReportLine]) error {
+	report, err := s.PrintTaxSummary(stream.Context(), query)
+	if err != nil {
+		return err
+	}
+	return sendReportLines(report, stream)
+}
+
+func (s *GRPCServer) StreamAgingReport(query *PartyQuery, stream grpc.ServerStreamingServer[ReportLine]) error {
+	report, err := s.PrintAgingReport(stream.Context(), query)
+	if err != nil {
+		return err
+	}
+	return sendReportLines(report, stream)
+}
+
+func (s *GRPCServer) StreamPartyStatement(query *PartyQuery, stream grpc.ServerStreamingServer[ReportLine]) error {
+	report, err := s.PrintPartyStatement(stream.Context(), query)
+	if err != nil {
+		return err
+	}
+	return sendReportLines(report, stream)
+}
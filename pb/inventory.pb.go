@@ -9,6 +9,7 @@ package inventorypb
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -171,6 +172,7 @@ type Transaction struct {
 	Description      string                 `protobuf:"bytes,2,opt,name=Description,proto3" json:"Description,omitempty"`
 	DatetimeMs       int64                  `protobuf:"zigzag64,3,opt,name=DatetimeMs,proto3" json:"DatetimeMs,omitempty"`
 	TransactionLines []*TransactionLine     `protobuf:"bytes,4,rep,name=TransactionLines,proto3" json:"TransactionLines,omitempty"`
+	PartyUUID        []byte                 `protobuf:"bytes,5,opt,name=PartyUUID,proto3" json:"PartyUUID,omitempty"`
 	unknownFields    protoimpl.UnknownFields
 	sizeCache        protoimpl.SizeCache
 }
@@ -233,6 +235,13 @@ func (x *Transaction) GetTransactionLines() []*TransactionLine {
 	return nil
 }
 
+func (x *Transaction) GetPartyUUID() []byte {
+	if x != nil {
+		return x.PartyUUID
+	}
+	return nil
+}
+
 type TransactionLine struct {
 	state           protoimpl.MessageState `protogen:"open.v1"`
 	UUID            []byte                 `protobuf:"bytes,1,opt,name=UUID,proto3" json:"UUID,omitempty"`
@@ -244,6 +253,8 @@ type TransactionLine struct {
 	Price           int64                  `protobuf:"zigzag64,7,opt,name=Price,proto3" json:"Price,omitempty"`
 	Currency        string                 `protobuf:"bytes,8,opt,name=Currency,proto3" json:"Currency,omitempty"`
 	Note            string                 `protobuf:"bytes,9,opt,name=Note,proto3" json:"Note,omitempty"`
+	TaxCodeUUID     []byte                 `protobuf:"bytes,10,opt,name=TaxCodeUUID,proto3" json:"TaxCodeUUID,omitempty"`
+	PartyUUID       []byte                 `protobuf:"bytes,11,opt,name=PartyUUID,proto3" json:"PartyUUID,omitempty"`
 	unknownFields   protoimpl.UnknownFields
 	sizeCache       protoimpl.SizeCache
 }
@@ -341,6 +352,20 @@ func (x *TransactionLine) GetNote() string {
 	return ""
 }
 
+func (x *TransactionLine) GetTaxCodeUUID() []byte {
+	if x != nil {
+		return x.TaxCodeUUID
+	}
+	return nil
+}
+
+func (x *TransactionLine) GetPartyUUID() []byte {
+	if x != nil {
+		return x.PartyUUID
+	}
+	return nil
+}
+
 type BalanceHistoryReferences struct {
 	state               protoimpl.MessageState `protogen:"open.v1"`
 	TransactionLineUUID []byte                 `protobuf:"bytes,1,opt,name=TransactionLineUUID,proto3" json:"TransactionLineUUID,omitempty"`
@@ -761,30 +786,27 @@ func (x *MarketPrice) GetCurrency() string {
 	return ""
 }
 
-type Packet struct {
+type MarketPriceList struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	UUID          []byte                 `protobuf:"bytes,1,opt,name=UUID,proto3" json:"UUID,omitempty"`
-	Type          int32                  `protobuf:"zigzag32,2,opt,name=Type,proto3" json:"Type,omitempty"`
-	Meta          map[string][]byte      `protobuf:"bytes,3,rep,name=Meta,proto3" json:"Meta,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	Body          map[string][]byte      `protobuf:"bytes,4,rep,name=Body,proto3" json:"Body,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	MarketPrices  []*MarketPrice         `protobuf:"bytes,1,rep,name=MarketPrices,proto3" json:"MarketPrices,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Packet) Reset() {
-	*x = Packet{}
+func (x *MarketPriceList) Reset() {
+	*x = MarketPriceList{}
 	mi := &file_inventory_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Packet) String() string {
+func (x *MarketPriceList) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Packet) ProtoMessage() {}
+func (*MarketPriceList) ProtoMessage() {}
 
-func (x *Packet) ProtoReflect() protoreflect.Message {
+func (x *MarketPriceList) ProtoReflect() protoreflect.Message {
 	mi := &file_inventory_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -796,61 +818,298 @@ func (x *Packet) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Packet.ProtoReflect.Descriptor instead.
-func (*Packet) Descriptor() ([]byte, []int) {
+// Deprecated: Use MarketPriceList.ProtoReflect.Descriptor instead.
+func (*MarketPriceList) Descriptor() ([]byte, []int) {
 	return file_inventory_proto_rawDescGZIP(), []int{9}
 }
 
-func (x *Packet) GetUUID() []byte {
+func (x *MarketPriceList) GetMarketPrices() []*MarketPrice {
+	if x != nil {
+		return x.MarketPrices
+	}
+	return nil
+}
+
+type MarketPriceImport struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Format        string                 `protobuf:"bytes,1,opt,name=Format,proto3" json:"Format,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=Data,proto3" json:"Data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MarketPriceImport) Reset() {
+	*x = MarketPriceImport{}
+	mi := &file_inventory_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MarketPriceImport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MarketPriceImport) ProtoMessage() {}
+
+func (x *MarketPriceImport) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MarketPriceImport.ProtoReflect.Descriptor instead.
+func (*MarketPriceImport) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *MarketPriceImport) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+func (x *MarketPriceImport) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type MarketPriceHistoryQuery struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ItemUUID       []byte                 `protobuf:"bytes,1,opt,name=ItemUUID,proto3" json:"ItemUUID,omitempty"`
+	FromDatetimeMs int64                  `protobuf:"zigzag64,2,opt,name=FromDatetimeMs,proto3" json:"FromDatetimeMs,omitempty"`
+	ToDatetimeMs   int64                  `protobuf:"zigzag64,3,opt,name=ToDatetimeMs,proto3" json:"ToDatetimeMs,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *MarketPriceHistoryQuery) Reset() {
+	*x = MarketPriceHistoryQuery{}
+	mi := &file_inventory_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MarketPriceHistoryQuery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MarketPriceHistoryQuery) ProtoMessage() {}
+
+func (x *MarketPriceHistoryQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MarketPriceHistoryQuery.ProtoReflect.Descriptor instead.
+func (*MarketPriceHistoryQuery) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *MarketPriceHistoryQuery) GetItemUUID() []byte {
+	if x != nil {
+		return x.ItemUUID
+	}
+	return nil
+}
+
+func (x *MarketPriceHistoryQuery) GetFromDatetimeMs() int64 {
+	if x != nil {
+		return x.FromDatetimeMs
+	}
+	return 0
+}
+
+func (x *MarketPriceHistoryQuery) GetToDatetimeMs() int64 {
+	if x != nil {
+		return x.ToDatetimeMs
+	}
+	return 0
+}
+
+type TaxCode struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	UUID                  []byte                 `protobuf:"bytes,1,opt,name=UUID,proto3" json:"UUID,omitempty"`
+	Code                  string                 `protobuf:"bytes,2,opt,name=Code,proto3" json:"Code,omitempty"`
+	Name                  string                 `protobuf:"bytes,3,opt,name=Name,proto3" json:"Name,omitempty"`
+	Rate                  int64                  `protobuf:"zigzag64,4,opt,name=Rate,proto3" json:"Rate,omitempty"`
+	Inclusive             bool                   `protobuf:"varint,5,opt,name=Inclusive,proto3" json:"Inclusive,omitempty"`
+	PayableAccountUUID    []byte                 `protobuf:"bytes,6,opt,name=PayableAccountUUID,proto3" json:"PayableAccountUUID,omitempty"`
+	ReceivableAccountUUID []byte                 `protobuf:"bytes,7,opt,name=ReceivableAccountUUID,proto3" json:"ReceivableAccountUUID,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *TaxCode) Reset() {
+	*x = TaxCode{}
+	mi := &file_inventory_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaxCode) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaxCode) ProtoMessage() {}
+
+func (x *TaxCode) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaxCode.ProtoReflect.Descriptor instead.
+func (*TaxCode) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *TaxCode) GetUUID() []byte {
 	if x != nil {
 		return x.UUID
 	}
 	return nil
 }
 
-func (x *Packet) GetType() int32 {
+func (x *TaxCode) GetCode() string {
 	if x != nil {
-		return x.Type
+		return x.Code
+	}
+	return ""
+}
+
+func (x *TaxCode) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *TaxCode) GetRate() int64 {
+	if x != nil {
+		return x.Rate
 	}
 	return 0
 }
 
-func (x *Packet) GetMeta() map[string][]byte {
+func (x *TaxCode) GetInclusive() bool {
 	if x != nil {
-		return x.Meta
+		return x.Inclusive
+	}
+	return false
+}
+
+func (x *TaxCode) GetPayableAccountUUID() []byte {
+	if x != nil {
+		return x.PayableAccountUUID
 	}
 	return nil
 }
 
-func (x *Packet) GetBody() map[string][]byte {
+func (x *TaxCode) GetReceivableAccountUUID() []byte {
 	if x != nil {
-		return x.Body
+		return x.ReceivableAccountUUID
 	}
 	return nil
 }
 
-type MapOfBytes struct {
+type PeriodQuery struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	FromDatetimeMs int64                  `protobuf:"zigzag64,1,opt,name=FromDatetimeMs,proto3" json:"FromDatetimeMs,omitempty"`
+	ToDatetimeMs   int64                  `protobuf:"zigzag64,2,opt,name=ToDatetimeMs,proto3" json:"ToDatetimeMs,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *PeriodQuery) Reset() {
+	*x = PeriodQuery{}
+	mi := &file_inventory_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeriodQuery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeriodQuery) ProtoMessage() {}
+
+func (x *PeriodQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeriodQuery.ProtoReflect.Descriptor instead.
+func (*PeriodQuery) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *PeriodQuery) GetFromDatetimeMs() int64 {
+	if x != nil {
+		return x.FromDatetimeMs
+	}
+	return 0
+}
+
+func (x *PeriodQuery) GetToDatetimeMs() int64 {
+	if x != nil {
+		return x.ToDatetimeMs
+	}
+	return 0
+}
+
+type Party struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Content       map[string][]byte      `protobuf:"bytes,1,rep,name=content,proto3" json:"content,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	UUID          []byte                 `protobuf:"bytes,1,opt,name=UUID,proto3" json:"UUID,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=Name,proto3" json:"Name,omitempty"`
+	Kind          string                 `protobuf:"bytes,3,opt,name=Kind,proto3" json:"Kind,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *MapOfBytes) Reset() {
-	*x = MapOfBytes{}
-	mi := &file_inventory_proto_msgTypes[10]
+func (x *Party) Reset() {
+	*x = Party{}
+	mi := &file_inventory_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *MapOfBytes) String() string {
+func (x *Party) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*MapOfBytes) ProtoMessage() {}
+func (*Party) ProtoMessage() {}
 
-func (x *MapOfBytes) ProtoReflect() protoreflect.Message {
-	mi := &file_inventory_proto_msgTypes[10]
+func (x *Party) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -861,73 +1120,1159 @@ func (x *MapOfBytes) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use MapOfBytes.ProtoReflect.Descriptor instead.
-func (*MapOfBytes) Descriptor() ([]byte, []int) {
-	return file_inventory_proto_rawDescGZIP(), []int{10}
+// Deprecated: Use Party.ProtoReflect.Descriptor instead.
+func (*Party) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{14}
 }
 
-func (x *MapOfBytes) GetContent() map[string][]byte {
+func (x *Party) GetUUID() []byte {
 	if x != nil {
-		return x.Content
+		return x.UUID
 	}
 	return nil
 }
 
-var File_inventory_proto protoreflect.FileDescriptor
+func (x *Party) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
 
-const file_inventory_proto_rawDesc = "" +
-	"\n" +
-	"\x0finventory.proto\x12\vinventorypb\"\x9b\x01\n" +
-	"\aAccount\x12\x12\n" +
-	"\x04UUID\x18\x01 \x01(\fR\x04UUID\x12\x12\n" +
-	"\x04Name\x18\x02 \x01(\tR\x04Name\x12\x1e\n" +
-	"\n" +
-	"ParentUUID\x18\x03 \x01(\fR\n" +
-	"ParentUUID\x12H\n" +
-	"\x10TransactionLines\x18\x04 \x03(\v2\x1c.inventorypb.TransactionLineR\x10TransactionLines\"\xae\x01\n" +
-	"\x04Item\x12\x12\n" +
-	"\x04UUID\x18\x01 \x01(\fR\x04UUID\x12\x12\n" +
-	"\x04Name\x18\x02 \x01(\tR\x04Name\x12 \n" +
-	"\vDescription\x18\x03 \x01(\tR\vDescription\x12\x12\n" +
-	"\x04Unit\x18\x04 \x01(\tR\x04Unit\x12H\n" +
-	"\x10TransactionLines\x18\x05 \x03(\v2\x1c.inventorypb.TransactionLineR\x10TransactionLines\"\xad\x01\n" +
-	"\vTransaction\x12\x12\n" +
-	"\x04UUID\x18\x01 \x01(\fR\x04UUID\x12 \n" +
-	"\vDescription\x18\x02 \x01(\tR\vDescription\x12\x1e\n" +
-	"\n" +
-	"DatetimeMs\x18\x03 \x01(\x12R\n" +
-	"DatetimeMs\x12H\n" +
-	"\x10TransactionLines\x18\x04 \x03(\v2\x1c.inventorypb.TransactionLineR\x10TransactionLines\"\x83\x02\n" +
-	"\x0fTransactionLine\x12\x12\n" +
-	"\x04UUID\x18\x01 \x01(\fR\x04UUID\x12(\n" +
-	"\x0fTransactionUUID\x18\x02 \x01(\fR\x0fTransactionUUID\x12 \n" +
-	"\vAccountUUID\x18\x03 \x01(\fR\vAccountUUID\x12\x1a\n" +
-	"\bItemUUID\x18\x04 \x01(\fR\bItemUUID\x12\x1a\n" +
-	"\bQuantity\x18\x05 \x01(\x12R\bQuantity\x12\x12\n" +
-	"\x04Unit\x18\x06 \x01(\tR\x04Unit\x12\x14\n" +
-	"\x05Price\x18\a \x01(\x12R\x05Price\x12\x1a\n" +
-	"\bCurrency\x18\b \x01(\tR\bCurrency\x12\x12\n" +
-	"\x04Note\x18\t \x01(\tR\x04Note\"\xb4\x01\n" +
-	"\x18BalanceHistoryReferences\x120\n" +
-	"\x13TransactionLineUUID\x18\x01 \x01(\fR\x13TransactionLineUUID\x12(\n" +
-	"\x0fTransactionUUID\x18\x02 \x01(\fR\x0fTransactionUUID\x12 \n" +
-	"\vAccountUUID\x18\x03 \x01(\fR\vAccountUUID\x12\x1a\n" +
-	"\bItemUUID\x18\x04 \x01(\fR\bItemUUID\"\xad\x03\n" +
-	"\x0eBalanceHistory\x12\x12\n" +
-	"\x04UUID\x18\x01 \x01(\fR\x04UUID\x12\x12\n" +
-	"\x04Path\x18\x02 \x03(\tR\x04Path\x12E\n" +
-	"\n" +
-	"references\x18\x03 \x01(\v2%.inventorypb.BalanceHistoryReferencesR\n" +
-	"references\x12\x12\n" +
-	"\x04Unit\x18\x04 \x01(\fR\x04Unit\x12\x1a\n" +
-	"\bQuantity\x18\x05 \x01(\x12R\bQuantity\x12\x18\n" +
-	"\aAvgCost\x18\x06 \x01(\x12R\aAvgCost\x12\x14\n" +
-	"\x05Value\x18\a \x01(\x12R\x05Value\x12\x1e\n" +
-	"\n" +
-	"DatetimeMs\x18\b \x01(\x12R\n" +
-	"DatetimeMs\x12*\n" +
-	"\x10TransactionPrice\x18\t \x01(\x12R\x10TransactionPrice\x12 \n" +
-	"\vMarketPrice\x18\n" +
+func (x *Party) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+type PartyBalance struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PartyUUID     []byte                 `protobuf:"bytes,1,opt,name=PartyUUID,proto3" json:"PartyUUID,omitempty"`
+	Side          string                 `protobuf:"bytes,2,opt,name=Side,proto3" json:"Side,omitempty"`
+	Currency      string                 `protobuf:"bytes,3,opt,name=Currency,proto3" json:"Currency,omitempty"`
+	Balance       int64                  `protobuf:"zigzag64,4,opt,name=Balance,proto3" json:"Balance,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PartyBalance) Reset() {
+	*x = PartyBalance{}
+	mi := &file_inventory_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PartyBalance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PartyBalance) ProtoMessage() {}
+
+func (x *PartyBalance) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PartyBalance.ProtoReflect.Descriptor instead.
+func (*PartyBalance) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *PartyBalance) GetPartyUUID() []byte {
+	if x != nil {
+		return x.PartyUUID
+	}
+	return nil
+}
+
+func (x *PartyBalance) GetSide() string {
+	if x != nil {
+		return x.Side
+	}
+	return ""
+}
+
+func (x *PartyBalance) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *PartyBalance) GetBalance() int64 {
+	if x != nil {
+		return x.Balance
+	}
+	return 0
+}
+
+type PartyBalanceList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PartyBalances []*PartyBalance        `protobuf:"bytes,1,rep,name=PartyBalances,proto3" json:"PartyBalances,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PartyBalanceList) Reset() {
+	*x = PartyBalanceList{}
+	mi := &file_inventory_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PartyBalanceList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PartyBalanceList) ProtoMessage() {}
+
+func (x *PartyBalanceList) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PartyBalanceList.ProtoReflect.Descriptor instead.
+func (*PartyBalanceList) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *PartyBalanceList) GetPartyBalances() []*PartyBalance {
+	if x != nil {
+		return x.PartyBalances
+	}
+	return nil
+}
+
+// empty PartyUUID means every party, balances and aging are as of ToDatetimeMs
+type PartyQuery struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	PartyUUID      []byte                 `protobuf:"bytes,1,opt,name=PartyUUID,proto3" json:"PartyUUID,omitempty"`
+	FromDatetimeMs int64                  `protobuf:"zigzag64,2,opt,name=FromDatetimeMs,proto3" json:"FromDatetimeMs,omitempty"`
+	ToDatetimeMs   int64                  `protobuf:"zigzag64,3,opt,name=ToDatetimeMs,proto3" json:"ToDatetimeMs,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *PartyQuery) Reset() {
+	*x = PartyQuery{}
+	mi := &file_inventory_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PartyQuery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PartyQuery) ProtoMessage() {}
+
+func (x *PartyQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PartyQuery.ProtoReflect.Descriptor instead.
+func (*PartyQuery) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *PartyQuery) GetPartyUUID() []byte {
+	if x != nil {
+		return x.PartyUUID
+	}
+	return nil
+}
+
+func (x *PartyQuery) GetFromDatetimeMs() int64 {
+	if x != nil {
+		return x.FromDatetimeMs
+	}
+	return 0
+}
+
+func (x *PartyQuery) GetToDatetimeMs() int64 {
+	if x != nil {
+		return x.ToDatetimeMs
+	}
+	return 0
+}
+
+// Template holds the description, party and lines posted on each occurrence
+type RecurringTransaction struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	UUID            []byte                 `protobuf:"bytes,1,opt,name=UUID,proto3" json:"UUID,omitempty"`
+	Name            string                 `protobuf:"bytes,2,opt,name=Name,proto3" json:"Name,omitempty"`
+	Schedule        string                 `protobuf:"bytes,3,opt,name=Schedule,proto3" json:"Schedule,omitempty"`
+	DayOfMonth      int32                  `protobuf:"zigzag32,4,opt,name=DayOfMonth,proto3" json:"DayOfMonth,omitempty"`
+	Weekday         int32                  `protobuf:"zigzag32,5,opt,name=Weekday,proto3" json:"Weekday,omitempty"`
+	Cron            string                 `protobuf:"bytes,6,opt,name=Cron,proto3" json:"Cron,omitempty"`
+	StartDatetimeMs int64                  `protobuf:"zigzag64,7,opt,name=StartDatetimeMs,proto3" json:"StartDatetimeMs,omitempty"`
+	EndDatetimeMs   int64                  `protobuf:"zigzag64,8,opt,name=EndDatetimeMs,proto3" json:"EndDatetimeMs,omitempty"`
+	Active          bool                   `protobuf:"varint,9,opt,name=Active,proto3" json:"Active,omitempty"`
+	Template        *Transaction           `protobuf:"bytes,10,opt,name=Template,proto3" json:"Template,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *RecurringTransaction) Reset() {
+	*x = RecurringTransaction{}
+	mi := &file_inventory_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecurringTransaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecurringTransaction) ProtoMessage() {}
+
+func (x *RecurringTransaction) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecurringTransaction.ProtoReflect.Descriptor instead.
+func (*RecurringTransaction) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *RecurringTransaction) GetUUID() []byte {
+	if x != nil {
+		return x.UUID
+	}
+	return nil
+}
+
+func (x *RecurringTransaction) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RecurringTransaction) GetSchedule() string {
+	if x != nil {
+		return x.Schedule
+	}
+	return ""
+}
+
+func (x *RecurringTransaction) GetDayOfMonth() int32 {
+	if x != nil {
+		return x.DayOfMonth
+	}
+	return 0
+}
+
+func (x *RecurringTransaction) GetWeekday() int32 {
+	if x != nil {
+		return x.Weekday
+	}
+	return 0
+}
+
+func (x *RecurringTransaction) GetCron() string {
+	if x != nil {
+		return x.Cron
+	}
+	return ""
+}
+
+func (x *RecurringTransaction) GetStartDatetimeMs() int64 {
+	if x != nil {
+		return x.StartDatetimeMs
+	}
+	return 0
+}
+
+func (x *RecurringTransaction) GetEndDatetimeMs() int64 {
+	if x != nil {
+		return x.EndDatetimeMs
+	}
+	return 0
+}
+
+func (x *RecurringTransaction) GetActive() bool {
+	if x != nil {
+		return x.Active
+	}
+	return false
+}
+
+func (x *RecurringTransaction) GetTemplate() *Transaction {
+	if x != nil {
+		return x.Template
+	}
+	return nil
+}
+
+type RecurringTransactionList struct {
+	state                 protoimpl.MessageState  `protogen:"open.v1"`
+	RecurringTransactions []*RecurringTransaction `protobuf:"bytes,1,rep,name=RecurringTransactions,proto3" json:"RecurringTransactions,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *RecurringTransactionList) Reset() {
+	*x = RecurringTransactionList{}
+	mi := &file_inventory_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecurringTransactionList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecurringTransactionList) ProtoMessage() {}
+
+func (x *RecurringTransactionList) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecurringTransactionList.ProtoReflect.Descriptor instead.
+func (*RecurringTransactionList) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *RecurringTransactionList) GetRecurringTransactions() []*RecurringTransaction {
+	if x != nil {
+		return x.RecurringTransactions
+	}
+	return nil
+}
+
+// empty TransactionUUID means not posted yet
+type RecurringOccurrence struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	RecurringUUID   []byte                 `protobuf:"bytes,1,opt,name=RecurringUUID,proto3" json:"RecurringUUID,omitempty"`
+	DatetimeMs      int64                  `protobuf:"zigzag64,2,opt,name=DatetimeMs,proto3" json:"DatetimeMs,omitempty"`
+	TransactionUUID []byte                 `protobuf:"bytes,3,opt,name=TransactionUUID,proto3" json:"TransactionUUID,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *RecurringOccurrence) Reset() {
+	*x = RecurringOccurrence{}
+	mi := &file_inventory_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecurringOccurrence) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecurringOccurrence) ProtoMessage() {}
+
+func (x *RecurringOccurrence) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecurringOccurrence.ProtoReflect.Descriptor instead.
+func (*RecurringOccurrence) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *RecurringOccurrence) GetRecurringUUID() []byte {
+	if x != nil {
+		return x.RecurringUUID
+	}
+	return nil
+}
+
+func (x *RecurringOccurrence) GetDatetimeMs() int64 {
+	if x != nil {
+		return x.DatetimeMs
+	}
+	return 0
+}
+
+func (x *RecurringOccurrence) GetTransactionUUID() []byte {
+	if x != nil {
+		return x.TransactionUUID
+	}
+	return nil
+}
+
+type RecurringOccurrenceList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Occurrences   []*RecurringOccurrence `protobuf:"bytes,1,rep,name=Occurrences,proto3" json:"Occurrences,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecurringOccurrenceList) Reset() {
+	*x = RecurringOccurrenceList{}
+	mi := &file_inventory_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecurringOccurrenceList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecurringOccurrenceList) ProtoMessage() {}
+
+func (x *RecurringOccurrenceList) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecurringOccurrenceList.ProtoReflect.Descriptor instead.
+func (*RecurringOccurrenceList) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *RecurringOccurrenceList) GetOccurrences() []*RecurringOccurrence {
+	if x != nil {
+		return x.Occurrences
+	}
+	return nil
+}
+
+type TemplateParam struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=Name,proto3" json:"Name,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=Type,proto3" json:"Type,omitempty"`
+	Default       string                 `protobuf:"bytes,3,opt,name=Default,proto3" json:"Default,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TemplateParam) Reset() {
+	*x = TemplateParam{}
+	mi := &file_inventory_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TemplateParam) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TemplateParam) ProtoMessage() {}
+
+func (x *TemplateParam) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TemplateParam.ProtoReflect.Descriptor instead.
+func (*TemplateParam) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *TemplateParam) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *TemplateParam) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *TemplateParam) GetDefault() string {
+	if x != nil {
+		return x.Default
+	}
+	return ""
+}
+
+type TemplateLine struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Account       string                 `protobuf:"bytes,1,opt,name=Account,proto3" json:"Account,omitempty"`
+	Item          string                 `protobuf:"bytes,2,opt,name=Item,proto3" json:"Item,omitempty"`
+	Quantity      string                 `protobuf:"bytes,3,opt,name=Quantity,proto3" json:"Quantity,omitempty"`
+	Unit          string                 `protobuf:"bytes,4,opt,name=Unit,proto3" json:"Unit,omitempty"`
+	Price         string                 `protobuf:"bytes,5,opt,name=Price,proto3" json:"Price,omitempty"`
+	Currency      string                 `protobuf:"bytes,6,opt,name=Currency,proto3" json:"Currency,omitempty"`
+	Note          string                 `protobuf:"bytes,7,opt,name=Note,proto3" json:"Note,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TemplateLine) Reset() {
+	*x = TemplateLine{}
+	mi := &file_inventory_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TemplateLine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TemplateLine) ProtoMessage() {}
+
+func (x *TemplateLine) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TemplateLine.ProtoReflect.Descriptor instead.
+func (*TemplateLine) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *TemplateLine) GetAccount() string {
+	if x != nil {
+		return x.Account
+	}
+	return ""
+}
+
+func (x *TemplateLine) GetItem() string {
+	if x != nil {
+		return x.Item
+	}
+	return ""
+}
+
+func (x *TemplateLine) GetQuantity() string {
+	if x != nil {
+		return x.Quantity
+	}
+	return ""
+}
+
+func (x *TemplateLine) GetUnit() string {
+	if x != nil {
+		return x.Unit
+	}
+	return ""
+}
+
+func (x *TemplateLine) GetPrice() string {
+	if x != nil {
+		return x.Price
+	}
+	return ""
+}
+
+func (x *TemplateLine) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *TemplateLine) GetNote() string {
+	if x != nil {
+		return x.Note
+	}
+	return ""
+}
+
+type TransactionTemplate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UUID          []byte                 `protobuf:"bytes,1,opt,name=UUID,proto3" json:"UUID,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=Name,proto3" json:"Name,omitempty"`
+	Description   string                 `protobuf:"bytes,3,opt,name=Description,proto3" json:"Description,omitempty"`
+	Datetime      string                 `protobuf:"bytes,4,opt,name=Datetime,proto3" json:"Datetime,omitempty"`
+	Params        []*TemplateParam       `protobuf:"bytes,5,rep,name=Params,proto3" json:"Params,omitempty"`
+	Lines         []*TemplateLine        `protobuf:"bytes,6,rep,name=Lines,proto3" json:"Lines,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransactionTemplate) Reset() {
+	*x = TransactionTemplate{}
+	mi := &file_inventory_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactionTemplate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionTemplate) ProtoMessage() {}
+
+func (x *TransactionTemplate) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionTemplate.ProtoReflect.Descriptor instead.
+func (*TransactionTemplate) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *TransactionTemplate) GetUUID() []byte {
+	if x != nil {
+		return x.UUID
+	}
+	return nil
+}
+
+func (x *TransactionTemplate) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *TransactionTemplate) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *TransactionTemplate) GetDatetime() string {
+	if x != nil {
+		return x.Datetime
+	}
+	return ""
+}
+
+func (x *TransactionTemplate) GetParams() []*TemplateParam {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+func (x *TransactionTemplate) GetLines() []*TemplateLine {
+	if x != nil {
+		return x.Lines
+	}
+	return nil
+}
+
+type TransactionTemplateList struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	TransactionTemplates []*TransactionTemplate `protobuf:"bytes,1,rep,name=TransactionTemplates,proto3" json:"TransactionTemplates,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *TransactionTemplateList) Reset() {
+	*x = TransactionTemplateList{}
+	mi := &file_inventory_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactionTemplateList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionTemplateList) ProtoMessage() {}
+
+func (x *TransactionTemplateList) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionTemplateList.ProtoReflect.Descriptor instead.
+func (*TransactionTemplateList) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *TransactionTemplateList) GetTransactionTemplates() []*TransactionTemplate {
+	if x != nil {
+		return x.TransactionTemplates
+	}
+	return nil
+}
+
+// the template is looked up by TemplateUUID, or by TemplateName when empty
+type TemplateInstantiation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TemplateUUID  []byte                 `protobuf:"bytes,1,opt,name=TemplateUUID,proto3" json:"TemplateUUID,omitempty"`
+	TemplateName  string                 `protobuf:"bytes,2,opt,name=TemplateName,proto3" json:"TemplateName,omitempty"`
+	Args          map[string]string      `protobuf:"bytes,3,rep,name=Args,proto3" json:"Args,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TemplateInstantiation) Reset() {
+	*x = TemplateInstantiation{}
+	mi := &file_inventory_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TemplateInstantiation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TemplateInstantiation) ProtoMessage() {}
+
+func (x *TemplateInstantiation) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TemplateInstantiation.ProtoReflect.Descriptor instead.
+func (*TemplateInstantiation) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *TemplateInstantiation) GetTemplateUUID() []byte {
+	if x != nil {
+		return x.TemplateUUID
+	}
+	return nil
+}
+
+func (x *TemplateInstantiation) GetTemplateName() string {
+	if x != nil {
+		return x.TemplateName
+	}
+	return ""
+}
+
+func (x *TemplateInstantiation) GetArgs() map[string]string {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+type Packet struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UUID          []byte                 `protobuf:"bytes,1,opt,name=UUID,proto3" json:"UUID,omitempty"`
+	Type          int32                  `protobuf:"zigzag32,2,opt,name=Type,proto3" json:"Type,omitempty"`
+	Meta          map[string][]byte      `protobuf:"bytes,3,rep,name=Meta,proto3" json:"Meta,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Body          map[string][]byte      `protobuf:"bytes,4,rep,name=Body,proto3" json:"Body,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Packet) Reset() {
+	*x = Packet{}
+	mi := &file_inventory_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Packet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Packet) ProtoMessage() {}
+
+func (x *Packet) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Packet.ProtoReflect.Descriptor instead.
+func (*Packet) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *Packet) GetUUID() []byte {
+	if x != nil {
+		return x.UUID
+	}
+	return nil
+}
+
+func (x *Packet) GetType() int32 {
+	if x != nil {
+		return x.Type
+	}
+	return 0
+}
+
+func (x *Packet) GetMeta() map[string][]byte {
+	if x != nil {
+		return x.Meta
+	}
+	return nil
+}
+
+func (x *Packet) GetBody() map[string][]byte {
+	if x != nil {
+		return x.Body
+	}
+	return nil
+}
+
+type MapOfBytes struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Content       map[string][]byte      `protobuf:"bytes,1,rep,name=content,proto3" json:"content,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MapOfBytes) Reset() {
+	*x = MapOfBytes{}
+	mi := &file_inventory_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MapOfBytes) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MapOfBytes) ProtoMessage() {}
+
+func (x *MapOfBytes) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MapOfBytes.ProtoReflect.Descriptor instead.
+func (*MapOfBytes) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *MapOfBytes) GetContent() map[string][]byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+type UUID struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UUID          []byte                 `protobuf:"bytes,1,opt,name=UUID,proto3" json:"UUID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UUID) Reset() {
+	*x = UUID{}
+	mi := &file_inventory_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UUID) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UUID) ProtoMessage() {}
+
+func (x *UUID) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UUID.ProtoReflect.Descriptor instead.
+func (*UUID) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *UUID) GetUUID() []byte {
+	if x != nil {
+		return x.UUID
+	}
+	return nil
+}
+
+type Count struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Count         int32                  `protobuf:"zigzag32,1,opt,name=Count,proto3" json:"Count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Count) Reset() {
+	*x = Count{}
+	mi := &file_inventory_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Count) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Count) ProtoMessage() {}
+
+func (x *Count) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Count.ProtoReflect.Descriptor instead.
+func (*Count) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *Count) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type Report struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=Text,proto3" json:"Text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Report) Reset() {
+	*x = Report{}
+	mi := &file_inventory_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Report) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Report) ProtoMessage() {}
+
+func (x *Report) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Report.ProtoReflect.Descriptor instead.
+func (*Report) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *Report) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+type ReportLine struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Line          string                 `protobuf:"bytes,1,opt,name=Line,proto3" json:"Line,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportLine) Reset() {
+	*x = ReportLine{}
+	mi := &file_inventory_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportLine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportLine) ProtoMessage() {}
+
+func (x *ReportLine) ProtoReflect() protoreflect.Message {
+	mi := &file_inventory_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportLine.ProtoReflect.Descriptor instead.
+func (*ReportLine) Descriptor() ([]byte, []int) {
+	return file_inventory_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *ReportLine) GetLine() string {
+	if x != nil {
+		return x.Line
+	}
+	return ""
+}
+
+var File_inventory_proto protoreflect.FileDescriptor
+
+const file_inventory_proto_rawDesc = "" +
+	"\n" +
+	"\x0finventory.proto\x12\vinventorypb\x1a\x1bgoogle/protobuf/empty.proto\"\x9b\x01\n" +
+	"\aAccount\x12\x12\n" +
+	"\x04UUID\x18\x01 \x01(\fR\x04UUID\x12\x12\n" +
+	"\x04Name\x18\x02 \x01(\tR\x04Name\x12\x1e\n" +
+	"\n" +
+	"ParentUUID\x18\x03 \x01(\fR\n" +
+	"ParentUUID\x12H\n" +
+	"\x10TransactionLines\x18\x04 \x03(\v2\x1c.inventorypb.TransactionLineR\x10TransactionLines\"\xae\x01\n" +
+	"\x04Item\x12\x12\n" +
+	"\x04UUID\x18\x01 \x01(\fR\x04UUID\x12\x12\n" +
+	"\x04Name\x18\x02 \x01(\tR\x04Name\x12 \n" +
+	"\vDescription\x18\x03 \x01(\tR\vDescription\x12\x12\n" +
+	"\x04Unit\x18\x04 \x01(\tR\x04Unit\x12H\n" +
+	"\x10TransactionLines\x18\x05 \x03(\v2\x1c.inventorypb.TransactionLineR\x10TransactionLines\"\xcb\x01\n" +
+	"\vTransaction\x12\x12\n" +
+	"\x04UUID\x18\x01 \x01(\fR\x04UUID\x12 \n" +
+	"\vDescription\x18\x02 \x01(\tR\vDescription\x12\x1e\n" +
+	"\n" +
+	"DatetimeMs\x18\x03 \x01(\x12R\n" +
+	"DatetimeMs\x12H\n" +
+	"\x10TransactionLines\x18\x04 \x03(\v2\x1c.inventorypb.TransactionLineR\x10TransactionLines\x12\x1c\n" +
+	"\tPartyUUID\x18\x05 \x01(\fR\tPartyUUID\"\xc3\x02\n" +
+	"\x0fTransactionLine\x12\x12\n" +
+	"\x04UUID\x18\x01 \x01(\fR\x04UUID\x12(\n" +
+	"\x0fTransactionUUID\x18\x02 \x01(\fR\x0fTransactionUUID\x12 \n" +
+	"\vAccountUUID\x18\x03 \x01(\fR\vAccountUUID\x12\x1a\n" +
+	"\bItemUUID\x18\x04 \x01(\fR\bItemUUID\x12\x1a\n" +
+	"\bQuantity\x18\x05 \x01(\x12R\bQuantity\x12\x12\n" +
+	"\x04Unit\x18\x06 \x01(\tR\x04Unit\x12\x14\n" +
+	"\x05Price\x18\a \x01(\x12R\x05Price\x12\x1a\n" +
+	"\bCurrency\x18\b \x01(\tR\bCurrency\x12\x12\n" +
+	"\x04Note\x18\t \x01(\tR\x04Note\x12 \n" +
+	"\vTaxCodeUUID\x18\n" +
+	" \x01(\fR\vTaxCodeUUID\x12\x1c\n" +
+	"\tPartyUUID\x18\v \x01(\fR\tPartyUUID\"\xb4\x01\n" +
+	"\x18BalanceHistoryReferences\x120\n" +
+	"\x13TransactionLineUUID\x18\x01 \x01(\fR\x13TransactionLineUUID\x12(\n" +
+	"\x0fTransactionUUID\x18\x02 \x01(\fR\x0fTransactionUUID\x12 \n" +
+	"\vAccountUUID\x18\x03 \x01(\fR\vAccountUUID\x12\x1a\n" +
+	"\bItemUUID\x18\x04 \x01(\fR\bItemUUID\"\xad\x03\n" +
+	"\x0eBalanceHistory\x12\x12\n" +
+	"\x04UUID\x18\x01 \x01(\fR\x04UUID\x12\x12\n" +
+	"\x04Path\x18\x02 \x03(\tR\x04Path\x12E\n" +
+	"\n" +
+	"references\x18\x03 \x01(\v2%.inventorypb.BalanceHistoryReferencesR\n" +
+	"references\x12\x12\n" +
+	"\x04Unit\x18\x04 \x01(\fR\x04Unit\x12\x1a\n" +
+	"\bQuantity\x18\x05 \x01(\x12R\bQuantity\x12\x18\n" +
+	"\aAvgCost\x18\x06 \x01(\x12R\aAvgCost\x12\x14\n" +
+	"\x05Value\x18\a \x01(\x12R\x05Value\x12\x1e\n" +
+	"\n" +
+	"DatetimeMs\x18\b \x01(\x12R\n" +
+	"DatetimeMs\x12*\n" +
+	"\x10TransactionPrice\x18\t \x01(\x12R\x10TransactionPrice\x12 \n" +
+	"\vMarketPrice\x18\n" +
 	" \x01(\x12R\vMarketPrice\x12\x1a\n" +
 	"\bCurrency\x18\v \x01(\tR\bCurrency\x12 \n" +
 	"\vMarketValue\x18\f \x01(\x12R\vMarketValue\x12 \n" +
@@ -955,7 +2300,95 @@ const file_inventory_proto_rawDesc = "" +
 	"DatetimeMs\x12\x14\n" +
 	"\x05Price\x18\x03 \x01(\x12R\x05Price\x12\x12\n" +
 	"\x04Unit\x18\x04 \x01(\tR\x04Unit\x12\x1a\n" +
-	"\bCurrency\x18\x05 \x01(\tR\bCurrency\"\x88\x02\n" +
+	"\bCurrency\x18\x05 \x01(\tR\bCurrency\"O\n" +
+	"\x0fMarketPriceList\x12<\n" +
+	"\fMarketPrices\x18\x01 \x03(\v2\x18.inventorypb.MarketPriceR\fMarketPrices\"?\n" +
+	"\x11MarketPriceImport\x12\x16\n" +
+	"\x06Format\x18\x01 \x01(\tR\x06Format\x12\x12\n" +
+	"\x04Data\x18\x02 \x01(\fR\x04Data\"\x81\x01\n" +
+	"\x17MarketPriceHistoryQuery\x12\x1a\n" +
+	"\bItemUUID\x18\x01 \x01(\fR\bItemUUID\x12&\n" +
+	"\x0eFromDatetimeMs\x18\x02 \x01(\x12R\x0eFromDatetimeMs\x12\"\n" +
+	"\fToDatetimeMs\x18\x03 \x01(\x12R\fToDatetimeMs\"\xdd\x01\n" +
+	"\aTaxCode\x12\x12\n" +
+	"\x04UUID\x18\x01 \x01(\fR\x04UUID\x12\x12\n" +
+	"\x04Code\x18\x02 \x01(\tR\x04Code\x12\x12\n" +
+	"\x04Name\x18\x03 \x01(\tR\x04Name\x12\x12\n" +
+	"\x04Rate\x18\x04 \x01(\x12R\x04Rate\x12\x1c\n" +
+	"\tInclusive\x18\x05 \x01(\bR\tInclusive\x12.\n" +
+	"\x12PayableAccountUUID\x18\x06 \x01(\fR\x12PayableAccountUUID\x124\n" +
+	"\x15ReceivableAccountUUID\x18\a \x01(\fR\x15ReceivableAccountUUID\"Y\n" +
+	"\vPeriodQuery\x12&\n" +
+	"\x0eFromDatetimeMs\x18\x01 \x01(\x12R\x0eFromDatetimeMs\x12\"\n" +
+	"\fToDatetimeMs\x18\x02 \x01(\x12R\fToDatetimeMs\"C\n" +
+	"\x05Party\x12\x12\n" +
+	"\x04UUID\x18\x01 \x01(\fR\x04UUID\x12\x12\n" +
+	"\x04Name\x18\x02 \x01(\tR\x04Name\x12\x12\n" +
+	"\x04Kind\x18\x03 \x01(\tR\x04Kind\"v\n" +
+	"\fPartyBalance\x12\x1c\n" +
+	"\tPartyUUID\x18\x01 \x01(\fR\tPartyUUID\x12\x12\n" +
+	"\x04Side\x18\x02 \x01(\tR\x04Side\x12\x1a\n" +
+	"\bCurrency\x18\x03 \x01(\tR\bCurrency\x12\x18\n" +
+	"\aBalance\x18\x04 \x01(\x12R\aBalance\"S\n" +
+	"\x10PartyBalanceList\x12?\n" +
+	"\rPartyBalances\x18\x01 \x03(\v2\x19.inventorypb.PartyBalanceR\rPartyBalances\"v\n" +
+	"\n" +
+	"PartyQuery\x12\x1c\n" +
+	"\tPartyUUID\x18\x01 \x01(\fR\tPartyUUID\x12&\n" +
+	"\x0eFromDatetimeMs\x18\x02 \x01(\x12R\x0eFromDatetimeMs\x12\"\n" +
+	"\fToDatetimeMs\x18\x03 \x01(\x12R\fToDatetimeMs\"\xc6\x02\n" +
+	"\x14RecurringTransaction\x12\x12\n" +
+	"\x04UUID\x18\x01 \x01(\fR\x04UUID\x12\x12\n" +
+	"\x04Name\x18\x02 \x01(\tR\x04Name\x12\x1a\n" +
+	"\bSchedule\x18\x03 \x01(\tR\bSchedule\x12\x1e\n" +
+	"\n" +
+	"DayOfMonth\x18\x04 \x01(\x11R\n" +
+	"DayOfMonth\x12\x18\n" +
+	"\aWeekday\x18\x05 \x01(\x11R\aWeekday\x12\x12\n" +
+	"\x04Cron\x18\x06 \x01(\tR\x04Cron\x12(\n" +
+	"\x0fStartDatetimeMs\x18\a \x01(\x12R\x0fStartDatetimeMs\x12$\n" +
+	"\rEndDatetimeMs\x18\b \x01(\x12R\rEndDatetimeMs\x12\x16\n" +
+	"\x06Active\x18\t \x01(\bR\x06Active\x124\n" +
+	"\bTemplate\x18\n" +
+	" \x01(\v2\x18.inventorypb.TransactionR\bTemplate\"s\n" +
+	"\x18RecurringTransactionList\x12W\n" +
+	"\x15RecurringTransactions\x18\x01 \x03(\v2!.inventorypb.RecurringTransactionR\x15RecurringTransactions\"\x85\x01\n" +
+	"\x13RecurringOccurrence\x12$\n" +
+	"\rRecurringUUID\x18\x01 \x01(\fR\rRecurringUUID\x12\x1e\n" +
+	"\n" +
+	"DatetimeMs\x18\x02 \x01(\x12R\n" +
+	"DatetimeMs\x12(\n" +
+	"\x0fTransactionUUID\x18\x03 \x01(\fR\x0fTransactionUUID\"]\n" +
+	"\x17RecurringOccurrenceList\x12B\n" +
+	"\vOccurrences\x18\x01 \x03(\v2 .inventorypb.RecurringOccurrenceR\vOccurrences\"Q\n" +
+	"\rTemplateParam\x12\x12\n" +
+	"\x04Name\x18\x01 \x01(\tR\x04Name\x12\x12\n" +
+	"\x04Type\x18\x02 \x01(\tR\x04Type\x12\x18\n" +
+	"\aDefault\x18\x03 \x01(\tR\aDefault\"\xb2\x01\n" +
+	"\fTemplateLine\x12\x18\n" +
+	"\aAccount\x18\x01 \x01(\tR\aAccount\x12\x12\n" +
+	"\x04Item\x18\x02 \x01(\tR\x04Item\x12\x1a\n" +
+	"\bQuantity\x18\x03 \x01(\tR\bQuantity\x12\x12\n" +
+	"\x04Unit\x18\x04 \x01(\tR\x04Unit\x12\x14\n" +
+	"\x05Price\x18\x05 \x01(\tR\x05Price\x12\x1a\n" +
+	"\bCurrency\x18\x06 \x01(\tR\bCurrency\x12\x12\n" +
+	"\x04Note\x18\a \x01(\tR\x04Note\"\xe0\x01\n" +
+	"\x13TransactionTemplate\x12\x12\n" +
+	"\x04UUID\x18\x01 \x01(\fR\x04UUID\x12\x12\n" +
+	"\x04Name\x18\x02 \x01(\tR\x04Name\x12 \n" +
+	"\vDescription\x18\x03 \x01(\tR\vDescription\x12\x1a\n" +
+	"\bDatetime\x18\x04 \x01(\tR\bDatetime\x122\n" +
+	"\x06Params\x18\x05 \x03(\v2\x1a.inventorypb.TemplateParamR\x06Params\x12/\n" +
+	"\x05Lines\x18\x06 \x03(\v2\x19.inventorypb.TemplateLineR\x05Lines\"o\n" +
+	"\x17TransactionTemplateList\x12T\n" +
+	"\x14TransactionTemplates\x18\x01 \x03(\v2 .inventorypb.TransactionTemplateR\x14TransactionTemplates\"\xda\x01\n" +
+	"\x15TemplateInstantiation\x12\"\n" +
+	"\fTemplateUUID\x18\x01 \x01(\fR\fTemplateUUID\x12\"\n" +
+	"\fTemplateName\x18\x02 \x01(\tR\fTemplateName\x12@\n" +
+	"\x04Args\x18\x03 \x03(\v2,.inventorypb.TemplateInstantiation.ArgsEntryR\x04Args\x1a7\n" +
+	"\tArgsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x88\x02\n" +
 	"\x06Packet\x12\x12\n" +
 	"\x04UUID\x18\x01 \x01(\fR\x04UUID\x12\x12\n" +
 	"\x04Type\x18\x02 \x01(\x11R\x04Type\x121\n" +
@@ -972,7 +2405,53 @@ const file_inventory_proto_rawDesc = "" +
 	"\acontent\x18\x01 \x03(\v2$.inventorypb.MapOfBytes.ContentEntryR\acontent\x1a:\n" +
 	"\fContentEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\fR\x05value:\x028\x01B\x17Z\x15inventory/inventorypbb\x06proto3"
+	"\x05value\x18\x02 \x01(\fR\x05value:\x028\x01\"\x1a\n" +
+	"\x04UUID\x12\x12\n" +
+	"\x04UUID\x18\x01 \x01(\fR\x04UUID\"\x1d\n" +
+	"\x05Count\x12\x14\n" +
+	"\x05Count\x18\x01 \x01(\x11R\x05Count\"\x1c\n" +
+	"\x06Report\x12\x12\n" +
+	"\x04Text\x18\x01 \x01(\tR\x04Text\" \n" +
+	"\n" +
+	"ReportLine\x12\x12\n" +
+	"\x04Line\x18\x01 \x01(\tR\x04Line2\xc6\x13\n" +
+	"\tInventory\x126\n" +
+	"\tGetCurrDB\x12\x16.google.protobuf.Empty\x1a\x11.inventorypb.UUID\x126\n" +
+	"\x0eOpenOrCreateDB\x12\x11.inventorypb.UUID\x1a\x11.inventorypb.UUID\x12/\n" +
+	"\aAddItem\x12\x11.inventorypb.Item\x1a\x11.inventorypb.UUID\x125\n" +
+	"\n" +
+	"AddAccount\x12\x14.inventorypb.Account\x1a\x11.inventorypb.UUID\x12?\n" +
+	"\x10ApplyTransaction\x12\x18.inventorypb.Transaction\x1a\x11.inventorypb.UUID\x12B\n" +
+	"\x0fGetMainAccounts\x12\x16.google.protobuf.Empty\x1a\x17.inventorypb.MapOfBytes\x12E\n" +
+	"\x11UpdateMarketPrice\x12\x18.inventorypb.MarketPrice\x1a\x16.google.protobuf.Empty\x12H\n" +
+	"\x12ImportMarketPrices\x12\x1e.inventorypb.MarketPriceImport\x1a\x12.inventorypb.Count\x12[\n" +
+	"\x15GetMarketPriceHistory\x12$.inventorypb.MarketPriceHistoryQuery\x1a\x1c.inventorypb.MarketPriceList\x125\n" +
+	"\n" +
+	"AddTaxCode\x12\x14.inventorypb.TaxCode\x1a\x11.inventorypb.UUID\x12@\n" +
+	"\x0fPrintTaxSummary\x12\x18.inventorypb.PeriodQuery\x1a\x13.inventorypb.Report\x121\n" +
+	"\bAddParty\x12\x12.inventorypb.Party\x1a\x11.inventorypb.UUID\x12J\n" +
+	"\x10GetPartyBalances\x12\x17.inventorypb.PartyQuery\x1a\x1d.inventorypb.PartyBalanceList\x12@\n" +
+	"\x10PrintAgingReport\x12\x17.inventorypb.PartyQuery\x1a\x13.inventorypb.Report\x12C\n" +
+	"\x13PrintPartyStatement\x12\x17.inventorypb.PartyQuery\x1a\x13.inventorypb.Report\x12O\n" +
+	"\x17AddRecurringTransaction\x12!.inventorypb.RecurringTransaction\x1a\x11.inventorypb.UUID\x12Y\n" +
+	"\x18GetRecurringTransactions\x12\x16.google.protobuf.Empty\x1a%.inventorypb.RecurringTransactionList\x12Z\n" +
+	"\x1dSetRecurringTransactionActive\x12!.inventorypb.RecurringTransaction\x1a\x16.google.protobuf.Empty\x12^\n" +
+	"\x1cPreviewRecurringTransactions\x12\x18.inventorypb.PeriodQuery\x1a$.inventorypb.RecurringOccurrenceList\x12Z\n" +
+	"\x18RunRecurringTransactions\x12\x18.inventorypb.PeriodQuery\x1a$.inventorypb.RecurringOccurrenceList\x12M\n" +
+	"\x16AddTransactionTemplate\x12 .inventorypb.TransactionTemplate\x1a\x11.inventorypb.UUID\x12W\n" +
+	"\x17GetTransactionTemplates\x12\x16.google.protobuf.Empty\x1a$.inventorypb.TransactionTemplateList\x12Q\n" +
+	"\x18ApplyTransactionTemplate\x12\".inventorypb.TemplateInstantiation\x1a\x11.inventorypb.UUID\x12<\n" +
+	"\rPrintBalances\x12\x16.google.protobuf.Empty\x1a\x13.inventorypb.Report\x12B\n" +
+	"\x13PrintMarketBalances\x12\x16.google.protobuf.Empty\x1a\x13.inventorypb.Report\x12=\n" +
+	"\vCloseCurrDB\x12\x16.google.protobuf.Empty\x1a\x16.google.protobuf.Empty\x12\\\n" +
+	"\x18StreamMarketPriceHistory\x12$.inventorypb.MarketPriceHistoryQuery\x1a\x18.inventorypb.MarketPrice0\x01\x12K\n" +
+	"\x13StreamPartyBalances\x12\x17.inventorypb.PartyQuery\x1a\x19.inventorypb.PartyBalance0\x01\x12V\n" +
+	"\x16StreamRecurringPreview\x12\x18.inventorypb.PeriodQuery\x1a .inventorypb.RecurringOccurrence0\x01\x12C\n" +
+	"\x0eStreamBalances\x12\x16.google.protobuf.Empty\x1a\x17.inventorypb.ReportLine0\x01\x12I\n" +
+	"\x14StreamMarketBalances\x12\x16.google.protobuf.Empty\x1a\x17.inventorypb.ReportLine0\x01\x12G\n" +
+	"\x10StreamTaxSummary\x12\x18.inventorypb.PeriodQuery\x1a\x17.inventorypb.ReportLine0\x01\x12G\n" +
+	"\x11StreamAgingReport\x12\x17.inventorypb.PartyQuery\x1a\x17.inventorypb.ReportLine0\x01\x12J\n" +
+	"\x14StreamPartyStatement\x12\x17.inventorypb.PartyQuery\x1a\x17.inventorypb.ReportLine0\x01B\x17Z\x15inventory/inventorypbb\x06proto3"
 
 var (
 	file_inventory_proto_rawDescOnce sync.Once
@@ -986,7 +2465,7 @@ func file_inventory_proto_rawDescGZIP() []byte {
 	return file_inventory_proto_rawDescData
 }
 
-var file_inventory_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
+var file_inventory_proto_msgTypes = make([]protoimpl.MessageInfo, 37)
 var file_inventory_proto_goTypes = []any{
 	(*Account)(nil),                  // 0: inventorypb.Account
 	(*Item)(nil),                     // 1: inventorypb.Item
@@ -997,25 +2476,126 @@ var file_inventory_proto_goTypes = []any{
 	(*UnitConversions)(nil),          // 6: inventorypb.UnitConversions
 	(*CurrencyConversions)(nil),      // 7: inventorypb.CurrencyConversions
 	(*MarketPrice)(nil),              // 8: inventorypb.MarketPrice
-	(*Packet)(nil),                   // 9: inventorypb.Packet
-	(*MapOfBytes)(nil),               // 10: inventorypb.MapOfBytes
-	nil,                              // 11: inventorypb.Packet.MetaEntry
-	nil,                              // 12: inventorypb.Packet.BodyEntry
-	nil,                              // 13: inventorypb.MapOfBytes.ContentEntry
+	(*MarketPriceList)(nil),          // 9: inventorypb.MarketPriceList
+	(*MarketPriceImport)(nil),        // 10: inventorypb.MarketPriceImport
+	(*MarketPriceHistoryQuery)(nil),  // 11: inventorypb.MarketPriceHistoryQuery
+	(*TaxCode)(nil),                  // 12: inventorypb.TaxCode
+	(*PeriodQuery)(nil),              // 13: inventorypb.PeriodQuery
+	(*Party)(nil),                    // 14: inventorypb.Party
+	(*PartyBalance)(nil),             // 15: inventorypb.PartyBalance
+	(*PartyBalanceList)(nil),         // 16: inventorypb.PartyBalanceList
+	(*PartyQuery)(nil),               // 17: inventorypb.PartyQuery
+	(*RecurringTransaction)(nil),     // 18: inventorypb.RecurringTransaction
+	(*RecurringTransactionList)(nil), // 19: inventorypb.RecurringTransactionList
+	(*RecurringOccurrence)(nil),      // 20: inventorypb.RecurringOccurrence
+	(*RecurringOccurrenceList)(nil),  // 21: inventorypb.RecurringOccurrenceList
+	(*TemplateParam)(nil),            // 22: inventorypb.TemplateParam
+	(*TemplateLine)(nil),             // 23: inventorypb.TemplateLine
+	(*TransactionTemplate)(nil),      // 24: inventorypb.TransactionTemplate
+	(*TransactionTemplateList)(nil),  // 25: inventorypb.TransactionTemplateList
+	(*TemplateInstantiation)(nil),    // 26: inventorypb.TemplateInstantiation
+	(*Packet)(nil),                   // 27: inventorypb.Packet
+	(*MapOfBytes)(nil),               // 28: inventorypb.MapOfBytes
+	(*UUID)(nil),                     // 29: inventorypb.UUID
+	(*Count)(nil),                    // 30: inventorypb.Count
+	(*Report)(nil),                   // 31: inventorypb.Report
+	(*ReportLine)(nil),               // 32: inventorypb.ReportLine
+	nil,                              // 33: inventorypb.TemplateInstantiation.ArgsEntry
+	nil,                              // 34: inventorypb.Packet.MetaEntry
+	nil,                              // 35: inventorypb.Packet.BodyEntry
+	nil,                              // 36: inventorypb.MapOfBytes.ContentEntry
+	(*emptypb.Empty)(nil),            // 37: google.protobuf.Empty
 }
 var file_inventory_proto_depIdxs = []int32{
 	3,  // 0: inventorypb.Account.TransactionLines:type_name -> inventorypb.TransactionLine
 	3,  // 1: inventorypb.Item.TransactionLines:type_name -> inventorypb.TransactionLine
 	3,  // 2: inventorypb.Transaction.TransactionLines:type_name -> inventorypb.TransactionLine
 	4,  // 3: inventorypb.BalanceHistory.references:type_name -> inventorypb.BalanceHistoryReferences
-	11, // 4: inventorypb.Packet.Meta:type_name -> inventorypb.Packet.MetaEntry
-	12, // 5: inventorypb.Packet.Body:type_name -> inventorypb.Packet.BodyEntry
-	13, // 6: inventorypb.MapOfBytes.content:type_name -> inventorypb.MapOfBytes.ContentEntry
-	7,  // [7:7] is the sub-list for method output_type
-	7,  // [7:7] is the sub-list for method input_type
-	7,  // [7:7] is the sub-list for extension type_name
-	7,  // [7:7] is the sub-list for extension extendee
-	0,  // [0:7] is the sub-list for field type_name
+	8,  // 4: inventorypb.MarketPriceList.MarketPrices:type_name -> inventorypb.MarketPrice
+	15, // 5: inventorypb.PartyBalanceList.PartyBalances:type_name -> inventorypb.PartyBalance
+	2,  // 6: inventorypb.RecurringTransaction.Template:type_name -> inventorypb.Transaction
+	18, // 7: inventorypb.RecurringTransactionList.RecurringTransactions:type_name -> inventorypb.RecurringTransaction
+	20, // 8: inventorypb.RecurringOccurrenceList.Occurrences:type_name -> inventorypb.RecurringOccurrence
+	22, // 9: inventorypb.TransactionTemplate.Params:type_name -> inventorypb.TemplateParam
+	23, // 10: inventorypb.TransactionTemplate.Lines:type_name -> inventorypb.TemplateLine
+	24, // 11: inventorypb.TransactionTemplateList.TransactionTemplates:type_name -> inventorypb.TransactionTemplate
+	33, // 12: inventorypb.TemplateInstantiation.Args:type_name -> inventorypb.TemplateInstantiation.ArgsEntry
+	34, // 13: inventorypb.Packet.Meta:type_name -> inventorypb.Packet.MetaEntry
+	35, // 14: inventorypb.Packet.Body:type_name -> inventorypb.Packet.BodyEntry
+	36, // 15: inventorypb.MapOfBytes.content:type_name -> inventorypb.MapOfBytes.ContentEntry
+	37, // 16: inventorypb.Inventory.GetCurrDB:input_type -> google.protobuf.Empty
+	29, // 17: inventorypb.Inventory.OpenOrCreateDB:input_type -> inventorypb.UUID
+	1,  // 18: inventorypb.Inventory.AddItem:input_type -> inventorypb.Item
+	0,  // 19: inventorypb.Inventory.AddAccount:input_type -> inventorypb.Account
+	2,  // 20: inventorypb.Inventory.ApplyTransaction:input_type -> inventorypb.Transaction
+	37, // 21: inventorypb.Inventory.GetMainAccounts:input_type -> google.protobuf.Empty
+	8,  // 22: inventorypb.Inventory.UpdateMarketPrice:input_type -> inventorypb.MarketPrice
+	10, // 23: inventorypb.Inventory.ImportMarketPrices:input_type -> inventorypb.MarketPriceImport
+	11, // 24: inventorypb.Inventory.GetMarketPriceHistory:input_type -> inventorypb.MarketPriceHistoryQuery
+	12, // 25: inventorypb.Inventory.AddTaxCode:input_type -> inventorypb.TaxCode
+	13, // 26: inventorypb.Inventory.PrintTaxSummary:input_type -> inventorypb.PeriodQuery
+	14, // 27: inventorypb.Inventory.AddParty:input_type -> inventorypb.Party
+	17, // 28: inventorypb.Inventory.GetPartyBalances:input_type -> inventorypb.PartyQuery
+	17, // 29: inventorypb.Inventory.PrintAgingReport:input_type -> inventorypb.PartyQuery
+	17, // 30: inventorypb.Inventory.PrintPartyStatement:input_type -> inventorypb.PartyQuery
+	18, // 31: inventorypb.Inventory.AddRecurringTransaction:input_type -> inventorypb.RecurringTransaction
+	37, // 32: inventorypb.Inventory.GetRecurringTransactions:input_type -> google.protobuf.Empty
+	18, // 33: inventorypb.Inventory.SetRecurringTransactionActive:input_type -> inventorypb.RecurringTransaction
+	13, // 34: inventorypb.Inventory.PreviewRecurringTransactions:input_type -> inventorypb.PeriodQuery
+	13, // 35: inventorypb.Inventory.RunRecurringTransactions:input_type -> inventorypb.PeriodQuery
+	24, // 36: inventorypb.Inventory.AddTransactionTemplate:input_type -> inventorypb.TransactionTemplate
+	37, // 37: inventorypb.Inventory.GetTransactionTemplates:input_type -> google.protobuf.Empty
+	26, // 38: inventorypb.Inventory.ApplyTransactionTemplate:input_type -> inventorypb.TemplateInstantiation
+	37, // 39: inventorypb.Inventory.PrintBalances:input_type -> google.protobuf.Empty
+	37, // 40: inventorypb.Inventory.PrintMarketBalances:input_type -> google.protobuf.Empty
+	37, // 41: inventorypb.Inventory.CloseCurrDB:input_type -> google.protobuf.Empty
+	11, // 42: inventorypb.Inventory.StreamMarketPriceHistory:input_type -> inventorypb.MarketPriceHistoryQuery
+	17, // 43: inventorypb.Inventory.StreamPartyBalances:input_type -> inventorypb.PartyQuery
+	13, // 44: inventorypb.Inventory.StreamRecurringPreview:input_type -> inventorypb.PeriodQuery
+	37, // 45: inventorypb.Inventory.StreamBalances:input_type -> google.protobuf.Empty
+	37, // 46: inventorypb.Inventory.StreamMarketBalances:input_type -> google.protobuf.Empty
+	13, // 47: inventorypb.Inventory.StreamTaxSummary:input_type -> inventorypb.PeriodQuery
+	17, // 48: inventorypb.Inventory.StreamAgingReport:input_type -> inventorypb.PartyQuery
+	17, // 49: inventorypb.Inventory.StreamPartyStatement:input_type -> inventorypb.PartyQuery
+	29, // 50: inventorypb.Inventory.GetCurrDB:output_type -> inventorypb.UUID
+	29, // 51: inventorypb.Inventory.OpenOrCreateDB:output_type -> inventorypb.UUID
+	29, // 52: inventorypb.Inventory.AddItem:output_type -> inventorypb.UUID
+	29, // 53: inventorypb.Inventory.AddAccount:output_type -> inventorypb.UUID
+	29, // 54: inventorypb.Inventory.ApplyTransaction:output_type -> inventorypb.UUID
+	28, // 55: inventorypb.Inventory.GetMainAccounts:output_type -> inventorypb.MapOfBytes
+	37, // 56: inventorypb.Inventory.UpdateMarketPrice:output_type -> google.protobuf.Empty
+	30, // 57: inventorypb.Inventory.ImportMarketPrices:output_type -> inventorypb.Count
+	9,  // 58: inventorypb.Inventory.GetMarketPriceHistory:output_type -> inventorypb.MarketPriceList
+	29, // 59: inventorypb.Inventory.AddTaxCode:output_type -> inventorypb.UUID
+	31, // 60: inventorypb.Inventory.PrintTaxSummary:output_type -> inventorypb.Report
+	29, // 61: inventorypb.Inventory.AddParty:output_type -> inventorypb.UUID
+	16, // 62: inventorypb.Inventory.GetPartyBalances:output_type -> inventorypb.PartyBalanceList
+	31, // 63: inventorypb.Inventory.PrintAgingReport:output_type -> inventorypb.Report
+	31, // 64: inventorypb.Inventory.PrintPartyStatement:output_type -> inventorypb.Report
+	29, // 65: inventorypb.Inventory.AddRecurringTransaction:output_type -> inventorypb.UUID
+	19, // 66: inventorypb.Inventory.GetRecurringTransactions:output_type -> inventorypb.RecurringTransactionList
+	37, // 67: inventorypb.Inventory.SetRecurringTransactionActive:output_type -> google.protobuf.Empty
+	21, // 68: inventorypb.Inventory.PreviewRecurringTransactions:output_type -> inventorypb.RecurringOccurrenceList
+	21, // 69: inventorypb.Inventory.RunRecurringTransactions:output_type -> inventorypb.RecurringOccurrenceList
+	29, // 70: inventorypb.Inventory.AddTransactionTemplate:output_type -> inventorypb.UUID
+	25, // 71: inventorypb.Inventory.GetTransactionTemplates:output_type -> inventorypb.TransactionTemplateList
+	29, // 72: inventorypb.Inventory.ApplyTransactionTemplate:output_type -> inventorypb.UUID
+	31, // 73: inventorypb.Inventory.PrintBalances:output_type -> inventorypb.Report
+	31, // 74: inventorypb.Inventory.PrintMarketBalances:output_type -> inventorypb.Report
+	37, // 75: inventorypb.Inventory.CloseCurrDB:output_type -> google.protobuf.Empty
+	8,  // 76: inventorypb.Inventory.StreamMarketPriceHistory:output_type -> inventorypb.MarketPrice
+	15, // 77: inventorypb.Inventory.StreamPartyBalances:output_type -> inventorypb.PartyBalance
+	20, // 78: inventorypb.Inventory.StreamRecurringPreview:output_type -> inventorypb.RecurringOccurrence
+	32, // 79: inventorypb.Inventory.StreamBalances:output_type -> inventorypb.ReportLine
+	32, // 80: inventorypb.Inventory.StreamMarketBalances:output_type -> inventorypb.ReportLine
+	32, // 81: inventorypb.Inventory.StreamTaxSummary:output_type -> inventorypb.ReportLine
+	32, // 82: inventorypb.Inventory.StreamAgingReport:output_type -> inventorypb.ReportLine
+	32, // 83: inventorypb.Inventory.StreamPartyStatement:output_type -> inventorypb.ReportLine
+	50, // [50:84] is the sub-list for method output_type
+	16, // [16:50] is the sub-list for method input_type
+	16, // [16:16] is the sub-list for extension type_name
+	16, // [16:16] is the sub-list for extension extendee
+	0,  // [0:16] is the sub-list for field type_name
 }
 
 func init() { file_inventory_proto_init() }
@@ -1029,9 +2609,9 @@ func file_inventory_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_inventory_proto_rawDesc), len(file_inventory_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   14,
+			NumMessages:   37,
 			NumExtensions: 0,
-			NumServices:   0,
+			NumServices:   1,
 		},
 		GoTypes:           file_inventory_proto_goTypes,
 		DependencyIndexes: file_inventory_proto_depIdxs,
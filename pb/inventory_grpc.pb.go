@@ -0,0 +1,1413 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v3.12.4
+// source: inventory.proto
+
+package inventorypb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Inventory_GetCurrDB_FullMethodName                     = "/inventorypb.Inventory/GetCurrDB"
+	Inventory_OpenOrCreateDB_FullMethodName                = "/inventorypb.Inventory/OpenOrCreateDB"
+	Inventory_AddItem_FullMethodName                       = "/inventorypb.Inventory/AddItem"
+	Inventory_AddAccount_FullMethodName                    = "/inventorypb.Inventory/AddAccount"
+	Inventory_ApplyTransaction_FullMethodName              = "/inventorypb.Inventory/ApplyTransaction"
+	Inventory_GetMainAccounts_FullMethodName               = "/inventorypb.Inventory/GetMainAccounts"
+	Inventory_UpdateMarketPrice_FullMethodName             = "/inventorypb.Inventory/UpdateMarketPrice"
+	Inventory_ImportMarketPrices_FullMethodName            = "/inventorypb.Inventory/ImportMarketPrices"
+	Inventory_GetMarketPriceHistory_FullMethodName         = "/inventorypb.Inventory/GetMarketPriceHistory"
+	Inventory_AddTaxCode_FullMethodName                    = "/inventorypb.Inventory/AddTaxCode"
+	Inventory_PrintTaxSummary_FullMethodName               = "/inventorypb.Inventory/PrintTaxSummary"
+	Inventory_AddParty_FullMethodName                      = "/inventorypb.Inventory/AddParty"
+	Inventory_GetPartyBalances_FullMethodName              = "/inventorypb.Inventory/GetPartyBalances"
+	Inventory_PrintAgingReport_FullMethodName              = "/inventorypb.Inventory/PrintAgingReport"
+	Inventory_PrintPartyStatement_FullMethodName           = "/inventorypb.Inventory/PrintPartyStatement"
+	Inventory_AddRecurringTransaction_FullMethodName       = "/inventorypb.Inventory/AddRecurringTransaction"
+	Inventory_GetRecurringTransactions_FullMethodName      = "/inventorypb.Inventory/GetRecurringTransactions"
+	Inventory_SetRecurringTransactionActive_FullMethodName = "/inventorypb.Inventory/SetRecurringTransactionActive"
+	Inventory_PreviewRecurringTransactions_FullMethodName  = "/inventorypb.Inventory/PreviewRecurringTransactions"
+	Inventory_RunRecurringTransactions_FullMethodName      = "/inventorypb.Inventory/RunRecurringTransactions"
+	Inventory_AddTransactionTemplate_FullMethodName        = "/inventorypb.Inventory/AddTransactionTemplate"
+	Inventory_GetTransactionTemplates_FullMethodName       = "/inventorypb.Inventory/GetTransactionTemplates"
+	Inventory_ApplyTransactionTemplate_FullMethodName      = "/inventorypb.Inventory/ApplyTransactionTemplate"
+	Inventory_PrintBalances_FullMethodName                 = "/inventorypb.Inventory/PrintBalances"
+	Inventory_PrintMarketBalances_FullMethodName           = "/inventorypb.Inventory/PrintMarketBalances"
+	Inventory_CloseCurrDB_FullMethodName                   = "/inventorypb.Inventory/CloseCurrDB"
+	Inventory_StreamMarketPriceHistory_FullMethodName      = "/inventorypb.Inventory/StreamMarketPriceHistory"
+	Inventory_StreamPartyBalances_FullMethodName           = "/inventorypb.Inventory/StreamPartyBalances"
+	Inventory_StreamRecurringPreview_FullMethodName        = "/inventorypb.Inventory/StreamRecurringPreview"
+	Inventory_StreamBalances_FullMethodName                = "/inventorypb.Inventory/StreamBalances"
+	Inventory_StreamMarketBalances_FullMethodName          = "/inventorypb.Inventory/StreamMarketBalances"
+	Inventory_StreamTaxSummary_FullMethodName              = "/inventorypb.Inventory/StreamTaxSummary"
+	Inventory_StreamAgingReport_FullMethodName             = "/inventorypb.Inventory/StreamAgingReport"
+	Inventory_StreamPartyStatement_FullMethodName          = "/inventorypb.Inventory/StreamPartyStatement"
+)
+
+// InventoryClient is the client API for Inventory service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// one rpc per function of ServerFuncs, see GRPCServer. the Stream rpcs send
+// a list or a text report one element or line at a time.
+type InventoryClient interface {
+	GetCurrDB(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*UUID, error)
+	// empty UUID creates a new db
+	OpenOrCreateDB(ctx context.Context, in *UUID, opts ...grpc.CallOption) (*UUID, error)
+	AddItem(ctx context.Context, in *Item, opts ...grpc.CallOption) (*UUID, error)
+	AddAccount(ctx context.Context, in *Account, opts ...grpc.CallOption) (*UUID, error)
+	ApplyTransaction(ctx context.Context, in *Transaction, opts ...grpc.CallOption) (*UUID, error)
+	// main account names to their uuids
+	GetMainAccounts(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*MapOfBytes, error)
+	UpdateMarketPrice(ctx context.Context, in *MarketPrice, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	ImportMarketPrices(ctx context.Context, in *MarketPriceImport, opts ...grpc.CallOption) (*Count, error)
+	GetMarketPriceHistory(ctx context.Context, in *MarketPriceHistoryQuery, opts ...grpc.CallOption) (*MarketPriceList, error)
+	AddTaxCode(ctx context.Context, in *TaxCode, opts ...grpc.CallOption) (*UUID, error)
+	PrintTaxSummary(ctx context.Context, in *PeriodQuery, opts ...grpc.CallOption) (*Report, error)
+	AddParty(ctx context.Context, in *Party, opts ...grpc.CallOption) (*UUID, error)
+	GetPartyBalances(ctx context.Context, in *PartyQuery, opts ...grpc.CallOption) (*PartyBalanceList, error)
+	PrintAgingReport(ctx context.Context, in *PartyQuery, opts ...grpc.CallOption) (*Report, error)
+	PrintPartyStatement(ctx context.Context, in *PartyQuery, opts ...grpc.CallOption) (*Report, error)
+	AddRecurringTransaction(ctx context.Context, in *RecurringTransaction, opts ...grpc.CallOption) (*UUID, error)
+	GetRecurringTransactions(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*RecurringTransactionList, error)
+	SetRecurringTransactionActive(ctx context.Context, in *RecurringTransaction, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	PreviewRecurringTransactions(ctx context.Context, in *PeriodQuery, opts ...grpc.CallOption) (*RecurringOccurrenceList, error)
+	// zero ToDatetimeMs posts everything due by now
+	RunRecurringTransactions(ctx context.Context, in *PeriodQuery, opts ...grpc.CallOption) (*RecurringOccurrenceList, error)
+	AddTransactionTemplate(ctx context.Context, in *TransactionTemplate, opts ...grpc.CallOption) (*UUID, error)
+	GetTransactionTemplates(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*TransactionTemplateList, error)
+	ApplyTransactionTemplate(ctx context.Context, in *TemplateInstantiation, opts ...grpc.CallOption) (*UUID, error)
+	PrintBalances(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*Report, error)
+	PrintMarketBalances(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*Report, error)
+	CloseCurrDB(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	StreamMarketPriceHistory(ctx context.Context, in *MarketPriceHistoryQuery, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MarketPrice], error)
+	StreamPartyBalances(ctx context.Context, in *PartyQuery, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PartyBalance], error)
+	StreamRecurringPreview(ctx context.Context, in *PeriodQuery, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RecurringOccurrence], error)
+	StreamBalances(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ReportLine], error)
+	StreamMarketBalances(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ReportLine], error)
+	StreamTaxSummary(ctx context.Context, in *PeriodQuery, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ReportLine], error)
+	StreamAgingReport(ctx context.Context, in *PartyQuery, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ReportLine], error)
+	StreamPartyStatement(ctx context.Context, in *PartyQuery, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ReportLine], error)
+}
+
+type inventoryClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewInventoryClient(cc grpc.ClientConnInterface) InventoryClient {
+	return &inventoryClient{cc}
+}
+
+func (c *inventoryClient) GetCurrDB(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*UUID, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UUID)
+	err := c.cc.Invoke(ctx, Inventory_GetCurrDB_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) OpenOrCreateDB(ctx context.Context, in *UUID, opts ...grpc.CallOption) (*UUID, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UUID)
+	err := c.cc.Invoke(ctx, Inventory_OpenOrCreateDB_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) AddItem(ctx context.Context, in *Item, opts ...grpc.CallOption) (*UUID, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UUID)
+	err := c.cc.Invoke(ctx, Inventory_AddItem_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) AddAccount(ctx context.Context, in *Account, opts ...grpc.CallOption) (*UUID, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UUID)
+	err := c.cc.Invoke(ctx, Inventory_AddAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) ApplyTransaction(ctx context.Context, in *Transaction, opts ...grpc.CallOption) (*UUID, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UUID)
+	err := c.cc.Invoke(ctx, Inventory_ApplyTransaction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) GetMainAccounts(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*MapOfBytes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MapOfBytes)
+	err := c.cc.Invoke(ctx, Inventory_GetMainAccounts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) UpdateMarketPrice(ctx context.Context, in *MarketPrice, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Inventory_UpdateMarketPrice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) ImportMarketPrices(ctx context.Context, in *MarketPriceImport, opts ...grpc.CallOption) (*Count, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Count)
+	err := c.cc.Invoke(ctx, Inventory_ImportMarketPrices_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) GetMarketPriceHistory(ctx context.Context, in *MarketPriceHistoryQuery, opts ...grpc.CallOption) (*MarketPriceList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MarketPriceList)
+	err := c.cc.Invoke(ctx, Inventory_GetMarketPriceHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) AddTaxCode(ctx context.Context, in *TaxCode, opts ...grpc.CallOption) (*UUID, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UUID)
+	err := c.cc.Invoke(ctx, Inventory_AddTaxCode_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) PrintTaxSummary(ctx context.Context, in *PeriodQuery, opts ...grpc.CallOption) (*Report, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Report)
+	err := c.cc.Invoke(ctx, Inventory_PrintTaxSummary_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) AddParty(ctx context.Context, in *Party, opts ...grpc.CallOption) (*UUID, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UUID)
+	err := c.cc.Invoke(ctx, Inventory_AddParty_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) GetPartyBalances(ctx context.Context, in *PartyQuery, opts ...grpc.CallOption) (*PartyBalanceList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PartyBalanceList)
+	err := c.cc.Invoke(ctx, Inventory_GetPartyBalances_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) PrintAgingReport(ctx context.Context, in *PartyQuery, opts ...grpc.CallOption) (*Report, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Report)
+	err := c.cc.Invoke(ctx, Inventory_PrintAgingReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) PrintPartyStatement(ctx context.Context, in *PartyQuery, opts ...grpc.CallOption) (*Report, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Report)
+	err := c.cc.Invoke(ctx, Inventory_PrintPartyStatement_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) AddRecurringTransaction(ctx context.Context, in *RecurringTransaction, opts ...grpc.CallOption) (*UUID, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UUID)
+	err := c.cc.Invoke(ctx, Inventory_AddRecurringTransaction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) GetRecurringTransactions(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*RecurringTransactionList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RecurringTransactionList)
+	err := c.cc.Invoke(ctx, Inventory_GetRecurringTransactions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) SetRecurringTransactionActive(ctx context.Context, in *RecurringTransaction, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Inventory_SetRecurringTransactionActive_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) PreviewRecurringTransactions(ctx context.Context, in *PeriodQuery, opts ...grpc.CallOption) (*RecurringOccurrenceList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RecurringOccurrenceList)
+	err := c.cc.Invoke(ctx, Inventory_PreviewRecurringTransactions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) RunRecurringTransactions(ctx context.Context, in *PeriodQuery, opts ...grpc.CallOption) (*RecurringOccurrenceList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RecurringOccurrenceList)
+	err := c.cc.Invoke(ctx, Inventory_RunRecurringTransactions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) AddTransactionTemplate(ctx context.Context, in *TransactionTemplate, opts ...grpc.CallOption) (*UUID, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UUID)
+	err := c.cc.Invoke(ctx, Inventory_AddTransactionTemplate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) GetTransactionTemplates(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*TransactionTemplateList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TransactionTemplateList)
+	err := c.cc.Invoke(ctx, Inventory_GetTransactionTemplates_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) ApplyTransactionTemplate(ctx context.Context, in *TemplateInstantiation, opts ...grpc.CallOption) (*UUID, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UUID)
+	err := c.cc.Invoke(ctx, Inventory_ApplyTransactionTemplate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) PrintBalances(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*Report, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Report)
+	err := c.cc.Invoke(ctx, Inventory_PrintBalances_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) PrintMarketBalances(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*Report, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Report)
+	err := c.cc.Invoke(ctx, Inventory_PrintMarketBalances_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) CloseCurrDB(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Inventory_CloseCurrDB_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *inventoryClient) StreamMarketPriceHistory(ctx context.Context, in *MarketPriceHistoryQuery, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MarketPrice], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Inventory_ServiceDesc.Streams[0], Inventory_StreamMarketPriceHistory_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[MarketPriceHistoryQuery, MarketPrice]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_StreamMarketPriceHistoryClient = grpc.ServerStreamingClient[MarketPrice]
+
+func (c *inventoryClient) StreamPartyBalances(ctx context.Context, in *PartyQuery, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PartyBalance], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Inventory_ServiceDesc.Streams[1], Inventory_StreamPartyBalances_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PartyQuery, PartyBalance]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_StreamPartyBalancesClient = grpc.ServerStreamingClient[PartyBalance]
+
+func (c *inventoryClient) StreamRecurringPreview(ctx context.Context, in *PeriodQuery, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RecurringOccurrence], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Inventory_ServiceDesc.Streams[2], Inventory_StreamRecurringPreview_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PeriodQuery, RecurringOccurrence]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_StreamRecurringPreviewClient = grpc.ServerStreamingClient[RecurringOccurrence]
+
+func (c *inventoryClient) StreamBalances(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ReportLine], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Inventory_ServiceDesc.Streams[3], Inventory_StreamBalances_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[emptypb.Empty, ReportLine]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_StreamBalancesClient = grpc.ServerStreamingClient[ReportLine]
+
+func (c *inventoryClient) StreamMarketBalances(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ReportLine], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Inventory_ServiceDesc.Streams[4], Inventory_StreamMarketBalances_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[emptypb.Empty, ReportLine]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_StreamMarketBalancesClient = grpc.ServerStreamingClient[ReportLine]
+
+func (c *inventoryClient) StreamTaxSummary(ctx context.Context, in *PeriodQuery, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ReportLine], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Inventory_ServiceDesc.Streams[5], Inventory_StreamTaxSummary_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PeriodQuery, ReportLine]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_StreamTaxSummaryClient = grpc.ServerStreamingClient[ReportLine]
+
+func (c *inventoryClient) StreamAgingReport(ctx context.Context, in *PartyQuery, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ReportLine], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Inventory_ServiceDesc.Streams[6], Inventory_StreamAgingReport_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PartyQuery, ReportLine]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_StreamAgingReportClient = grpc.ServerStreamingClient[ReportLine]
+
+func (c *inventoryClient) StreamPartyStatement(ctx context.Context, in *PartyQuery, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ReportLine], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Inventory_ServiceDesc.Streams[7], Inventory_StreamPartyStatement_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PartyQuery, ReportLine]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_StreamPartyStatementClient = grpc.ServerStreamingClient[ReportLine]
+
+// InventoryServer is the server API for Inventory service.
+// All implementations must embed UnimplementedInventoryServer
+// for forward compatibility.
+//
+// one rpc per function of ServerFuncs, see GRPCServer. the Stream rpcs send
+// a list or a text report one element or line at a time.
+type InventoryServer interface {
+	GetCurrDB(context.Context, *emptypb.Empty) (*UUID, error)
+	// empty UUID creates a new db
+	OpenOrCreateDB(context.Context, *UUID) (*UUID, error)
+	AddItem(context.Context, *Item) (*UUID, error)
+	AddAccount(context.Context, *Account) (*UUID, error)
+	ApplyTransaction(context.Context, *Transaction) (*UUID, error)
+	// main account names to their uuids
+	GetMainAccounts(context.Context, *emptypb.Empty) (*MapOfBytes, error)
+	UpdateMarketPrice(context.Context, *MarketPrice) (*emptypb.Empty, error)
+	ImportMarketPrices(context.Context, *MarketPriceImport) (*Count, error)
+	GetMarketPriceHistory(context.Context, *MarketPriceHistoryQuery) (*MarketPriceList, error)
+	AddTaxCode(context.Context, *TaxCode) (*UUID, error)
+	PrintTaxSummary(context.Context, *PeriodQuery) (*Report, error)
+	AddParty(context.Context, *Party) (*UUID, error)
+	GetPartyBalances(context.Context, *PartyQuery) (*PartyBalanceList, error)
+	PrintAgingReport(context.Context, *PartyQuery) (*Report, error)
+	PrintPartyStatement(context.Context, *PartyQuery) (*Report, error)
+	AddRecurringTransaction(context.Context, *RecurringTransaction) (*UUID, error)
+	GetRecurringTransactions(context.Context, *emptypb.Empty) (*RecurringTransactionList, error)
+	SetRecurringTransactionActive(context.Context, *RecurringTransaction) (*emptypb.Empty, error)
+	PreviewRecurringTransactions(context.Context, *PeriodQuery) (*RecurringOccurrenceList, error)
+	// zero ToDatetimeMs posts everything due by now
+	RunRecurringTransactions(context.Context, *PeriodQuery) (*RecurringOccurrenceList, error)
+	AddTransactionTemplate(context.Context, *TransactionTemplate) (*UUID, error)
+	GetTransactionTemplates(context.Context, *emptypb.Empty) (*TransactionTemplateList, error)
+	ApplyTransactionTemplate(context.Context, *TemplateInstantiation) (*UUID, error)
+	PrintBalances(context.Context, *emptypb.Empty) (*Report, error)
+	PrintMarketBalances(context.Context, *emptypb.Empty) (*Report, error)
+	CloseCurrDB(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
+	StreamMarketPriceHistory(*MarketPriceHistoryQuery, grpc.ServerStreamingServer[MarketPrice]) error
+	StreamPartyBalances(*PartyQuery, grpc.ServerStreamingServer[PartyBalance]) error
+	StreamRecurringPreview(*PeriodQuery, grpc.ServerStreamingServer[RecurringOccurrence]) error
+	StreamBalances(*emptypb.Empty, grpc.ServerStreamingServer[ReportLine]) error
+	StreamMarketBalances(*emptypb.Empty, grpc.ServerStreamingServer[ReportLine]) error
+	StreamTaxSummary(*PeriodQuery, grpc.ServerStreamingServer[ReportLine]) error
+	StreamAgingReport(*PartyQuery, grpc.ServerStreamingServer[ReportLine]) error
+	StreamPartyStatement(*PartyQuery, grpc.ServerStreamingServer[ReportLine]) error
+	mustEmbedUnimplementedInventoryServer()
+}
+
+// UnimplementedInventoryServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedInventoryServer struct{}
+
+func (UnimplementedInventoryServer) GetCurrDB(context.Context, *emptypb.Empty) (*UUID, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCurrDB not implemented")
+}
+func (UnimplementedInventoryServer) OpenOrCreateDB(context.Context, *UUID) (*UUID, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method OpenOrCreateDB not implemented")
+}
+func (UnimplementedInventoryServer) AddItem(context.Context, *Item) (*UUID, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddItem not implemented")
+}
+func (UnimplementedInventoryServer) AddAccount(context.Context, *Account) (*UUID, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddAccount not implemented")
+}
+func (UnimplementedInventoryServer) ApplyTransaction(context.Context, *Transaction) (*UUID, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApplyTransaction not implemented")
+}
+func (UnimplementedInventoryServer) GetMainAccounts(context.Context, *emptypb.Empty) (*MapOfBytes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMainAccounts not implemented")
+}
+func (UnimplementedInventoryServer) UpdateMarketPrice(context.Context, *MarketPrice) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateMarketPrice not implemented")
+}
+func (UnimplementedInventoryServer) ImportMarketPrices(context.Context, *MarketPriceImport) (*Count, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportMarketPrices not implemented")
+}
+func (UnimplementedInventoryServer) GetMarketPriceHistory(context.Context, *MarketPriceHistoryQuery) (*MarketPriceList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMarketPriceHistory not implemented")
+}
+func (UnimplementedInventoryServer) AddTaxCode(context.Context, *TaxCode) (*UUID, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddTaxCode not implemented")
+}
+func (UnimplementedInventoryServer) PrintTaxSummary(context.Context, *PeriodQuery) (*Report, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PrintTaxSummary not implemented")
+}
+func (UnimplementedInventoryServer) AddParty(context.Context, *Party) (*UUID, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddParty not implemented")
+}
+func (UnimplementedInventoryServer) GetPartyBalances(context.Context, *PartyQuery) (*PartyBalanceList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPartyBalances not implemented")
+}
+func (UnimplementedInventoryServer) PrintAgingReport(context.Context, *PartyQuery) (*Report, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PrintAgingReport not implemented")
+}
+func (UnimplementedInventoryServer) PrintPartyStatement(context.Context, *PartyQuery) (*Report, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PrintPartyStatement not implemented")
+}
+func (UnimplementedInventoryServer) AddRecurringTransaction(context.Context, *RecurringTransaction) (*UUID, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddRecurringTransaction not implemented")
+}
+func (UnimplementedInventoryServer) GetRecurringTransactions(context.Context, *emptypb.Empty) (*RecurringTransactionList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRecurringTransactions not implemented")
+}
+func (UnimplementedInventoryServer) SetRecurringTransactionActive(context.Context, *RecurringTransaction) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetRecurringTransactionActive not implemented")
+}
+func (UnimplementedInventoryServer) PreviewRecurringTransactions(context.Context, *PeriodQuery) (*RecurringOccurrenceList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PreviewRecurringTransactions not implemented")
+}
+func (UnimplementedInventoryServer) RunRecurringTransactions(context.Context, *PeriodQuery) (*RecurringOccurrenceList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RunRecurringTransactions not implemented")
+}
+func (UnimplementedInventoryServer) AddTransactionTemplate(context.Context, *TransactionTemplate) (*UUID, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddTransactionTemplate not implemented")
+}
+func (UnimplementedInventoryServer) GetTransactionTemplates(context.Context, *emptypb.Empty) (*TransactionTemplateList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTransactionTemplates not implemented")
+}
+func (UnimplementedInventoryServer) ApplyTransactionTemplate(context.Context, *TemplateInstantiation) (*UUID, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApplyTransactionTemplate not implemented")
+}
+func (UnimplementedInventoryServer) PrintBalances(context.Context, *emptypb.Empty) (*Report, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PrintBalances not implemented")
+}
+func (UnimplementedInventoryServer) PrintMarketBalances(context.Context, *emptypb.Empty) (*Report, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PrintMarketBalances not implemented")
+}
+func (UnimplementedInventoryServer) CloseCurrDB(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloseCurrDB not implemented")
+}
+func (UnimplementedInventoryServer) StreamMarketPriceHistory(*MarketPriceHistoryQuery, grpc.ServerStreamingServer[MarketPrice]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamMarketPriceHistory not implemented")
+}
+func (UnimplementedInventoryServer) StreamPartyBalances(*PartyQuery, grpc.ServerStreamingServer[PartyBalance]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamPartyBalances not implemented")
+}
+func (UnimplementedInventoryServer) StreamRecurringPreview(*PeriodQuery, grpc.ServerStreamingServer[RecurringOccurrence]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamRecurringPreview not implemented")
+}
+func (UnimplementedInventoryServer) StreamBalances(*emptypb.Empty, grpc.ServerStreamingServer[ReportLine]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamBalances not implemented")
+}
+func (UnimplementedInventoryServer) StreamMarketBalances(*emptypb.Empty, grpc.ServerStreamingServer[ReportLine]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamMarketBalances not implemented")
+}
+func (UnimplementedInventoryServer) StreamTaxSummary(*PeriodQuery, grpc.ServerStreamingServer[ReportLine]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamTaxSummary not implemented")
+}
+func (UnimplementedInventoryServer) StreamAgingReport(*PartyQuery, grpc.ServerStreamingServer[ReportLine]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamAgingReport not implemented")
+}
+func (UnimplementedInventoryServer) StreamPartyStatement(*PartyQuery, grpc.ServerStreamingServer[ReportLine]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamPartyStatement not implemented")
+}
+func (UnimplementedInventoryServer) mustEmbedUnimplementedInventoryServer() {}
+func (UnimplementedInventoryServer) testEmbeddedByValue()                   {}
+
+// UnsafeInventoryServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to InventoryServer will
+// result in compilation errors.
+type UnsafeInventoryServer interface {
+	mustEmbedUnimplementedInventoryServer()
+}
+
+func RegisterInventoryServer(s grpc.ServiceRegistrar, srv InventoryServer) {
+	// If the following call pancis, it indicates UnimplementedInventoryServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Inventory_ServiceDesc, srv)
+}
+
+func _Inventory_GetCurrDB_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).GetCurrDB(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_GetCurrDB_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).GetCurrDB(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_OpenOrCreateDB_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UUID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).OpenOrCreateDB(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_OpenOrCreateDB_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).OpenOrCreateDB(ctx, req.(*UUID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_AddItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Item)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).AddItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_AddItem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).AddItem(ctx, req.(*Item))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_AddAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Account)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).AddAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_AddAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).AddAccount(ctx, req.(*Account))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_ApplyTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Transaction)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).ApplyTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_ApplyTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).ApplyTransaction(ctx, req.(*Transaction))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_GetMainAccounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).GetMainAccounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_GetMainAccounts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).GetMainAccounts(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_UpdateMarketPrice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MarketPrice)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).UpdateMarketPrice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_UpdateMarketPrice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).UpdateMarketPrice(ctx, req.(*MarketPrice))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_ImportMarketPrices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MarketPriceImport)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).ImportMarketPrices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_ImportMarketPrices_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).ImportMarketPrices(ctx, req.(*MarketPriceImport))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_GetMarketPriceHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MarketPriceHistoryQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).GetMarketPriceHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_GetMarketPriceHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).GetMarketPriceHistory(ctx, req.(*MarketPriceHistoryQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_AddTaxCode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaxCode)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).AddTaxCode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_AddTaxCode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).AddTaxCode(ctx, req.(*TaxCode))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_PrintTaxSummary_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PeriodQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).PrintTaxSummary(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_PrintTaxSummary_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).PrintTaxSummary(ctx, req.(*PeriodQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_AddParty_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Party)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).AddParty(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_AddParty_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).AddParty(ctx, req.(*Party))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_GetPartyBalances_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PartyQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).GetPartyBalances(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_GetPartyBalances_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).GetPartyBalances(ctx, req.(*PartyQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_PrintAgingReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PartyQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).PrintAgingReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_PrintAgingReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).PrintAgingReport(ctx, req.(*PartyQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_PrintPartyStatement_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PartyQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).PrintPartyStatement(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_PrintPartyStatement_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).PrintPartyStatement(ctx, req.(*PartyQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_AddRecurringTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecurringTransaction)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).AddRecurringTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_AddRecurringTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).AddRecurringTransaction(ctx, req.(*RecurringTransaction))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_GetRecurringTransactions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).GetRecurringTransactions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_GetRecurringTransactions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).GetRecurringTransactions(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_SetRecurringTransactionActive_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecurringTransaction)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).SetRecurringTransactionActive(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_SetRecurringTransactionActive_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).SetRecurringTransactionActive(ctx, req.(*RecurringTransaction))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_PreviewRecurringTransactions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PeriodQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).PreviewRecurringTransactions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_PreviewRecurringTransactions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).PreviewRecurringTransactions(ctx, req.(*PeriodQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_RunRecurringTransactions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PeriodQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).RunRecurringTransactions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_RunRecurringTransactions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).RunRecurringTransactions(ctx, req.(*PeriodQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_AddTransactionTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransactionTemplate)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).AddTransactionTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_AddTransactionTemplate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).AddTransactionTemplate(ctx, req.(*TransactionTemplate))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_GetTransactionTemplates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).GetTransactionTemplates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_GetTransactionTemplates_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).GetTransactionTemplates(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_ApplyTransactionTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TemplateInstantiation)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).ApplyTransactionTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_ApplyTransactionTemplate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).ApplyTransactionTemplate(ctx, req.(*TemplateInstantiation))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_PrintBalances_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).PrintBalances(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_PrintBalances_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).PrintBalances(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_PrintMarketBalances_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).PrintMarketBalances(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_PrintMarketBalances_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).PrintMarketBalances(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_CloseCurrDB_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InventoryServer).CloseCurrDB(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Inventory_CloseCurrDB_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InventoryServer).CloseCurrDB(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Inventory_StreamMarketPriceHistory_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(MarketPriceHistoryQuery)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(InventoryServer).StreamMarketPriceHistory(m, &grpc.GenericServerStream[MarketPriceHistoryQuery, MarketPrice]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_StreamMarketPriceHistoryServer = grpc.ServerStreamingServer[MarketPrice]
+
+func _Inventory_StreamPartyBalances_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PartyQuery)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(InventoryServer).StreamPartyBalances(m, &grpc.GenericServerStream[PartyQuery, PartyBalance]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_StreamPartyBalancesServer = grpc.ServerStreamingServer[PartyBalance]
+
+func _Inventory_StreamRecurringPreview_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PeriodQuery)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(InventoryServer).StreamRecurringPreview(m, &grpc.GenericServerStream[PeriodQuery, RecurringOccurrence]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_StreamRecurringPreviewServer = grpc.ServerStreamingServer[RecurringOccurrence]
+
+func _Inventory_StreamBalances_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(emptypb.Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(InventoryServer).StreamBalances(m, &grpc.GenericServerStream[emptypb.Empty, ReportLine]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_StreamBalancesServer = grpc.ServerStreamingServer[ReportLine]
+
+func _Inventory_StreamMarketBalances_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(emptypb.Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(InventoryServer).StreamMarketBalances(m, &grpc.GenericServerStream[emptypb.Empty, ReportLine]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_StreamMarketBalancesServer = grpc.ServerStreamingServer[ReportLine]
+
+func _Inventory_StreamTaxSummary_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PeriodQuery)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(InventoryServer).StreamTaxSummary(m, &grpc.GenericServerStream[PeriodQuery, ReportLine]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_StreamTaxSummaryServer = grpc.ServerStreamingServer[ReportLine]
+
+func _Inventory_StreamAgingReport_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PartyQuery)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(InventoryServer).StreamAgingReport(m, &grpc.GenericServerStream[PartyQuery, ReportLine]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_StreamAgingReportServer = grpc.ServerStreamingServer[ReportLine]
+
+func _Inventory_StreamPartyStatement_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PartyQuery)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(InventoryServer).StreamPartyStatement(m, &grpc.GenericServerStream[PartyQuery, ReportLine]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Inventory_StreamPartyStatementServer = grpc.ServerStreamingServer[ReportLine]
+
+// Inventory_ServiceDesc is the grpc.ServiceDesc for Inventory service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Inventory_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "inventorypb.Inventory",
+	HandlerType: (*InventoryServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetCurrDB",
+			Handler:    _Inventory_GetCurrDB_Handler,
+		},
+		{
+			MethodName: "OpenOrCreateDB",
+			Handler:    _Inventory_OpenOrCreateDB_Handler,
+		},
+		{
+			MethodName: "AddItem",
+			Handler:    _Inventory_AddItem_Handler,
+		},
+		{
+			MethodName: "AddAccount",
+			Handler:    _Inventory_AddAccount_Handler,
+		},
+		{
+			MethodName: "ApplyTransaction",
+			Handler:    _Inventory_ApplyTransaction_Handler,
+		},
+		{
+			MethodName: "GetMainAccounts",
+			Handler:    _Inventory_GetMainAccounts_Handler,
+		},
+		{
+			MethodName: "UpdateMarketPrice",
+			Handler:    _Inventory_UpdateMarketPrice_Handler,
+		},
+		{
+			MethodName: "ImportMarketPrices",
+			Handler:    _Inventory_ImportMarketPrices_Handler,
+		},
+		{
+			MethodName: "GetMarketPriceHistory",
+			Handler:    _Inventory_GetMarketPriceHistory_Handler,
+		},
+		{
+			MethodName: "AddTaxCode",
+			Handler:    _Inventory_AddTaxCode_Handler,
+		},
+		{
+			MethodName: "PrintTaxSummary",
+			Handler:    _Inventory_PrintTaxSummary_Handler,
+		},
+		{
+			MethodName: "AddParty",
+			Handler:    _Inventory_AddParty_Handler,
+		},
+		{
+			MethodName: "GetPartyBalances",
+			Handler:    _Inventory_GetPartyBalances_Handler,
+		},
+		{
+			MethodName: "PrintAgingReport",
+			Handler:    _Inventory_PrintAgingReport_Handler,
+		},
+		{
+			MethodName: "PrintPartyStatement",
+			Handler:    _Inventory_PrintPartyStatement_Handler,
+		},
+		{
+			MethodName: "AddRecurringTransaction",
+			Handler:    _Inventory_AddRecurringTransaction_Handler,
+		},
+		{
+			MethodName: "GetRecurringTransactions",
+			Handler:    _Inventory_GetRecurringTransactions_Handler,
+		},
+		{
+			MethodName: "SetRecurringTransactionActive",
+			Handler:    _Inventory_SetRecurringTransactionActive_Handler,
+		},
+		{
+			MethodName: "PreviewRecurringTransactions",
+			Handler:    _Inventory_PreviewRecurringTransactions_Handler,
+		},
+		{
+			MethodName: "RunRecurringTransactions",
+			Handler:    _Inventory_RunRecurringTransactions_Handler,
+		},
+		{
+			MethodName: "AddTransactionTemplate",
+			Handler:    _Inventory_AddTransactionTemplate_Handler,
+		},
+		{
+			MethodName: "GetTransactionTemplates",
+			Handler:    _Inventory_GetTransactionTemplates_Handler,
+		},
+		{
+			MethodName: "ApplyTransactionTemplate",
+			Handler:    _Inventory_ApplyTransactionTemplate_Handler,
+		},
+		{
+			MethodName: "PrintBalances",
+			Handler:    _Inventory_PrintBalances_Handler,
+		},
+		{
+			MethodName: "PrintMarketBalances",
+			Handler:    _Inventory_PrintMarketBalances_Handler,
+		},
+		{
+			MethodName: "CloseCurrDB",
+			Handler:    _Inventory_CloseCurrDB_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamMarketPriceHistory",
+			Handler:       _Inventory_StreamMarketPriceHistory_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamPartyBalances",
+			Handler:       _Inventory_StreamPartyBalances_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamRecurringPreview",
+			Handler:       _Inventory_StreamRecurringPreview_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamBalances",
+			Handler:       _Inventory_StreamBalances_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamMarketBalances",
+			Handler:       _Inventory_StreamMarketBalances_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamTaxSummary",
+			Handler:       _Inventory_StreamTaxSummary_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamAgingReport",
+			Handler:       _Inventory_StreamAgingReport_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamPartyStatement",
+			Handler:       _Inventory_StreamPartyStatement_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "inventory.proto",
+}
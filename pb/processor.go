@@ -1,7 +1,6 @@
 package inventorypb
 
 import (
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"inventoryrpc"
@@ -47,51 +46,25 @@ func UnmarshalPkt(receivedPktBin []byte) (*inventoryrpc.Packet, error) {
 // }
 
 var ErrNoProcessor = errors.New("no processor")
-var ErrCurrDbNotRegistered = errors.New("curr db not registered")
-var ErrReqHasNoFunc = errors.New("request has no function")
-var ErrCurrDbNil = errors.New("curr db nil")
-var ErrReqHasNoArg = errors.New("request has no arg")
-var ErrNoSuchFunc = errors.New("no such func")
-
-// returns packet, message, code, error
-func CreateRespPkt(UUID uuid.UUID, code int32, payload map[string][]byte, err error, format string, a ...any) (*inventoryrpc.Packet, string, int32, error) {
-	msg := fmt.Sprintf(format, a...)
-	// respPktBody := &ResponsePktBody{
-	// 	UUID:    UUID,
-	// 	Message: msg,
-	// 	Code:    code,
-	// }
-	codeBytes := make([]byte, 4)
-	binary.LittleEndian.PutUint32(codeBytes, uint32(code))
-
-	pkt := &inventoryrpc.Packet{
-		UUID: UUID,
-		Type: inventoryrpc.TypeResp,
-		Meta: nil,
-		Body: map[string][]byte{
-			"code":    codeBytes,
-			"message": []byte(msg),
-		},
-	}
-	for k, v := range payload {
-		pkt.Body[k] = v
-	}
-	return pkt, msg, code, err
-}
 
-func CreateRespPktErrUnmarshall(UUID uuid.UUID, err error) (*inventoryrpc.Packet, string, int32, error) {
-	return CreateRespPkt(UUID, -101, nil, err, "error unmarshall: %s", err.Error())
-}
+type ConsumeProcessingResponseFunc func(responsePktByte []byte)
 
-func CreateRespPktErrExecFunc(UUID uuid.UUID, err error) (*inventoryrpc.Packet, string, int32, error) {
-	return CreateRespPkt(UUID, -102, nil, err, "error execute function: %s", err.Error())
+type ProcessorInterface = inventoryrpc.ProcessorInterface
+
+// inventoryrpc.Codec for the protobuf Packet, for inventoryrpc.Server and
+// inventoryrpc.Conn, and the inventoryrpc.ArgCodec of NewRegistry
+type Codec struct{}
+
+func (Codec) Name() string {
+	return "protobuf"
 }
 
-type ConsumeProcessingResponseFunc func(responsePktByte []byte)
+func (Codec) Marshal(pkt *inventoryrpc.Packet) ([]byte, error) {
+	return proto.Marshal(NewPacket(pkt))
+}
 
-type ProcessorInterface interface {
-	ProcessPkt(pkt *inventoryrpc.Packet) (*inventoryrpc.Packet, string, int32, error)
-	PostProcessPkt(responsePkt *inventoryrpc.Packet) error
+func (Codec) Unmarshal(data []byte) (*inventoryrpc.Packet, error) {
+	return UnmarshalPkt(data)
 }
 
 type PacketReceiver struct {
@@ -0,0 +1,173 @@
+package inventorypb
+
+import (
+	"inventory"
+	"inventoryrpc"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+)
+
+type ServerFunc = inventoryrpc.ServerFunc
+type Request = inventoryrpc.Request
+type HandlerFunc = inventoryrpc.HandlerFunc
+type Middleware = inventoryrpc.Middleware
+type Registry = inventoryrpc.Registry
+
+// a Registry reading args and writing results as protobuf, see
+// inventoryrpc.RegisterBuiltinFuncs for the built in functions
+func NewRegistry() *Registry {
+	return inventoryrpc.NewRegistry(Codec{})
+}
+
+// what a ServerProcessor without its own Registry uses, holds the built in
+// functions
+var DefaultRegistry = NewRegistry()
+
+// names registered on DefaultRegistry, in registration order
+var ServerFuncs []string
+
+// registers f on DefaultRegistry
+func RegisterServerFunc(f *ServerFunc) {
+	DefaultRegistry.Register(f)
+	ServerFuncs = DefaultRegistry.Names()
+}
+
+// registers the built in functions on r, for a registry other than
+// DefaultRegistry
+func RegisterBuiltinFuncs(r *Registry) {
+	inventoryrpc.RegisterBuiltinFuncs(r)
+}
+
+func init() {
+	inventoryrpc.RegisterBuiltinFuncs(DefaultRegistry)
+	ServerFuncs = DefaultRegistry.Names()
+}
+
+// uuid.Nil for an empty one
+func argUUID(b []byte) (uuid.UUID, error) {
+	if len(b) == 0 {
+		return uuid.Nil, nil
+	}
+	return uuid.FromBytes(b)
+}
+
+// unmarshals raw into the message standing for the type arg points to and
+// converts it, an inventoryrpc.ArgCodec method. an arg that is a message
+// already, of a function of your own, is unmarshalled as it is.
+func (Codec) DecodeArg(arg any, raw []byte) (any, error) {
+	switch arg.(type) {
+	case *inventory.Item:
+		var m Item
+		if err := proto.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return ToInvItem(&m), nil
+	case *inventory.Account:
+		var m Account
+		if err := proto.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return ToInvAccount(&m), nil
+	case *inventory.Transaction:
+		var m Transaction
+		if err := proto.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return ToInvTransaction(&m), nil
+	case *inventory.MarketPrice:
+		var m MarketPrice
+		if err := proto.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return ToInvMarketPrice(&m), nil
+	case *inventory.TaxCode:
+		var m TaxCode
+		if err := proto.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return ToInvTaxCode(&m), nil
+	case *inventory.Party:
+		var m Party
+		if err := proto.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return ToInvParty(&m), nil
+	case *inventory.RecurringTransaction:
+		var m RecurringTransaction
+		if err := proto.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return ToInvRecurringTransaction(&m), nil
+	case *inventory.TransactionTemplate:
+		var m TransactionTemplate
+		if err := proto.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return ToInvTransactionTemplate(&m), nil
+	case *inventoryrpc.MarketPriceImport:
+		var m MarketPriceImport
+		if err := proto.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return &inventoryrpc.MarketPriceImport{Format: m.Format, Data: m.Data}, nil
+	case *inventoryrpc.MarketPriceHistoryQuery:
+		var m MarketPriceHistoryQuery
+		if err := proto.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		itemUUID, err := uuid.FromBytes(m.ItemUUID)
+		if err != nil {
+			return nil, err
+		}
+		return &inventoryrpc.MarketPriceHistoryQuery{ItemUUID: itemUUID, FromDatetimeMs: m.FromDatetimeMs, ToDatetimeMs: m.ToDatetimeMs}, nil
+	case *inventoryrpc.PeriodQuery:
+		var m PeriodQuery
+		if err := proto.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return &inventoryrpc.PeriodQuery{FromDatetimeMs: m.FromDatetimeMs, ToDatetimeMs: m.ToDatetimeMs}, nil
+	case *inventoryrpc.PartyQuery:
+		var m PartyQuery
+		if err := proto.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return &inventoryrpc.PartyQuery{Party: ToInvPartyRef(m.PartyUUID), FromDatetimeMs: m.FromDatetimeMs, ToDatetimeMs: m.ToDatetimeMs}, nil
+	case *inventoryrpc.TemplateInstantiation:
+		var m TemplateInstantiation
+		if err := proto.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		tmplUUID, err := argUUID(m.TemplateUUID)
+		if err != nil {
+			return nil, err
+		}
+		return &inventoryrpc.TemplateInstantiation{TemplateUUID: tmplUUID, TemplateName: m.TemplateName, Args: m.Args}, nil
+	}
+	if m, ok := arg.(proto.Message); ok {
+		m = m.ProtoReflect().New().Interface()
+		return m, proto.Unmarshal(raw, m)
+	}
+	return nil, inventoryrpc.ErrUnsupportedType
+}
+
+// the inventory lists the built in functions answer as their list message,
+// any other message as it is, an inventoryrpc.ArgCodec method
+func (Codec) EncodeResult(v any) ([]byte, error) {
+	switch v := v.(type) {
+	case []inventory.MarketPrice:
+		return proto.Marshal(NewMarketPriceList(v))
+	case []inventory.PartyBalance:
+		return proto.Marshal(NewPartyBalanceList(v))
+	case []*inventory.RecurringTransaction:
+		return proto.Marshal(NewRecurringTransactionList(v))
+	case []inventory.RecurringOccurrence:
+		return proto.Marshal(NewRecurringOccurrenceList(v))
+	case []*inventory.TransactionTemplate:
+		return proto.Marshal(NewTransactionTemplateList(v))
+	}
+	if m, ok := v.(proto.Message); ok {
+		return proto.Marshal(m)
+	}
+	return nil, inventoryrpc.ErrUnsupportedType
+}
@@ -3,7 +3,6 @@ package inventorypb
 import (
 	"inventory"
 	"inventoryrpc"
-	"log"
 
 	"github.com/google/uuid"
 	"google.golang.org/protobuf/proto"
@@ -46,6 +45,12 @@ func NewTransactionLine(transactionLine *inventory.TransactionLine) *Transaction
 	if transactionLine.Item != nil {
 		trLine.ItemUUID = transactionLine.Item.UUID[:]
 	}
+	if transactionLine.TaxCode != nil {
+		trLine.TaxCodeUUID = transactionLine.TaxCode.UUID[:]
+	}
+	if transactionLine.Party != nil {
+		trLine.PartyUUID = transactionLine.Party.UUID[:]
+	}
 	return trLine
 }
 
@@ -54,12 +59,16 @@ func NewTransaction(transaction *inventory.Transaction) *Transaction {
 	for i := range transaction.TransactionLines {
 		lines = append(lines, NewTransactionLine(transaction.TransactionLines[i]))
 	}
-	return &Transaction{
+	tr := &Transaction{
 		UUID:             transaction.UUID[:],
 		Description:      transaction.Description,
 		DatetimeMs:       transaction.DatetimeMs,
 		TransactionLines: lines,
 	}
+	if transaction.Party != nil {
+		tr.PartyUUID = transaction.Party.UUID[:]
+	}
+	return tr
 }
 
 func NewItem(item *inventory.Item, transactionLines []*inventory.TransactionLine) *Item {
@@ -141,6 +150,12 @@ func ToInvTransaction(tr *Transaction) *inventory.Transaction {
 		} else {
 			item = nil
 		}
+		var taxCode *inventory.TaxCode
+		if taxCodeUUID, _ := uuid.FromBytes(trl.TaxCodeUUID); taxCodeUUID != uuid.Nil {
+			taxCode = &inventory.TaxCode{
+				UUID: taxCodeUUID,
+			}
+		}
 		trLines = append(trLines, &inventory.TransactionLine{
 			UUID: trLineUUID,
 			Transaction: &inventory.Transaction{
@@ -153,12 +168,15 @@ func ToInvTransaction(tr *Transaction) *inventory.Transaction {
 			Price:    inventory.NewDecimal(trl.Price),
 			Currency: trl.Currency,
 			Note:     trl.Note,
+			TaxCode:  taxCode,
+			Party:    ToInvPartyRef(trl.PartyUUID),
 		})
 	}
 	return &inventory.Transaction{
 		UUID:             trUUID,
 		Description:      tr.Description,
 		DatetimeMs:       tr.DatetimeMs,
+		Party:            ToInvPartyRef(tr.PartyUUID),
 		TransactionLines: trLines,
 	}
 }
@@ -192,32 +210,318 @@ func ToInvMarketPrice(p *MarketPrice) *inventory.MarketPrice {
 	}
 }
 
+func NewMarketPriceList(prices []inventory.MarketPrice) *MarketPriceList {
+	var list []*MarketPrice
+	for i := range prices {
+		list = append(list, NewMarketPrice(&prices[i]))
+	}
+	return &MarketPriceList{
+		MarketPrices: list,
+	}
+}
+
+func ToInvMarketPrices(list *MarketPriceList) []*inventory.MarketPrice {
+	var prices []*inventory.MarketPrice
+	for i := range list.MarketPrices {
+		prices = append(prices, ToInvMarketPrice(list.MarketPrices[i]))
+	}
+	return prices
+}
+
+func NewTaxCode(tc *inventory.TaxCode) *TaxCode {
+	taxCode := &TaxCode{
+		UUID:      tc.UUID[:],
+		Code:      tc.Code,
+		Name:      tc.Name,
+		Rate:      tc.Rate.Data,
+		Inclusive: tc.Inclusive,
+	}
+	if tc.PayableAccount != nil {
+		taxCode.PayableAccountUUID = tc.PayableAccount.UUID[:]
+	}
+	if tc.ReceivableAccount != nil {
+		taxCode.ReceivableAccountUUID = tc.ReceivableAccount.UUID[:]
+	}
+	return taxCode
+}
+
+func ToInvTaxCode(tc *TaxCode) *inventory.TaxCode {
+	tcUUID, _ := uuid.FromBytes(tc.UUID)
+	taxCode := &inventory.TaxCode{
+		UUID:      tcUUID,
+		Code:      tc.Code,
+		Name:      tc.Name,
+		Rate:      inventory.NewDecimal(tc.Rate),
+		Inclusive: tc.Inclusive,
+	}
+	if accUUID, _ := uuid.FromBytes(tc.PayableAccountUUID); accUUID != uuid.Nil {
+		taxCode.PayableAccount = &inventory.Account{UUID: accUUID}
+	}
+	if accUUID, _ := uuid.FromBytes(tc.ReceivableAccountUUID); accUUID != uuid.Nil {
+		taxCode.ReceivableAccount = &inventory.Account{UUID: accUUID}
+	}
+	return taxCode
+}
+
+func NewParty(p *inventory.Party) *Party {
+	return &Party{
+		UUID: p.UUID[:],
+		Name: p.Name,
+		Kind: p.Kind,
+	}
+}
+
+func ToInvParty(p *Party) *inventory.Party {
+	partyUUID, _ := uuid.FromBytes(p.UUID)
+	return &inventory.Party{
+		UUID: partyUUID,
+		Name: p.Name,
+		Kind: p.Kind,
+	}
+}
+
+// nil for an empty or nil uuid
+func ToInvPartyRef(partyUUIDBytes []byte) *inventory.Party {
+	partyUUID, _ := uuid.FromBytes(partyUUIDBytes)
+	if partyUUID == uuid.Nil {
+		return nil
+	}
+	return &inventory.Party{
+		UUID: partyUUID,
+	}
+}
+
+func NewPartyBalanceList(balances []inventory.PartyBalance) *PartyBalanceList {
+	var list []*PartyBalance
+	for _, b := range balances {
+		list = append(list, &PartyBalance{
+			PartyUUID: b.Party.UUID[:],
+			Side:      b.Side,
+			Currency:  b.Currency,
+			Balance:   b.Balance.Data,
+		})
+	}
+	return &PartyBalanceList{
+		PartyBalances: list,
+	}
+}
+
+func NewRecurringTransaction(r *inventory.RecurringTransaction) *RecurringTransaction {
+	rec := &RecurringTransaction{
+		UUID:            r.UUID[:],
+		Name:            r.Name,
+		Schedule:        r.Schedule,
+		DayOfMonth:      int32(r.DayOfMonth),
+		Weekday:         int32(r.Weekday),
+		Cron:            r.Cron,
+		StartDatetimeMs: r.StartDatetimeMs,
+		EndDatetimeMs:   r.EndDatetimeMs,
+		Active:          r.Active,
+	}
+	if r.Template != nil {
+		rec.Template = NewTransaction(r.Template)
+	}
+	return rec
+}
+
+func ToInvRecurringTransaction(r *RecurringTransaction) *inventory.RecurringTransaction {
+	recUUID, _ := uuid.FromBytes(r.UUID)
+	rec := &inventory.RecurringTransaction{
+		UUID:            recUUID,
+		Name:            r.Name,
+		Schedule:        r.Schedule,
+		DayOfMonth:      int(r.DayOfMonth),
+		Weekday:         int(r.Weekday),
+		Cron:            r.Cron,
+		StartDatetimeMs: r.StartDatetimeMs,
+		EndDatetimeMs:   r.EndDatetimeMs,
+		Active:          r.Active,
+	}
+	if r.Template != nil {
+		rec.Template = ToInvTransaction(r.Template)
+	}
+	return rec
+}
+
+func ToInvPartyBalances(list *PartyBalanceList) []inventory.PartyBalance {
+	var balances []inventory.PartyBalance
+	for _, b := range list.PartyBalances {
+		balances = append(balances, inventory.PartyBalance{
+			Party:    ToInvPartyRef(b.PartyUUID),
+			Side:     b.Side,
+			Currency: b.Currency,
+			Balance:  inventory.NewDecimal(b.Balance),
+		})
+	}
+	return balances
+}
+
+func NewRecurringTransactionList(recs []*inventory.RecurringTransaction) *RecurringTransactionList {
+	var list []*RecurringTransaction
+	for _, r := range recs {
+		list = append(list, NewRecurringTransaction(r))
+	}
+	return &RecurringTransactionList{
+		RecurringTransactions: list,
+	}
+}
+
+func NewRecurringOccurrenceList(occurrences []inventory.RecurringOccurrence) *RecurringOccurrenceList {
+	var list []*RecurringOccurrence
+	for _, o := range occurrences {
+		occ := &RecurringOccurrence{
+			RecurringUUID: o.Recurring.UUID[:],
+			DatetimeMs:    o.DatetimeMs,
+		}
+		if o.Transaction != nil {
+			occ.TransactionUUID = o.Transaction.UUID[:]
+		}
+		list = append(list, occ)
+	}
+	return &RecurringOccurrenceList{
+		Occurrences: list,
+	}
+}
+
+func ToInvRecurringTransactions(list *RecurringTransactionList) []*inventory.RecurringTransaction {
+	var recs []*inventory.RecurringTransaction
+	for _, r := range list.RecurringTransactions {
+		recs = append(recs, ToInvRecurringTransaction(r))
+	}
+	return recs
+}
+
+func ToInvRecurringOccurrences(list *RecurringOccurrenceList) []inventory.RecurringOccurrence {
+	var occurrences []inventory.RecurringOccurrence
+	for _, o := range list.Occurrences {
+		recUUID, _ := uuid.FromBytes(o.RecurringUUID)
+		occ := inventory.RecurringOccurrence{
+			Recurring:  &inventory.RecurringTransaction{UUID: recUUID},
+			DatetimeMs: o.DatetimeMs,
+		}
+		if trUUID, _ := uuid.FromBytes(o.TransactionUUID); trUUID != uuid.Nil {
+			occ.Transaction = &inventory.Transaction{UUID: trUUID}
+		}
+		occurrences = append(occurrences, occ)
+	}
+	return occurrences
+}
+
+func NewTransactionTemplate(t *inventory.TransactionTemplate) *TransactionTemplate {
+	tmpl := &TransactionTemplate{
+		UUID:        t.UUID[:],
+		Name:        t.Name,
+		Description: t.Description,
+		Datetime:    t.Datetime,
+	}
+	for _, p := range t.Params {
+		tmpl.Params = append(tmpl.Params, &TemplateParam{
+			Name:    p.Name,
+			Type:    p.Type,
+			Default: p.Default,
+		})
+	}
+	for _, l := range t.Lines {
+		tmpl.Lines = append(tmpl.Lines, &TemplateLine{
+			Account:  l.Account,
+			Item:     l.Item,
+			Quantity: l.Quantity,
+			Unit:     l.Unit,
+			Price:    l.Price,
+			Currency: l.Currency,
+			Note:     l.Note,
+		})
+	}
+	return tmpl
+}
+
+func ToInvTransactionTemplate(t *TransactionTemplate) *inventory.TransactionTemplate {
+	tmplUUID, _ := uuid.FromBytes(t.UUID)
+	tmpl := &inventory.TransactionTemplate{
+		UUID:        tmplUUID,
+		Name:        t.Name,
+		Description: t.Description,
+		Datetime:    t.Datetime,
+	}
+	for _, p := range t.Params {
+		tmpl.Params = append(tmpl.Params, inventory.TemplateParam{
+			Name:    p.Name,
+			Type:    p.Type,
+			Default: p.Default,
+		})
+	}
+	for _, l := range t.Lines {
+		tmpl.Lines = append(tmpl.Lines, &inventory.TemplateLine{
+			Account:  l.Account,
+			Item:     l.Item,
+			Quantity: l.Quantity,
+			Unit:     l.Unit,
+			Price:    l.Price,
+			Currency: l.Currency,
+			Note:     l.Note,
+		})
+	}
+	return tmpl
+}
+
+func NewTransactionTemplateList(templates []*inventory.TransactionTemplate) *TransactionTemplateList {
+	var list []*TransactionTemplate
+	for _, t := range templates {
+		list = append(list, NewTransactionTemplate(t))
+	}
+	return &TransactionTemplateList{
+		TransactionTemplates: list,
+	}
+}
+
+func ToInvTransactionTemplates(list *TransactionTemplateList) []*inventory.TransactionTemplate {
+	var templates []*inventory.TransactionTemplate
+	for _, t := range list.TransactionTemplates {
+		templates = append(templates, ToInvTransactionTemplate(t))
+	}
+	return templates
+}
+
 func NewMapOfBytes(m map[string][]byte) *MapOfBytes {
 	return &MapOfBytes{
 		Content: m,
 	}
 }
 
-func CreateRequest(reqFunc string, params proto.Message) (uuid.UUID, []byte, error) {
+// request packet calling reqFunc with params, nil params sends no arg
+func NewRequestPkt(reqFunc string, params proto.Message) (*inventoryrpc.Packet, error) {
 	paramBin, err := proto.Marshal(params)
 	if err != nil {
-		return uuid.UUID{}, nil, err
+		return nil, err
 	}
+	return NewRawRequestPkt(reqFunc, paramBin)
+}
 
-	pktUUID, err := uuid.NewV6()
+// for functions taking a non message arg, OpenOrCreateDB takes a bare uuid
+func NewRawRequestPkt(reqFunc string, paramBin []byte) (*inventoryrpc.Packet, error) {
+	pktUUID, err := uuid.NewV7()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	pktBin, err := proto.Marshal(&Packet{
-		UUID: pktUUID[:],
+	return &inventoryrpc.Packet{
+		UUID: pktUUID,
 		Type: inventoryrpc.TypeReq,
 		Meta: nil,
 		Body: map[string][]byte{
 			"function": []byte(reqFunc),
 			"arg":      paramBin,
 		},
-	})
+	}, nil
+}
+
+func CreateRequest(reqFunc string, params proto.Message) (uuid.UUID, []byte, error) {
+	pkt, err := NewRequestPkt(reqFunc, params)
+	if err != nil {
+		return uuid.UUID{}, nil, err
+	}
+
+	pktBin, err := proto.Marshal(NewPacket(pkt))
 
-	return pktUUID, pktBin, err
+	return pkt.UUID, pktBin, err
 }
@@ -0,0 +1,351 @@
+package inventory
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MatchByReference  = "reference"
+	MatchByAmountDate = "amount_date"
+	MatchManual       = "manual"
+)
+
+var ErrAlreadyReconciled = errors.New("line is already reconciled")
+var ErrReconcileAccountMismatch = errors.New("statement line and transaction line are on different accounts")
+
+type BankMatch struct {
+	StatementLine   *BankStatementLine
+	TransactionLine *TransactionLine
+	Method          string
+}
+
+type BankReconciliation struct {
+	Account            *Account
+	Currency           string
+	DatetimeMs         int64
+	BookBalance        Decimal
+	StatementBalance   Decimal
+	UnmatchedStatement []*BankStatementLine
+	UnmatchedBook      []*TransactionLine
+}
+
+// statement lines of the account not matched yet, up to toMs
+func getUnmatchedStatementLines(q interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}, accountID int, toMs int64) ([]*BankStatementLine, error) {
+	rows, err := q.Query(`
+		SELECT s.id, s.uuid, s.external_id, s.datetime_ms, s.amount, s.currency, s.reference, s.description
+		FROM bank_statement_lines s
+		LEFT JOIN bank_reconciliations r ON r.statement_line_id = s.id
+		WHERE s.account_id=? AND s.datetime_ms <= ? AND r.id IS NULL
+		ORDER BY s.datetime_ms, s.id`, accountID, toMs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []*BankStatementLine
+	for rows.Next() {
+		l := &BankStatementLine{Amount: NewDecimal(0)}
+		var lineUUID []byte
+		var externalID, currency, reference, description sql.NullString
+		err = rows.Scan(&l.ID, &lineUUID, &externalID, &l.DatetimeMs, &l.Amount, &currency, &reference, &description)
+		if err != nil {
+			return nil, err
+		}
+		l.UUID, err = uuid.FromBytes(lineUUID)
+		if err != nil {
+			return nil, err
+		}
+		l.ExternalID = externalID.String
+		l.Currency = currency.String
+		l.Reference = reference.String
+		l.Description = description.String
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+// financial lines posted to the account and not matched yet, up to toMs
+func getUnmatchedBookLines(q interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}, account *Account, toMs int64) ([]*TransactionLine, error) {
+	rows, err := q.Query(`
+		SELECT l.id, l.uuid, l.quantity, l.currency, l.note, t.id, t.uuid, t.description, t.datetime_ms
+		FROM transaction_lines l
+		JOIN transactions t ON l.transaction_id = t.id
+		LEFT JOIN bank_reconciliations r ON r.transaction_line_id = l.id
+		WHERE l.account_id=? AND l.item_id IS NULL AND t.datetime_ms <= ? AND r.id IS NULL
+		ORDER BY t.datetime_ms, l.id`, account.ID, toMs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []*TransactionLine
+	for rows.Next() {
+		l := &TransactionLine{Account: account, Quantity: NewDecimal(0), Price: NewDecimal(0), Transaction: &Transaction{}}
+		var lineUUID, trUUID []byte
+		var currency, note, description sql.NullString
+		err = rows.Scan(&l.ID, &lineUUID, &l.Quantity, &currency, &note,
+			&l.Transaction.ID, &trUUID, &description, &l.Transaction.DatetimeMs)
+		if err != nil {
+			return nil, err
+		}
+		l.UUID, err = uuid.FromBytes(lineUUID)
+		if err != nil {
+			return nil, err
+		}
+		l.Transaction.UUID, err = uuid.FromBytes(trUUID)
+		if err != nil {
+			return nil, err
+		}
+		l.Currency = currency.String
+		l.Note = note.String
+		l.Transaction.Description = description.String
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+func absMs(ms int64) int64 {
+	if ms < 0 {
+		return -ms
+	}
+	return ms
+}
+
+func bookLineMentions(l *TransactionLine, reference string) bool {
+	reference = strings.ToLower(reference)
+	return strings.Contains(strings.ToLower(l.Note), reference) ||
+		strings.Contains(strings.ToLower(l.Transaction.Description), reference)
+}
+
+// index of the unused book line with the same amount and currency closest in
+// date, -1 if none qualifies
+func closestBookLine(s *BankStatementLine, book []*TransactionLine, used []bool, qualifies func(*TransactionLine) bool) int {
+	best := -1
+	for i, l := range book {
+		if used[i] || l.Quantity.Data != s.Amount.Data || (s.Currency != "" && l.Currency != s.Currency) || !qualifies(l) {
+			continue
+		}
+		if best < 0 || absMs(l.Transaction.DatetimeMs-s.DatetimeMs) < absMs(book[best].Transaction.DatetimeMs-s.DatetimeMs) {
+			best = i
+		}
+	}
+	return best
+}
+
+func insertBankMatchTx(tx *sql.Tx, m BankMatch) error {
+	_, err := tx.Exec(`INSERT INTO bank_reconciliations (statement_line_id, transaction_line_id, method, datetime_ms) VALUES (?, ?, ?, ?)`,
+		m.StatementLine.ID, m.TransactionLine.ID, m.Method, time.Now().UnixMilli())
+	return err
+}
+
+// matches unmatched statement lines of the account with unmatched cash
+// lines. first by reference found in the line note or transaction
+// description with the same amount, then by amount within toleranceDays.
+func ReconcileBankAccount(db *sql.DB, account *Account, toleranceDays int) ([]BankMatch, error) {
+	err := resolveAccount(db, account)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	statement, err := getUnmatchedStatementLines(tx, account.ID, math.MaxInt64)
+	if err != nil {
+		return nil, err
+	}
+	book, err := getUnmatchedBookLines(tx, account, math.MaxInt64)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []BankMatch
+	matched := make([]bool, len(statement))
+	used := make([]bool, len(book))
+	tolerance := int64(toleranceDays) * (24 * time.Hour).Milliseconds()
+	passes := []struct {
+		method    string
+		qualifies func(s *BankStatementLine) func(*TransactionLine) bool
+	}{
+		{MatchByReference, func(s *BankStatementLine) func(*TransactionLine) bool {
+			return func(l *TransactionLine) bool {
+				return s.Reference != "" && bookLineMentions(l, s.Reference)
+			}
+		}},
+		{MatchByAmountDate, func(s *BankStatementLine) func(*TransactionLine) bool {
+			return func(l *TransactionLine) bool {
+				return absMs(l.Transaction.DatetimeMs-s.DatetimeMs) <= tolerance
+			}
+		}},
+	}
+	for _, pass := range passes {
+		for i, s := range statement {
+			if matched[i] {
+				continue
+			}
+			j := closestBookLine(s, book, used, pass.qualifies(s))
+			if j < 0 {
+				continue
+			}
+			s.TransactionLine = book[j]
+			m := BankMatch{StatementLine: s, TransactionLine: book[j], Method: pass.method}
+			err = insertBankMatchTx(tx, m)
+			if err != nil {
+				return nil, err
+			}
+			matched[i], used[j] = true, true
+			matches = append(matches, m)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].StatementLine.DatetimeMs < matches[j].StatementLine.DatetimeMs
+	})
+	return matches, tx.Commit()
+}
+
+// matches a statement line with a cash line by hand, e.g. when the bank
+// took a fee from the amount
+func MatchBankStatementLine(db *sql.DB, statementLineUUID, transactionLineUUID []byte) error {
+	var stLineID, stAccountID, trLineID, trAccountID int
+	err := db.QueryRow(`SELECT id, account_id FROM bank_statement_lines WHERE uuid=?`, statementLineUUID).Scan(&stLineID, &stAccountID)
+	if err != nil {
+		return err
+	}
+	err = db.QueryRow(`SELECT id, account_id FROM transaction_lines WHERE uuid=?`, transactionLineUUID).Scan(&trLineID, &trAccountID)
+	if err != nil {
+		return err
+	}
+	if stAccountID != trAccountID {
+		return ErrReconcileAccountMismatch
+	}
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM bank_reconciliations WHERE statement_line_id=? OR transaction_line_id=?`,
+		stLineID, trLineID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrAlreadyReconciled
+	}
+	_, err = db.Exec(`INSERT INTO bank_reconciliations (statement_line_id, transaction_line_id, method, datetime_ms) VALUES (?, ?, ?, ?)`,
+		stLineID, trLineID, MatchManual, time.Now().UnixMilli())
+	return err
+}
+
+func UnmatchBankStatementLine(db *sql.DB, statementLineUUID []byte) error {
+	_, err := db.Exec(`DELETE FROM bank_reconciliations WHERE statement_line_id = (SELECT id FROM bank_statement_lines WHERE uuid=?)`,
+		statementLineUUID)
+	return err
+}
+
+func IsTransactionLineReconciled(db *sql.DB, transactionLineUUID []byte) (bool, error) {
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM bank_reconciliations r
+		JOIN transaction_lines l ON r.transaction_line_id = l.id
+		WHERE l.uuid=?`, transactionLineUUID).Scan(&count)
+	return count > 0, err
+}
+
+// book balance of the account against the balance of the latest statement
+// up to datetimeMs, with what's unmatched on either side. statements
+// without balances are summed up from their lines instead.
+func GetBankReconciliation(db *sql.DB, account *Account, datetimeMs int64) (*BankReconciliation, error) {
+	err := resolveAccount(db, account)
+	if err != nil {
+		return nil, err
+	}
+	rec := &BankReconciliation{Account: account, DatetimeMs: datetimeMs, BookBalance: NewDecimal(0), StatementBalance: NewDecimal(0)}
+
+	var currency sql.NullString
+	var closing int64
+	var hasBalances bool
+	err = db.QueryRow(`
+		SELECT currency, closing_balance, has_balances FROM bank_statements
+		WHERE account_id=? AND to_datetime_ms <= ?
+		ORDER BY to_datetime_ms DESC, id DESC LIMIT 1`, account.ID, datetimeMs).Scan(&currency, &closing, &hasBalances)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	rec.Currency = currency.String
+	if hasBalances {
+		rec.StatementBalance = NewDecimal(closing)
+	} else {
+		var sum sql.NullInt64
+		err = db.QueryRow(`SELECT SUM(amount) FROM bank_statement_lines WHERE account_id=? AND datetime_ms <= ?`,
+			account.ID, datetimeMs).Scan(&sum)
+		if err != nil {
+			return nil, err
+		}
+		rec.StatementBalance = NewDecimal(sum.Int64)
+	}
+
+	var bookSum sql.NullInt64
+	err = db.QueryRow(`
+		SELECT SUM(l.quantity) FROM transaction_lines l
+		JOIN transactions t ON l.transaction_id = t.id
+		WHERE l.account_id=? AND l.item_id IS NULL AND t.datetime_ms <= ? AND (? = '' OR l.currency = ?)`,
+		account.ID, datetimeMs, rec.Currency, rec.Currency).Scan(&bookSum)
+	if err != nil {
+		return nil, err
+	}
+	rec.BookBalance = NewDecimal(bookSum.Int64)
+
+	rec.UnmatchedStatement, err = getUnmatchedStatementLines(db, account.ID, datetimeMs)
+	if err != nil {
+		return nil, err
+	}
+	rec.UnmatchedBook, err = getUnmatchedBookLines(db, account, datetimeMs)
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func SprintBankReconciliation(db *sql.DB, account *Account, datetimeMs int64) (string, error) {
+	rec, err := GetBankReconciliation(db, account, datetimeMs)
+	if err != nil {
+		return "", err
+	}
+	dateFmt := "2006-01-02"
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("=== Bank Reconciliation %s as of %s ===\n", rec.Account.Name, time.UnixMilli(datetimeMs).Format(dateFmt)))
+	sb.WriteString(fmt.Sprintf("Book balance:      %s %s\n", rec.BookBalance.ToString(), rec.Currency))
+	sb.WriteString(fmt.Sprintf("Statement balance: %s %s\n", rec.StatementBalance.ToString(), rec.Currency))
+	sb.WriteString(fmt.Sprintf("Difference:        %s\n", NewDecimal(rec.StatementBalance.Data-rec.BookBalance.Data).ToString()))
+	sb.WriteString("--- On statement, not in books ---\n")
+	for _, l := range rec.UnmatchedStatement {
+		sb.WriteString(fmt.Sprintf("%s | %14s | %-15s | %s\n",
+			time.UnixMilli(l.DatetimeMs).Format(dateFmt), l.Amount.ToString(), l.Reference, l.Description))
+	}
+	sb.WriteString("--- In books, not on statement ---\n")
+	for _, l := range rec.UnmatchedBook {
+		sb.WriteString(fmt.Sprintf("%s | %14s | %-15s | %s\n",
+			time.UnixMilli(l.Transaction.DatetimeMs).Format(dateFmt), l.Quantity.ToString(), l.Note, l.Transaction.Description))
+	}
+	return sb.String(), nil
+}
+
+func PrintBankReconciliation(db *sql.DB, account *Account, datetimeMs int64) error {
+	str, err := SprintBankReconciliation(db, account, datetimeMs)
+	if err != nil {
+		return err
+	}
+	fmt.Print(str)
+	return nil
+}
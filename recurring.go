@@ -0,0 +1,648 @@
+package inventory
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ScheduleMonthly = "monthly"
+	ScheduleWeekly  = "weekly"
+	ScheduleCron    = "cron"
+)
+
+var ErrUnknownSchedule = errors.New("schedule must be monthly, weekly or cron")
+var ErrInvalidDayOfMonth = errors.New("day of month must be between 1 and 31")
+var ErrInvalidWeekday = errors.New("weekday must be between 0 (sunday) and 6")
+var ErrInvalidCron = errors.New("invalid cron expression")
+var ErrEmptyTemplate = errors.New("template has no lines")
+
+// Template is posted once per occurrence with its DatetimeMs set to the
+// occurrence. monthly runs on DayOfMonth (the last day for shorter months),
+// weekly on Weekday, both at the time of day of StartDatetimeMs. Cron is the
+// usual "minute hour day-of-month month day-of-week" in local time.
+// EndDatetimeMs 0 means no end.
+type RecurringTransaction struct {
+	ID              int
+	UUID            uuid.UUID
+	Name            string
+	Schedule        string
+	DayOfMonth      int
+	Weekday         int
+	Cron            string
+	StartDatetimeMs int64
+	EndDatetimeMs   int64
+	Active          bool
+	Template        *Transaction
+}
+
+// Transaction is nil for an occurrence that isn't posted yet
+type RecurringOccurrence struct {
+	Recurring   *RecurringTransaction
+	DatetimeMs  int64
+	Transaction *Transaction
+}
+
+type cronSchedule struct {
+	minutes, hours, days, months, weekdays map[int]bool
+	anyDay, anyWeekday                     bool
+}
+
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	set := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			rangePart = part[:i]
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("%q: %w", field, ErrInvalidCron)
+			}
+		}
+		lo, hi := min, max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			lo, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, fmt.Errorf("%q: %w", field, ErrInvalidCron)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				hi, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return nil, fmt.Errorf("%q: %w", field, ErrInvalidCron)
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("%q: %w", field, ErrInvalidCron)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%q: %w", expr, ErrInvalidCron)
+	}
+	c := &cronSchedule{anyDay: fields[2] == "*", anyWeekday: fields[4] == "*"}
+	var err error
+	if c.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if c.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if c.days, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if c.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if c.weekdays, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	// 7 is sunday as well
+	if c.weekdays[7] {
+		c.weekdays[0] = true
+	}
+	return c, nil
+}
+
+// like cron, a day matches on either field when both are restricted
+func (c *cronSchedule) matchesDay(t time.Time) bool {
+	if !c.months[int(t.Month())] {
+		return false
+	}
+	day, weekday := c.days[t.Day()], c.weekdays[int(t.Weekday())]
+	switch {
+	case c.anyDay && c.anyWeekday:
+		return true
+	case c.anyDay:
+		return weekday
+	case c.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+func (r *RecurringTransaction) validate() error {
+	switch r.Schedule {
+	case ScheduleMonthly:
+		if r.DayOfMonth < 1 || r.DayOfMonth > 31 {
+			return ErrInvalidDayOfMonth
+		}
+	case ScheduleWeekly:
+		if r.Weekday < 0 || r.Weekday > 6 {
+			return ErrInvalidWeekday
+		}
+	case ScheduleCron:
+		if _, err := parseCron(r.Cron); err != nil {
+			return err
+		}
+	default:
+		return ErrUnknownSchedule
+	}
+	if r.Template == nil || len(r.Template.TransactionLines) == 0 {
+		return ErrEmptyTemplate
+	}
+	return nil
+}
+
+// occurrences within [fromMs, toMs], also bounded by start and end
+func (r *RecurringTransaction) Occurrences(fromMs, toMs int64) ([]int64, error) {
+	if fromMs < r.StartDatetimeMs {
+		fromMs = r.StartDatetimeMs
+	}
+	if r.EndDatetimeMs > 0 && toMs > r.EndDatetimeMs {
+		toMs = r.EndDatetimeMs
+	}
+	if fromMs > toMs {
+		return nil, nil
+	}
+
+	start := time.UnixMilli(r.StartDatetimeMs)
+	from, to := time.UnixMilli(fromMs), time.UnixMilli(toMs)
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local)
+	atStartTime := func(d time.Time) time.Time {
+		return time.Date(d.Year(), d.Month(), d.Day(), start.Hour(), start.Minute(), start.Second(), 0, time.Local)
+	}
+
+	var cron *cronSchedule
+	if r.Schedule == ScheduleCron {
+		var err error
+		cron, err = parseCron(r.Cron)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var result []int64
+	add := func(t time.Time) {
+		if !t.Before(from) && !t.After(to) {
+			result = append(result, t.UnixMilli())
+		}
+	}
+	for ; !day.After(to); day = day.AddDate(0, 0, 1) {
+		switch r.Schedule {
+		case ScheduleMonthly:
+			lastDay := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.Local).Day()
+			target := r.DayOfMonth
+			if target > lastDay {
+				target = lastDay
+			}
+			if day.Day() == target {
+				add(atStartTime(day))
+			}
+		case ScheduleWeekly:
+			if int(day.Weekday()) == r.Weekday {
+				add(atStartTime(day))
+			}
+		case ScheduleCron:
+			if !cron.matchesDay(day) {
+				continue
+			}
+			for h := 0; h < 24; h++ {
+				if !cron.hours[h] {
+					continue
+				}
+				for m := 0; m < 60; m++ {
+					if cron.minutes[m] {
+						add(time.Date(day.Year(), day.Month(), day.Day(), h, m, 0, 0, time.Local))
+					}
+				}
+			}
+		default:
+			return nil, ErrUnknownSchedule
+		}
+	}
+	return result, nil
+}
+
+func AddRecurringTransaction(db *sql.DB, r *RecurringTransaction) ([]byte, error) {
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+	partyID, err := resolvePartyID(db, r.Template.Party)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range r.Template.TransactionLines {
+		if err := resolveAccount(db, l.Account); err != nil {
+			return nil, err
+		}
+		if _, err := resolveItemID(db, l.Item); err != nil {
+			return nil, err
+		}
+		if l.TaxCode != nil {
+			if l.TaxCode, err = resolveTaxCode(db, l.TaxCode); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := resolvePartyID(db, l.Party); err != nil {
+			return nil, err
+		}
+	}
+
+	recUUID, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO recurring_transactions (uuid, name, schedule, day_of_month, weekday, cron, start_datetime_ms, end_datetime_ms, active, description, party_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1, ?, ?)`,
+		recUUID[:], r.Name, r.Schedule, r.DayOfMonth, r.Weekday, r.Cron, r.StartDatetimeMs, r.EndDatetimeMs, r.Template.Description, partyID)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	err = insertTemplateLinesTx(tx, "recurring_transaction_lines", "recurring_id", id, r.Template.TransactionLines)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	r.ID = int(id)
+	r.UUID = recUUID
+	r.Active = true
+	return recUUID[:], nil
+}
+
+// template lines are stored like transaction lines, against their owner
+func insertTemplateLinesTx(tx *sql.Tx, table, ownerColumn string, ownerID int64, lines []*TransactionLine) error {
+	for _, l := range lines {
+		itemID := sql.NullInt64{}
+		if l.Item != nil {
+			itemID = sql.NullInt64{Int64: int64(l.Item.ID), Valid: true}
+		}
+		taxCodeID := sql.NullInt64{}
+		if l.TaxCode != nil {
+			taxCodeID = sql.NullInt64{Int64: int64(l.TaxCode.ID), Valid: true}
+		}
+		partyID := sql.NullInt64{}
+		if l.Party != nil {
+			partyID = sql.NullInt64{Int64: int64(l.Party.ID), Valid: true}
+		}
+		_, err := tx.Exec(`INSERT INTO `+table+` (`+ownerColumn+`, account_id, item_id, quantity, unit, price, currency, note, tax_code_id, party_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			ownerID, l.Account.ID, itemID, l.Quantity, l.Unit, l.Price, l.Currency, l.Note, taxCodeID, partyID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadTemplateLines(db *sql.DB, table, ownerColumn string, ownerID int) ([]*TransactionLine, error) {
+	rows, err := db.Query(`SELECT account_id, item_id, quantity, unit, price, currency, note, tax_code_id, party_id
+		FROM `+table+` WHERE `+ownerColumn+`=? ORDER BY id`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	type lineRefs struct {
+		accountID                  int
+		itemID, taxCodeID, partyID sql.NullInt64
+	}
+	var lines []*TransactionLine
+	var refs []lineRefs
+	for rows.Next() {
+		l := &TransactionLine{Quantity: NewDecimal(0), Price: NewDecimal(0)}
+		var ref lineRefs
+		var unit, currency, note sql.NullString
+		err = rows.Scan(&ref.accountID, &ref.itemID, &l.Quantity, &unit, &l.Price, &currency, &note, &ref.taxCodeID, &ref.partyID)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		l.Unit, l.Currency, l.Note = unit.String, currency.String, note.String
+		lines = append(lines, l)
+		refs = append(refs, ref)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, l := range lines {
+		if l.Account, err = GetAccountByID(db, refs[i].accountID); err != nil {
+			return nil, err
+		}
+		if refs[i].itemID.Valid {
+			if l.Item, err = GetItemByID(db, int(refs[i].itemID.Int64)); err != nil {
+				return nil, err
+			}
+		}
+		if refs[i].taxCodeID.Valid {
+			if l.TaxCode, err = GetTaxCodeByID(db, int(refs[i].taxCodeID.Int64)); err != nil {
+				return nil, err
+			}
+		}
+		if refs[i].partyID.Valid {
+			if l.Party, err = GetPartyByID(db, int(refs[i].partyID.Int64)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return lines, nil
+}
+
+func scanRecurringTransactions(db *sql.DB, where string, args ...any) ([]*RecurringTransaction, error) {
+	rows, err := db.Query(`
+		SELECT id, uuid, name, schedule, day_of_month, weekday, cron, start_datetime_ms, end_datetime_ms, active, description, party_id
+		FROM recurring_transactions `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	var recs []*RecurringTransaction
+	var partyIDs []sql.NullInt64
+	for rows.Next() {
+		r := &RecurringTransaction{Template: &Transaction{}}
+		var name, cron, description sql.NullString
+		var partyID sql.NullInt64
+		err = rows.Scan(&r.ID, &r.UUID, &name, &r.Schedule, &r.DayOfMonth, &r.Weekday, &cron,
+			&r.StartDatetimeMs, &r.EndDatetimeMs, &r.Active, &description, &partyID)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		r.Name, r.Cron, r.Template.Description = name.String, cron.String, description.String
+		recs = append(recs, r)
+		partyIDs = append(partyIDs, partyID)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, r := range recs {
+		if partyIDs[i].Valid {
+			if r.Template.Party, err = GetPartyByID(db, int(partyIDs[i].Int64)); err != nil {
+				return nil, err
+			}
+		}
+		r.Template.TransactionLines, err = loadTemplateLines(db, "recurring_transaction_lines", "recurring_id", r.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return recs, nil
+}
+
+func GetRecurringTransactions(db *sql.DB) ([]*RecurringTransaction, error) {
+	return scanRecurringTransactions(db, "")
+}
+
+func GetRecurringTransactionByUUID(db *sql.DB, recUUID []byte) (*RecurringTransaction, error) {
+	recs, err := scanRecurringTransactions(db, "WHERE uuid=?", recUUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(recs) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return recs[0], nil
+}
+
+// stops further postings, posted occurrences are kept
+func SetRecurringTransactionActive(db *sql.DB, recUUID []byte, active bool) error {
+	res, err := db.Exec(`UPDATE recurring_transactions SET active=? WHERE uuid=?`, active, recUUID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func postedOccurrences(db *sql.DB, recID int) (map[int64]int, int64, error) {
+	rows, err := db.Query(`SELECT datetime_ms, transaction_id FROM recurring_occurrences WHERE recurring_id=?`, recID)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	posted := map[int64]int{}
+	var last int64
+	for rows.Next() {
+		var ms int64
+		var trID int
+		if err = rows.Scan(&ms, &trID); err != nil {
+			return nil, 0, err
+		}
+		posted[ms] = trID
+		if ms > last {
+			last = ms
+		}
+	}
+	return posted, last, rows.Err()
+}
+
+// occurrences of the active templates up to untilMs that are not posted yet
+func PreviewRecurringTransactions(db *sql.DB, untilMs int64) ([]RecurringOccurrence, error) {
+	recs, err := scanRecurringTransactions(db, "WHERE active=1")
+	if err != nil {
+		return nil, err
+	}
+	var result []RecurringOccurrence
+	for _, r := range recs {
+		posted, last, err := postedOccurrences(db, r.ID)
+		if err != nil {
+			return nil, err
+		}
+		occurrences, err := r.Occurrences(last, untilMs)
+		if err != nil {
+			return nil, err
+		}
+		for _, ms := range occurrences {
+			if _, ok := posted[ms]; ok {
+				continue
+			}
+			result = append(result, RecurringOccurrence{Recurring: r, DatetimeMs: ms})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].DatetimeMs < result[j].DatetimeMs
+	})
+	return result, nil
+}
+
+func templateInstance(tmpl *Transaction, datetimeMs int64) *Transaction {
+	tr := &Transaction{
+		Description: tmpl.Description,
+		DatetimeMs:  datetimeMs,
+		Party:       tmpl.Party,
+	}
+	for _, l := range tmpl.TransactionLines {
+		line := *l
+		tr.TransactionLines = append(tr.TransactionLines, &line)
+	}
+	return tr
+}
+
+// posts every due occurrence up to nowMs. each occurrence is recorded with its
+// transaction, so running again, or after a restart, skips what was posted.
+func RunRecurringTransactions(db *sql.DB, nowMs int64) ([]RecurringOccurrence, error) {
+	due, err := PreviewRecurringTransactions(db, nowMs)
+	if err != nil {
+		return nil, err
+	}
+	var posted []RecurringOccurrence
+	for _, o := range due {
+		tr := templateInstance(o.Recurring.Template, o.DatetimeMs)
+		err = postOccurrence(db, o.Recurring, tr)
+		if err != nil {
+			return posted, fmt.Errorf("%s at %s: %w", o.Recurring.Name, time.UnixMilli(o.DatetimeMs).Format(time.RFC3339), err)
+		}
+		o.Transaction = tr
+		posted = append(posted, o)
+	}
+	return posted, nil
+}
+
+func postOccurrence(db *sql.DB, r *RecurringTransaction, tr *Transaction) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, trID, err := applyTransactionTx(db, tx, tr)
+	if err != nil {
+		return err
+	}
+	// the unique key makes a concurrent run fail here instead of posting twice
+	_, err = tx.Exec(`INSERT INTO recurring_occurrences (recurring_id, datetime_ms, transaction_id) VALUES (?, ?, ?)`,
+		r.ID, tr.DatetimeMs, trID)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	publishTransactions(db, trID)
+	return nil
+}
+
+// how often a scheduler with no positive Interval runs
+const DefaultRecurringInterval = time.Minute
+
+// runs RunRecurringTransactions on start and then every Interval
+type RecurringScheduler struct {
+	DB       *sql.DB
+	Interval time.Duration
+	OnPosted func([]RecurringOccurrence)
+	OnError  func(error)
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewRecurringScheduler(db *sql.DB, interval time.Duration) *RecurringScheduler {
+	return &RecurringScheduler{DB: db, Interval: interval}
+}
+
+func (s *RecurringScheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		return
+	}
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultRecurringInterval
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.loop(interval, s.stop, s.done)
+}
+
+func (s *RecurringScheduler) Stop() {
+	s.mu.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done = nil, nil
+	s.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (s *RecurringScheduler) loop(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		posted, err := RunRecurringTransactions(s.DB, time.Now().UnixMilli())
+		if len(posted) > 0 && s.OnPosted != nil {
+			s.OnPosted(posted)
+		}
+		if err != nil && s.OnError != nil {
+			s.OnError(err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func SprintRecurringPreview(db *sql.DB, untilMs int64) (string, error) {
+	occurrences, err := PreviewRecurringTransactions(db, untilMs)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("=== Upcoming Recurring Transactions until %s ===\n", time.UnixMilli(untilMs).Format("2006-01-02")))
+	for _, o := range occurrences {
+		sb.WriteString(fmt.Sprintf("%s | %-20s | %s\n", time.UnixMilli(o.DatetimeMs).Format("2006-01-02 15:04"),
+			o.Recurring.Name, o.Recurring.Template.Description))
+	}
+	return sb.String(), nil
+}
+
+func PrintRecurringPreview(db *sql.DB, untilMs int64) error {
+	str, err := SprintRecurringPreview(db, untilMs)
+	if err != nil {
+		return err
+	}
+	fmt.Print(str)
+	return nil
+}
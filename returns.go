@@ -0,0 +1,331 @@
+package inventory
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ReturnFromCustomer = "customer"
+	ReturnToVendor     = "vendor"
+)
+
+const (
+	ConditionResellable = "resellable"
+	ConditionScrap      = "scrap"
+)
+
+const SettingScrapAccount = "scrap_account"
+
+var ErrUnknownReturnType = errors.New("return type must be customer or vendor")
+var ErrUnknownCondition = errors.New("condition must be resellable or scrap")
+var ErrNoScrapAccount = errors.New("scrap account not set")
+var ErrNoSourceLine = errors.New("source transaction has no stock movement for the item")
+var ErrReturnExceedsSource = errors.New("return quantity exceeds what is left to return")
+
+// returned goods go back at the unit cost they moved at in Source. a
+// customer return puts resellable goods back on the stock account and scrap
+// on the scrap account. a vendor return takes the goods out of the stock
+// account they came into. when Source received them against a payable
+// instead of an item line, e.g. a purchase invoice, only the stock is
+// reversed and ExtraLines carry the other side, a debit on the payable or a
+// refund.
+type Return struct {
+	ID          int
+	UUID        uuid.UUID
+	Type        string
+	Source      *Transaction
+	Party       *Party
+	DatetimeMs  int64
+	Reason      string
+	Lines       []*ReturnLine
+	ExtraLines  []*TransactionLine
+	Transaction *Transaction
+}
+
+// Quantity is positive, in the unit of the source line. Account and UnitCost
+// are filled in from the source.
+type ReturnLine struct {
+	Item      *Item
+	Quantity  Decimal
+	Condition string
+	Note      string
+	Account   *Account
+	UnitCost  Decimal
+}
+
+func SetScrapAccount(db *sql.DB, acc *Account) error {
+	return SetAccountSetting(db, SettingScrapAccount, acc)
+}
+
+func GetScrapAccount(db *sql.DB) (*Account, error) {
+	acc, err := GetAccountSetting(db, SettingScrapAccount)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoScrapAccount
+	}
+	return acc, err
+}
+
+// unit cost the transaction moved the item at on the account, from the change
+// it made to the balance_history of that account and item
+func sourceUnitCost(db *sql.DB, trID, accountID, itemID int) (Decimal, error) {
+	rows, err := db.Query(`
+		SELECT h.transaction_id, h.quantity, h.total_cost
+		FROM balance_history h
+		JOIN transactions t ON h.transaction_id = t.id
+		WHERE h.item_id=? AND h.account_id=?
+		ORDER BY t.datetime_ms, h.id`, itemID, accountID)
+	if err != nil {
+		return NewDecimal(0), err
+	}
+	defer rows.Close()
+
+	var prevQty, prevTotal, movedQty, movedTotal int64
+	for rows.Next() {
+		var id int
+		qty, total := NewDecimal(0), NewDecimal(0)
+		err = rows.Scan(&id, &qty, &total)
+		if err != nil {
+			return NewDecimal(0), err
+		}
+		if id == trID {
+			movedQty += qty.Data - prevQty
+			movedTotal += total.Data - prevTotal
+		}
+		prevQty, prevTotal = qty.Data, total.Data
+	}
+	if err = rows.Err(); err != nil {
+		return NewDecimal(0), err
+	}
+	if movedQty == 0 {
+		return NewDecimal(0), ErrNoSourceLine
+	}
+	return NewDecimal(movedTotal).Divide(NewDecimal(movedQty)), nil
+}
+
+// quantity of the item already returned against the source transaction
+func returnedQuantity(db *sql.DB, sourceID, itemID int) (Decimal, error) {
+	var sum sql.NullInt64
+	err := db.QueryRow(`SELECT SUM(quantity) FROM return_lines WHERE source_transaction_id=? AND item_id=?`,
+		sourceID, itemID).Scan(&sum)
+	return NewDecimal(sum.Int64), err
+}
+
+// stock line (on an asset account) and its counter item line for the item in
+// the source transaction. customers got goods out of stock, vendors put them
+// in. purchase invoices and consignment settlements have no counter line.
+func findSourceLines(source *Transaction, item *Item, returnType string, accMap map[int]*Account) (*TransactionLine, *TransactionLine) {
+	var stock, counter *TransactionLine
+	for _, l := range source.TransactionLines {
+		if l.Item == nil || l.Item.ID != item.ID {
+			continue
+		}
+		outgoing := l.Quantity.Data < 0
+		isStock := accMap[l.Account.ID] != nil && accMap[l.Account.ID].IsChildOfOrItself(AssetAcc)
+		wantOutgoing := returnType == ReturnFromCustomer
+		if isStock && outgoing == wantOutgoing && stock == nil {
+			stock = l
+		} else if !isStock && outgoing != wantOutgoing && counter == nil {
+			counter = l
+		}
+	}
+	return stock, counter
+}
+
+// posts the reverse movement of the returned goods, plus r.ExtraLines (a
+// refund for example), and records reason and condition
+func CreateReturn(db *sql.DB, r *Return) ([]byte, error) {
+	if r.Type != ReturnFromCustomer && r.Type != ReturnToVendor {
+		return nil, ErrUnknownReturnType
+	}
+	if r.Source == nil {
+		return nil, sql.ErrNoRows
+	}
+	source, err := GetTransactionByUUID(db, r.Source.UUID[:])
+	if err != nil {
+		return nil, err
+	}
+	r.Source = source
+	if r.Party == nil {
+		r.Party = source.Party
+	}
+	_, accMap, err := BuildAccountTree(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var scrapAcc *Account
+	var lines []*TransactionLine
+	requested := map[int]int64{}
+	for _, rl := range r.Lines {
+		if rl.Condition == "" {
+			rl.Condition = ConditionResellable
+		}
+		if rl.Condition != ConditionResellable && rl.Condition != ConditionScrap {
+			return nil, ErrUnknownCondition
+		}
+		if rl.Item == nil {
+			return nil, sql.ErrNoRows
+		}
+		if rl.Item.ID <= 0 {
+			item, err := GetItemByUUID(db, rl.Item.UUID[:])
+			if err != nil {
+				return nil, err
+			}
+			*rl.Item = *item
+		}
+		stock, counter := findSourceLines(source, rl.Item, r.Type, accMap)
+		if stock == nil || (counter == nil && r.Type == ReturnFromCustomer) {
+			return nil, fmt.Errorf("%s: %w", rl.Item.Name, ErrNoSourceLine)
+		}
+
+		returned, err := returnedQuantity(db, source.ID, rl.Item.ID)
+		if err != nil {
+			return nil, err
+		}
+		requested[rl.Item.ID] += rl.Quantity.Data
+		sourceQty := stock.Quantity.Data
+		if sourceQty < 0 {
+			sourceQty = -sourceQty
+		}
+		if rl.Quantity.Data <= 0 || returned.Data+requested[rl.Item.ID] > sourceQty {
+			return nil, fmt.Errorf("%s returning %s, %s of %s already returned: %w", rl.Item.Name,
+				rl.Quantity.ToString(), returned.ToString(), NewDecimal(sourceQty).ToString(), ErrReturnExceedsSource)
+		}
+
+		rl.UnitCost, err = sourceUnitCost(db, source.ID, stock.Account.ID, rl.Item.ID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", rl.Item.Name, err)
+		}
+		rl.Account = stock.Account
+		if r.Type == ReturnFromCustomer && rl.Condition == ConditionScrap {
+			if scrapAcc == nil {
+				scrapAcc, err = GetScrapAccount(db)
+				if err != nil {
+					return nil, err
+				}
+			}
+			rl.Account = scrapAcc
+		}
+
+		qty := rl.Quantity
+		if r.Type == ReturnToVendor {
+			qty = NewDecimal(-qty.Data)
+		}
+		note := strings.TrimSpace(fmt.Sprintf("return %s %s", rl.Condition, rl.Note))
+		stockLine := CreateInventoryTrLine(rl.Account, rl.Item, qty, stock.Unit, rl.UnitCost, stock.Currency)
+		stockLine.Note = note
+		lines = append(lines, stockLine)
+		if counter != nil {
+			counterLine := CreateInventoryTrLine(counter.Account, rl.Item, NewDecimal(-qty.Data), counter.Unit, rl.UnitCost, counter.Currency)
+			counterLine.Note = note
+			lines = append(lines, counterLine)
+		}
+	}
+	lines = append(lines, r.ExtraLines...)
+
+	description := fmt.Sprintf("Return %s: %s", r.Type, source.Description)
+	if r.Reason != "" {
+		description += " (" + r.Reason + ")"
+	}
+	transaction := &Transaction{
+		Description:      description,
+		DatetimeMs:       r.DatetimeMs,
+		Party:            r.Party,
+		TransactionLines: lines,
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, trID, err := applyTransactionTx(db, tx, transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	retUUID, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	partyID, err := resolvePartyID(db, r.Party)
+	if err != nil {
+		return nil, err
+	}
+	res, err := tx.Exec(`INSERT INTO returns (uuid, type, source_transaction_id, transaction_id, party_id, datetime_ms, reason)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, retUUID[:], r.Type, source.ID, trID, partyID, r.DatetimeMs, r.Reason)
+	if err != nil {
+		return nil, err
+	}
+	retID, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	for _, rl := range r.Lines {
+		_, err = tx.Exec(`INSERT INTO return_lines (return_id, source_transaction_id, item_id, account_id, quantity, unit_cost, condition, note)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			retID, source.ID, rl.Item.ID, rl.Account.ID, rl.Quantity, rl.UnitCost, rl.Condition, rl.Note)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	publishTransactions(db, trID)
+	r.ID = int(retID)
+	r.UUID = retUUID
+	r.Transaction = transaction
+	return retUUID[:], nil
+}
+
+func SprintReturns(db *sql.DB, fromMs, toMs int64) (string, error) {
+	rows, err := db.Query(`
+		SELECT r.datetime_ms, r.type, r.reason, t.description, i.name, l.quantity, l.unit_cost, l.condition, a.name
+		FROM return_lines l
+		JOIN returns r ON l.return_id = r.id
+		JOIN transactions t ON r.source_transaction_id = t.id
+		JOIN items i ON l.item_id = i.id
+		JOIN accounts a ON l.account_id = a.id
+		WHERE r.datetime_ms >= ? AND r.datetime_ms <= ?
+		ORDER BY r.datetime_ms, l.id`, fromMs, toMs)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var sb strings.Builder
+	sb.WriteString("=== Returns ===\n")
+	for rows.Next() {
+		var datetimeMs int64
+		var returnType, itemName, condition, accName string
+		var reason, sourceDesc sql.NullString
+		qty, unitCost := NewDecimal(0), NewDecimal(0)
+		err = rows.Scan(&datetimeMs, &returnType, &reason, &sourceDesc, &itemName, &qty, &unitCost, &condition, &accName)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(fmt.Sprintf("%s | %-8s | %-20s | %s %s @ %s | %-10s -> %s | %s\n",
+			time.UnixMilli(datetimeMs).Format("2006-01-02"), returnType, sourceDesc.String, qty.ToString(), itemName,
+			unitCost.ToString(), condition, accName, reason.String))
+	}
+	return sb.String(), rows.Err()
+}
+
+func PrintReturns(db *sql.DB, fromMs, toMs int64) error {
+	str, err := SprintReturns(db, fromMs, toMs)
+	if err != nil {
+		return err
+	}
+	fmt.Print(str)
+	return nil
+}
@@ -0,0 +1,143 @@
+package inventoryrpc
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// turns packets into payload bytes and back, the framing is done by
+// EncodePacketWrapper and PacketBuffer. Name is what the handshake offers it
+// as, "protobuf", "msgpack" or "json".
+type Codec interface {
+	Name() string
+	Marshal(pkt *Packet) ([]byte, error)
+	Unmarshal(data []byte) (*Packet, error)
+}
+
+type ProcessorInterface interface {
+	ProcessPkt(pkt *Packet) (*Packet, string, int32, error)
+	PostProcessPkt(responsePkt *Packet) error
+}
+
+// a framed packet stream over a net.Conn, used on both ends. writes are safe
+// from several goroutines, reads are not.
+type Conn struct {
+	NetConn net.Conn
+	Codec   Codec
+	// 0 is no deadline
+	WriteTimeout time.Duration
+	// what the handshake settled on, see Handshake. without one the version
+	// is 0 and nothing is compressed.
+	Version     int
+	Compression string
+	Features    []string
+
+	buf     PacketBuffer
+	pending [][]byte
+	readBuf []byte
+	writeMu sync.Mutex
+}
+
+func NewConn(c net.Conn, codec Codec) *Conn {
+	return &Conn{
+		NetConn: c,
+		Codec:   codec,
+		readBuf: make([]byte, 4096),
+	}
+}
+
+// network is "tcp" or "unix", no handshake is done
+func Dial(network, address string, codec Codec) (*Conn, error) {
+	c, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(c, codec), nil
+}
+
+func (c *Conn) HasFeature(feature string) bool {
+	for _, f := range c.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Conn) WritePacket(pkt *Packet) error {
+	pktBytes, err := c.Codec.Marshal(pkt)
+	if err != nil {
+		return err
+	}
+	pktBytes, err = compress(c.Compression, pktBytes)
+	if err != nil {
+		return err
+	}
+	return c.writeFrame(pktBytes)
+}
+
+func (c *Conn) writeFrame(payload []byte) error {
+	data, err := EncodePacketWrapper(payload)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.WriteTimeout > 0 {
+		c.NetConn.SetWriteDeadline(time.Now().Add(c.WriteTimeout))
+	}
+	_, err = c.NetConn.Write(data)
+	return err
+}
+
+// blocks until a whole packet arrived. a read error (a deadline for example)
+// leaves the partial data buffered, so reading can go on after it.
+func (c *Conn) ReadPacket() (*Packet, error) {
+	frame, err := c.readFrame()
+	if err != nil {
+		return nil, err
+	}
+	return c.decodeFrame(frame)
+}
+
+func (c *Conn) decodeFrame(frame []byte) (*Packet, error) {
+	pktBytes, err := decompress(c.Compression, frame)
+	if err != nil {
+		return nil, err
+	}
+	return c.Codec.Unmarshal(pktBytes)
+}
+
+func (c *Conn) readFrame() ([]byte, error) {
+	for len(c.pending) == 0 {
+		n, err := c.NetConn.Read(c.readBuf)
+		if n > 0 {
+			if err := c.feed(c.readBuf[:n]); err != nil {
+				return nil, err
+			}
+		}
+		if err != nil && len(c.pending) == 0 {
+			return nil, err
+		}
+	}
+	frame := c.pending[0]
+	c.pending = c.pending[1:]
+	return frame, nil
+}
+
+func (c *Conn) feed(data []byte) error {
+	wrappers, err := c.buf.Feed(data)
+	if err != nil {
+		return err
+	}
+	for _, w := range wrappers {
+		// PacketBytes points into the buffer, which the next Feed reuses
+		c.pending = append(c.pending, append([]byte(nil), w.PacketBytes...))
+	}
+	return nil
+}
+
+func (c *Conn) Close() error {
+	return c.NetConn.Close()
+}
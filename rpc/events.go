@@ -0,0 +1,268 @@
+package inventoryrpc
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"inventory"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// handshake feature of servers with an EventHub
+const FeatureEvents = "events"
+
+// answered by Server itself when it has an EventHub. the arg is the topics
+// separated by newlines, Unsubscribe without an arg drops all of them. the
+// response carries the topics subscribed after the call under "topics".
+const (
+	FuncSubscribe   = "Subscribe"
+	FuncUnsubscribe = "Unsubscribe"
+)
+
+// subscribed to, gets every event
+const TopicAll = "*"
+
+const defaultEventQueueSize = 64
+
+// pushes events to the connections subscribed to their topics. every
+// subscriber has its own queue, a subscriber too slow to keep up loses the
+// events that don't fit, and the next one it gets tells how many under
+// "dropped". with DisconnectSlow its connection is closed instead.
+type EventHub struct {
+	// events queued per subscriber, 0 is 64
+	QueueSize      int
+	DisconnectSlow bool
+
+	mu   sync.RWMutex
+	subs map[*eventSub]struct{}
+}
+
+type eventSub struct {
+	conn   *Conn
+	topics map[string]bool
+	queue  chan *Packet
+	done   chan struct{}
+
+	mu      sync.Mutex
+	dropped uint32
+	slow    bool
+}
+
+func NewEventHub() *EventHub {
+	return &EventHub{}
+}
+
+func (h *EventHub) NumSubscribers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs)
+}
+
+// a packet of TypeEvent, Body["event"] is kind
+func NewEventPkt(kind string, body map[string][]byte) (*Packet, error) {
+	pktUUID, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	pkt := &Packet{
+		UUID: pktUUID,
+		Type: TypeEvent,
+		Body: map[string][]byte{},
+	}
+	for k, v := range body {
+		pkt.Body[k] = v
+	}
+	pkt.Body["event"] = []byte(kind)
+	return pkt, nil
+}
+
+// events the subscriber lost before pkt because its queue was full
+func DroppedEvents(pkt *Packet) int {
+	dropped := pkt.Body["dropped"]
+	if len(dropped) < 4 {
+		return 0
+	}
+	return int(binary.LittleEndian.Uint32(dropped))
+}
+
+// publishes the inventory events of changes made in db until stop is called.
+// nil is whichever database is inventory.CurrentDB() when the change is made, the
+// one the built in functions work on.
+func (h *EventHub) PublishEvents(db *sql.DB) (stop func()) {
+	return inventory.AddDBEventListener(db, func(e *inventory.Event) {
+		if db == nil && e.DB != inventory.CurrentDB() {
+			return
+		}
+		// Publish fails only when no packet uuid can be made
+		h.Publish(e.Kind, e.Topics, e.Payload())
+	})
+}
+
+// queues the event for every subscriber of one of topics, never blocks
+func (h *EventHub) Publish(kind string, topics []string, body map[string][]byte) error {
+	pkt, err := NewEventPkt(kind, body)
+	if err != nil {
+		return err
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subs {
+		if !sub.matches(topics) {
+			continue
+		}
+		select {
+		case sub.queue <- pkt:
+		default:
+			sub.overflow(h.DisconnectSlow)
+		}
+	}
+	return nil
+}
+
+func (sub *eventSub) matches(topics []string) bool {
+	if sub.topics[TopicAll] {
+		return true
+	}
+	for _, t := range topics {
+		if sub.topics[t] {
+			return true
+		}
+	}
+	return false
+}
+
+func (sub *eventSub) overflow(disconnect bool) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if disconnect {
+		if !sub.slow {
+			sub.slow = true
+			sub.conn.Close()
+		}
+		return
+	}
+	sub.dropped++
+}
+
+func (sub *eventSub) takeDropped() uint32 {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	dropped := sub.dropped
+	sub.dropped = 0
+	return dropped
+}
+
+func (sub *eventSub) writeLoop() {
+	for {
+		select {
+		case pkt := <-sub.queue:
+			if dropped := sub.takeDropped(); dropped > 0 {
+				// the packet is shared with the other subscribers
+				copied := *pkt
+				copied.Body = map[string][]byte{}
+				for k, v := range pkt.Body {
+					copied.Body[k] = v
+				}
+				copied.Body["dropped"] = binary.LittleEndian.AppendUint32(nil, dropped)
+				pkt = &copied
+			}
+			// a failed write means the connection is gone, serve notices
+			// that and removes the subscriber
+			sub.conn.WritePacket(pkt)
+		case <-sub.done:
+			return
+		}
+	}
+}
+
+func (sub *eventSub) topicList() []string {
+	topics := make([]string, 0, len(sub.topics))
+	for t := range sub.topics {
+		topics = append(topics, t)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// adds topics for conn, sub is nil on its first subscribe
+func (h *EventHub) subscribe(sub *eventSub, conn *Conn, topics []string) (*eventSub, []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if sub == nil {
+		size := h.QueueSize
+		if size <= 0 {
+			size = defaultEventQueueSize
+		}
+		sub = &eventSub{
+			conn:   conn,
+			topics: map[string]bool{},
+			queue:  make(chan *Packet, size),
+			done:   make(chan struct{}),
+		}
+		if h.subs == nil {
+			h.subs = map[*eventSub]struct{}{}
+		}
+		h.subs[sub] = struct{}{}
+		go sub.writeLoop()
+	}
+	for _, t := range topics {
+		sub.topics[t] = true
+	}
+	return sub, sub.topicList()
+}
+
+// no topics drops all of them
+func (h *EventHub) unsubscribe(sub *eventSub, topics []string) []string {
+	if sub == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(topics) == 0 {
+		sub.topics = map[string]bool{}
+	}
+	for _, t := range topics {
+		delete(sub.topics, t)
+	}
+	return sub.topicList()
+}
+
+// stops the write loop, events still queued are not sent
+func (h *EventHub) remove(sub *eventSub) {
+	if sub == nil {
+		return
+	}
+	h.mu.Lock()
+	delete(h.subs, sub)
+	h.mu.Unlock()
+	close(sub.done)
+}
+
+func splitTopics(arg []byte) []string {
+	var topics []string
+	for _, t := range strings.Split(string(arg), "\n") {
+		if t = strings.TrimSpace(t); t != "" {
+			topics = append(topics, t)
+		}
+	}
+	return topics
+}
+
+// the code and message keys are what the codec packages answer with
+func newRespPkt(UUID uuid.UUID, code int32, message string, payload map[string][]byte) *Packet {
+	pkt := &Packet{
+		UUID: UUID,
+		Type: TypeResp,
+		Body: map[string][]byte{
+			"code":    binary.LittleEndian.AppendUint32(nil, uint32(code)),
+			"message": []byte(message),
+		},
+	}
+	for k, v := range payload {
+		pkt.Body[k] = v
+	}
+	return pkt
+}
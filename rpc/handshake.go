@@ -0,0 +1,242 @@
+package inventoryrpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// versions of the packet protocol this build speaks
+const (
+	ProtocolVersion    = 1
+	MinProtocolVersion = 1
+)
+
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+)
+
+// the first frame on a connection, before any codec is agreed on, is one of
+// these prefixes followed by json. a packet in any codec never starts with 0.
+var (
+	helloPrefix    = []byte("\x00inventoryrpc-hello\n")
+	helloAckPrefix = []byte("\x00inventoryrpc-hello-ack\n")
+)
+
+var ErrIncompatiblePeer = errors.New("incompatible peer")
+var ErrHandshakeRequired = errors.New("handshake required")
+var ErrUnknownCompression = errors.New("unknown compression")
+
+// what a client offers, Codecs and Compression in order of preference
+type Hello struct {
+	Version     int      `json:"version"`
+	MinVersion  int      `json:"min_version"`
+	Codecs      []string `json:"codecs"`
+	Compression []string `json:"compression,omitempty"`
+	Features    []string `json:"features,omitempty"`
+}
+
+// what the server settled on, Error set when it refused the client
+type HelloAck struct {
+	Version     int      `json:"version"`
+	Codec       string   `json:"codec"`
+	Compression string   `json:"compression"`
+	Features    []string `json:"features,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+type HandshakeError struct {
+	Reason string
+}
+
+func (e *HandshakeError) Error() string {
+	return "incompatible peer: " + e.Reason
+}
+
+func (e *HandshakeError) Unwrap() error {
+	return ErrIncompatiblePeer
+}
+
+func NewHello(codecs ...string) Hello {
+	return Hello{
+		Version:    ProtocolVersion,
+		MinVersion: MinProtocolVersion,
+		Codecs:     codecs,
+	}
+}
+
+func contains(strs []string, s string) bool {
+	for _, v := range strs {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// what the server answers to hello, given the codecs, compressions and
+// features it supports. version is the highest both speak.
+func Negotiate(hello Hello, codecs []string, compressions []string, features []string) HelloAck {
+	version := min(hello.Version, ProtocolVersion)
+	if version < hello.MinVersion || version < MinProtocolVersion {
+		return HelloAck{Error: fmt.Sprintf("protocol version %d-%d not supported, server speaks %d-%d",
+			hello.MinVersion, hello.Version, MinProtocolVersion, ProtocolVersion)}
+	}
+
+	ack := HelloAck{Version: version, Compression: CompressionNone}
+	for _, c := range hello.Codecs {
+		if contains(codecs, c) {
+			ack.Codec = c
+			break
+		}
+	}
+	if ack.Codec == "" {
+		return HelloAck{Error: fmt.Sprintf("no common codec, client offers [%s], server supports [%s]",
+			strings.Join(hello.Codecs, ", "), strings.Join(codecs, ", "))}
+	}
+	for _, c := range hello.Compression {
+		if c == CompressionNone || contains(compressions, c) {
+			ack.Compression = c
+			break
+		}
+	}
+	for _, f := range hello.Features {
+		if contains(features, f) {
+			ack.Features = append(ack.Features, f)
+		}
+	}
+	return ack
+}
+
+func encodeHello(prefix []byte, v any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, prefix...), b...), nil
+}
+
+// sends hello and waits for the server's answer, then switches c to the
+// agreed codec and compression. codecs holds a Codec for every name offered.
+// a refusal comes back as *HandshakeError.
+func (c *Conn) Handshake(hello Hello, codecs ...Codec) (*HelloAck, error) {
+	frame, err := encodeHello(helloPrefix, hello)
+	if err != nil {
+		return nil, err
+	}
+	err = c.writeFrame(frame)
+	if err != nil {
+		return nil, err
+	}
+	frame, err = c.readFrame()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(frame, helloAckPrefix) {
+		return nil, &HandshakeError{Reason: "server does not speak the handshake"}
+	}
+	var ack HelloAck
+	err = json.Unmarshal(frame[len(helloAckPrefix):], &ack)
+	if err != nil {
+		return nil, err
+	}
+	if ack.Error != "" {
+		return &ack, &HandshakeError{Reason: ack.Error}
+	}
+	err = c.apply(&ack, codecs)
+	if err != nil {
+		return &ack, err
+	}
+	return &ack, nil
+}
+
+func (c *Conn) apply(ack *HelloAck, codecs []Codec) error {
+	var codec Codec
+	for _, cd := range codecs {
+		if cd.Name() == ack.Codec {
+			codec = cd
+		}
+	}
+	if codec == nil {
+		return &HandshakeError{Reason: "agreed on codec " + ack.Codec + " which is not available"}
+	}
+	if ack.Compression != CompressionNone && ack.Compression != CompressionGzip {
+		return &HandshakeError{Reason: "agreed on compression " + ack.Compression + " which is not available"}
+	}
+	c.Codec = codec
+	c.Version = ack.Version
+	c.Compression = ack.Compression
+	c.Features = ack.Features
+	return nil
+}
+
+// server side of Handshake for the first frame of a connection. ok is false
+// when the frame is not a hello. a refused client gets the reason before the
+// error is returned.
+func (c *Conn) acceptHello(frame []byte, codecs []Codec, features []string) (ok bool, err error) {
+	if !bytes.HasPrefix(frame, helloPrefix) {
+		return false, nil
+	}
+	var hello Hello
+	err = json.Unmarshal(frame[len(helloPrefix):], &hello)
+	if err != nil {
+		return true, err
+	}
+	var names []string
+	for _, cd := range codecs {
+		names = append(names, cd.Name())
+	}
+	ack := Negotiate(hello, names, []string{CompressionGzip}, features)
+	reply, err := encodeHello(helloAckPrefix, ack)
+	if err != nil {
+		return true, err
+	}
+	err = c.writeFrame(reply)
+	if err != nil {
+		return true, err
+	}
+	if ack.Error != "" {
+		return true, &HandshakeError{Reason: ack.Error}
+	}
+	return true, c.apply(&ack, codecs)
+}
+
+func compress(compression string, data []byte) ([]byte, error) {
+	switch compression {
+	case "", CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write(data)
+		if err != nil {
+			return nil, err
+		}
+		err = w.Close()
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, ErrUnknownCompression
+}
+
+func decompress(compression string, data []byte) ([]byte, error) {
+	switch compression {
+	case "", CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	}
+	return nil, ErrUnknownCompression
+}
@@ -0,0 +1,135 @@
+package inventoryrpc
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// in process datagram link for trying ReliableConn, every datagram can be
+// dropped, duplicated and delayed by up to MaxDelay, which also reorders them
+type LossyConfig struct {
+	LossRate      float64
+	DuplicateRate float64
+	MaxDelay      time.Duration
+	Seed          int64
+}
+
+type LossyStats struct {
+	Sent       int
+	Dropped    int
+	Duplicated int
+}
+
+type lossyAddr string
+
+func (a lossyAddr) Network() string {
+	return "lossy"
+}
+
+func (a lossyAddr) String() string {
+	return string(a)
+}
+
+type lossyDatagram struct {
+	data []byte
+	from net.Addr
+}
+
+// one end of a LossyLink, a PacketConn that only reaches the other end
+type LossyConn struct {
+	link   *lossyLink
+	addr   lossyAddr
+	peer   *LossyConn
+	inbox  chan lossyDatagram
+	done   chan struct{}
+	closed sync.Once
+}
+
+type lossyLink struct {
+	cfg   LossyConfig
+	mu    sync.Mutex
+	rand  *rand.Rand
+	stats LossyStats
+}
+
+func NewLossyLink(cfg LossyConfig) (*LossyConn, *LossyConn) {
+	link := &lossyLink{cfg: cfg, rand: rand.New(rand.NewSource(cfg.Seed))}
+	a := &LossyConn{link: link, addr: "a", inbox: make(chan lossyDatagram, 1024), done: make(chan struct{})}
+	b := &LossyConn{link: link, addr: "b", inbox: make(chan lossyDatagram, 1024), done: make(chan struct{})}
+	a.peer, b.peer = b, a
+	return a, b
+}
+
+func (c *LossyConn) LocalAddr() net.Addr {
+	return c.addr
+}
+
+// counts for both directions
+func (c *LossyConn) Stats() LossyStats {
+	c.link.mu.Lock()
+	defer c.link.mu.Unlock()
+	return c.link.stats
+}
+
+func (c *LossyConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	select {
+	case d := <-c.inbox:
+		return copy(p, d.data), d.from, nil
+	case <-c.done:
+		return 0, nil, net.ErrClosed
+	}
+}
+
+// addr is ignored, the datagram goes to the other end
+func (c *LossyConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	select {
+	case <-c.done:
+		return 0, net.ErrClosed
+	default:
+	}
+
+	link := c.link
+	link.mu.Lock()
+	link.stats.Sent++
+	if link.rand.Float64() < link.cfg.LossRate {
+		link.stats.Dropped++
+		link.mu.Unlock()
+		return len(p), nil
+	}
+	copies := 1
+	if link.rand.Float64() < link.cfg.DuplicateRate {
+		link.stats.Duplicated++
+		copies = 2
+	}
+	var delays []time.Duration
+	for i := 0; i < copies; i++ {
+		var delay time.Duration
+		if link.cfg.MaxDelay > 0 {
+			delay = time.Duration(link.rand.Int63n(int64(link.cfg.MaxDelay)))
+		}
+		delays = append(delays, delay)
+	}
+	link.mu.Unlock()
+
+	d := lossyDatagram{data: append([]byte{}, p...), from: c.addr}
+	for _, delay := range delays {
+		time.AfterFunc(delay, func() {
+			select {
+			case c.peer.inbox <- d:
+			case <-c.peer.done:
+			default:
+				// a full inbox drops like a full socket buffer
+			}
+		})
+	}
+	return len(p), nil
+}
+
+func (c *LossyConn) Close() error {
+	c.closed.Do(func() {
+		close(c.done)
+	})
+	return nil
+}
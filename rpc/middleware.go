@@ -0,0 +1,103 @@
+package inventoryrpc
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrUnauthorized = errors.New("unauthorized")
+
+const CodeUnauthorized = -301
+
+// logs every call with its duration and error, nil l logs through the log
+// package
+func LogRequests(l *log.Logger) Middleware {
+	logf := log.Printf
+	if l != nil {
+		logf = l.Printf
+	}
+	return func(next HandlerFunc) HandlerFunc {
+		return func(req *Request) error {
+			start := time.Now()
+			err := next(req)
+			if err != nil {
+				logf("%s %s: %v (%v)", req.Func.Name, req.Pkt.UUID, err, time.Since(start))
+			} else {
+				logf("%s %s: ok (%v)", req.Func.Name, req.Pkt.UUID, time.Since(start))
+			}
+			return err
+		}
+	}
+}
+
+// refuses calls whose Meta[key] check does not accept with CodeUnauthorized,
+// the client sets it through Packet.Meta, inventorypb.Client.Meta for example
+func RequireMeta(key string, check func(value []byte) bool) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(req *Request) error {
+			value, ok := req.Pkt.Meta[key]
+			if !ok || !check(value) {
+				return &CodeError{Code: CodeUnauthorized, Err: ErrUnauthorized}
+			}
+			return next(req)
+		}
+	}
+}
+
+type FuncMetrics struct {
+	Name     string
+	Calls    int
+	Errors   int
+	Duration time.Duration
+}
+
+// call counts and time spent per function
+type Metrics struct {
+	mu    sync.Mutex
+	funcs map[string]*FuncMetrics
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		funcs: map[string]*FuncMetrics{},
+	}
+}
+
+func (m *Metrics) Middleware(next HandlerFunc) HandlerFunc {
+	return func(req *Request) error {
+		start := time.Now()
+		err := next(req)
+		elapsed := time.Since(start)
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		fm, ok := m.funcs[req.Func.Name]
+		if !ok {
+			fm = &FuncMetrics{Name: req.Func.Name}
+			m.funcs[req.Func.Name] = fm
+		}
+		fm.Calls++
+		if err != nil {
+			fm.Errors++
+		}
+		fm.Duration += elapsed
+		return err
+	}
+}
+
+// sorted by name
+func (m *Metrics) Snapshot() []FuncMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []FuncMetrics
+	for _, fm := range m.funcs {
+		list = append(list, *fm)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
@@ -0,0 +1,251 @@
+package inventoryrpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"inventory"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+var ErrCurrDbNotRegistered = errors.New("curr db not registered")
+var ErrReqHasNoFunc = errors.New("request has no function")
+var ErrCurrDbNil = errors.New("curr db nil")
+var ErrReqHasNoArg = errors.New("request has no arg")
+var ErrNoSuchFunc = errors.New("no such func")
+var ErrUnsupportedType = errors.New("type not supported by the codec")
+
+// the codec specific half of a Registry. DecodeArg returns a new value of
+// the type arg points to, filled from raw. EncodeResult marshals a value a
+// handler answers with, see Request.SetResult.
+type ArgCodec interface {
+	DecodeArg(arg any, raw []byte) (any, error)
+	EncodeResult(v any) ([]byte, error)
+}
+
+// a function the server answers. Arg points to an empty value of the type
+// the arg is decoded into, an inventory type or one of the queries here. nil
+// leaves the arg raw in Request.RawArg.
+type ServerFunc struct {
+	Name     string
+	Arg      any
+	NeedsDB  bool
+	NeedsArg bool
+	Handler  HandlerFunc
+}
+
+// one call being processed. Arg is nil when no arg was sent. the handler
+// puts its results into Payload. Send is set when the transport streams, see
+// Registry.ProcessStream, a handler able to stream sends its results through
+// it part by part instead.
+type Request struct {
+	Pkt     *Packet
+	Func    *ServerFunc
+	Arg     any
+	RawArg  []byte
+	Payload map[string][]byte
+	Send    func(payload map[string][]byte) error
+
+	codec ArgCodec
+}
+
+// puts v into Payload[key] in the encoding of the registry's codec
+func (req *Request) SetResult(key string, v any) error {
+	b, err := req.codec.EncodeResult(v)
+	if err != nil {
+		return err
+	}
+	req.Payload[key] = b
+	return nil
+}
+
+// returning an *UnmarshallError answers -101, a *CodeError its Code and any
+// other error -102
+type HandlerFunc func(req *Request) error
+
+// wraps every handler of a registry, it runs after the func, db and arg
+// checks and can stop the call by returning an error without calling next
+type Middleware func(next HandlerFunc) HandlerFunc
+
+type UnmarshallError struct {
+	Err error
+}
+
+func (e *UnmarshallError) Error() string {
+	return "error unmarshall: " + e.Err.Error()
+}
+
+func (e *UnmarshallError) Unwrap() error {
+	return e.Err
+}
+
+func unmarshallErr(err error) error {
+	return &UnmarshallError{Err: err}
+}
+
+// answers with Code instead of -102, for middleware refusing a call for
+// example. keep Code below -300, the lower ones are taken.
+type CodeError struct {
+	Code int32
+	Err  error
+}
+
+func (e *CodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CodeError) Unwrap() error {
+	return e.Err
+}
+
+type Registry struct {
+	mu         sync.RWMutex
+	codec      ArgCodec
+	funcs      map[string]*ServerFunc
+	names      []string
+	middleware []Middleware
+}
+
+func NewRegistry(codec ArgCodec) *Registry {
+	return &Registry{
+		codec: codec,
+		funcs: map[string]*ServerFunc{},
+	}
+}
+
+// registering a name again replaces its function
+func (r *Registry) Register(f *ServerFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.funcs[f.Name]; !ok {
+		r.names = append(r.names, f.Name)
+	}
+	r.funcs[f.Name] = f
+}
+
+// the first middleware given is the outermost
+func (r *Registry) Use(mw ...Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.middleware = append(r.middleware, mw...)
+}
+
+func (r *Registry) Lookup(name string) (*ServerFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f, ok := r.funcs[name]
+	return f, ok
+}
+
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.names...)
+}
+
+// answers pkt with the response packet, its message, code and the error the
+// function failed with
+func (r *Registry) Process(pkt *Packet) (*Packet, string, int32, error) {
+	return r.process(pkt, nil)
+}
+
+// as Process, with send as Request.Send. the checks and middleware run the
+// same, a handler that can't stream answers in the response packet.
+func (r *Registry) ProcessStream(pkt *Packet, send func(payload map[string][]byte) error) (*Packet, string, int32, error) {
+	return r.process(pkt, send)
+}
+
+func (r *Registry) process(pkt *Packet, send func(payload map[string][]byte) error) (*Packet, string, int32, error) {
+	// layer 0, check func
+	funcBytes, ok := pkt.Body["function"]
+	if !ok {
+		return CreateRespPkt(pkt.UUID, -201, nil, ErrReqHasNoFunc, ErrReqHasNoFunc.Error())
+	}
+
+	r.mu.RLock()
+	f, ok := r.funcs[string(funcBytes)]
+	middleware := r.middleware
+	r.mu.RUnlock()
+	if !ok {
+		return CreateRespPkt(pkt.UUID, -202, nil, ErrNoSuchFunc, ErrNoSuchFunc.Error())
+	}
+
+	// layer 1, check curr db
+	if f.NeedsDB && inventory.CurrentDB() == nil {
+		return CreateRespPkt(pkt.UUID, -203, nil, ErrCurrDbNil, ErrCurrDbNil.Error())
+	}
+
+	// layer 2, check arg ok
+	req := &Request{
+		Pkt:     pkt,
+		Func:    f,
+		RawArg:  pkt.Body["arg"],
+		Payload: map[string][]byte{},
+		Send:    send,
+		codec:   r.codec,
+	}
+	if len(req.RawArg) == 0 {
+		if f.NeedsArg {
+			return CreateRespPkt(pkt.UUID, -204, nil, ErrReqHasNoArg, ErrReqHasNoArg.Error())
+		}
+	} else if f.Arg != nil {
+		arg, err := r.codec.DecodeArg(f.Arg, req.RawArg)
+		if err != nil {
+			return CreateRespPktErrUnmarshall(pkt.UUID, err)
+		}
+		req.Arg = arg
+	}
+
+	// layer last
+	handler := f.Handler
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	err := handler(req)
+
+	var unmarshallErr *UnmarshallError
+	var codeErr *CodeError
+	if errors.As(err, &unmarshallErr) {
+		return CreateRespPktErrUnmarshall(pkt.UUID, unmarshallErr.Err)
+	}
+	if errors.As(err, &codeErr) {
+		return CreateRespPkt(pkt.UUID, codeErr.Code, nil, err, "%s", err.Error())
+	}
+	if err != nil {
+		return CreateRespPktErrExecFunc(pkt.UUID, err)
+	}
+
+	return CreateRespPkt(pkt.UUID, 0, req.Payload, nil, "ok")
+}
+
+// returns packet, message, code, error. the codes are the same whatever the
+// codec, a client does not need to know it to read them.
+func CreateRespPkt(UUID uuid.UUID, code int32, payload map[string][]byte, err error, format string, a ...any) (*Packet, string, int32, error) {
+	msg := fmt.Sprintf(format, a...)
+	codeBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(codeBytes, uint32(code))
+
+	pkt := &Packet{
+		UUID: UUID,
+		Type: TypeResp,
+		Meta: nil,
+		Body: map[string][]byte{
+			"code":    codeBytes,
+			"message": []byte(msg),
+		},
+	}
+	for k, v := range payload {
+		pkt.Body[k] = v
+	}
+	return pkt, msg, code, err
+}
+
+func CreateRespPktErrUnmarshall(UUID uuid.UUID, err error) (*Packet, string, int32, error) {
+	return CreateRespPkt(UUID, -101, nil, err, "error unmarshall: %s", err.Error())
+}
+
+func CreateRespPktErrExecFunc(UUID uuid.UUID, err error) (*Packet, string, int32, error) {
+	return CreateRespPkt(UUID, -102, nil, err, "error execute function: %s", err.Error())
+}
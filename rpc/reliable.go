@@ -0,0 +1,475 @@
+package inventoryrpc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	DatagramMagic = []byte{0xa0, 0xa2}
+)
+
+const (
+	datagramData = 0
+	datagramAck  = 1
+	datagramNack = 2
+)
+
+// magic, kind, seq, fragment index, fragment count, then the payload and a
+// crc32 of everything before it
+const datagramHeaderLen = 2 + 1 + 4 + 2 + 2
+const datagramOverhead = datagramHeaderLen + 4
+
+var ErrDeliveryFailed = errors.New("delivery failed, no ack after retries")
+var ErrReliableConnClosed = errors.New("reliable conn closed")
+var ErrReceiveStopped = errors.New("receiving stopped")
+var ErrMessageTooLarge = errors.New("message too large")
+
+// what ReliableConn sends over, net.PacketConn (udp) and LossyConn have it
+type PacketConn interface {
+	ReadFrom(p []byte) (n int, addr net.Addr, err error)
+	WriteTo(p []byte, addr net.Addr) (n int, err error)
+	Close() error
+}
+
+// whole messages over an unreliable datagram conn. a message is split into
+// fragments of up to MTU bytes, every fragment is acked by the receiver and
+// resent with a doubling timeout until it is. the receiver nacks the gaps it
+// sees so they are resent right away, reassembles the message and drops
+// messages it already delivered. messages are not ordered against each other.
+type ReliableConn struct {
+	Conn PacketConn
+	// largest datagram sent, header included
+	MTU               int
+	RetransmitTimeout time.Duration
+	MaxRetransmit     time.Duration
+	MaxRetries        int
+	// how long a delivered message is remembered to drop its duplicates, and
+	// how long an incomplete one or an idle peer is kept
+	DuplicateWindow time.Duration
+	// largest message sent or reassembled, in bytes and in fragments, and how
+	// many messages a peer can have half received. fragments past any of them
+	// are dropped unacked.
+	MaxMessageSize        int
+	MaxFragments          int
+	MaxIncompleteMessages int
+
+	mu      sync.Mutex
+	peers   map[string]*reliablePeer
+	nextSeq uint32
+	inbox   []reliableMessage
+	cond    *sync.Cond
+	stopped bool
+	closed  bool
+	done    chan struct{}
+	readErr error
+	timer   sync.Once
+}
+
+type reliableMessage struct {
+	data []byte
+	addr net.Addr
+}
+
+type reliablePeer struct {
+	addr      net.Addr
+	lastSeen  time.Time
+	out       map[uint32]*outMessage
+	in        map[uint32]*inMessage
+	delivered map[uint32]time.Time
+}
+
+type outMessage struct {
+	frags     [][]byte
+	acked     []bool
+	remaining int
+	retries   int
+	rto       time.Duration
+	nextRetry time.Time
+	done      chan error
+}
+
+type inMessage struct {
+	frags    [][]byte
+	got      int
+	size     int
+	highest  int
+	lastSeen time.Time
+}
+
+// starts reading from c, set the fields before the first Send or Receive
+func NewReliableConn(c PacketConn) *ReliableConn {
+	r := &ReliableConn{
+		Conn:                  c,
+		MTU:                   1200,
+		RetransmitTimeout:     100 * time.Millisecond,
+		MaxRetransmit:         2 * time.Second,
+		MaxRetries:            10,
+		DuplicateWindow:       time.Minute,
+		MaxMessageSize:        4 << 20,
+		MaxFragments:          4096,
+		MaxIncompleteMessages: 64,
+		peers:                 map[string]*reliablePeer{},
+		// a restarted sender does not reuse the seqs the peer remembers
+		nextSeq: rand.Uint32(),
+		done:    make(chan struct{}),
+	}
+	r.cond = sync.NewCond(&r.mu)
+	go r.readLoop()
+	return r
+}
+
+func (r *ReliableConn) peer(addr net.Addr) *reliablePeer {
+	key := addr.String()
+	p, ok := r.peers[key]
+	if !ok {
+		p = &reliablePeer{
+			addr:      addr,
+			out:       map[uint32]*outMessage{},
+			in:        map[uint32]*inMessage{},
+			delivered: map[uint32]time.Time{},
+		}
+		r.peers[key] = p
+	}
+	p.lastSeen = time.Now()
+	return p
+}
+
+func encodeDatagram(kind byte, seq uint32, index, count uint16, payload []byte) []byte {
+	b := make([]byte, datagramHeaderLen, datagramOverhead+len(payload))
+	copy(b, DatagramMagic)
+	b[2] = kind
+	binary.LittleEndian.PutUint32(b[3:7], seq)
+	binary.LittleEndian.PutUint16(b[7:9], index)
+	binary.LittleEndian.PutUint16(b[9:11], count)
+	b = append(b, payload...)
+	return binary.LittleEndian.AppendUint32(b, crc32.ChecksumIEEE(b))
+}
+
+func decodeDatagram(b []byte) (kind byte, seq uint32, index, count uint16, payload []byte, ok bool) {
+	if len(b) < datagramOverhead || b[0] != DatagramMagic[0] || b[1] != DatagramMagic[1] {
+		return
+	}
+	end := len(b) - 4
+	if crc32.ChecksumIEEE(b[:end]) != binary.LittleEndian.Uint32(b[end:]) {
+		return
+	}
+	return b[2], binary.LittleEndian.Uint32(b[3:7]), binary.LittleEndian.Uint16(b[7:9]),
+		binary.LittleEndian.Uint16(b[9:11]), b[datagramHeaderLen:end], true
+}
+
+// blocks until every fragment of data is acked by addr, the retries run out
+// or ctx ends
+func (r *ReliableConn) Send(ctx context.Context, data []byte, addr net.Addr) error {
+	fragSize := r.MTU - datagramOverhead
+	if fragSize <= 0 {
+		return ErrMessageTooLarge
+	}
+	count := (len(data) + fragSize - 1) / fragSize
+	if count == 0 {
+		count = 1
+	}
+	if count > 0xffff || count > r.MaxFragments || len(data) > r.MaxMessageSize {
+		return ErrMessageTooLarge
+	}
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return ErrReliableConnClosed
+	}
+	seq := r.nextSeq
+	r.nextSeq++
+	msg := &outMessage{
+		frags:     make([][]byte, count),
+		acked:     make([]bool, count),
+		remaining: count,
+		rto:       r.RetransmitTimeout,
+		nextRetry: time.Now().Add(r.RetransmitTimeout),
+		done:      make(chan error, 1),
+	}
+	for i := range msg.frags {
+		end := min((i+1)*fragSize, len(data))
+		msg.frags[i] = encodeDatagram(datagramData, seq, uint16(i), uint16(count), data[i*fragSize:end])
+	}
+	p := r.peer(addr)
+	p.out[seq] = msg
+	r.mu.Unlock()
+	r.startTimer()
+
+	for _, frag := range msg.frags {
+		r.Conn.WriteTo(frag, addr)
+	}
+
+	select {
+	case err := <-msg.done:
+		return err
+	case <-ctx.Done():
+		r.mu.Lock()
+		delete(p.out, seq)
+		r.mu.Unlock()
+		return ctx.Err()
+	case <-r.done:
+		return ErrReliableConnClosed
+	}
+}
+
+// next whole message and who sent it
+func (r *ReliableConn) Receive() ([]byte, net.Addr, error) {
+	r.startTimer()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for {
+		if r.stopped || r.closed {
+			return nil, nil, ErrReceiveStopped
+		}
+		if len(r.inbox) > 0 {
+			msg := r.inbox[0]
+			r.inbox = r.inbox[1:]
+			return msg.data, msg.addr, nil
+		}
+		if r.readErr != nil {
+			return nil, nil, r.readErr
+		}
+		r.cond.Wait()
+	}
+}
+
+// makes Receive return ErrReceiveStopped while acks and sends go on, for a
+// graceful shutdown
+func (r *ReliableConn) StopReceiving() {
+	r.mu.Lock()
+	r.stopped = true
+	r.cond.Broadcast()
+	r.mu.Unlock()
+}
+
+func (r *ReliableConn) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.done)
+	r.cond.Broadcast()
+	r.mu.Unlock()
+	return r.Conn.Close()
+}
+
+func (r *ReliableConn) readLoop() {
+	buf := make([]byte, 65536)
+	for {
+		n, addr, err := r.Conn.ReadFrom(buf)
+		if err != nil {
+			r.mu.Lock()
+			if r.closed {
+				err = ErrReliableConnClosed
+			}
+			r.readErr = err
+			r.cond.Broadcast()
+			r.mu.Unlock()
+			return
+		}
+		kind, seq, index, count, payload, ok := decodeDatagram(buf[:n])
+		if !ok {
+			continue
+		}
+		switch kind {
+		case datagramData:
+			r.handleData(addr, seq, index, count, payload)
+		case datagramAck:
+			r.handleAck(addr, seq, index)
+		case datagramNack:
+			r.handleNack(addr, seq, payload)
+		}
+	}
+}
+
+func (r *ReliableConn) handleData(addr net.Addr, seq uint32, index, count uint16, payload []byte) {
+	ack := encodeDatagram(datagramAck, seq, index, 0, nil)
+	if index >= count || int(count) > r.MaxFragments {
+		return
+	}
+
+	r.mu.Lock()
+	p := r.peer(addr)
+	if _, ok := p.delivered[seq]; ok {
+		// our ack got lost, the message is not delivered again
+		r.mu.Unlock()
+		r.Conn.WriteTo(ack, addr)
+		return
+	}
+	msg, ok := p.in[seq]
+	if !ok {
+		if len(p.in) >= r.MaxIncompleteMessages {
+			r.mu.Unlock()
+			return
+		}
+		msg = &inMessage{frags: make([][]byte, count), highest: -1}
+		p.in[seq] = msg
+	}
+	if int(count) != len(msg.frags) {
+		r.mu.Unlock()
+		return
+	}
+	msg.lastSeen = time.Now()
+	if msg.frags[index] == nil {
+		if msg.size+len(payload) > r.MaxMessageSize {
+			delete(p.in, seq)
+			r.mu.Unlock()
+			return
+		}
+		msg.frags[index] = append([]byte{}, payload...)
+		msg.got++
+		msg.size += len(payload)
+	}
+
+	// fragments are sent in order, a jump means the ones between got lost
+	var missing []byte
+	if int(index) > msg.highest+1 {
+		for i := msg.highest + 1; i < int(index); i++ {
+			if msg.frags[i] == nil {
+				missing = binary.LittleEndian.AppendUint16(missing, uint16(i))
+			}
+		}
+	}
+	if int(index) > msg.highest {
+		msg.highest = int(index)
+	}
+
+	if msg.got == len(msg.frags) {
+		var data []byte
+		for _, frag := range msg.frags {
+			data = append(data, frag...)
+		}
+		delete(p.in, seq)
+		p.delivered[seq] = time.Now()
+		r.inbox = append(r.inbox, reliableMessage{data: data, addr: addr})
+		r.cond.Broadcast()
+	}
+	r.mu.Unlock()
+
+	r.Conn.WriteTo(ack, addr)
+	if len(missing) > 0 {
+		r.Conn.WriteTo(encodeDatagram(datagramNack, seq, 0, uint16(len(missing)/2), missing), addr)
+	}
+}
+
+func (r *ReliableConn) handleAck(addr net.Addr, seq uint32, index uint16) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.peers[addr.String()]
+	if !ok {
+		return
+	}
+	msg, ok := p.out[seq]
+	if !ok || int(index) >= len(msg.acked) || msg.acked[index] {
+		return
+	}
+	msg.acked[index] = true
+	msg.remaining--
+	if msg.remaining == 0 {
+		delete(p.out, seq)
+		msg.done <- nil
+	}
+}
+
+func (r *ReliableConn) handleNack(addr net.Addr, seq uint32, payload []byte) {
+	var resend [][]byte
+	r.mu.Lock()
+	var msg *outMessage
+	p, ok := r.peers[addr.String()]
+	if ok {
+		msg, ok = p.out[seq]
+	}
+	if ok {
+		for i := 0; i+2 <= len(payload); i += 2 {
+			index := int(binary.LittleEndian.Uint16(payload[i:]))
+			if index < len(msg.acked) && !msg.acked[index] {
+				resend = append(resend, msg.frags[index])
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	for _, frag := range resend {
+		r.Conn.WriteTo(frag, addr)
+	}
+}
+
+// the fields are read from here on
+func (r *ReliableConn) startTimer() {
+	r.timer.Do(func() {
+		go r.timerLoop()
+	})
+}
+
+// resends what is not acked in time and forgets old receive state
+func (r *ReliableConn) timerLoop() {
+	tick := max(r.RetransmitTimeout/4, 5*time.Millisecond)
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+		}
+
+		type resend struct {
+			frag []byte
+			addr net.Addr
+		}
+		var resends []resend
+		now := time.Now()
+
+		r.mu.Lock()
+		for key, p := range r.peers {
+			for seq, msg := range p.out {
+				if now.Before(msg.nextRetry) {
+					continue
+				}
+				if msg.retries >= r.MaxRetries {
+					delete(p.out, seq)
+					msg.done <- ErrDeliveryFailed
+					continue
+				}
+				msg.retries++
+				msg.rto = min(msg.rto*2, r.MaxRetransmit)
+				msg.nextRetry = now.Add(msg.rto)
+				for i, frag := range msg.frags {
+					if !msg.acked[i] {
+						resends = append(resends, resend{frag, p.addr})
+					}
+				}
+			}
+			for seq, msg := range p.in {
+				if now.Sub(msg.lastSeen) > r.DuplicateWindow {
+					delete(p.in, seq)
+				}
+			}
+			for seq, at := range p.delivered {
+				if now.Sub(at) > r.DuplicateWindow {
+					delete(p.delivered, seq)
+				}
+			}
+			if len(p.out) == 0 && len(p.in) == 0 && len(p.delivered) == 0 &&
+				now.Sub(p.lastSeen) > r.DuplicateWindow {
+				delete(r.peers, key)
+			}
+		}
+		r.mu.Unlock()
+
+		for _, rs := range resends {
+			r.Conn.WriteTo(rs.frag, rs.addr)
+		}
+	}
+}
@@ -15,6 +15,8 @@ var (
 const (
 	TypeReq  = 0
 	TypeResp = 1
+	// pushed by the server to subscribers, see EventHub
+	TypeEvent = 2
 )
 
 type PacketWrapper struct {
@@ -0,0 +1,477 @@
+package inventoryrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrServerClosed = errors.New("server closed")
+var ErrNoCodec = errors.New("no codec")
+var ErrServerNoProcessor = errors.New("no processor")
+
+// serves the packet protocol over stream listeners (tcp, unix). every
+// connection keeps its own PacketBuffer, requests on it are processed
+// concurrently and the responses are written back on the same connection.
+//
+// the server is single-tenant: the built in functions work on
+// inventory.CurrentDB, so every connection shares the database the last
+// OpenOrCreateDB selected, whichever client sent it.
+type Server struct {
+	Processor ProcessorInterface
+	// used for clients that skip the handshake, and offered first to those
+	// doing it
+	Codec Codec
+	// other codecs a handshake can agree on
+	Codecs []Codec
+	// feature flags a handshake can agree on, FeatureEvents is added when
+	// Events is set
+	Features []string
+	// answers FuncSubscribe and FuncUnsubscribe on stream connections, nil
+	// leaves them to Processor. see EventHub.PublishEvents for what it pushes.
+	Events *EventHub
+	// close connections whose first frame is not a hello instead of serving
+	// them with Codec
+	RequireHandshake bool
+
+	// connections over the limit are closed right after accept, 0 is no limit
+	MaxConns int
+	// a connection with no request in flight that sends nothing for this long
+	// is closed, 0 is no timeout
+	IdleTimeout  time.Duration
+	WriteTimeout time.Duration
+	// nil logs through the log package
+	ErrorLog *log.Logger
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[*serverConn]struct{}
+	datagrams map[*ReliableConn]struct{}
+	closing   bool
+	connWg    sync.WaitGroup
+}
+
+type serverConn struct {
+	*Conn
+	server   *Server
+	inFlight sync.WaitGroup
+	mu       sync.Mutex
+	active   int
+	closing  bool
+	// set on the first subscribe, only touched by the read loop
+	sub *eventSub
+}
+
+func (s *Server) logf(format string, a ...any) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, a...)
+		return
+	}
+	log.Printf(format, a...)
+}
+
+// network is "tcp" or "unix"
+func (s *Server) ListenAndServe(network, address string) error {
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// accepts connections on l until Shutdown or Close, always returns a non nil
+// error, ErrServerClosed after a shutdown
+func (s *Server) Serve(l net.Listener) error {
+	if s.Processor == nil {
+		return ErrServerNoProcessor
+	}
+	if s.Codec == nil {
+		return ErrNoCodec
+	}
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	if s.listeners == nil {
+		s.listeners = map[net.Listener]struct{}{}
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+		l.Close()
+	}()
+
+	var delay time.Duration
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			if s.isClosing() {
+				return ErrServerClosed
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				if delay == 0 {
+					delay = 5 * time.Millisecond
+				} else if delay *= 2; delay > time.Second {
+					delay = time.Second
+				}
+				s.logf("inventoryrpc: accept error: %v, retrying in %v", err, delay)
+				time.Sleep(delay)
+				continue
+			}
+			return err
+		}
+		delay = 0
+
+		sc := &serverConn{Conn: NewConn(c, s.Codec), server: s}
+		sc.WriteTimeout = s.WriteTimeout
+		if !s.trackConn(sc) {
+			c.Close()
+			continue
+		}
+		go sc.serve()
+	}
+}
+
+func (s *Server) isClosing() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closing
+}
+
+func (s *Server) trackConn(sc *serverConn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing {
+		return false
+	}
+	if s.MaxConns > 0 && len(s.conns) >= s.MaxConns {
+		s.logf("inventoryrpc: connection limit %d reached, rejecting %s", s.MaxConns, sc.NetConn.RemoteAddr())
+		return false
+	}
+	if s.conns == nil {
+		s.conns = map[*serverConn]struct{}{}
+	}
+	s.conns[sc] = struct{}{}
+	s.connWg.Add(1)
+	return true
+}
+
+func (s *Server) untrackConn(sc *serverConn) {
+	s.mu.Lock()
+	delete(s.conns, sc)
+	s.mu.Unlock()
+	s.connWg.Done()
+}
+
+func (s *Server) NumConns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// stops accepting, stops reading new requests and waits for the ones in
+// flight to be answered before closing the connections. when ctx ends first
+// the remaining connections are closed and ctx.Err() is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closing = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for sc := range s.conns {
+		sc.stopReading()
+	}
+	for r := range s.datagrams {
+		r.StopReceiving()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.connWg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.Close()
+		return ctx.Err()
+	}
+}
+
+// closes listeners and connections at once, requests in flight get no answer
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closing = true
+	var err error
+	for l := range s.listeners {
+		if cerr := l.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	for sc := range s.conns {
+		sc.NetConn.Close()
+	}
+	for r := range s.datagrams {
+		r.Close()
+	}
+	return err
+}
+
+// serves requests arriving as messages on r, each message one packet encoded
+// by Codec, there is no handshake. sends every response back to the address it came from. r is
+// closed on return.
+func (s *Server) ServeDatagram(r *ReliableConn) error {
+	if s.Processor == nil {
+		return ErrServerNoProcessor
+	}
+	if s.Codec == nil {
+		return ErrNoCodec
+	}
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		r.Close()
+		return ErrServerClosed
+	}
+	if s.datagrams == nil {
+		s.datagrams = map[*ReliableConn]struct{}{}
+	}
+	s.datagrams[r] = struct{}{}
+	s.connWg.Add(1)
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.datagrams, r)
+		s.mu.Unlock()
+		r.Close()
+		s.connWg.Done()
+	}()
+
+	var inFlight sync.WaitGroup
+	var err error
+	for {
+		var data []byte
+		var addr net.Addr
+		data, addr, err = r.Receive()
+		if err != nil {
+			break
+		}
+		pkt, uerr := s.Codec.Unmarshal(data)
+		if uerr != nil {
+			s.logf("inventoryrpc: %s: %v", addr, uerr)
+			continue
+		}
+		if pkt.Type != TypeReq {
+			continue
+		}
+		inFlight.Add(1)
+		go func() {
+			defer inFlight.Done()
+			responsePkt, message, _, err := s.Processor.ProcessPkt(pkt)
+			if err != nil {
+				s.logf("inventoryrpc: %s", message)
+			}
+			if responsePkt == nil {
+				return
+			}
+			responseBytes, err := s.Codec.Marshal(responsePkt)
+			if err != nil {
+				s.logf("inventoryrpc: %s: %v", addr, err)
+				return
+			}
+			err = r.Send(context.Background(), responseBytes, addr)
+			if err != nil {
+				s.logf("inventoryrpc: %s: send response: %v", addr, err)
+			}
+		}()
+	}
+	inFlight.Wait()
+	if s.isClosing() {
+		return ErrServerClosed
+	}
+	return err
+}
+
+// wakes the read loop up so it sees closing
+func (sc *serverConn) stopReading() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.closing = true
+	sc.NetConn.SetReadDeadline(time.Now())
+}
+
+// sets the idle deadline unless stopReading already ran, under the same lock
+// so its deadline is not overwritten
+func (sc *serverConn) armReadDeadline() bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.closing {
+		return false
+	}
+	if sc.server.IdleTimeout > 0 {
+		sc.NetConn.SetReadDeadline(time.Now().Add(sc.server.IdleTimeout))
+	} else {
+		sc.NetConn.SetReadDeadline(time.Time{})
+	}
+	return true
+}
+
+func (sc *serverConn) isClosing() bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.closing
+}
+
+func (sc *serverConn) busy() bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.active > 0
+}
+
+func (s *Server) codecs() []Codec {
+	codecs := []Codec{s.Codec}
+	for _, c := range s.Codecs {
+		if c.Name() != s.Codec.Name() {
+			codecs = append(codecs, c)
+		}
+	}
+	return codecs
+}
+
+func (s *Server) features() []string {
+	if s.Events == nil || contains(s.Features, FeatureEvents) {
+		return s.Features
+	}
+	return append(append([]string{}, s.Features...), FeatureEvents)
+}
+
+func (sc *serverConn) serve() {
+	s := sc.server
+	defer s.untrackConn(sc)
+	defer sc.NetConn.Close()
+	defer func() {
+		if s.Events != nil {
+			s.Events.remove(sc.sub)
+		}
+	}()
+
+	first := true
+	for sc.armReadDeadline() {
+		frame, err := sc.readFrame()
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() && !sc.isClosing() && sc.busy() {
+				// not idle while a response is still owed
+				continue
+			}
+			if !errors.As(err, &ne) && !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				s.logf("inventoryrpc: %s: %v", sc.NetConn.RemoteAddr(), err)
+			}
+			break
+		}
+		if first {
+			first = false
+			isHello, err := sc.acceptHello(frame, s.codecs(), s.features())
+			if err != nil {
+				s.logf("inventoryrpc: %s: %v", sc.NetConn.RemoteAddr(), err)
+				break
+			}
+			if isHello {
+				continue
+			}
+			if s.RequireHandshake {
+				s.logf("inventoryrpc: %s: %v", sc.NetConn.RemoteAddr(), ErrHandshakeRequired)
+				break
+			}
+		}
+		pkt, err := sc.decodeFrame(frame)
+		if err != nil {
+			s.logf("inventoryrpc: %s: %v", sc.NetConn.RemoteAddr(), err)
+			break
+		}
+		if pkt.Type != TypeReq {
+			continue
+		}
+		// answered in order, so calls sent after a subscribe see its events
+		if sc.handleSubscription(pkt) {
+			continue
+		}
+
+		sc.mu.Lock()
+		sc.active++
+		sc.mu.Unlock()
+		sc.inFlight.Add(1)
+		go sc.handle(pkt)
+	}
+	sc.inFlight.Wait()
+}
+
+func (sc *serverConn) handle(pkt *Packet) {
+	s := sc.server
+	defer func() {
+		sc.mu.Lock()
+		sc.active--
+		sc.mu.Unlock()
+		sc.inFlight.Done()
+	}()
+
+	responsePkt, message, _, err := s.Processor.ProcessPkt(pkt)
+	if err != nil {
+		s.logf("inventoryrpc: %s", message)
+	}
+	if responsePkt == nil {
+		return
+	}
+	err = sc.WritePacket(responsePkt)
+	if err != nil {
+		s.logf("inventoryrpc: %s: write response: %v", sc.NetConn.RemoteAddr(), err)
+	}
+}
+
+// answers pkt when it is a subscribe or unsubscribe and the server has an
+// EventHub
+func (sc *serverConn) handleSubscription(pkt *Packet) bool {
+	hub := sc.server.Events
+	if hub == nil {
+		return false
+	}
+	function := string(pkt.Body["function"])
+	if function != FuncSubscribe && function != FuncUnsubscribe {
+		return false
+	}
+
+	topics := splitTopics(pkt.Body["arg"])
+	var responsePkt *Packet
+	switch {
+	case function == FuncUnsubscribe:
+		subscribed := hub.unsubscribe(sc.sub, topics)
+		responsePkt = newRespPkt(pkt.UUID, 0, "ok", map[string][]byte{"topics": []byte(strings.Join(subscribed, "\n"))})
+	case len(topics) == 0:
+		responsePkt = newRespPkt(pkt.UUID, -204, "request has no arg", nil)
+	default:
+		var subscribed []string
+		sc.sub, subscribed = hub.subscribe(sc.sub, sc.Conn, topics)
+		responsePkt = newRespPkt(pkt.UUID, 0, "ok", map[string][]byte{"topics": []byte(strings.Join(subscribed, "\n"))})
+	}
+	err := sc.WritePacket(responsePkt)
+	if err != nil {
+		sc.server.logf("inventoryrpc: %s: write response: %v", sc.NetConn.RemoteAddr(), err)
+	}
+	return true
+}
@@ -0,0 +1,362 @@
+package inventoryrpc
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"inventory"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// the args of the built in functions that are no inventory type. every codec
+// has a message of its own for each.
+
+type MarketPriceImport struct {
+	Format string
+	Data   []byte
+}
+
+type MarketPriceHistoryQuery struct {
+	ItemUUID       uuid.UUID
+	FromDatetimeMs int64
+	ToDatetimeMs   int64
+}
+
+type PeriodQuery struct {
+	FromDatetimeMs int64
+	ToDatetimeMs   int64
+}
+
+// Party is nil for every party
+type PartyQuery struct {
+	Party          *inventory.Party
+	FromDatetimeMs int64
+	ToDatetimeMs   int64
+}
+
+// the template is looked up by TemplateUUID, or by TemplateName when it is
+// uuid.Nil
+type TemplateInstantiation struct {
+	TemplateUUID uuid.UUID
+	TemplateName string
+	Args         map[string]string
+}
+
+// the functions every codec's server answers. the results they set are
+// []inventory.MarketPrice, []inventory.PartyBalance,
+// []*inventory.RecurringTransaction, []inventory.RecurringOccurrence and
+// []*inventory.TransactionTemplate, an ArgCodec encodes those.
+var builtinFuncs = []*ServerFunc{
+	{Name: "GetCurrDB", NeedsDB: true, Handler: handleGetCurrDB},
+	{Name: "OpenOrCreateDB", Handler: handleOpenOrCreateDB},
+	{Name: "AddItem", Arg: &inventory.Item{}, NeedsDB: true, NeedsArg: true, Handler: handleAddItem},
+	{Name: "AddAccount", Arg: &inventory.Account{}, NeedsDB: true, NeedsArg: true, Handler: handleAddAccount},
+	{Name: "ApplyTransaction", Arg: &inventory.Transaction{}, NeedsDB: true, NeedsArg: true, Handler: handleApplyTransaction},
+	{Name: "GetMainAccounts", NeedsDB: true, Handler: handleGetMainAccounts},
+	{Name: "UpdateMarketPrice", Arg: &inventory.MarketPrice{}, NeedsDB: true, NeedsArg: true, Handler: handleUpdateMarketPrice},
+	{Name: "ImportMarketPrices", Arg: &MarketPriceImport{}, NeedsDB: true, NeedsArg: true, Handler: handleImportMarketPrices},
+	{Name: "GetMarketPriceHistory", Arg: &MarketPriceHistoryQuery{}, NeedsDB: true, NeedsArg: true, Handler: handleGetMarketPriceHistory},
+	{Name: "AddTaxCode", Arg: &inventory.TaxCode{}, NeedsDB: true, NeedsArg: true, Handler: handleAddTaxCode},
+	{Name: "PrintTaxSummary", Arg: &PeriodQuery{}, NeedsDB: true, NeedsArg: true, Handler: handlePrintTaxSummary},
+	{Name: "AddParty", Arg: &inventory.Party{}, NeedsDB: true, NeedsArg: true, Handler: handleAddParty},
+	{Name: "GetPartyBalances", Arg: &PartyQuery{}, NeedsDB: true, NeedsArg: true, Handler: handleGetPartyBalances},
+	{Name: "PrintAgingReport", Arg: &PartyQuery{}, NeedsDB: true, NeedsArg: true, Handler: handlePrintAgingReport},
+	{Name: "PrintPartyStatement", Arg: &PartyQuery{}, NeedsDB: true, NeedsArg: true, Handler: handlePrintPartyStatement},
+	{Name: "AddRecurringTransaction", Arg: &inventory.RecurringTransaction{}, NeedsDB: true, NeedsArg: true, Handler: handleAddRecurringTransaction},
+	{Name: "GetRecurringTransactions", NeedsDB: true, Handler: handleGetRecurringTransactions},
+	{Name: "SetRecurringTransactionActive", Arg: &inventory.RecurringTransaction{}, NeedsDB: true, NeedsArg: true, Handler: handleSetRecurringTransactionActive},
+	{Name: "PreviewRecurringTransactions", Arg: &PeriodQuery{}, NeedsDB: true, NeedsArg: true, Handler: handlePreviewRecurringTransactions},
+	{Name: "RunRecurringTransactions", Arg: &PeriodQuery{}, NeedsDB: true, Handler: handleRunRecurringTransactions},
+	{Name: "AddTransactionTemplate", Arg: &inventory.TransactionTemplate{}, NeedsDB: true, NeedsArg: true, Handler: handleAddTransactionTemplate},
+	{Name: "GetTransactionTemplates", NeedsDB: true, Handler: handleGetTransactionTemplates},
+	{Name: "ApplyTransactionTemplate", Arg: &TemplateInstantiation{}, NeedsDB: true, NeedsArg: true, Handler: handleApplyTransactionTemplate},
+	{Name: "PrintBalances", NeedsDB: true, Handler: handlePrintBalances},
+	{Name: "PrintMarketBalances", NeedsDB: true, Handler: handlePrintMarketBalances},
+	{Name: "CloseCurrDB", NeedsDB: true, Handler: handleCloseCurrDB},
+}
+
+// registers the built in functions on r
+func RegisterBuiltinFuncs(r *Registry) {
+	for _, f := range builtinFuncs {
+		r.Register(f)
+	}
+}
+
+func handleGetCurrDB(req *Request) error {
+	_, dbUUIDBytes := inventory.GetCurrDBUUID()
+	if dbUUIDBytes == uuid.Nil {
+		return ErrCurrDbNotRegistered
+	}
+	req.Payload["uuid"] = dbUUIDBytes[:]
+	return nil
+}
+
+func handleOpenOrCreateDB(req *Request) error {
+	var err error
+	var dbUUID uuid.UUID
+	if len(req.RawArg) > 0 {
+		dbUUID, err = uuid.FromBytes(req.RawArg)
+		if err != nil {
+			return unmarshallErr(err)
+		}
+	} else {
+		dbUUID, err = uuid.NewV7()
+		if err != nil {
+			return err
+		}
+	}
+	_, err = inventory.OpenOrCreateDB(dbUUID)
+	if err != nil {
+		return err
+	}
+	req.Payload["uuid"] = dbUUID[:]
+	return nil
+}
+
+func handleAddItem(req *Request) error {
+	entityUUIDBytes, err := inventory.AddItem(inventory.CurrentDB(), req.Arg.(*inventory.Item))
+	if err != nil {
+		return err
+	}
+	req.Payload["uuid"] = entityUUIDBytes
+	return nil
+}
+
+func handleAddAccount(req *Request) error {
+	entityUUIDBytes, err := inventory.AddAccount(inventory.CurrentDB(), req.Arg.(*inventory.Account))
+	if err != nil {
+		return err
+	}
+	req.Payload["uuid"] = entityUUIDBytes
+	return nil
+}
+
+func handleApplyTransaction(req *Request) error {
+	entityUUIDBytes, err := inventory.ApplyTransaction(inventory.CurrentDB(), req.Arg.(*inventory.Transaction))
+	if err != nil {
+		return err
+	}
+	req.Payload["uuid"] = entityUUIDBytes
+	return nil
+}
+
+func handleGetMainAccounts(req *Request) error {
+	accs := []*inventory.Account{
+		inventory.AssetAcc,
+		inventory.EquityAcc,
+		inventory.LiabilityAcc,
+		inventory.IncomeAcc,
+		inventory.ExpenseAcc,
+	}
+	// the main account variables keep the uuids of the last tree built, which
+	// may be another database's. their ids are the same in every database.
+	db := inventory.CurrentDB()
+	for _, a := range accs {
+		acc, err := inventory.GetAccountByID(db, a.ID)
+		if err != nil {
+			return err
+		}
+		req.Payload[acc.Name] = acc.UUID[:]
+	}
+	return nil
+}
+
+func handleUpdateMarketPrice(req *Request) error {
+	return inventory.UpdateMarketPrice(inventory.CurrentDB(), req.Arg.(*inventory.MarketPrice))
+}
+
+func handleImportMarketPrices(req *Request) error {
+	priceImport := req.Arg.(*MarketPriceImport)
+	count, err := inventory.ImportMarketPrices(inventory.CurrentDB(), bytes.NewReader(priceImport.Data), priceImport.Format)
+	if err != nil {
+		return err
+	}
+	countBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(countBytes, uint32(count))
+	req.Payload["count"] = countBytes
+	return nil
+}
+
+func handleGetMarketPriceHistory(req *Request) error {
+	query := req.Arg.(*MarketPriceHistoryQuery)
+	prices, err := inventory.GetMarketPriceHistory(inventory.CurrentDB(), &inventory.Item{UUID: query.ItemUUID}, query.FromDatetimeMs, query.ToDatetimeMs)
+	if err != nil {
+		return err
+	}
+	return req.SetResult("prices", prices)
+}
+
+func handleAddTaxCode(req *Request) error {
+	entityUUIDBytes, err := inventory.AddTaxCode(inventory.CurrentDB(), req.Arg.(*inventory.TaxCode))
+	if err != nil {
+		return err
+	}
+	req.Payload["uuid"] = entityUUIDBytes
+	return nil
+}
+
+func handlePrintTaxSummary(req *Request) error {
+	query := req.Arg.(*PeriodQuery)
+	str, err := inventory.SprintTaxSummary(inventory.CurrentDB(), query.FromDatetimeMs, query.ToDatetimeMs)
+	if err != nil {
+		return err
+	}
+	req.Payload["summary"] = []byte(str)
+	return nil
+}
+
+func handleAddParty(req *Request) error {
+	entityUUIDBytes, err := inventory.AddParty(inventory.CurrentDB(), req.Arg.(*inventory.Party))
+	if err != nil {
+		return err
+	}
+	req.Payload["uuid"] = entityUUIDBytes
+	return nil
+}
+
+func handleGetPartyBalances(req *Request) error {
+	query := req.Arg.(*PartyQuery)
+	balances, err := inventory.GetPartyBalances(inventory.CurrentDB(), query.Party, query.ToDatetimeMs)
+	if err != nil {
+		return err
+	}
+	return req.SetResult("balances", balances)
+}
+
+func handlePrintAgingReport(req *Request) error {
+	query := req.Arg.(*PartyQuery)
+	str, err := inventory.SprintAgingReport(inventory.CurrentDB(), query.Party, query.ToDatetimeMs)
+	if err != nil {
+		return err
+	}
+	req.Payload["report"] = []byte(str)
+	return nil
+}
+
+func handlePrintPartyStatement(req *Request) error {
+	query := req.Arg.(*PartyQuery)
+	str, err := inventory.SprintPartyStatement(inventory.CurrentDB(), query.Party, query.FromDatetimeMs, query.ToDatetimeMs)
+	if err != nil {
+		return err
+	}
+	req.Payload["statement"] = []byte(str)
+	return nil
+}
+
+func handleAddRecurringTransaction(req *Request) error {
+	entityUUIDBytes, err := inventory.AddRecurringTransaction(inventory.CurrentDB(), req.Arg.(*inventory.RecurringTransaction))
+	if err != nil {
+		return err
+	}
+	req.Payload["uuid"] = entityUUIDBytes
+	return nil
+}
+
+func handleGetRecurringTransactions(req *Request) error {
+	recs, err := inventory.GetRecurringTransactions(inventory.CurrentDB())
+	if err != nil {
+		return err
+	}
+	return req.SetResult("recurring", recs)
+}
+
+func handleSetRecurringTransactionActive(req *Request) error {
+	rec := req.Arg.(*inventory.RecurringTransaction)
+	return inventory.SetRecurringTransactionActive(inventory.CurrentDB(), rec.UUID[:], rec.Active)
+}
+
+func handlePreviewRecurringTransactions(req *Request) error {
+	query := req.Arg.(*PeriodQuery)
+	occurrences, err := inventory.PreviewRecurringTransactions(inventory.CurrentDB(), query.ToDatetimeMs)
+	if err != nil {
+		return err
+	}
+	return req.SetResult("occurrences", occurrences)
+}
+
+func handleRunRecurringTransactions(req *Request) error {
+	// without an arg everything due by now is posted
+	nowMs := time.Now().UnixMilli()
+	if req.Arg != nil {
+		nowMs = req.Arg.(*PeriodQuery).ToDatetimeMs
+	}
+	occurrences, err := inventory.RunRecurringTransactions(inventory.CurrentDB(), nowMs)
+	if err != nil {
+		return err
+	}
+	return req.SetResult("occurrences", occurrences)
+}
+
+func handleAddTransactionTemplate(req *Request) error {
+	entityUUIDBytes, err := inventory.AddTransactionTemplate(inventory.CurrentDB(), req.Arg.(*inventory.TransactionTemplate))
+	if err != nil {
+		return err
+	}
+	req.Payload["uuid"] = entityUUIDBytes
+	return nil
+}
+
+func handleGetTransactionTemplates(req *Request) error {
+	templates, err := inventory.GetTransactionTemplates(inventory.CurrentDB())
+	if err != nil {
+		return err
+	}
+	return req.SetResult("templates", templates)
+}
+
+func handleApplyTransactionTemplate(req *Request) error {
+	inst := req.Arg.(*TemplateInstantiation)
+	var tmpl *inventory.TransactionTemplate
+	var err error
+	if inst.TemplateUUID != uuid.Nil {
+		tmpl, err = inventory.GetTransactionTemplateByUUID(inventory.CurrentDB(), inst.TemplateUUID[:])
+	} else {
+		tmpl, err = inventory.GetTransactionTemplateByName(inventory.CurrentDB(), inst.TemplateName)
+	}
+	if err != nil {
+		return err
+	}
+	entityUUIDBytes, _, err := inventory.ApplyTransactionTemplate(inventory.CurrentDB(), tmpl, inst.Args)
+	if err != nil {
+		return err
+	}
+	req.Payload["uuid"] = entityUUIDBytes
+	return nil
+}
+
+// streamed as one "line" per balance
+func sendBalanceLines(req *Request, each func(db *sql.DB, fn func(line string) error) error) error {
+	return each(inventory.CurrentDB(), func(line string) error {
+		return req.Send(map[string][]byte{"line": []byte(line)})
+	})
+}
+
+func handlePrintBalances(req *Request) error {
+	if req.Send != nil {
+		return sendBalanceLines(req, inventory.EachBalanceLine)
+	}
+	str, err := inventory.SprintBalances(inventory.CurrentDB())
+	if err != nil {
+		return err
+	}
+	req.Payload["balances"] = []byte(str)
+	return nil
+}
+
+func handlePrintMarketBalances(req *Request) error {
+	if req.Send != nil {
+		return sendBalanceLines(req, inventory.EachMarketBalanceLine)
+	}
+	str, err := inventory.SprintMarketBalances(inventory.CurrentDB())
+	if err != nil {
+		return err
+	}
+	req.Payload["balances"] = []byte(str)
+	return nil
+}
+
+func handleCloseCurrDB(req *Request) error {
+	return inventory.CloseCurrDB()
+}
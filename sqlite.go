@@ -2,8 +2,10 @@ package inventory
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
+	"sync"
 	"syscall"
 	"time"
 
@@ -18,6 +20,17 @@ var Prefix string = "./db"
 var DBMap = map[uuid.UUID]*sql.DB{}
 var CurrDB *sql.DB = nil
 
+// guards DBMap and CurrDB. there is one current database per process, code
+// running while other goroutines open or close databases reads it through
+// CurrentDB.
+var dbMu sync.RWMutex
+
+func CurrentDB() *sql.DB {
+	dbMu.RLock()
+	defer dbMu.RUnlock()
+	return CurrDB
+}
+
 func PathExists(path string) bool {
 	_, err := os.Stat(path)
 	if err != nil {
@@ -31,6 +44,9 @@ func PathExists(path string) bool {
 }
 
 func OpenOrCreateDB(dbUUID uuid.UUID) (*sql.DB, error) {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+
 	db, ok := DBMap[dbUUID]
 	if ok {
 		CurrDB = db
@@ -65,6 +81,10 @@ func OpenOrCreateDB(dbUUID uuid.UUID) (*sql.DB, error) {
 			return db, err
 		}
 	} else {
+		err = MigrateSchema(db)
+		if err != nil {
+			return db, err
+		}
 		_, _, err = BuildAccountTree(db)
 		if err != nil {
 			return db, err
@@ -78,6 +98,12 @@ func OpenOrCreateDB(dbUUID uuid.UUID) (*sql.DB, error) {
 }
 
 func GetCurrDBUUID() (*sql.DB, uuid.UUID) {
+	dbMu.RLock()
+	defer dbMu.RUnlock()
+	return currDBUUID()
+}
+
+func currDBUUID() (*sql.DB, uuid.UUID) {
 	var resDbUUID uuid.UUID
 	var resDb *sql.DB = nil
 	for dbUUID, db := range DBMap {
@@ -90,7 +116,10 @@ func GetCurrDBUUID() (*sql.DB, uuid.UUID) {
 }
 
 func CloseCurrDB() error {
-	db, dbUUID := GetCurrDBUUID()
+	dbMu.Lock()
+	defer dbMu.Unlock()
+
+	db, dbUUID := currDBUUID()
 	if db == nil {
 		return nil
 	}
@@ -137,8 +166,7 @@ func DbExistsInStorage(UUID uuid.UUID) (bool, error) {
 	return true, nil
 }
 
-func InitSchema(db *sql.DB) error {
-	schema := `
+const schema = `
 PRAGMA foreign_keys = ON;
 
 CREATE TABLE IF NOT EXISTS accounts (
@@ -162,7 +190,8 @@ CREATE TABLE IF NOT EXISTS transactions (
     description TEXT,
     datetime_ms INTEGER NOT NULL,
     year INTEGER NOT NULL,
-    month INTEGER NOT NULL
+    month INTEGER NOT NULL,
+    party_id INTEGER
 );
 
 CREATE INDEX IF NOT EXISTS idx_transactions_year_month
@@ -179,6 +208,9 @@ CREATE TABLE IF NOT EXISTS transaction_lines (
     price BIGINT,
     currency TEXT,
     note TEXT,
+    tax_code_id INTEGER,
+    party_id INTEGER,
+    value_adjustment INTEGER NOT NULL DEFAULT 0,
     FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE,
     FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE SET NULL
 );
@@ -203,10 +235,15 @@ CREATE TABLE IF NOT EXISTS balance_history (
 CREATE TABLE IF NOT EXISTS unit_conversions (
     from_unit TEXT NOT NULL,
     to_unit TEXT NOT NULL,
+    item_id INTEGER,
     factor BIGINT NOT NULL,
-    datetime_ms INTEGER NOT NULL
+    datetime_ms INTEGER NOT NULL,
+    FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE
 );
 
+CREATE INDEX IF NOT EXISTS idx_unit_conversions_date
+    ON unit_conversions(datetime_ms);
+
 CREATE TABLE IF NOT EXISTS currency_conversions (
     from_currency TEXT NOT NULL,
     to_currency TEXT NOT NULL,
@@ -214,9 +251,401 @@ CREATE TABLE IF NOT EXISTS currency_conversions (
     datetime_ms INTEGER NOT NULL
 );
 
+CREATE INDEX IF NOT EXISTS idx_currency_conversions_pair_date
+    ON currency_conversions(from_currency, to_currency, datetime_ms);
+
+CREATE TABLE IF NOT EXISTS fx_revaluations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid BLOB UNIQUE NOT NULL,
+    transaction_id INTEGER NOT NULL,
+    account_id INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    datetime_ms INTEGER NOT NULL,
+    rate BIGINT,
+    balance BIGINT,
+    book_value BIGINT,
+    revalued_value BIGINT,
+    adjustment BIGINT,
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS lcm_adjustments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid BLOB UNIQUE NOT NULL,
+    transaction_id INTEGER NOT NULL,
+    account_id INTEGER NOT NULL,
+    item_id INTEGER NOT NULL,
+    datetime_ms INTEGER NOT NULL,
+    quantity BIGINT,
+    cost BIGINT,
+    carrying_value BIGINT,
+    market_price BIGINT,
+    market_value BIGINT,
+    adjustment BIGINT,
+    currency TEXT,
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE,
+    FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS landed_costs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid BLOB UNIQUE NOT NULL,
+    transaction_id INTEGER NOT NULL,
+    description TEXT,
+    datetime_ms INTEGER NOT NULL,
+    method TEXT NOT NULL,
+    weight_unit TEXT,
+    amount BIGINT,
+    currency TEXT,
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS landed_cost_allocations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid BLOB UNIQUE NOT NULL,
+    landed_cost_id INTEGER NOT NULL,
+    receipt_transaction_id INTEGER NOT NULL,
+    receipt_line_id INTEGER NOT NULL,
+    basis BIGINT,
+    amount BIGINT,
+    expensed BIGINT,
+    FOREIGN KEY (landed_cost_id) REFERENCES landed_costs(id) ON DELETE CASCADE,
+    FOREIGN KEY (receipt_line_id) REFERENCES transaction_lines(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS fixed_assets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid BLOB UNIQUE NOT NULL,
+    name TEXT,
+    transaction_line_id INTEGER NOT NULL,
+    account_id INTEGER NOT NULL,
+    item_id INTEGER,
+    quantity BIGINT,
+    unit TEXT,
+    cost BIGINT,
+    currency TEXT,
+    salvage_value BIGINT,
+    method TEXT NOT NULL,
+    useful_life_months INTEGER NOT NULL,
+    declining_factor BIGINT,
+    start_datetime_ms INTEGER NOT NULL,
+    expense_account_id INTEGER NOT NULL,
+    accumulated_account_id INTEGER NOT NULL,
+    status TEXT NOT NULL,
+    disposed_datetime_ms INTEGER,
+    disposal_transaction_id INTEGER,
+    proceeds BIGINT,
+    FOREIGN KEY (transaction_line_id) REFERENCES transaction_lines(id)
+);
+
+CREATE TABLE IF NOT EXISTS fixed_asset_depreciations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid BLOB UNIQUE NOT NULL,
+    fixed_asset_id INTEGER NOT NULL,
+    transaction_id INTEGER NOT NULL,
+    period INTEGER NOT NULL,
+    datetime_ms INTEGER NOT NULL,
+    amount BIGINT,
+    accumulated BIGINT,
+    book_value BIGINT,
+    UNIQUE (fixed_asset_id, period),
+    FOREIGN KEY (fixed_asset_id) REFERENCES fixed_assets(id) ON DELETE CASCADE,
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS parties (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid BLOB UNIQUE NOT NULL,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS invoices (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid BLOB UNIQUE NOT NULL,
+    type TEXT NOT NULL,
+    number TEXT,
+    description TEXT,
+    party_id INTEGER NOT NULL,
+    transaction_id INTEGER NOT NULL,
+    datetime_ms INTEGER NOT NULL,
+    due_datetime_ms INTEGER NOT NULL,
+    currency TEXT,
+    total BIGINT NOT NULL,
+    UNIQUE (type, number),
+    FOREIGN KEY (party_id) REFERENCES parties(id),
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id)
+);
+
+CREATE TABLE IF NOT EXISTS payments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid BLOB UNIQUE NOT NULL,
+    type TEXT NOT NULL,
+    reference TEXT,
+    party_id INTEGER NOT NULL,
+    account_id INTEGER NOT NULL,
+    transaction_id INTEGER NOT NULL,
+    datetime_ms INTEGER NOT NULL,
+    amount BIGINT NOT NULL,
+    currency TEXT,
+    FOREIGN KEY (party_id) REFERENCES parties(id),
+    FOREIGN KEY (account_id) REFERENCES accounts(id),
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id)
+);
+
+CREATE TABLE IF NOT EXISTS payment_allocations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid BLOB UNIQUE NOT NULL,
+    payment_id INTEGER NOT NULL,
+    invoice_id INTEGER NOT NULL,
+    amount BIGINT NOT NULL,
+    datetime_ms INTEGER NOT NULL,
+    FOREIGN KEY (payment_id) REFERENCES payments(id) ON DELETE CASCADE,
+    FOREIGN KEY (invoice_id) REFERENCES invoices(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS bank_statements (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid BLOB UNIQUE NOT NULL,
+    account_id INTEGER NOT NULL,
+    format TEXT NOT NULL,
+    currency TEXT,
+    opening_balance BIGINT,
+    closing_balance BIGINT,
+    has_balances INTEGER NOT NULL,
+    from_datetime_ms INTEGER NOT NULL,
+    to_datetime_ms INTEGER NOT NULL,
+    imported_ms INTEGER NOT NULL,
+    FOREIGN KEY (account_id) REFERENCES accounts(id)
+);
+
+CREATE TABLE IF NOT EXISTS bank_statement_lines (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid BLOB UNIQUE NOT NULL,
+    statement_id INTEGER NOT NULL,
+    account_id INTEGER NOT NULL,
+    external_id TEXT,
+    datetime_ms INTEGER NOT NULL,
+    amount BIGINT NOT NULL,
+    currency TEXT,
+    reference TEXT,
+    description TEXT,
+    UNIQUE (account_id, external_id),
+    FOREIGN KEY (statement_id) REFERENCES bank_statements(id) ON DELETE CASCADE,
+    FOREIGN KEY (account_id) REFERENCES accounts(id)
+);
+
+CREATE TABLE IF NOT EXISTS bank_reconciliations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    statement_line_id INTEGER UNIQUE NOT NULL,
+    transaction_line_id INTEGER UNIQUE NOT NULL,
+    method TEXT NOT NULL,
+    datetime_ms INTEGER NOT NULL,
+    FOREIGN KEY (statement_line_id) REFERENCES bank_statement_lines(id) ON DELETE CASCADE,
+    FOREIGN KEY (transaction_line_id) REFERENCES transaction_lines(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS returns (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid BLOB UNIQUE NOT NULL,
+    type TEXT NOT NULL,
+    source_transaction_id INTEGER NOT NULL,
+    transaction_id INTEGER NOT NULL,
+    party_id INTEGER,
+    datetime_ms INTEGER NOT NULL,
+    reason TEXT,
+    FOREIGN KEY (source_transaction_id) REFERENCES transactions(id),
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE,
+    FOREIGN KEY (party_id) REFERENCES parties(id)
+);
+
+CREATE TABLE IF NOT EXISTS return_lines (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    return_id INTEGER NOT NULL,
+    source_transaction_id INTEGER NOT NULL,
+    item_id INTEGER NOT NULL,
+    account_id INTEGER NOT NULL,
+    quantity BIGINT NOT NULL,
+    unit_cost BIGINT NOT NULL,
+    condition TEXT NOT NULL,
+    note TEXT,
+    FOREIGN KEY (return_id) REFERENCES returns(id) ON DELETE CASCADE,
+    FOREIGN KEY (source_transaction_id) REFERENCES transactions(id),
+    FOREIGN KEY (item_id) REFERENCES items(id),
+    FOREIGN KEY (account_id) REFERENCES accounts(id)
+);
+
+CREATE TABLE IF NOT EXISTS consignment_accounts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    account_id INTEGER UNIQUE NOT NULL,
+    party_id INTEGER NOT NULL,
+    FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE,
+    FOREIGN KEY (party_id) REFERENCES parties(id)
+);
+
+CREATE TABLE IF NOT EXISTS consignment_settlements (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid BLOB UNIQUE NOT NULL,
+    account_id INTEGER NOT NULL,
+    stock_account_id INTEGER NOT NULL,
+    item_id INTEGER NOT NULL,
+    quantity BIGINT NOT NULL,
+    price BIGINT NOT NULL,
+    invoice_id INTEGER NOT NULL,
+    datetime_ms INTEGER NOT NULL,
+    FOREIGN KEY (account_id) REFERENCES accounts(id),
+    FOREIGN KEY (stock_account_id) REFERENCES accounts(id),
+    FOREIGN KEY (item_id) REFERENCES items(id),
+    FOREIGN KEY (invoice_id) REFERENCES invoices(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS transfer_orders (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid BLOB UNIQUE NOT NULL,
+    number TEXT,
+    from_account_id INTEGER NOT NULL,
+    to_account_id INTEGER NOT NULL,
+    note TEXT,
+    status TEXT NOT NULL,
+    ship_transaction_id INTEGER NOT NULL,
+    receive_transaction_id INTEGER,
+    shipped_datetime_ms INTEGER NOT NULL,
+    received_datetime_ms INTEGER,
+    FOREIGN KEY (from_account_id) REFERENCES accounts(id),
+    FOREIGN KEY (to_account_id) REFERENCES accounts(id),
+    FOREIGN KEY (ship_transaction_id) REFERENCES transactions(id) ON DELETE CASCADE,
+    FOREIGN KEY (receive_transaction_id) REFERENCES transactions(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfer_orders_status
+    ON transfer_orders(status);
+
+CREATE TABLE IF NOT EXISTS transfer_order_lines (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    transfer_order_id INTEGER NOT NULL,
+    item_id INTEGER NOT NULL,
+    unit TEXT,
+    quantity BIGINT NOT NULL,
+    unit_cost BIGINT NOT NULL,
+    received_quantity BIGINT,
+    FOREIGN KEY (transfer_order_id) REFERENCES transfer_orders(id) ON DELETE CASCADE,
+    FOREIGN KEY (item_id) REFERENCES items(id)
+);
+
+CREATE TABLE IF NOT EXISTS recurring_transactions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid BLOB UNIQUE NOT NULL,
+    name TEXT,
+    schedule TEXT NOT NULL,
+    day_of_month INTEGER NOT NULL DEFAULT 0,
+    weekday INTEGER NOT NULL DEFAULT 0,
+    cron TEXT,
+    start_datetime_ms INTEGER NOT NULL,
+    end_datetime_ms INTEGER NOT NULL DEFAULT 0,
+    active INTEGER NOT NULL DEFAULT 1,
+    description TEXT,
+    party_id INTEGER,
+    FOREIGN KEY (party_id) REFERENCES parties(id)
+);
+
+CREATE TABLE IF NOT EXISTS recurring_transaction_lines (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    recurring_id INTEGER NOT NULL,
+    account_id INTEGER NOT NULL,
+    item_id INTEGER,
+    quantity BIGINT,
+    unit TEXT,
+    price BIGINT,
+    currency TEXT,
+    note TEXT,
+    tax_code_id INTEGER,
+    party_id INTEGER,
+    FOREIGN KEY (recurring_id) REFERENCES recurring_transactions(id) ON DELETE CASCADE,
+    FOREIGN KEY (account_id) REFERENCES accounts(id),
+    FOREIGN KEY (item_id) REFERENCES items(id)
+);
+
+CREATE TABLE IF NOT EXISTS recurring_occurrences (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    recurring_id INTEGER NOT NULL,
+    datetime_ms INTEGER NOT NULL,
+    transaction_id INTEGER NOT NULL,
+    UNIQUE(recurring_id, datetime_ms),
+    FOREIGN KEY (recurring_id) REFERENCES recurring_transactions(id) ON DELETE CASCADE,
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS transaction_templates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid BLOB UNIQUE NOT NULL,
+    name TEXT UNIQUE NOT NULL,
+    description TEXT,
+    datetime TEXT
+);
+
+CREATE TABLE IF NOT EXISTS transaction_template_params (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    template_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    type TEXT NOT NULL,
+    default_value TEXT,
+    UNIQUE(template_id, name),
+    FOREIGN KEY (template_id) REFERENCES transaction_templates(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS transaction_template_lines (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    template_id INTEGER NOT NULL,
+    account TEXT NOT NULL,
+    item TEXT,
+    quantity TEXT,
+    unit TEXT,
+    price TEXT,
+    currency TEXT,
+    note TEXT,
+    FOREIGN KEY (template_id) REFERENCES transaction_templates(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS tax_codes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid BLOB UNIQUE NOT NULL,
+    code TEXT UNIQUE NOT NULL,
+    name TEXT,
+    rate BIGINT NOT NULL,
+    inclusive INTEGER NOT NULL,
+    payable_account_id INTEGER,
+    receivable_account_id INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS tax_entries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid BLOB UNIQUE NOT NULL,
+    transaction_id INTEGER NOT NULL,
+    base_line_id INTEGER NOT NULL,
+    tax_line_id INTEGER NOT NULL,
+    tax_code_id INTEGER NOT NULL,
+    direction TEXT NOT NULL,
+    base BIGINT,
+    tax BIGINT,
+    currency TEXT,
+    datetime_ms INTEGER NOT NULL,
+    year INTEGER NOT NULL,
+    month INTEGER NOT NULL,
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE,
+    FOREIGN KEY (tax_code_id) REFERENCES tax_codes(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tax_entries_year_month
+    ON tax_entries(year, month);
+
+CREATE TABLE IF NOT EXISTS settings (
+    key TEXT PRIMARY KEY,
+    value TEXT
+);
+
 CREATE TABLE IF NOT EXISTS market_prices (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
-    item_id BLOB NOT NULL,
+    item_id INTEGER NOT NULL,
     datetime_ms INTEGER NOT NULL,
     price BIGINT,
     unit TEXT,
@@ -227,10 +656,16 @@ CREATE TABLE IF NOT EXISTS market_prices (
 CREATE INDEX IF NOT EXISTS idx_market_prices_item_date
     ON market_prices(item_id, datetime_ms);
 `
+
+func InitSchema(db *sql.DB) error {
 	_, err := db.Exec(schema)
 	if err != nil {
 		return err
 	}
+	err = setSchemaVersion(db, len(migrations))
+	if err != nil {
+		return err
+	}
 
 	_, err = AddAccount(db, &Account{Name: "asset"})
 	if err != nil {
@@ -326,7 +761,7 @@ func GetAccountByID(db *sql.DB, accID int) (*Account, error) {
 // }
 
 func AddAccount(db *sql.DB, acc *Account) ([]byte, error) {
-	accUUID, err := uuid.NewV6()
+	accUUID, err := uuid.NewV7()
 	if err != nil {
 		return accUUID[:], err
 	}
@@ -382,8 +817,99 @@ func GetItemByUUID(db *sql.DB, itUUID []byte) (*Item, error) {
 	}, nil
 }
 
+func GetItemByID(db *sql.DB, itID int) (*Item, error) {
+	rows, err := db.Query(`SELECT * FROM items WHERE id=?`, itID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, sql.ErrNoRows
+	}
+
+	var itUUID []byte
+	var name, description, unit string
+
+	err = rows.Scan(&itID, &itUUID, &name, &description, &unit)
+	if err != nil {
+		return nil, err
+	}
+
+	bUUID, err := uuid.FromBytes(itUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Item{
+		ID: itID, UUID: bUUID, Name: name, Description: description, Unit: unit,
+	}, nil
+}
+
+var ErrAmbiguousItemName = errors.New("more than one item with that name")
+
+func GetItemByName(db *sql.DB, name string) (*Item, error) {
+	rows, err := db.Query(`SELECT uuid FROM items WHERE name=? LIMIT 2`, name)
+	if err != nil {
+		return nil, err
+	}
+	var itUUIDs [][]byte
+	for rows.Next() {
+		var itUUID []byte
+		err = rows.Scan(&itUUID)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		itUUIDs = append(itUUIDs, itUUID)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	switch len(itUUIDs) {
+	case 0:
+		return nil, sql.ErrNoRows
+	case 1:
+		return GetItemByUUID(db, itUUIDs[0])
+	}
+	return nil, ErrAmbiguousItemName
+}
+
+var ErrAmbiguousAccountName = errors.New("more than one account with that name")
+
+func GetAccountByName(db *sql.DB, name string) (*Account, error) {
+	rows, err := db.Query(`SELECT uuid FROM accounts WHERE name=? LIMIT 2`, name)
+	if err != nil {
+		return nil, err
+	}
+	var accUUIDs [][]byte
+	for rows.Next() {
+		var accUUID []byte
+		err = rows.Scan(&accUUID)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		accUUIDs = append(accUUIDs, accUUID)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	switch len(accUUIDs) {
+	case 0:
+		return nil, sql.ErrNoRows
+	case 1:
+		return GetAccountByUUID(db, accUUIDs[0])
+	}
+	return nil, ErrAmbiguousAccountName
+}
+
 func AddItem(db *sql.DB, item *Item) ([]byte, error) {
-	itUUID, err := uuid.NewV6()
+	itUUID, err := uuid.NewV7()
 	if err != nil {
 		return itUUID[:], err
 	}
@@ -442,6 +968,20 @@ func CreateFinancialTrLineWithAccUUIDBytes(accUUIDBytes []byte, debet Decimal, k
 	return CreateFinancialTrLine(acc, debet, kredit, currency), nil
 }
 
+// a line that only changes the value of the balance, Price holds the amount
+// added to the total cost. other zero quantity lines add nothing.
+func CreateValueAdjustmentTrLine(acc *Account, item *Item, amount Decimal, currency string) *TransactionLine {
+	return &TransactionLine{
+		Account:         acc,
+		Item:            item,
+		Quantity:        NewDecimal(0),
+		Unit:            item.Unit,
+		Price:           amount,
+		Currency:        currency,
+		ValueAdjustment: true,
+	}
+}
+
 func ApplyTransaction(db *sql.DB, transaction *Transaction) ([]byte, error) {
 	tx, err := db.Begin()
 	if err != nil {
@@ -449,42 +989,66 @@ func ApplyTransaction(db *sql.DB, transaction *Transaction) ([]byte, error) {
 	}
 	defer tx.Rollback()
 
-	trUUID, err := uuid.NewV6()
+	trUUID, trID, err := applyTransactionTx(db, tx, transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	// fmt.Println("committing")
+	err = tx.Commit()
 	if err != nil {
 		return nil, err
 	}
+	publishTransactions(db, trID)
+	return trUUID, nil
+}
+
+// inserts the transaction, its lines and balance history inside tx, so other
+// documents can be posted atomically together with their transaction
+func applyTransactionTx(db *sql.DB, tx *sql.Tx, transaction *Transaction) ([]byte, int64, error) {
+	trUUID, err := uuid.NewV7()
+	if err != nil {
+		return nil, 0, err
+	}
 
 	var res sql.Result
 	date := time.UnixMilli(transaction.DatetimeMs)
 
+	trPartyID, err := resolvePartyID(db, transaction.Party)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	// fmt.Println("inserting transaction")
-	res, err = tx.Exec("INSERT INTO transactions (uuid,datetime_ms,year,month,description) VALUES(?,?,?,?,?)", trUUID[:], transaction.DatetimeMs, date.Year(), int(date.Month()), transaction.Description)
+	res, err = tx.Exec("INSERT INTO transactions (uuid,datetime_ms,year,month,description,party_id) VALUES(?,?,?,?,?,?)", trUUID[:], transaction.DatetimeMs, date.Year(), int(date.Month()), transaction.Description, trPartyID)
 
 	if err != nil {
-		tx.Rollback()
-		return nil, err
+		return nil, 0, err
 	}
 
 	trID, err := res.LastInsertId()
 	if err != nil {
-		tx.Rollback()
-		return nil, err
+		return nil, 0, err
+	}
+
+	lines, taxEntries, err := expandTaxLines(db, transaction.TransactionLines)
+	if err != nil {
+		return nil, 0, err
 	}
+	transaction.TransactionLines = lines
 
 	for _, l := range transaction.TransactionLines {
 		// fmt.Println("inserting line")
-		lineUUID, err := uuid.NewV6()
+		lineUUID, err := uuid.NewV7()
 		if err != nil {
-			tx.Rollback()
-			return nil, err
+			return nil, 0, err
 		}
 		var itemID int
 		if l.Item != nil {
 			if l.Item.ID <= 0 {
 				tmpItem, err := GetItemByUUID(db, l.Item.UUID[:])
 				if err != nil {
-					tx.Rollback()
-					return nil, err
+					return nil, 0, err
 				}
 				*l.Item = *tmpItem
 			}
@@ -495,19 +1059,32 @@ func ApplyTransaction(db *sql.DB, transaction *Transaction) ([]byte, error) {
 		if l.Account.ID <= 0 {
 			tmpAcc, err := GetAccountByUUID(db, l.Account.UUID[:])
 			if err != nil {
-				tx.Rollback()
-				return nil, err
+				return nil, 0, err
 			}
 			*l.Account = *tmpAcc
 		}
 		// fmt.Println(trID, l.Account.ID, itemID, "qty", l.Quantity.ToString(), l.Unit, "pri", l.Price.ToString(), l.Currency, l.Note)
-		_, err = tx.Exec(
-			"INSERT INTO transaction_lines (uuid,transaction_id,account_id,item_id,quantity,unit,price,currency,note) VALUES(?,?,?,?,?,?,?,?,?)",
-			lineUUID[:], trID, l.Account.ID, sql.NullInt64{Int64: int64(itemID), Valid: itemID != -1}, l.Quantity, l.Unit, l.Price, l.Currency, l.Note)
+		taxCodeID := sql.NullInt64{}
+		if l.TaxCode != nil {
+			taxCodeID = sql.NullInt64{Int64: int64(l.TaxCode.ID), Valid: true}
+		}
+		linePartyID, err := resolvePartyID(db, l.Party)
 		if err != nil {
-			tx.Rollback()
-			return nil, err
+			return nil, 0, err
+		}
+		res, err = tx.Exec(
+			"INSERT INTO transaction_lines (uuid,transaction_id,account_id,item_id,quantity,unit,price,currency,note,tax_code_id,party_id,value_adjustment) VALUES(?,?,?,?,?,?,?,?,?,?,?,?)",
+			lineUUID[:], trID, l.Account.ID, sql.NullInt64{Int64: int64(itemID), Valid: itemID != -1}, l.Quantity, l.Unit, l.Price, l.Currency, l.Note, taxCodeID, linePartyID, l.ValueAdjustment)
+		if err != nil {
+			return nil, 0, err
+		}
+		lineID, err := res.LastInsertId()
+		if err != nil {
+			return nil, 0, err
 		}
+		l.ID = int(lineID)
+		l.UUID = lineUUID
+		l.Transaction = transaction
 
 		// fmt.Println("finding prev qty and prev total")
 		prevQty := NewDecimal(0)
@@ -517,43 +1094,164 @@ func ApplyTransaction(db *sql.DB, transaction *Transaction) ([]byte, error) {
 			FROM balance_history h
 			JOIN transactions t ON h.transaction_id = t.id
 			WHERE h.item_id=? AND h.account_id=? AND t.datetime_ms <= ?
-			ORDER BY t.datetime_ms DESC
+			ORDER BY t.datetime_ms DESC, h.id DESC
 			LIMIT 1`,
 			itemID, l.Account.ID, date.UnixMilli()).Scan(&prevQty, &prevTotal)
 
 		if err == sql.ErrNoRows {
 			prevQty, prevTotal = NewDecimal(0), NewDecimal(0)
 		} else if err != nil {
-			tx.Rollback()
-			return nil, err
+			return nil, 0, err
 		}
 
 		newQty := NewDecimal(prevQty.Data + l.Quantity.Data)
-		newTotal := NewDecimal(prevTotal.Data + l.Quantity.Multiply(l.Price).Data)
+		lineValue := l.Quantity.Multiply(l.Price)
+		if l.ValueAdjustment {
+			lineValue = l.Price
+		}
+		newTotal := NewDecimal(prevTotal.Data + lineValue.Data)
 		avgCost := NewDecimal(0)
 		if newQty.Data != 0 {
 			avgCost = newTotal.Divide(newQty)
 		}
 
 		// fmt.Printf("prev qty tot %v %v new qty tot %v %v new avg cost %v\n", prevQty.ToString(), prevTotal.ToString(), newQty.ToString(), newTotal.ToString(), avgCost.ToString())
-		histUUID, err := uuid.NewV6()
+		histUUID, err := uuid.NewV7()
 		if err != nil {
-			tx.Rollback()
-			return nil, err
+			return nil, 0, err
 		}
 
 		_, err = tx.Exec(`INSERT INTO balance_history(uuid,item_id,account_id,transaction_id,quantity,total_cost,avg_cost)
 		                  VALUES(?,?,?,?,?,?,?)`,
 			histUUID[:], itemID, l.Account.ID, trID, newQty, newTotal, avgCost.Data)
 		if err != nil {
-			tx.Rollback()
+			return nil, 0, err
+		}
+	}
+
+	err = insertTaxEntriesTx(tx, trID, date, taxEntries)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	transaction.ID = int(trID)
+	transaction.UUID = trUUID
+	return trUUID[:], trID, nil
+}
+
+func GetTransactionByUUID(db *sql.DB, trUUID []byte) (*Transaction, error) {
+	var id int
+	err := db.QueryRow(`SELECT id FROM transactions WHERE uuid=?`, trUUID).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	return GetTransactionByID(db, id)
+}
+
+// loads the transaction with its lines, line accounts and items carry id,
+// uuid and name only
+func GetTransactionByID(db *sql.DB, trID int) (*Transaction, error) {
+	tr := &Transaction{}
+	var trUUID []byte
+	var desc sql.NullString
+	var partyID sql.NullInt64
+	err := db.QueryRow(`SELECT id,uuid,description,datetime_ms,year,month,party_id FROM transactions WHERE id=?`, trID).
+		Scan(&tr.ID, &trUUID, &desc, &tr.DatetimeMs, &tr.Year, &tr.Month, &partyID)
+	if err != nil {
+		return nil, err
+	}
+	tr.UUID, err = uuid.FromBytes(trUUID)
+	if err != nil {
+		return nil, err
+	}
+	tr.Description = desc.String
+	if partyID.Valid {
+		tr.Party, err = GetPartyByID(db, int(partyID.Int64))
+		if err != nil {
 			return nil, err
 		}
 	}
 
-	// fmt.Println("committing")
-	tx.Commit()
-	return trUUID[:], nil
+	rows, err := db.Query(`
+		SELECT l.id, l.uuid, a.id, a.uuid, a.name, i.id, i.uuid, i.name, i.unit,
+			l.quantity, l.unit, l.price, l.currency, l.note, l.tax_code_id, tc.uuid, tc.code,
+			p.id, p.uuid, p.name, p.kind, l.value_adjustment
+		FROM transaction_lines l
+		JOIN accounts a ON l.account_id = a.id
+		LEFT JOIN items i ON l.item_id = i.id
+		LEFT JOIN tax_codes tc ON l.tax_code_id = tc.id
+		LEFT JOIN parties p ON l.party_id = p.id
+		WHERE l.transaction_id=?
+		ORDER BY l.id`, trID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		l := &TransactionLine{Transaction: tr, Account: &Account{}, Quantity: NewDecimal(0), Price: NewDecimal(0)}
+		var lineUUID, accUUID, itUUID, taxCodeUUID, partyUUID []byte
+		var itID, taxCodeID, linePartyID sql.NullInt64
+		var itName, itUnit, unit, currency, note, taxCode, partyName, partyKind sql.NullString
+		err = rows.Scan(&l.ID, &lineUUID, &l.Account.ID, &accUUID, &l.Account.Name, &itID, &itUUID, &itName, &itUnit,
+			&l.Quantity, &unit, &l.Price, &currency, &note, &taxCodeID, &taxCodeUUID, &taxCode,
+			&linePartyID, &partyUUID, &partyName, &partyKind, &l.ValueAdjustment)
+		if err != nil {
+			return nil, err
+		}
+		l.UUID, err = uuid.FromBytes(lineUUID)
+		if err != nil {
+			return nil, err
+		}
+		l.Account.UUID, err = uuid.FromBytes(accUUID)
+		if err != nil {
+			return nil, err
+		}
+		if itID.Valid {
+			l.Item = &Item{ID: int(itID.Int64), Name: itName.String, Unit: itUnit.String}
+			l.Item.UUID, err = uuid.FromBytes(itUUID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if taxCodeID.Valid {
+			l.TaxCode = &TaxCode{ID: int(taxCodeID.Int64), Code: taxCode.String}
+			l.TaxCode.UUID, err = uuid.FromBytes(taxCodeUUID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if linePartyID.Valid {
+			l.Party = &Party{ID: int(linePartyID.Int64), Name: partyName.String, Kind: partyKind.String}
+			l.Party.UUID, err = uuid.FromBytes(partyUUID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		l.Unit = unit.String
+		l.Currency = currency.String
+		l.Note = note.String
+		tr.TransactionLines = append(tr.TransactionLines, l)
+	}
+	return tr, rows.Err()
+}
+
+func GetTransactionLineByUUID(db *sql.DB, lineUUID []byte) (*TransactionLine, error) {
+	var trID, lineID int
+	err := db.QueryRow(`SELECT id,transaction_id FROM transaction_lines WHERE uuid=?`, lineUUID).Scan(&lineID, &trID)
+	if err != nil {
+		return nil, err
+	}
+	tr, err := GetTransactionByID(db, trID)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range tr.TransactionLines {
+		if l.ID == lineID {
+			return l, nil
+		}
+	}
+	return nil, sql.ErrNoRows
 }
 
 func UpdateMarketPrice(db *sql.DB, marketPrice *MarketPrice) error {
@@ -566,8 +1264,11 @@ func UpdateMarketPrice(db *sql.DB, marketPrice *MarketPrice) error {
 		INSERT INTO market_prices(item_id,datetime_ms,price,currency,unit)
 		VALUES(?,?,?,?,?)
 	`, item.ID, marketPrice.DatetimeMs, marketPrice.Price, marketPrice.Currency, marketPrice.Unit)
-
-	return err
+	if err != nil {
+		return err
+	}
+	publishMarketPrices(db, &MarketPrice{Item: item, DatetimeMs: marketPrice.DatetimeMs, Price: marketPrice.Price, Unit: marketPrice.Unit, Currency: marketPrice.Currency})
+	return nil
 }
 
 func BuildAccountTree(db *sql.DB) (map[int][]string, map[int]*Account, error) {
@@ -590,45 +1291,27 @@ func BuildAccountTree(db *sql.DB) (map[int][]string, map[int]*Account, error) {
 		rows.Scan(&id, &accUUID, &name, &parent)
 		// fmt.Println(id, accUUID, name, parent)
 
+		bUUID, err := uuid.FromBytes(accUUID)
+		if err != nil {
+			return nil, nil, err
+		}
+
 		var acc *Account
 		switch name {
 		case "asset":
-			if AssetAcc == nil {
-				AssetAcc = new(Account)
-			}
-			acc = AssetAcc
+			acc = setMainAccount(&AssetAcc, id, bUUID, name)
 		case "equity":
-			if EquityAcc == nil {
-				EquityAcc = new(Account)
-			}
-			acc = EquityAcc
+			acc = setMainAccount(&EquityAcc, id, bUUID, name)
 		case "liability":
-			if LiabilityAcc == nil {
-				LiabilityAcc = new(Account)
-			}
-			acc = LiabilityAcc
+			acc = setMainAccount(&LiabilityAcc, id, bUUID, name)
 		case "income":
-			if IncomeAcc == nil {
-				IncomeAcc = new(Account)
-			}
-			acc = IncomeAcc
+			acc = setMainAccount(&IncomeAcc, id, bUUID, name)
 		case "expense":
-			if ExpenseAcc == nil {
-				ExpenseAcc = new(Account)
-			}
-			acc = ExpenseAcc
+			acc = setMainAccount(&ExpenseAcc, id, bUUID, name)
 		default:
-			acc = new(Account)
-		}
-
-		acc.ID = id
-		acc.UUID, err = uuid.FromBytes(accUUID)
-		if err != nil {
-			return nil, nil, err
+			acc = &Account{ID: id, UUID: bUUID, Name: name}
 		}
 
-		acc.Name = name
-
 		parentAcc, ok := accMap[parent]
 		if ok {
 			acc.Parent = parentAcc
@@ -663,9 +1346,55 @@ func BuildAccountTree(db *sql.DB) (map[int][]string, map[int]*Account, error) {
 	return paths, accMap, nil
 }
 
+// the main accounts are shared by every database and every goroutine building
+// a tree. they are only written when they change, which for a process working
+// on one database is the first time its tree is built.
+var mainAccMu sync.Mutex
+
+func setMainAccount(acc **Account, id int, accUUID uuid.UUID, name string) *Account {
+	mainAccMu.Lock()
+	defer mainAccMu.Unlock()
+	if *acc == nil {
+		*acc = new(Account)
+	}
+	a := *acc
+	if a.ID != id || a.UUID != accUUID || a.Name != name {
+		a.ID, a.UUID, a.Name = id, accUUID, name
+	}
+	return a
+}
+
 // --- Fetch & Rollup Historical Balances ---
 
+// consignment stock is listed with zero value
 func FetchLeafBalances(db *sql.DB, accountMap map[int]*Account) ([]BalanceHistory, error) {
+	return fetchLeafBalances(db, accountMap, true)
+}
+
+// leaves out stock held on consignment for other parties
+func FetchLeafBalancesExcludingConsignment(db *sql.DB, accountMap map[int]*Account) ([]BalanceHistory, error) {
+	return fetchLeafBalances(db, accountMap, false)
+}
+
+func fetchLeafBalances(db *sql.DB, accountMap map[int]*Account, includeConsignment bool) ([]BalanceHistory, error) {
+	var balances []BalanceHistory
+	err := eachLeafBalance(db, accountMap, includeConsignment, func(h BalanceHistory) error {
+		balances = append(balances, h)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return balances, nil
+}
+
+// calls fn with each leaf balance as the query reads it, stops at the first
+// error fn returns
+func eachLeafBalance(db *sql.DB, accountMap map[int]*Account, includeConsignment bool, fn func(h BalanceHistory) error) error {
+	consignmentIDs, err := consignmentAccountIDs(db)
+	if err != nil {
+		return err
+	}
 	rows, err := db.Query(`
 select * from (
 	select
@@ -695,11 +1424,10 @@ select * from (
 group by account_id,item_id;
 `)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var balances []BalanceHistory
 	for rows.Next() {
 		var itemName, unit sql.NullString
 		var desc string
@@ -709,7 +1437,10 @@ group by account_id,item_id;
 		trPrice, qty, avgCost, value, marketPrice, marketValue := NewDecimal(0), NewDecimal(0), NewDecimal(0), NewDecimal(0), NewDecimal(0), NewDecimal(0)
 		var marketPriceNull, marketValueNull sql.NullInt64
 		if err := rows.Scan(&accID, &lineID, &itemID, &itemName, &trID, &desc, &trPrice, &marketPriceNull, &qty, &unit, &avgCost, &value, &marketValueNull, &date); err != nil {
-			return nil, err
+			return err
+		}
+		if consignmentIDs[accID] && !includeConsignment {
+			continue
 		}
 		acc, ok := accountMap[accID]
 		if !ok {
@@ -752,38 +1483,147 @@ group by account_id,item_id;
 		if marketValueNull.Valid {
 			// 	h.MarketValue = NewDecimal(marketValue.Int64)
 		}
+		if consignmentIDs[accID] {
+			h.Value = NewDecimal(0)
+			h.MarketValue = NewDecimal(0)
+		}
 		// fmt.Println(accID, trID, "qty", qty.ToString(), "pri", trPrice.ToString(), "val", h.Value.ToString(), "mpri", marketPrice.ToString(), "mval", marketValue.ToString())
-		balances = append(balances, h)
+		err = fn(h)
+		if err != nil {
+			return err
+		}
 	}
-	return balances, nil
+	return rows.Err()
+}
+
+func resolveItemID(db *sql.DB, item *Item) (sql.NullInt64, error) {
+	if item == nil {
+		return sql.NullInt64{}, nil
+	}
+	if item.ID <= 0 {
+		tmpItem, err := GetItemByUUID(db, item.UUID[:])
+		if err != nil {
+			return sql.NullInt64{}, err
+		}
+		*item = *tmpItem
+	}
+	return sql.NullInt64{Int64: int64(item.ID), Valid: true}, nil
 }
 
 func AddUnitConversionRule(db *sql.DB, rule UnitConversionRule) error {
-	_, err := db.Exec("INSERT INTO unit_conversions(from_unit,to_unit,factor) VALUES(?,?,?)", rule.FromUnit, rule.ToUnit, rule.Factor)
+	if rule.Factor.Data <= 0 {
+		return ErrInvalidConversionFactor
+	}
+	itemID, err := resolveItemID(db, rule.Item)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("INSERT INTO unit_conversions(from_unit,to_unit,item_id,factor,datetime_ms) VALUES(?,?,?,?,?)",
+		rule.FromUnit, rule.ToUnit, itemID, rule.Factor, rule.DatetimeMs)
 	return err
 }
 
 func AddCurrencyConversionRule(db *sql.DB, rule CurrencyConversionRule) error {
-	_, err := db.Exec("INSERT INTO currency_conversions(from_currency,to_currency,rate) VALUES(?,?,?)", rule.FromCurrency, rule.ToCurrency, rule.Rate)
+	if rule.Rate.Data <= 0 {
+		return ErrInvalidConversionFactor
+	}
+	_, err := db.Exec("INSERT INTO currency_conversions(from_currency,to_currency,rate,datetime_ms) VALUES(?,?,?,?)",
+		rule.FromCurrency, rule.ToCurrency, rule.Rate, rule.DatetimeMs)
 	return err
 }
 
-func LoadConversionRule(db *sql.DB, fromUnit, toUnit string) (UnitConversionRule, error) {
-	var rule UnitConversionRule
-	err := db.QueryRow("SELECT from_unit,to_unit,factor FROM unit_conversions WHERE from_unit=? AND to_unit=?", fromUnit, toUnit).
-		Scan(&rule.FromUnit, &rule.ToUnit, &rule.Factor)
+// returns generic rules plus the ones specific to item that are effective at
+// datetimeMs, ordered from oldest to newest
+func LoadConversionRules(db *sql.DB, item *Item, datetimeMs int64) ([]UnitConversionRule, error) {
+	itemID, err := resolveItemID(db, item)
 	if err != nil {
-		return UnitConversionRule{}, err
+		return nil, err
 	}
-	return rule, nil
+	rows, err := db.Query(`
+		SELECT from_unit,to_unit,item_id,factor,datetime_ms
+		FROM unit_conversions
+		WHERE datetime_ms <= ? AND (item_id IS NULL OR item_id = ?)
+		ORDER BY datetime_ms ASC`, datetimeMs, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []UnitConversionRule
+	for rows.Next() {
+		var rule UnitConversionRule
+		var ruleItemID sql.NullInt64
+		err = rows.Scan(&rule.FromUnit, &rule.ToUnit, &ruleItemID, &rule.Factor, &rule.DatetimeMs)
+		if err != nil {
+			return nil, err
+		}
+		if ruleItemID.Valid {
+			rule.Item = item
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
 }
 
-func LoadCurrencyConversionRule(db *sql.DB, fromCurrency, toCurrency string) (CurrencyConversionRule, error) {
+// returns the newest rule effective at datetimeMs, either fromCurrency ->
+// toCurrency or the inverse toCurrency -> fromCurrency
+func LoadCurrencyConversionRule(db *sql.DB, fromCurrency, toCurrency string, datetimeMs int64) (CurrencyConversionRule, error) {
 	var rule CurrencyConversionRule
-	err := db.QueryRow("SELECT from_currency,to_currency,rate FROM currency_conversions WHERE from_currency=? AND to_currency=?", fromCurrency, toCurrency).
-		Scan(&rule.FromCurrency, &rule.ToCurrency, &rule.Rate)
+	err := db.QueryRow(`
+		SELECT from_currency,to_currency,rate,datetime_ms
+		FROM currency_conversions
+		WHERE ((from_currency=? AND to_currency=?) OR (from_currency=? AND to_currency=?)) AND datetime_ms <= ?
+		ORDER BY datetime_ms DESC
+		LIMIT 1`, fromCurrency, toCurrency, toCurrency, fromCurrency, datetimeMs).
+		Scan(&rule.FromCurrency, &rule.ToCurrency, &rule.Rate, &rule.DatetimeMs)
 	if err != nil {
 		return CurrencyConversionRule{}, err
 	}
 	return rule, nil
 }
+
+func GetSetting(db *sql.DB, key string) (string, error) {
+	var value string
+	err := db.QueryRow("SELECT value FROM settings WHERE key=?", key).Scan(&value)
+	return value, err
+}
+
+func SetSetting(db *sql.DB, key, value string) error {
+	_, err := db.Exec("INSERT INTO settings(key,value) VALUES(?,?) ON CONFLICT(key) DO UPDATE SET value=excluded.value", key, value)
+	return err
+}
+
+func SetAccountSetting(db *sql.DB, key string, acc *Account) error {
+	if acc.ID <= 0 {
+		tmpAcc, err := GetAccountByUUID(db, acc.UUID[:])
+		if err != nil {
+			return err
+		}
+		*acc = *tmpAcc
+	}
+	return SetSetting(db, key, acc.UUID.String())
+}
+
+func GetAccountSetting(db *sql.DB, key string) (*Account, error) {
+	value, err := GetSetting(db, key)
+	if err != nil {
+		return nil, err
+	}
+	accUUID, err := uuid.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	return GetAccountByUUID(db, accUUID[:])
+}
+
+func GetBaseCurrency(db *sql.DB) (string, error) {
+	currency, err := GetSetting(db, SettingBaseCurrency)
+	if err == sql.ErrNoRows || (err == nil && currency == "") {
+		return "", ErrNoBaseCurrency
+	}
+	return currency, err
+}
+
+func SetBaseCurrency(db *sql.DB, currency string) error {
+	return SetSetting(db, SettingBaseCurrency, currency)
+}
@@ -0,0 +1,321 @@
+package inventory
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// taxed lines on the debit side are purchases (input tax, receivable),
+// lines on the credit side are sales (output tax, payable)
+const (
+	TaxPayable    = "payable"
+	TaxReceivable = "receivable"
+)
+
+var ErrInvalidTaxRate = errors.New("tax rate must not be negative")
+var ErrNoTaxAccount = errors.New("tax code has no account for this side")
+
+type TaxCode struct {
+	ID                int
+	UUID              uuid.UUID
+	Code              string
+	Name              string
+	Rate              Decimal
+	Inclusive         bool
+	PayableAccount    *Account
+	ReceivableAccount *Account
+}
+
+type TaxSummary struct {
+	Year           int
+	Month          int
+	TaxCode        *TaxCode
+	Currency       string
+	PayableBase    Decimal
+	PayableTax     Decimal
+	ReceivableBase Decimal
+	ReceivableTax  Decimal
+}
+
+type taxEntry struct {
+	baseLine  *TransactionLine
+	taxLine   *TransactionLine
+	taxCode   *TaxCode
+	direction string
+	base      Decimal
+	tax       Decimal
+}
+
+func AddTaxCode(db *sql.DB, tc *TaxCode) ([]byte, error) {
+	if tc.Rate.Data < 0 {
+		return nil, ErrInvalidTaxRate
+	}
+	var payableID, receivableID sql.NullInt64
+	if tc.PayableAccount != nil {
+		err := resolveAccount(db, tc.PayableAccount)
+		if err != nil {
+			return nil, fmt.Errorf("payable account: %w", err)
+		}
+		payableID = sql.NullInt64{Int64: int64(tc.PayableAccount.ID), Valid: true}
+	}
+	if tc.ReceivableAccount != nil {
+		err := resolveAccount(db, tc.ReceivableAccount)
+		if err != nil {
+			return nil, fmt.Errorf("receivable account: %w", err)
+		}
+		receivableID = sql.NullInt64{Int64: int64(tc.ReceivableAccount.ID), Valid: true}
+	}
+
+	tcUUID, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	res, err := db.Exec(`INSERT INTO tax_codes (uuid, code, name, rate, inclusive, payable_account_id, receivable_account_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, tcUUID[:], tc.Code, tc.Name, tc.Rate, tc.Inclusive, payableID, receivableID)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	tc.ID = int(id)
+	tc.UUID = tcUUID
+	return tcUUID[:], nil
+}
+
+func scanTaxCode(db *sql.DB, where string, arg any) (*TaxCode, error) {
+	var tc TaxCode
+	var tcUUID []byte
+	var name sql.NullString
+	var rate int64
+	var payableID, receivableID sql.NullInt64
+	err := db.QueryRow(`SELECT id, uuid, code, name, rate, inclusive, payable_account_id, receivable_account_id
+		FROM tax_codes `+where, arg).Scan(&tc.ID, &tcUUID, &tc.Code, &name, &rate, &tc.Inclusive, &payableID, &receivableID)
+	if err != nil {
+		return nil, err
+	}
+	tc.UUID, err = uuid.FromBytes(tcUUID)
+	if err != nil {
+		return nil, err
+	}
+	tc.Name = name.String
+	tc.Rate = NewDecimal(rate)
+	if payableID.Valid {
+		tc.PayableAccount, err = GetAccountByID(db, int(payableID.Int64))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if receivableID.Valid {
+		tc.ReceivableAccount, err = GetAccountByID(db, int(receivableID.Int64))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &tc, nil
+}
+
+func GetTaxCodeByID(db *sql.DB, id int) (*TaxCode, error) {
+	return scanTaxCode(db, "WHERE id=?", id)
+}
+
+func GetTaxCodeByUUID(db *sql.DB, tcUUID []byte) (*TaxCode, error) {
+	return scanTaxCode(db, "WHERE uuid=?", tcUUID)
+}
+
+func GetTaxCodeByCode(db *sql.DB, code string) (*TaxCode, error) {
+	return scanTaxCode(db, "WHERE code=?", code)
+}
+
+// id, uuid or code, in that order
+func resolveTaxCode(db *sql.DB, tc *TaxCode) (*TaxCode, error) {
+	if tc.ID > 0 {
+		return GetTaxCodeByID(db, tc.ID)
+	}
+	if tc.UUID != uuid.Nil {
+		return GetTaxCodeByUUID(db, tc.UUID[:])
+	}
+	return GetTaxCodeByCode(db, tc.Code)
+}
+
+// value of the line in its currency. the price of a value adjustment is the
+// amount.
+func lineAmount(l *TransactionLine) Decimal {
+	if l.Item == nil {
+		return l.Quantity
+	}
+	if l.ValueAdjustment {
+		return l.Price
+	}
+	return l.Quantity.Multiply(l.Price)
+}
+
+// net amount and tax of the line. inclusive lines get their quantity (or
+// price for item lines) reduced to the net amount.
+func applyLineTax(l *TransactionLine, tc *TaxCode) (Decimal, Decimal) {
+	gross := lineAmount(l)
+	if !tc.Inclusive {
+		return gross, gross.Multiply(tc.Rate)
+	}
+	onePlusRate := NewDecimal(int64(tc.Rate.FracDivisor) + tc.Rate.Data)
+	switch {
+	case l.Item == nil:
+		l.Quantity = gross.Divide(onePlusRate)
+	case l.ValueAdjustment:
+		l.Price = gross.Divide(onePlusRate)
+	default:
+		l.Price = l.Price.Divide(onePlusRate)
+	}
+	base := lineAmount(l)
+	return base, NewDecimal(gross.Data - base.Data)
+}
+
+// appends a tax line after every taxed line. inclusive lines are reduced to
+// their net amount so the transaction stays balanced as given.
+func expandTaxLines(db *sql.DB, lines []*TransactionLine) ([]*TransactionLine, []*taxEntry, error) {
+	var result []*TransactionLine
+	var entries []*taxEntry
+	for _, l := range lines {
+		result = append(result, l)
+		if l.TaxCode == nil {
+			continue
+		}
+		tc, err := resolveTaxCode(db, l.TaxCode)
+		if err != nil {
+			return nil, nil, fmt.Errorf("tax code %q: %w", l.TaxCode.Code, err)
+		}
+		l.TaxCode = tc
+
+		gross := lineAmount(l)
+		if gross.Data == 0 || tc.Rate.Data == 0 {
+			continue
+		}
+		base, tax := applyLineTax(l, tc)
+
+		acc, direction := tc.ReceivableAccount, TaxReceivable
+		if gross.Data < 0 {
+			acc, direction = tc.PayableAccount, TaxPayable
+		}
+		if acc == nil {
+			return nil, nil, fmt.Errorf("%s %s: %w", tc.Code, direction, ErrNoTaxAccount)
+		}
+
+		taxLine := CreateFinancialTrLine(acc, tax, NewDecimal(0), l.Currency)
+		taxLine.Note = strings.TrimSpace(fmt.Sprintf("%s %s", tc.Code, l.Note))
+		result = append(result, taxLine)
+
+		entries = append(entries, &taxEntry{
+			baseLine: l, taxLine: taxLine, taxCode: tc, direction: direction, base: base, tax: tax,
+		})
+	}
+	return result, entries, nil
+}
+
+func insertTaxEntriesTx(tx *sql.Tx, trID int64, date time.Time, entries []*taxEntry) error {
+	for _, e := range entries {
+		entryUUID, err := uuid.NewV7()
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO tax_entries (uuid, transaction_id, base_line_id, tax_line_id, tax_code_id,
+			direction, base, tax, currency, datetime_ms, year, month) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			entryUUID[:], trID, e.baseLine.ID, e.taxLine.ID, e.taxCode.ID,
+			e.direction, e.base, e.tax, e.baseLine.Currency, date.UnixMilli(), date.Year(), int(date.Month()))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// totals per month, tax code and currency. amounts are positive, payable
+// being output tax on sales and receivable input tax on purchases.
+func GetTaxSummary(db *sql.DB, fromMs, toMs int64) ([]TaxSummary, error) {
+	rows, err := db.Query(`
+		SELECT e.year, e.month, e.tax_code_id, e.currency,
+			COALESCE(SUM(CASE WHEN e.direction = ? THEN -e.base END), 0),
+			COALESCE(SUM(CASE WHEN e.direction = ? THEN -e.tax END), 0),
+			COALESCE(SUM(CASE WHEN e.direction = ? THEN e.base END), 0),
+			COALESCE(SUM(CASE WHEN e.direction = ? THEN e.tax END), 0)
+		FROM tax_entries e
+		WHERE e.datetime_ms >= ? AND e.datetime_ms <= ?
+		GROUP BY e.year, e.month, e.tax_code_id, e.currency
+		ORDER BY e.year, e.month, e.tax_code_id, e.currency`,
+		TaxPayable, TaxPayable, TaxReceivable, TaxReceivable, fromMs, toMs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []TaxSummary
+	var codeIDs []int
+	for rows.Next() {
+		var s TaxSummary
+		var codeID int
+		var currency sql.NullString
+		var payableBase, payableTax, receivableBase, receivableTax int64
+		err = rows.Scan(&s.Year, &s.Month, &codeID, &currency, &payableBase, &payableTax, &receivableBase, &receivableTax)
+		if err != nil {
+			return nil, err
+		}
+		s.Currency = currency.String
+		s.PayableBase = NewDecimal(payableBase)
+		s.PayableTax = NewDecimal(payableTax)
+		s.ReceivableBase = NewDecimal(receivableBase)
+		s.ReceivableTax = NewDecimal(receivableTax)
+		summaries = append(summaries, s)
+		codeIDs = append(codeIDs, codeID)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	codes := map[int]*TaxCode{}
+	for i, id := range codeIDs {
+		tc, ok := codes[id]
+		if !ok {
+			tc, err = GetTaxCodeByID(db, id)
+			if err != nil {
+				return nil, err
+			}
+			codes[id] = tc
+		}
+		summaries[i].TaxCode = tc
+	}
+	return summaries, nil
+}
+
+func SprintTaxSummary(db *sql.DB, fromMs, toMs int64) (string, error) {
+	summaries, err := GetTaxSummary(db, fromMs, toMs)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.WriteString("=== Tax Summary ===\n")
+	sb.WriteString(fmt.Sprintf("%-8s %-10s %-4s %14s %14s %14s %14s %14s\n",
+		"Period", "Code", "Cur", "Sales", "Output Tax", "Purchases", "Input Tax", "Net Payable"))
+	for _, s := range summaries {
+		net := NewDecimal(s.PayableTax.Data - s.ReceivableTax.Data)
+		sb.WriteString(fmt.Sprintf("%04d-%02d  %-10s %-4s %14s %14s %14s %14s %14s\n",
+			s.Year, s.Month, s.TaxCode.Code, s.Currency,
+			s.PayableBase.ToString(), s.PayableTax.ToString(),
+			s.ReceivableBase.ToString(), s.ReceivableTax.ToString(), net.ToString()))
+	}
+	return sb.String(), nil
+}
+
+func PrintTaxSummary(db *sql.DB, fromMs, toMs int64) error {
+	str, err := SprintTaxSummary(db, fromMs, toMs)
+	if err != nil {
+		return err
+	}
+	fmt.Print(str)
+	return nil
+}
@@ -0,0 +1,489 @@
+package inventory
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ParamDecimal = "decimal"
+	ParamItem    = "item"
+	ParamAccount = "account"
+	ParamDate    = "date"
+	ParamText    = "text"
+)
+
+var ErrUnknownParamType = errors.New("param type must be decimal, item, account, date or text")
+var ErrDuplicateParam = errors.New("param declared twice")
+var ErrUndeclaredParam = errors.New("param not declared")
+var ErrParamTypeMismatch = errors.New("param has the wrong type for the field")
+var ErrMissingParam = errors.New("param has no value and no default")
+var ErrInvalidExpression = errors.New("invalid template expression")
+var ErrTemplateLineNoAccount = errors.New("template line has no account")
+
+// a template field refers to a param as $name. Account and Item are a param
+// or a literal uuid or name. Quantity and Price are a product of params and
+// numbers with an optional leading minus, e.g. "-$qty" or "$qty*$price"; for
+// lines without an item Quantity is the signed amount. Unit, Currency, Note
+// and the template Description get params substituted as text. an empty
+// Datetime posts at the time of instantiation.
+type TransactionTemplate struct {
+	ID          int
+	UUID        uuid.UUID
+	Name        string
+	Description string
+	Datetime    string
+	Params      []TemplateParam
+	Lines       []*TemplateLine
+}
+
+type TemplateParam struct {
+	Name    string
+	Type    string
+	Default string
+}
+
+type TemplateLine struct {
+	Account  string
+	Item     string
+	Quantity string
+	Unit     string
+	Price    string
+	Currency string
+	Note     string
+}
+
+var paramRefRe = regexp.MustCompile(`\$([A-Za-z_][A-Za-z0-9_]*)`)
+var paramNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type templateValue struct {
+	param   TemplateParam
+	text    string
+	decimal Decimal
+	item    *Item
+	account *Account
+	ms      int64
+}
+
+func (t *TransactionTemplate) paramTypes() (map[string]string, error) {
+	types := map[string]string{}
+	for _, p := range t.Params {
+		if !paramNameRe.MatchString(p.Name) {
+			return nil, fmt.Errorf("param %q: %w", p.Name, ErrInvalidExpression)
+		}
+		switch p.Type {
+		case ParamDecimal, ParamItem, ParamAccount, ParamDate, ParamText:
+		default:
+			return nil, fmt.Errorf("param %s: %w", p.Name, ErrUnknownParamType)
+		}
+		if _, ok := types[p.Name]; ok {
+			return nil, fmt.Errorf("param %s: %w", p.Name, ErrDuplicateParam)
+		}
+		types[p.Name] = p.Type
+	}
+	return types, nil
+}
+
+// a single $param of the given type, or a literal when literalOk
+func checkRef(field string, types map[string]string, wantType string, literalOk bool) error {
+	field = strings.TrimSpace(field)
+	if !strings.HasPrefix(field, "$") {
+		if literalOk {
+			return nil
+		}
+		return fmt.Errorf("%q: %w", field, ErrInvalidExpression)
+	}
+	name := field[1:]
+	paramType, ok := types[name]
+	if !ok {
+		return fmt.Errorf("$%s: %w", name, ErrUndeclaredParam)
+	}
+	if paramType != wantType {
+		return fmt.Errorf("$%s is %s, want %s: %w", name, paramType, wantType, ErrParamTypeMismatch)
+	}
+	return nil
+}
+
+func checkText(field string, types map[string]string) error {
+	for _, m := range paramRefRe.FindAllStringSubmatch(field, -1) {
+		if _, ok := types[m[1]]; !ok {
+			return fmt.Errorf("$%s: %w", m[1], ErrUndeclaredParam)
+		}
+	}
+	return nil
+}
+
+// evaluates expr, or only checks it when values is nil
+func evalExpression(expr string, types map[string]string, values map[string]*templateValue) (Decimal, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return NewDecimal(0), nil
+	}
+	neg := strings.HasPrefix(expr, "-")
+	if neg {
+		expr = expr[1:]
+	}
+	var result Decimal
+	for i, factor := range strings.Split(expr, "*") {
+		factor = strings.TrimSpace(factor)
+		var value Decimal
+		if strings.HasPrefix(factor, "$") {
+			if err := checkRef(factor, types, ParamDecimal, false); err != nil {
+				return NewDecimal(0), err
+			}
+			if values != nil {
+				value = values[factor[1:]].decimal
+			}
+		} else {
+			var err error
+			value, err = ParseDecimal(factor)
+			if err != nil {
+				return NewDecimal(0), fmt.Errorf("%q: %w", expr, ErrInvalidExpression)
+			}
+		}
+		if values == nil {
+			continue
+		}
+		if i == 0 {
+			result = value
+		} else {
+			result = result.Multiply(value)
+		}
+	}
+	if values == nil {
+		return NewDecimal(0), nil
+	}
+	if neg {
+		result = NewDecimal(-result.Data)
+	}
+	return result, nil
+}
+
+func (t *TransactionTemplate) validate() error {
+	types, err := t.paramTypes()
+	if err != nil {
+		return err
+	}
+	if len(t.Lines) == 0 {
+		return ErrEmptyTemplate
+	}
+	if t.Datetime != "" {
+		if err := checkRef(t.Datetime, types, ParamDate, true); err != nil {
+			return err
+		}
+	}
+	if err := checkText(t.Description, types); err != nil {
+		return err
+	}
+	for i, l := range t.Lines {
+		wrap := func(err error) error {
+			return fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if strings.TrimSpace(l.Account) == "" {
+			return wrap(ErrTemplateLineNoAccount)
+		}
+		if err := checkRef(l.Account, types, ParamAccount, true); err != nil {
+			return wrap(err)
+		}
+		if l.Item != "" {
+			if err := checkRef(l.Item, types, ParamItem, true); err != nil {
+				return wrap(err)
+			}
+		}
+		if _, err := evalExpression(l.Quantity, types, nil); err != nil {
+			return wrap(err)
+		}
+		if _, err := evalExpression(l.Price, types, nil); err != nil {
+			return wrap(err)
+		}
+		for _, text := range []string{l.Unit, l.Currency, l.Note} {
+			if err := checkText(text, types); err != nil {
+				return wrap(err)
+			}
+		}
+	}
+	return nil
+}
+
+func findAccountByRef(db *sql.DB, ref string) (*Account, error) {
+	if accUUID, err := uuid.Parse(ref); err == nil {
+		return GetAccountByUUID(db, accUUID[:])
+	}
+	return GetAccountByName(db, ref)
+}
+
+func (t *TransactionTemplate) resolveValues(db *sql.DB, args map[string]string) (map[string]*templateValue, error) {
+	declared := map[string]bool{}
+	for _, p := range t.Params {
+		declared[p.Name] = true
+	}
+	for name := range args {
+		if !declared[name] {
+			return nil, fmt.Errorf("$%s: %w", name, ErrUndeclaredParam)
+		}
+	}
+
+	values := map[string]*templateValue{}
+	for _, p := range t.Params {
+		str, ok := args[p.Name]
+		if !ok {
+			str = p.Default
+		}
+		if str == "" && p.Type != ParamText {
+			return nil, fmt.Errorf("$%s: %w", p.Name, ErrMissingParam)
+		}
+		v := &templateValue{param: p, text: str}
+		var err error
+		switch p.Type {
+		case ParamDecimal:
+			v.decimal, err = ParseDecimal(str)
+		case ParamItem:
+			v.item, err = findItemForImport(db, str)
+			if err == nil {
+				v.text = v.item.Name
+			}
+		case ParamAccount:
+			v.account, err = findAccountByRef(db, str)
+			if err == nil {
+				v.text = v.account.Name
+			}
+		case ParamDate:
+			v.ms, err = ParseDatetimeMs(str)
+			if err == nil {
+				v.text = time.UnixMilli(v.ms).Format("2006-01-02")
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("$%s %q: %w", p.Name, str, err)
+		}
+		values[p.Name] = v
+	}
+	return values, nil
+}
+
+func substitute(text string, values map[string]*templateValue) string {
+	return paramRefRe.ReplaceAllStringFunc(text, func(ref string) string {
+		if v, ok := values[ref[1:]]; ok {
+			return v.text
+		}
+		return ref
+	})
+}
+
+// builds the transaction from the template and args, without applying it
+func (t *TransactionTemplate) Instantiate(db *sql.DB, args map[string]string, nowMs int64) (*Transaction, error) {
+	types, err := t.paramTypes()
+	if err != nil {
+		return nil, err
+	}
+	if err = t.validate(); err != nil {
+		return nil, err
+	}
+	values, err := t.resolveValues(db, args)
+	if err != nil {
+		return nil, err
+	}
+
+	tr := &Transaction{
+		Description: substitute(t.Description, values),
+		DatetimeMs:  nowMs,
+	}
+	if datetime := strings.TrimSpace(t.Datetime); datetime != "" {
+		if strings.HasPrefix(datetime, "$") {
+			tr.DatetimeMs = values[datetime[1:]].ms
+		} else if tr.DatetimeMs, err = ParseDatetimeMs(datetime); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, l := range t.Lines {
+		wrap := func(err error) error {
+			return fmt.Errorf("line %d: %w", i+1, err)
+		}
+		line := &TransactionLine{
+			Unit:     substitute(l.Unit, values),
+			Currency: substitute(l.Currency, values),
+			Note:     substitute(l.Note, values),
+		}
+		if ref := strings.TrimSpace(l.Account); strings.HasPrefix(ref, "$") {
+			line.Account = values[ref[1:]].account
+		} else if line.Account, err = findAccountByRef(db, ref); err != nil {
+			return nil, wrap(fmt.Errorf("account %q: %w", ref, err))
+		}
+		if ref := strings.TrimSpace(l.Item); strings.HasPrefix(ref, "$") {
+			line.Item = values[ref[1:]].item
+		} else if ref != "" {
+			if line.Item, err = findItemForImport(db, ref); err != nil {
+				return nil, wrap(fmt.Errorf("item %q: %w", ref, err))
+			}
+		}
+		if line.Quantity, err = evalExpression(l.Quantity, types, values); err != nil {
+			return nil, wrap(err)
+		}
+		if line.Price, err = evalExpression(l.Price, types, values); err != nil {
+			return nil, wrap(err)
+		}
+		if line.Item != nil && line.Unit == "" {
+			line.Unit = line.Item.Unit
+		}
+		tr.TransactionLines = append(tr.TransactionLines, line)
+	}
+	return tr, nil
+}
+
+func AddTransactionTemplate(db *sql.DB, t *TransactionTemplate) ([]byte, error) {
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	tmplUUID, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO transaction_templates (uuid, name, description, datetime) VALUES (?, ?, ?, ?)`,
+		tmplUUID[:], t.Name, t.Description, t.Datetime)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range t.Params {
+		_, err = tx.Exec(`INSERT INTO transaction_template_params (template_id, name, type, default_value) VALUES (?, ?, ?, ?)`,
+			id, p.Name, p.Type, p.Default)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, l := range t.Lines {
+		_, err = tx.Exec(`INSERT INTO transaction_template_lines (template_id, account, item, quantity, unit, price, currency, note)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, id, l.Account, l.Item, l.Quantity, l.Unit, l.Price, l.Currency, l.Note)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	t.ID = int(id)
+	t.UUID = tmplUUID
+	return tmplUUID[:], nil
+}
+
+func scanTransactionTemplates(db *sql.DB, where string, args ...any) ([]*TransactionTemplate, error) {
+	rows, err := db.Query(`SELECT id, uuid, name, description, datetime FROM transaction_templates `+where+` ORDER BY name, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	var templates []*TransactionTemplate
+	for rows.Next() {
+		t := &TransactionTemplate{}
+		var description, datetime sql.NullString
+		if err = rows.Scan(&t.ID, &t.UUID, &t.Name, &description, &datetime); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		t.Description, t.Datetime = description.String, datetime.String
+		templates = append(templates, t)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, t := range templates {
+		rows, err := db.Query(`SELECT name, type, default_value FROM transaction_template_params WHERE template_id=? ORDER BY id`, t.ID)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var p TemplateParam
+			var def sql.NullString
+			if err = rows.Scan(&p.Name, &p.Type, &def); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			p.Default = def.String
+			t.Params = append(t.Params, p)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+
+		rows, err = db.Query(`SELECT account, item, quantity, unit, price, currency, note FROM transaction_template_lines WHERE template_id=? ORDER BY id`, t.ID)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var f [7]sql.NullString
+			if err = rows.Scan(&f[0], &f[1], &f[2], &f[3], &f[4], &f[5], &f[6]); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			t.Lines = append(t.Lines, &TemplateLine{
+				Account: f[0].String, Item: f[1].String, Quantity: f[2].String, Unit: f[3].String,
+				Price: f[4].String, Currency: f[5].String, Note: f[6].String,
+			})
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return templates, nil
+}
+
+func GetTransactionTemplates(db *sql.DB) ([]*TransactionTemplate, error) {
+	return scanTransactionTemplates(db, "")
+}
+
+func GetTransactionTemplateByUUID(db *sql.DB, tmplUUID []byte) (*TransactionTemplate, error) {
+	templates, err := scanTransactionTemplates(db, "WHERE uuid=?", tmplUUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return templates[0], nil
+}
+
+func GetTransactionTemplateByName(db *sql.DB, name string) (*TransactionTemplate, error) {
+	templates, err := scanTransactionTemplates(db, "WHERE name=?", name)
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return templates[0], nil
+}
+
+// instantiates the stored template and applies it
+func ApplyTransactionTemplate(db *sql.DB, t *TransactionTemplate, args map[string]string) ([]byte, *Transaction, error) {
+	tr, err := t.Instantiate(db, args, time.Now().UnixMilli())
+	if err != nil {
+		return nil, nil, err
+	}
+	trUUID, err := ApplyTransaction(db, tr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return trUUID, tr, nil
+}
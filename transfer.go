@@ -0,0 +1,431 @@
+package inventory
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TransferShipped  = "shipped"
+	TransferReceived = "received"
+)
+
+const (
+	SettingInTransitAccount           = "in_transit_account"
+	SettingTransferDiscrepancyAccount = "transfer_discrepancy_account"
+)
+
+var ErrNoInTransitAccount = errors.New("in transit account not set")
+var ErrNoTransferDiscrepancyAccount = errors.New("transfer discrepancy account not set")
+var ErrEmptyTransfer = errors.New("transfer order has no lines")
+var ErrInvalidTransferQuantity = errors.New("transfer quantity must be positive")
+var ErrInsufficientStock = errors.New("not enough stock to ship")
+var ErrTransferNotOpen = errors.New("transfer order is not open")
+var ErrUnknownTransferLine = errors.New("item is not on the transfer order")
+
+// shipping moves the goods from From to the in transit account at their
+// average cost, receiving moves them on to To. a short or over delivery is
+// booked to the discrepancy account at the shipped cost.
+type TransferOrder struct {
+	ID                 int
+	UUID               uuid.UUID
+	Number             string
+	From               *Account
+	To                 *Account
+	Note               string
+	Status             string
+	ShippedDatetimeMs  int64
+	ReceivedDatetimeMs int64
+	Lines              []*TransferLine
+	ShipTransaction    *Transaction
+	ReceiveTransaction *Transaction
+}
+
+// UnitCost is filled in on shipping, Received on receiving
+type TransferLine struct {
+	ID       int
+	Item     *Item
+	Quantity Decimal
+	Unit     string
+	UnitCost Decimal
+	Received Decimal
+}
+
+// an order line left out of Lines is taken as not arrived. no lines at all
+// means everything arrived as shipped.
+type TransferReceipt struct {
+	Order      *TransferOrder
+	DatetimeMs int64
+	Lines      []TransferReceiptLine
+}
+
+type TransferReceiptLine struct {
+	Item     *Item
+	Quantity Decimal
+}
+
+func SetInTransitAccount(db *sql.DB, acc *Account) error {
+	return SetAccountSetting(db, SettingInTransitAccount, acc)
+}
+
+func GetInTransitAccount(db *sql.DB) (*Account, error) {
+	acc, err := GetAccountSetting(db, SettingInTransitAccount)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoInTransitAccount
+	}
+	return acc, err
+}
+
+func SetTransferDiscrepancyAccount(db *sql.DB, acc *Account) error {
+	return SetAccountSetting(db, SettingTransferDiscrepancyAccount, acc)
+}
+
+func GetTransferDiscrepancyAccount(db *sql.DB) (*Account, error) {
+	acc, err := GetAccountSetting(db, SettingTransferDiscrepancyAccount)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoTransferDiscrepancyAccount
+	}
+	return acc, err
+}
+
+// quantity and total cost of the item on the account as of datetimeMs
+func balanceAt(db *sql.DB, acc *Account, item *Item, datetimeMs int64) (Decimal, Decimal, error) {
+	qty, total := NewDecimal(0), NewDecimal(0)
+	err := db.QueryRow(`
+		SELECT h.quantity, h.total_cost FROM balance_history h
+		JOIN transactions t ON h.transaction_id = t.id
+		WHERE h.account_id=? AND h.item_id=? AND t.datetime_ms <= ?
+		ORDER BY t.datetime_ms DESC, h.id DESC LIMIT 1`, acc.ID, item.ID, datetimeMs).Scan(&qty, &total)
+	if err == sql.ErrNoRows {
+		return NewDecimal(0), NewDecimal(0), nil
+	}
+	return qty, total, err
+}
+
+func ShipTransferOrder(db *sql.DB, o *TransferOrder) ([]byte, error) {
+	if len(o.Lines) == 0 {
+		return nil, ErrEmptyTransfer
+	}
+	if err := resolveAccount(db, o.From); err != nil {
+		return nil, err
+	}
+	if err := resolveAccount(db, o.To); err != nil {
+		return nil, err
+	}
+	inTransit, err := GetInTransitAccount(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []*TransactionLine
+	shipping := map[int]int64{}
+	for _, l := range o.Lines {
+		if l.Quantity.Data <= 0 {
+			return nil, ErrInvalidTransferQuantity
+		}
+		if _, err := resolveItemID(db, l.Item); err != nil {
+			return nil, err
+		}
+		if l.Unit == "" {
+			l.Unit = l.Item.Unit
+		}
+		onHand, total, err := balanceAt(db, o.From, l.Item, o.ShippedDatetimeMs)
+		if err != nil {
+			return nil, err
+		}
+		shipping[l.Item.ID] += l.Quantity.Data
+		if onHand.Data < shipping[l.Item.ID] {
+			return nil, fmt.Errorf("%s has %s %s: %w", o.From.Name, onHand.ToString(), l.Item.Name, ErrInsufficientStock)
+		}
+		l.UnitCost = total.Divide(onHand)
+		lines = append(lines,
+			CreateInventoryTrLine(o.From, l.Item, NewDecimal(-l.Quantity.Data), l.Unit, l.UnitCost, ""),
+			CreateInventoryTrLine(inTransit, l.Item, l.Quantity, l.Unit, l.UnitCost, ""))
+	}
+
+	description := strings.TrimSpace(fmt.Sprintf("Transfer %s shipped: %s to %s", o.Number, o.From.Name, o.To.Name))
+	transaction := &Transaction{
+		Description:      description,
+		DatetimeMs:       o.ShippedDatetimeMs,
+		TransactionLines: lines,
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, trID, err := applyTransactionTx(db, tx, transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	orderUUID, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	res, err := tx.Exec(`INSERT INTO transfer_orders (uuid, number, from_account_id, to_account_id, note, status, ship_transaction_id, shipped_datetime_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		orderUUID[:], o.Number, o.From.ID, o.To.ID, o.Note, TransferShipped, trID, o.ShippedDatetimeMs)
+	if err != nil {
+		return nil, err
+	}
+	orderID, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range o.Lines {
+		res, err = tx.Exec(`INSERT INTO transfer_order_lines (transfer_order_id, item_id, unit, quantity, unit_cost)
+			VALUES (?, ?, ?, ?, ?)`, orderID, l.Item.ID, l.Unit, l.Quantity, l.UnitCost)
+		if err != nil {
+			return nil, err
+		}
+		lineID, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		l.ID = int(lineID)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	publishTransactions(db, trID)
+	o.ID = int(orderID)
+	o.UUID = orderUUID
+	o.Status = TransferShipped
+	o.ShipTransaction = transaction
+	return orderUUID[:], nil
+}
+
+func ReceiveTransferOrder(db *sql.DB, r *TransferReceipt) ([]byte, error) {
+	if r.Order == nil {
+		return nil, sql.ErrNoRows
+	}
+	o, err := GetTransferOrderByUUID(db, r.Order.UUID[:])
+	if err != nil {
+		return nil, err
+	}
+	if o.Status != TransferShipped {
+		return nil, fmt.Errorf("%s %s: %w", o.Number, o.Status, ErrTransferNotOpen)
+	}
+	inTransit, err := GetInTransitAccount(db)
+	if err != nil {
+		return nil, err
+	}
+
+	received := map[int]int64{}
+	for _, l := range o.Lines {
+		received[l.Item.ID] = 0
+		if len(r.Lines) == 0 {
+			received[l.Item.ID] += l.Quantity.Data
+		}
+	}
+	for _, rl := range r.Lines {
+		if _, err := resolveItemID(db, rl.Item); err != nil {
+			return nil, err
+		}
+		if _, ok := received[rl.Item.ID]; !ok {
+			return nil, fmt.Errorf("%s: %w", rl.Item.Name, ErrUnknownTransferLine)
+		}
+		if rl.Quantity.Data < 0 {
+			return nil, ErrInvalidTransferQuantity
+		}
+		received[rl.Item.ID] += rl.Quantity.Data
+	}
+
+	var discrepancyAcc *Account
+	var lines []*TransactionLine
+	for _, l := range o.Lines {
+		// an item on several lines is received in line order, the last line
+		// takes any excess
+		qty := received[l.Item.ID]
+		if qty > l.Quantity.Data && !lastLineOfItem(o.Lines, l) {
+			qty = l.Quantity.Data
+		}
+		received[l.Item.ID] -= qty
+		l.Received = NewDecimal(qty)
+
+		lines = append(lines, CreateInventoryTrLine(inTransit, l.Item, NewDecimal(-l.Quantity.Data), l.Unit, l.UnitCost, ""))
+		if qty > 0 {
+			lines = append(lines, CreateInventoryTrLine(o.To, l.Item, l.Received, l.Unit, l.UnitCost, ""))
+		}
+		if diff := l.Quantity.Data - qty; diff != 0 {
+			if discrepancyAcc == nil {
+				discrepancyAcc, err = GetTransferDiscrepancyAccount(db)
+				if err != nil {
+					return nil, err
+				}
+			}
+			discLine := CreateInventoryTrLine(discrepancyAcc, l.Item, NewDecimal(diff), l.Unit, l.UnitCost, "")
+			discLine.Note = fmt.Sprintf("shipped %s received %s", l.Quantity.ToString(), l.Received.ToString())
+			lines = append(lines, discLine)
+		}
+	}
+
+	description := strings.TrimSpace(fmt.Sprintf("Transfer %s received: %s to %s", o.Number, o.From.Name, o.To.Name))
+	transaction := &Transaction{
+		Description:      description,
+		DatetimeMs:       r.DatetimeMs,
+		TransactionLines: lines,
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	trUUID, trID, err := applyTransactionTx(db, tx, transaction)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(`UPDATE transfer_orders SET status=?, receive_transaction_id=?, received_datetime_ms=? WHERE id=?`,
+		TransferReceived, trID, r.DatetimeMs, o.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range o.Lines {
+		_, err = tx.Exec(`UPDATE transfer_order_lines SET received_quantity=? WHERE id=?`, l.Received, l.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	publishTransactions(db, trID)
+	o.Status = TransferReceived
+	o.ReceivedDatetimeMs = r.DatetimeMs
+	o.ReceiveTransaction = transaction
+	r.Order = o
+	return trUUID, nil
+}
+
+func lastLineOfItem(lines []*TransferLine, l *TransferLine) bool {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if lines[i].Item.ID == l.Item.ID {
+			return lines[i] == l
+		}
+	}
+	return false
+}
+
+func scanTransferOrders(db *sql.DB, where string, args ...any) ([]*TransferOrder, error) {
+	rows, err := db.Query(`
+		SELECT id, uuid, number, from_account_id, to_account_id, note, status, shipped_datetime_ms, received_datetime_ms
+		FROM transfer_orders `+where+` ORDER BY shipped_datetime_ms, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	type accIDs struct{ from, to int }
+	var orders []*TransferOrder
+	var ids []accIDs
+	for rows.Next() {
+		o := &TransferOrder{}
+		var number, note sql.NullString
+		var receivedMs sql.NullInt64
+		var a accIDs
+		err = rows.Scan(&o.ID, &o.UUID, &number, &a.from, &a.to, &note, &o.Status, &o.ShippedDatetimeMs, &receivedMs)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		o.Number = number.String
+		o.Note = note.String
+		o.ReceivedDatetimeMs = receivedMs.Int64
+		orders = append(orders, o)
+		ids = append(ids, a)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, o := range orders {
+		o.From, err = GetAccountByID(db, ids[i].from)
+		if err != nil {
+			return nil, err
+		}
+		o.To, err = GetAccountByID(db, ids[i].to)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := db.Query(`
+			SELECT l.id, l.unit, l.quantity, l.unit_cost, l.received_quantity, i.id, i.uuid, i.name, i.unit
+			FROM transfer_order_lines l JOIN items i ON l.item_id = i.id
+			WHERE l.transfer_order_id=? ORDER BY l.id`, o.ID)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			l := &TransferLine{Item: &Item{}, Quantity: NewDecimal(0), UnitCost: NewDecimal(0), Received: NewDecimal(0)}
+			var itemUnit sql.NullString
+			var received sql.NullInt64
+			err = rows.Scan(&l.ID, &l.Unit, &l.Quantity, &l.UnitCost, &received, &l.Item.ID, &l.Item.UUID, &l.Item.Name, &itemUnit)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			l.Received = NewDecimal(received.Int64)
+			l.Item.Unit = itemUnit.String
+			o.Lines = append(o.Lines, l)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return orders, nil
+}
+
+func GetTransferOrderByUUID(db *sql.DB, orderUUID []byte) (*TransferOrder, error) {
+	orders, err := scanTransferOrders(db, "WHERE uuid=?", orderUUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return orders[0], nil
+}
+
+// shipped but not yet received
+func GetOpenTransferOrders(db *sql.DB) ([]*TransferOrder, error) {
+	return scanTransferOrders(db, "WHERE status=?", TransferShipped)
+}
+
+func SprintOpenTransferOrders(db *sql.DB) (string, error) {
+	orders, err := GetOpenTransferOrders(db)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.WriteString("=== Open Transfer Orders ===\n")
+	for _, o := range orders {
+		sb.WriteString(fmt.Sprintf("%-10s | shipped %s | %s -> %s\n", o.Number,
+			time.UnixMilli(o.ShippedDatetimeMs).Format("2006-01-02"), o.From.Name, o.To.Name))
+		for _, l := range o.Lines {
+			sb.WriteString(fmt.Sprintf("    %-20s %s %s @ %s\n", l.Item.Name, l.Quantity.ToString(), l.Unit, l.UnitCost.ToString()))
+		}
+	}
+	return sb.String(), nil
+}
+
+func PrintOpenTransferOrders(db *sql.DB) error {
+	str, err := SprintOpenTransferOrders(db)
+	if err != nil {
+		return err
+	}
+	fmt.Print(str)
+	return nil
+}
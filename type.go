@@ -41,44 +41,50 @@ func NewDecimalFromFloat(fdata float64) Decimal {
 	return NewDecimal(int64(fdata * fracDivisor))
 }
 
-func NewDecimalFromStr(str string) Decimal {
-	var intPart int64
-	var fracPart int64
-	var fracStr string
-	strs := strings.Split(str, ".")
-	if len(strs) >= 1 {
-		iTmp, _ := strconv.Atoi(strs[0])
-		intPart = int64(iTmp)
-		if len(strs) > 1 {
-			fracStr = strs[1]
-		} else {
-			fracStr = "0"
-		}
+var ErrInvalidDecimal = errors.New("invalid decimal")
+
+// digits past DECIMALPRECISION are truncated
+func ParseDecimal(str string) (Decimal, error) {
+	str = strings.TrimSpace(str)
+	neg := false
+	if strings.HasPrefix(str, "-") || strings.HasPrefix(str, "+") {
+		neg = str[0] == '-'
+		str = str[1:]
+	}
+	intStr, fracStr, _ := strings.Cut(str, ".")
+	if intStr == "" && fracStr == "" {
+		return NewDecimal(0), ErrInvalidDecimal
 	}
-	frontZeroCount := 0
-	nonPaddedCount := 0
-	nonPaddedStr := ""
-	for _, c := range fracStr {
-		if nonPaddedCount == 0 {
-			if c == '0' {
-				frontZeroCount++
-			} else {
-				nonPaddedCount++
-				nonPaddedStr += string(c)
-			}
-		} else {
-			nonPaddedCount++
+	for _, c := range intStr + fracStr {
+		if c < '0' || c > '9' {
+			return NewDecimal(0), ErrInvalidDecimal
 		}
 	}
-	decPrecZeroDigitCount := DECIMALPRECISION
-	// misal 0.02, zeroCount = 1, nonPaddedCount = 1. (2 * 10^2)/10000
-	// misal 0.0002, zeroCount = 3, nonPaddedCount = 1 -> (2 * 10^0)/10000
-	// misal 0.2, zeroCount = 0, nonPaddedCount = 1 -> (2 * 10^3)/10000
-	// misal 0.20, zeroCount = 0, nonPaddedCount = 2 -> (20 * 10^2)/10000
-	powFactor := decPrecZeroDigitCount - (frontZeroCount + nonPaddedCount)
-	nonPaddedInt, _ := strconv.Atoi(nonPaddedStr)
-	fracPart = int64(nonPaddedInt) * int64(math.Pow10(powFactor))
-	return NewDecimalFromIntFrac(intPart, fracPart)
+	if intStr == "" {
+		intStr = "0"
+	}
+	intPart, err := strconv.ParseInt(intStr, 10, 64)
+	if err != nil {
+		return NewDecimal(0), ErrInvalidDecimal
+	}
+	if len(fracStr) > DECIMALPRECISION {
+		fracStr = fracStr[:DECIMALPRECISION]
+	}
+	fracStr += strings.Repeat("0", DECIMALPRECISION-len(fracStr))
+	fracPart, err := strconv.ParseInt(fracStr, 10, 64)
+	if err != nil {
+		return NewDecimal(0), ErrInvalidDecimal
+	}
+	d := NewDecimalFromIntFrac(intPart, fracPart)
+	if neg {
+		d.Data = -d.Data
+	}
+	return d, nil
+}
+
+func NewDecimalFromStr(str string) Decimal {
+	d, _ := ParseDecimal(str)
+	return d
 }
 
 func (d Decimal) ToFloat() float64 {
@@ -103,6 +109,9 @@ func (d Decimal) ToString() string {
 	if frac < 0 {
 		frac *= -1
 	}
+	if d.Data < 0 && d.Data > -int64(d.FracDivisor) {
+		return "-" + fmt.Sprintf(d.Format, 0, frac)
+	}
 	return fmt.Sprintf(d.Format, d.Data/int64(d.FracDivisor), frac)
 }
 
@@ -120,14 +129,10 @@ func (d Decimal) Value() (driver.Value, error) {
 	return d.Data, nil
 }
 
+// the quotient is truncated toward zero, -1 / 3 is -0.3333
 func (d Decimal) Divide(divisor Decimal) Decimal {
 	// d as dividend
-	tmp := d.Data * int64(d.FracDivisor) / divisor.Data
-	if tmp >= 0 {
-		return NewDecimalFromIntFrac(tmp/int64(d.FracDivisor), tmp%int64(d.FracDivisor))
-	} else {
-		return NewDecimalFromIntFrac(tmp/int64(d.FracDivisor), (-tmp % int64(d.FracDivisor)))
-	}
+	return NewDecimal(d.Data * int64(d.FracDivisor) / divisor.Data)
 }
 
 func (d Decimal) Multiply(multiplicand Decimal) Decimal {